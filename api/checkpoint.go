@@ -0,0 +1,60 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// checkpointTimestampPrefix is the prefix of the checkpoint extension line which records when the checkpoint was created.
+const checkpointTimestampPrefix = "timestamp "
+
+// FormatCheckpointTimestamp returns the checkpoint extension line which records that a checkpoint was created at t,
+// as included in checkpoints by logs configured with tessera.AppendOptions.WithCheckpointTimestamp.
+//
+// The line holds t as milliseconds since the Unix epoch, e.g. "timestamp 1700000000000".
+func FormatCheckpointTimestamp(t time.Time) string {
+	return checkpointTimestampPrefix + strconv.FormatInt(t.UnixMilli(), 10)
+}
+
+// CheckpointTimestamp returns the time recorded by the timestamp extension line in the provided checkpoint,
+// which may be either the raw signed checkpoint or just its body. The final return value is false if the
+// checkpoint has no timestamp line.
+//
+// The checkpoint is not verified, so this should only be used with checkpoints which already have been,
+// e.g. the raw checkpoint returned by client.FetchCheckpoint.
+func CheckpointTimestamp(cp []byte) (time.Time, bool, error) {
+	body, _, _ := strings.Cut(string(cp), "\n\n")
+	lines := strings.Split(body, "\n")
+	if len(lines) < 3 {
+		return time.Time{}, false, fmt.Errorf("invalid checkpoint: %q", cp)
+	}
+	// Extension lines follow the origin, size, and root hash lines.
+	for _, l := range lines[3:] {
+		v, ok := strings.CutPrefix(l, checkpointTimestampPrefix)
+		if !ok {
+			continue
+		}
+		ms, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("invalid checkpoint timestamp %q: %v", l, err)
+		}
+		return time.UnixMilli(ms), true, nil
+	}
+	return time.Time{}, false, nil
+}
@@ -0,0 +1,65 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCheckpointTimestamp(t *testing.T) {
+	ts := time.UnixMilli(1700000000123)
+	body := "example.com/log\n1\nAAAA\n"
+	for _, test := range []struct {
+		name    string
+		cp      string
+		want    time.Time
+		wantOK  bool
+		wantErr bool
+	}{
+		{
+			name: "no timestamp",
+			cp:   body + "\n— example.com/log sig\n",
+		}, {
+			name:   "body",
+			cp:     body + "extra\n" + FormatCheckpointTimestamp(ts) + "\n",
+			want:   ts,
+			wantOK: true,
+		}, {
+			name:   "signed",
+			cp:     body + FormatCheckpointTimestamp(ts) + "\n\n— example.com/log sig\n",
+			want:   ts,
+			wantOK: true,
+		}, {
+			name:    "malformed timestamp",
+			cp:      body + "timestamp yesterday\n",
+			wantErr: true,
+		}, {
+			name:    "not a checkpoint",
+			cp:      "nope",
+			wantErr: true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok, err := CheckpointTimestamp([]byte(test.cp))
+			if gotErr := err != nil; gotErr != test.wantErr {
+				t.Fatalf("CheckpointTimestamp: got err %v, want err %t", err, test.wantErr)
+			}
+			if ok != test.wantOK || !got.Equal(test.want) {
+				t.Errorf("CheckpointTimestamp: got (%v, %t), want (%v, %t)", got, ok, test.want, test.wantOK)
+			}
+		})
+	}
+}
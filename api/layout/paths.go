@@ -30,8 +30,18 @@ import (
 const (
 	// CheckpointPath is the location of the file containing the log checkpoint.
 	CheckpointPath = "checkpoint"
+
+	// DefaultShardDigits is the number of decimal digits in each directory name of the "N" part of
+	// a tlog-tiles path, e.g. "x001/x234/067".
+	DefaultShardDigits = 3
 )
 
+// ValidShardDigits returns true if d is a supported number of decimal digits per directory name for
+// ShardedTilePath and ShardedEntriesPath, i.e. 2, 3, or 4.
+func ValidShardDigits(d int) bool {
+	return d >= 2 && d <= 4
+}
+
 // EntriesPathForLogIndex builds the local path at which the leaf with the given index lives in.
 // Note that this will be an entry bundle containing up to 256 entries and thus multiple
 // indices can map to the same output path.
@@ -89,6 +99,38 @@ func Range(from, N, treeSize uint64) iter.Seq[RangeInfo] {
 	}
 }
 
+// TilePathsForSize returns the paths of all the tiles which make up a tree of the given size.
+//
+// This comprises every full tile, plus the partial tile at the right-hand edge of any level
+// whose width is not a multiple of TileWidth. A tree of size 0 has no tiles.
+func TilePathsForSize(size uint64) []string {
+	r := []string{}
+	for level, n := uint64(0), size; n > 0; level, n = level+1, n>>TileHeight {
+		for index := range n / TileWidth {
+			r = append(r, TilePath(level, index, 0))
+		}
+		if p := uint8(n % TileWidth); p > 0 {
+			r = append(r, TilePath(level, n/TileWidth, p))
+		}
+	}
+	return r
+}
+
+// EntryBundlePathsForSize returns the paths of all the entry bundles which make up a log of the given size.
+//
+// This comprises every full entry bundle, plus the partial bundle holding the most recent entries if
+// size is not a multiple of EntryBundleWidth. A log of size 0 has no entry bundles.
+func EntryBundlePathsForSize(size uint64) []string {
+	r := []string{}
+	for index := range size / EntryBundleWidth {
+		r = append(r, EntriesPath(index, 0))
+	}
+	if p := uint8(size % EntryBundleWidth); p > 0 {
+		r = append(r, EntriesPath(size/EntryBundleWidth, p))
+	}
+	return r
+}
+
 // RangeInfo describes a specific range of elements within a particular bundle/tile.
 //
 // Usage:
@@ -123,11 +165,63 @@ func EntriesPath(n uint64, p uint8) string {
 }
 
 // TilePath builds the path to the subtree tile with the given level and index in tile space.
-// If p > 0 the path represents a partial tile.
+// If p > 0 the path represents a partial tile of width p.
 func TilePath(tileLevel, tileIndex uint64, p uint8) string {
 	return fmt.Sprintf("tile/%d/%s", tileLevel, NWithSuffix(tileLevel, tileIndex, p))
 }
 
+// ShardedEntriesPath is like EntriesPath, but the digits of n are grouped into directory names of d digits
+// each, rather than DefaultShardDigits, e.g. "tile/entries/x01/x23/40/67" for d = 2. This allows the number
+// of entries in each directory to be tuned to the filesystem, at the cost of not following the tlog-tiles
+// spec unless d = DefaultShardDigits.
+//
+// Panics if d is not valid according to ValidShardDigits.
+func ShardedEntriesPath(d int, n uint64, p uint8) string {
+	return fmt.Sprintf("tile/entries/%s", shardedNWithSuffix(d, n, p))
+}
+
+// ShardedTilePath is like TilePath, but the digits of tileIndex are grouped into directory names of d
+// digits each, as described by ShardedEntriesPath.
+//
+// Panics if d is not valid according to ValidShardDigits.
+func ShardedTilePath(d int, tileLevel, tileIndex uint64, p uint8) string {
+	return fmt.Sprintf("tile/%d/%s", tileLevel, shardedNWithSuffix(d, tileIndex, p))
+}
+
+// shardedNWithSuffix returns the "N" path with d digits per directory, with a partial suffix if p > 0.
+func shardedNWithSuffix(d int, n uint64, p uint8) string {
+	suffix := ""
+	if p > 0 {
+		suffix = fmt.Sprintf(".p/%d", p)
+	}
+	return fmt.Sprintf("%s%s", fmtNDigits(n, d), suffix)
+}
+
+// FlatEntriesPath returns the path for the nth entry bundle in the flat layout, in which the digit groups of
+// n are separated with "-" rather than "/" so that bundles aren't spread across nested directories, e.g.
+// "tile/entries/x001-x234-067". p denotes the partial tile size, or 0 if the tile is complete, and is
+// appended as it is by EntriesPath.
+//
+// The flat layout is not part of the tlog-tiles spec, so logs stored this way can't be served statically.
+func FlatEntriesPath(n uint64, p uint8) string {
+	return fmt.Sprintf("tile/entries/%s", flatNWithSuffix(n, p))
+}
+
+// FlatTilePath builds the path to the subtree tile with the given level and index in tile space in the flat
+// layout described by FlatEntriesPath, e.g. "tile/0/x001-x234-067". If p > 0 the path represents a partial tile.
+func FlatTilePath(tileLevel, tileIndex uint64, p uint8) string {
+	return fmt.Sprintf("tile/%d/%s", tileLevel, flatNWithSuffix(tileIndex, p))
+}
+
+// flatNWithSuffix returns the "N" path of the flat layout, with a partial suffix if p > 0.
+func flatNWithSuffix(n uint64, p uint8) string {
+	suffix := ""
+	if p > 0 {
+		suffix = fmt.Sprintf(".p/%d", p)
+	}
+	return fmt.Sprintf("%s%s", strings.ReplaceAll(fmtN(n), "/", "-"), suffix)
+}
+
 // fmtN returns the "N" part of a Tiles-spec path.
 //
 // N is grouped into chunks of 3 decimal digits, starting with the most significant digit, and
@@ -137,15 +231,163 @@ func TilePath(tileLevel, tileIndex uint64, p uint8) string {
 //
 // See https://github.com/C2SP/C2SP/blob/main/tlog-tiles.md#:~:text=index%201234067%20will%20be%20encoded%20as%20x001/x234/067
 func fmtN(N uint64) string {
-	n := fmt.Sprintf("%03d", N%1000)
-	N /= 1000
+	return fmtNDigits(N, DefaultShardDigits)
+}
+
+// fmtNDigits is like fmtN, but groups N into chunks of d decimal digits.
+func fmtNDigits(N uint64, d int) string {
+	if !ValidShardDigits(d) {
+		panic(fmt.Sprintf("invalid number of shard digits %d", d))
+	}
+	mod := uint64(math.Pow10(d))
+	n := fmt.Sprintf("%0*d", d, N%mod)
+	N /= mod
 	for N > 0 {
-		n = fmt.Sprintf("x%03d/%s", N%1000, n)
-		N /= 1000
+		n = fmt.Sprintf("x%0*d/%s", d, N%mod, n)
+		N /= mod
 	}
 	return n
 }
 
+// ParseTilePath parses a tile path, as built by TilePath, into the level, index, and partial width of the tile.
+// A single leading "/", as found in HTTP request paths, is permitted.
+//
+// Only paths in their canonical form are accepted, e.g. "tile/0/x001/067.p/8" but not "tile/0/x001/067.p/08".
+func ParseTilePath(path string) (level, index uint64, partial uint8, err error) {
+	path = strings.TrimPrefix(path, "/")
+	rest, ok := strings.CutPrefix(path, "tile/")
+	if !ok {
+		return 0, 0, 0, fmt.Errorf("not a tile path: %q", path)
+	}
+	l, n, ok := strings.Cut(rest, "/")
+	if !ok || l == "entries" {
+		return 0, 0, 0, fmt.Errorf("not a tile path: %q", path)
+	}
+	level, index, partial, err = ParseTileLevelIndexPartial(l, n)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	if TilePath(level, index, partial) != path {
+		return 0, 0, 0, fmt.Errorf("non-canonical tile path: %q", path)
+	}
+	return level, index, partial, nil
+}
+
+// ParseEntriesPath parses an entry bundle path, as built by EntriesPath, into the index and partial width
+// of the bundle. A single leading "/", as found in HTTP request paths, is permitted.
+//
+// Only paths in their canonical form are accepted, e.g. "tile/entries/x001/067.p/8" but not "tile/entries/x001/067.p/08".
+func ParseEntriesPath(path string) (index uint64, partial uint8, err error) {
+	path = strings.TrimPrefix(path, "/")
+	n, ok := strings.CutPrefix(path, "tile/entries/")
+	if !ok {
+		return 0, 0, fmt.Errorf("not an entry bundle path: %q", path)
+	}
+	index, partial, err = ParseTileIndexPartial(n)
+	if err != nil {
+		return 0, 0, err
+	}
+	if EntriesPath(index, partial) != path {
+		return 0, 0, fmt.Errorf("non-canonical entry bundle path: %q", path)
+	}
+	return index, partial, nil
+}
+
+// ParseShardedTilePath parses a tile path with d digits per directory name, as built by ShardedTilePath, into the
+// level, index, and partial width of the tile. A single leading "/" is permitted.
+//
+// Only paths in their canonical form are accepted.
+func ParseShardedTilePath(d int, path string) (level, index uint64, partial uint8, err error) {
+	if !ValidShardDigits(d) {
+		return 0, 0, 0, fmt.Errorf("invalid number of shard digits %d", d)
+	}
+	path = strings.TrimPrefix(path, "/")
+	rest, ok := strings.CutPrefix(path, "tile/")
+	if !ok {
+		return 0, 0, 0, fmt.Errorf("not a tile path: %q", path)
+	}
+	l, n, ok := strings.Cut(rest, "/")
+	if !ok || l == "entries" {
+		return 0, 0, 0, fmt.Errorf("not a tile path: %q", path)
+	}
+	if level, err = ParseTileLevel(l); err != nil {
+		return 0, 0, 0, err
+	}
+	if index, partial, err = parseTileIndexPartial(n, d); err != nil {
+		return 0, 0, 0, err
+	}
+	if ShardedTilePath(d, level, index, partial) != path {
+		return 0, 0, 0, fmt.Errorf("non-canonical tile path: %q", path)
+	}
+	return level, index, partial, nil
+}
+
+// ParseShardedEntriesPath parses an entry bundle path with d digits per directory name, as built by
+// ShardedEntriesPath, into the index and partial width of the bundle. A single leading "/" is permitted.
+//
+// Only paths in their canonical form are accepted.
+func ParseShardedEntriesPath(d int, path string) (index uint64, partial uint8, err error) {
+	if !ValidShardDigits(d) {
+		return 0, 0, fmt.Errorf("invalid number of shard digits %d", d)
+	}
+	path = strings.TrimPrefix(path, "/")
+	n, ok := strings.CutPrefix(path, "tile/entries/")
+	if !ok {
+		return 0, 0, fmt.Errorf("not an entry bundle path: %q", path)
+	}
+	if index, partial, err = parseTileIndexPartial(n, d); err != nil {
+		return 0, 0, err
+	}
+	if ShardedEntriesPath(d, index, partial) != path {
+		return 0, 0, fmt.Errorf("non-canonical entry bundle path: %q", path)
+	}
+	return index, partial, nil
+}
+
+// ParseFlatTilePath parses a tile path in the flat layout, as built by FlatTilePath, into the level, index, and
+// partial width of the tile. A single leading "/" is permitted.
+//
+// Only paths in their canonical form are accepted.
+func ParseFlatTilePath(path string) (level, index uint64, partial uint8, err error) {
+	path = strings.TrimPrefix(path, "/")
+	rest, ok := strings.CutPrefix(path, "tile/")
+	if !ok {
+		return 0, 0, 0, fmt.Errorf("not a tile path: %q", path)
+	}
+	l, n, ok := strings.Cut(rest, "/")
+	if !ok || l == "entries" {
+		return 0, 0, 0, fmt.Errorf("not a tile path: %q", path)
+	}
+	level, index, partial, err = ParseTileLevelIndexPartial(l, strings.ReplaceAll(n, "-", "/"))
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	if FlatTilePath(level, index, partial) != path {
+		return 0, 0, 0, fmt.Errorf("non-canonical tile path: %q", path)
+	}
+	return level, index, partial, nil
+}
+
+// ParseFlatEntriesPath parses an entry bundle path in the flat layout, as built by FlatEntriesPath, into the
+// index and partial width of the bundle. A single leading "/" is permitted.
+//
+// Only paths in their canonical form are accepted.
+func ParseFlatEntriesPath(path string) (index uint64, partial uint8, err error) {
+	path = strings.TrimPrefix(path, "/")
+	n, ok := strings.CutPrefix(path, "tile/entries/")
+	if !ok {
+		return 0, 0, fmt.Errorf("not an entry bundle path: %q", path)
+	}
+	index, partial, err = ParseTileIndexPartial(strings.ReplaceAll(n, "-", "/"))
+	if err != nil {
+		return 0, 0, err
+	}
+	if FlatEntriesPath(index, partial) != path {
+		return 0, 0, fmt.Errorf("non-canonical entry bundle path: %q", path)
+	}
+	return index, partial, nil
+}
+
 // ParseTileLevelIndexPartial takes level and index in string, validates and returns the level, index and width in uint64.
 //
 // Examples:
@@ -177,6 +419,11 @@ func ParseTileLevel(level string) (uint64, error) {
 
 // ParseTileIndexPartial takes index in string, validates and returns the index and width in uint64.
 func ParseTileIndexPartial(index string) (uint64, uint8, error) {
+	return parseTileIndexPartial(index, DefaultShardDigits)
+}
+
+// parseTileIndexPartial is like ParseTileIndexPartial, but expects d digits per directory name.
+func parseTileIndexPartial(index string, d int) (uint64, uint8, error) {
 	w := uint8(0)
 	indexPaths := strings.Split(index, "/")
 
@@ -195,17 +442,18 @@ func ParseTileIndexPartial(index string) (uint64, uint8, error) {
 		return 0, 0, fmt.Errorf("failed to parse tile index")
 	}
 
+	mod := uint64(math.Pow10(d))
 	i := uint64(0)
 	for _, indexPath := range indexPaths {
 		indexPath = strings.TrimPrefix(indexPath, "x")
 		n, err := strconv.ParseUint(indexPath, 10, 64)
-		if err != nil || n >= 1000 || len(indexPath) != 3 {
+		if err != nil || n >= mod || len(indexPath) != d {
 			return 0, 0, fmt.Errorf("failed to parse tile index")
 		}
-		if i > (math.MaxUint64-n)/1000 {
+		if i > (math.MaxUint64-n)/mod {
 			return 0, 0, fmt.Errorf("failed to parse tile index")
 		}
-		i = i*1000 + n
+		i = i*mod + n
 	}
 
 	return i, w, nil
@@ -16,6 +16,7 @@ package layout
 
 import (
 	"fmt"
+	"math"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -313,6 +314,246 @@ func TestParseTileLevelIndexPartial(t *testing.T) {
 	}
 }
 
+func TestParseTilePath(t *testing.T) {
+	for _, test := range []struct {
+		path      string
+		wantLevel uint64
+		wantIndex uint64
+		wantP     uint8
+		wantErr   bool
+	}{
+		{
+			path:      "tile/0/000",
+			wantLevel: 0,
+			wantIndex: 0,
+		}, {
+			path:      "/tile/0/x001/x234/067.p/89",
+			wantLevel: 0,
+			wantIndex: 1234067,
+			wantP:     89,
+		}, {
+			path:      "tile/63/x018/x446/x744/x073/x709/x551/615",
+			wantLevel: 63,
+			wantIndex: math.MaxUint64,
+		}, {
+			path:    "tile/63/x018/x446/x744/x073/x709/x551/616",
+			wantErr: true,
+		}, {
+			path:    "tile/0/x000/001",
+			wantErr: true,
+		}, {
+			path:    "tile/0/001.p/08",
+			wantErr: true,
+		}, {
+			path:    "tile/00/001",
+			wantErr: true,
+		}, {
+			path:    "tile/0/001.p/0",
+			wantErr: true,
+		}, {
+			path:    "tile/entries/001",
+			wantErr: true,
+		}, {
+			path:    "tile/0",
+			wantErr: true,
+		}, {
+			path:    "//tile/0/001",
+			wantErr: true,
+		}, {
+			path:    "checkpoint",
+			wantErr: true,
+		},
+	} {
+		t.Run(test.path, func(t *testing.T) {
+			gotLevel, gotIndex, gotP, err := ParseTilePath(test.path)
+			if gotErr := err != nil; gotErr != test.wantErr {
+				t.Fatalf("got err %v want err %v", err, test.wantErr)
+			}
+			if gotLevel != test.wantLevel || gotIndex != test.wantIndex || gotP != test.wantP {
+				t.Errorf("got (%d, %d, %d) want (%d, %d, %d)", gotLevel, gotIndex, gotP, test.wantLevel, test.wantIndex, test.wantP)
+			}
+		})
+	}
+}
+
+func TestParseEntriesPath(t *testing.T) {
+	for _, test := range []struct {
+		path      string
+		wantIndex uint64
+		wantP     uint8
+		wantErr   bool
+	}{
+		{
+			path:      "tile/entries/000",
+			wantIndex: 0,
+		}, {
+			path:      "/tile/entries/x001/x234/067.p/255",
+			wantIndex: 1234067,
+			wantP:     255,
+		}, {
+			path:      "tile/entries/x018/x446/x744/x073/x709/x551/615",
+			wantIndex: math.MaxUint64,
+		}, {
+			path:    "tile/entries/x018/x446/x744/x073/x709/x551/616",
+			wantErr: true,
+		}, {
+			path:    "tile/entries/x000/001",
+			wantErr: true,
+		}, {
+			path:    "tile/entries/001.p/256",
+			wantErr: true,
+		}, {
+			path:    "tile/entries/001.p/",
+			wantErr: true,
+		}, {
+			path:    "tile/0/001",
+			wantErr: true,
+		}, {
+			path:    "tile/entries/",
+			wantErr: true,
+		},
+	} {
+		t.Run(test.path, func(t *testing.T) {
+			gotIndex, gotP, err := ParseEntriesPath(test.path)
+			if gotErr := err != nil; gotErr != test.wantErr {
+				t.Fatalf("got err %v want err %v", err, test.wantErr)
+			}
+			if gotIndex != test.wantIndex || gotP != test.wantP {
+				t.Errorf("got (%d, %d) want (%d, %d)", gotIndex, gotP, test.wantIndex, test.wantP)
+			}
+		})
+	}
+}
+
+func TestParsePathsRoundTrip(t *testing.T) {
+	for _, index := range []uint64{0, 1, 999, 1000, 1234067, math.MaxUint64 - 1, math.MaxUint64} {
+		for _, p := range []uint8{0, 1, 128, 255} {
+			for _, level := range []uint64{0, 1, 63} {
+				tp := TilePath(level, index, p)
+				gotLevel, gotIndex, gotP, err := ParseTilePath(tp)
+				if err != nil {
+					t.Fatalf("ParseTilePath(%q): %v", tp, err)
+				}
+				if gotLevel != level || gotIndex != index || gotP != p {
+					t.Errorf("ParseTilePath(%q): got (%d, %d, %d) want (%d, %d, %d)", tp, gotLevel, gotIndex, gotP, level, index, p)
+				}
+			}
+			ep := EntriesPath(index, p)
+			gotIndex, gotP, err := ParseEntriesPath(ep)
+			if err != nil {
+				t.Fatalf("ParseEntriesPath(%q): %v", ep, err)
+			}
+			if gotIndex != index || gotP != p {
+				t.Errorf("ParseEntriesPath(%q): got (%d, %d) want (%d, %d)", ep, gotIndex, gotP, index, p)
+			}
+		}
+	}
+}
+
+func TestFlatPaths(t *testing.T) {
+	for _, test := range []struct {
+		level, index uint64
+		p            uint8
+		wantTile     string
+		wantEntries  string
+	}{
+		{index: 0, wantTile: "tile/0/000", wantEntries: "tile/entries/000"},
+		{level: 1, index: 1234067, wantTile: "tile/1/x001-x234-067", wantEntries: "tile/entries/x001-x234-067"},
+		{index: 1234067, p: 8, wantTile: "tile/0/x001-x234-067.p/8", wantEntries: "tile/entries/x001-x234-067.p/8"},
+	} {
+		if got := FlatTilePath(test.level, test.index, test.p); got != test.wantTile {
+			t.Errorf("FlatTilePath(%d, %d, %d) = %q, want %q", test.level, test.index, test.p, got, test.wantTile)
+		}
+		if got := FlatEntriesPath(test.index, test.p); got != test.wantEntries {
+			t.Errorf("FlatEntriesPath(%d, %d) = %q, want %q", test.index, test.p, got, test.wantEntries)
+		}
+	}
+
+	for _, index := range []uint64{0, 1, 999, 1000, 1234067, math.MaxUint64} {
+		for _, p := range []uint8{0, 1, 255} {
+			tp := FlatTilePath(3, index, p)
+			if gotLevel, gotIndex, gotP, err := ParseFlatTilePath(tp); err != nil || gotLevel != 3 || gotIndex != index || gotP != p {
+				t.Errorf("ParseFlatTilePath(%q) = (%d, %d, %d), %v, want (3, %d, %d)", tp, gotLevel, gotIndex, gotP, err, index, p)
+			}
+			ep := FlatEntriesPath(index, p)
+			if gotIndex, gotP, err := ParseFlatEntriesPath(ep); err != nil || gotIndex != index || gotP != p {
+				t.Errorf("ParseFlatEntriesPath(%q) = (%d, %d), %v, want (%d, %d)", ep, gotIndex, gotP, err, index, p)
+			}
+		}
+	}
+
+	for _, path := range []string{"tile/0/x001/x234/067", "tile/0/x001-x234/067", "tile/0/x000-001", "tile/entries/001", "tile/0"} {
+		if _, _, _, err := ParseFlatTilePath(path); err == nil {
+			t.Errorf("ParseFlatTilePath(%q) succeeded", path)
+		}
+	}
+	for _, path := range []string{"tile/entries/x001/x234/067", "tile/entries/x000-001", "tile/0/001", "tile/entries/001.p-8"} {
+		if _, _, err := ParseFlatEntriesPath(path); err == nil {
+			t.Errorf("ParseFlatEntriesPath(%q) succeeded", path)
+		}
+	}
+}
+
+func TestShardedPaths(t *testing.T) {
+	for _, test := range []struct {
+		d            int
+		level, index uint64
+		p            uint8
+		wantTile     string
+		wantEntries  string
+	}{
+		{d: 2, index: 0, wantTile: "tile/0/00", wantEntries: "tile/entries/00"},
+		{d: 2, level: 1, index: 1234067, wantTile: "tile/1/x01/x23/x40/67", wantEntries: "tile/entries/x01/x23/x40/67"},
+		{d: 3, index: 1234067, p: 8, wantTile: "tile/0/x001/x234/067.p/8", wantEntries: "tile/entries/x001/x234/067.p/8"},
+		{d: 4, index: 1234067, p: 8, wantTile: "tile/0/x0123/4067.p/8", wantEntries: "tile/entries/x0123/4067.p/8"},
+	} {
+		if got := ShardedTilePath(test.d, test.level, test.index, test.p); got != test.wantTile {
+			t.Errorf("ShardedTilePath(%d, %d, %d, %d) = %q, want %q", test.d, test.level, test.index, test.p, got, test.wantTile)
+		}
+		if got := ShardedEntriesPath(test.d, test.index, test.p); got != test.wantEntries {
+			t.Errorf("ShardedEntriesPath(%d, %d, %d) = %q, want %q", test.d, test.index, test.p, got, test.wantEntries)
+		}
+	}
+
+	for d := 2; d <= 4; d++ {
+		for _, index := range []uint64{0, 1, 99, 100, 9999, 10000, 1234067, math.MaxUint64} {
+			for _, p := range []uint8{0, 1, 255} {
+				tp := ShardedTilePath(d, 3, index, p)
+				if d == DefaultShardDigits && tp != TilePath(3, index, p) {
+					t.Errorf("ShardedTilePath(%d, 3, %d, %d) = %q, want %q", d, index, p, tp, TilePath(3, index, p))
+				}
+				if gotLevel, gotIndex, gotP, err := ParseShardedTilePath(d, tp); err != nil || gotLevel != 3 || gotIndex != index || gotP != p {
+					t.Errorf("ParseShardedTilePath(%d, %q) = (%d, %d, %d), %v, want (3, %d, %d)", d, tp, gotLevel, gotIndex, gotP, err, index, p)
+				}
+				ep := ShardedEntriesPath(d, index, p)
+				if gotIndex, gotP, err := ParseShardedEntriesPath(d, ep); err != nil || gotIndex != index || gotP != p {
+					t.Errorf("ParseShardedEntriesPath(%d, %q) = (%d, %d), %v, want (%d, %d)", d, ep, gotIndex, gotP, err, index, p)
+				}
+			}
+		}
+	}
+
+	// Paths with a different number of digits per directory are rejected.
+	for _, path := range []string{"tile/0/x001/067", "tile/0/x1/67", "tile/0/x01/067", "tile/entries/01", "tile/0"} {
+		if _, _, _, err := ParseShardedTilePath(2, path); err == nil {
+			t.Errorf("ParseShardedTilePath(2, %q) succeeded", path)
+		}
+	}
+	for _, path := range []string{"tile/entries/x001/067", "tile/entries/x01/067", "tile/0/01", "tile/entries/00.p/0"} {
+		if _, _, err := ParseShardedEntriesPath(2, path); err == nil {
+			t.Errorf("ParseShardedEntriesPath(2, %q) succeeded", path)
+		}
+	}
+	for _, d := range []int{0, 1, 5} {
+		if ValidShardDigits(d) {
+			t.Errorf("ValidShardDigits(%d) = true", d)
+		}
+		if _, _, err := ParseShardedEntriesPath(d, "tile/entries/000"); err == nil {
+			t.Errorf("ParseShardedEntriesPath(%d, ...) succeeded", d)
+		}
+	}
+}
+
 func TestRange(t *testing.T) {
 	for _, test := range []struct {
 		from, N, treeSize uint64
@@ -398,3 +639,67 @@ func TestRange(t *testing.T) {
 		})
 	}
 }
+
+func TestTilePathsForSize(t *testing.T) {
+	for _, test := range []struct {
+		size uint64
+		want []string
+	}{
+		{
+			size: 0,
+			want: []string{},
+		}, {
+			size: 1,
+			want: []string{"tile/0/000.p/1"},
+		}, {
+			size: 256,
+			want: []string{"tile/0/000", "tile/1/000.p/1"},
+		}, {
+			size: 3*256 + 7,
+			want: []string{"tile/0/000", "tile/0/001", "tile/0/002", "tile/0/003.p/7", "tile/1/000.p/3"},
+		},
+	} {
+		t.Run(fmt.Sprintf("size %d", test.size), func(t *testing.T) {
+			got := TilePathsForSize(test.size)
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("unexpected paths (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	// A tree of 256*256 entries has 256 full level 0 tiles, one full level 1 tile, and a single node at level 2.
+	got := TilePathsForSize(256 * 256)
+	if len(got) != 256+2 {
+		t.Fatalf("got %d paths, want %d", len(got), 256+2)
+	}
+	if diff := cmp.Diff([]string{"tile/0/000", "tile/0/255", "tile/1/000", "tile/2/000.p/1"}, []string{got[0], got[255], got[256], got[257]}); diff != "" {
+		t.Errorf("unexpected paths (-want +got):\n%s", diff)
+	}
+}
+
+func TestEntryBundlePathsForSize(t *testing.T) {
+	for _, test := range []struct {
+		size uint64
+		want []string
+	}{
+		{
+			size: 0,
+			want: []string{},
+		}, {
+			size: 255,
+			want: []string{"tile/entries/000.p/255"},
+		}, {
+			size: 512,
+			want: []string{"tile/entries/000", "tile/entries/001"},
+		}, {
+			size: 513,
+			want: []string{"tile/entries/000", "tile/entries/001", "tile/entries/002.p/1"},
+		},
+	} {
+		t.Run(fmt.Sprintf("size %d", test.size), func(t *testing.T) {
+			if diff := cmp.Diff(test.want, EntryBundlePathsForSize(test.size)); diff != "" {
+				t.Errorf("unexpected paths (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"bytes"
 	"crypto/sha256"
 	"encoding/binary"
+	"errors"
 	"fmt"
 
 	"github.com/transparency-dev/tessera/api/layout"
@@ -63,6 +64,19 @@ func (t *HashTile) UnmarshalText(raw []byte) error {
 	return nil
 }
 
+var (
+	// ErrBundleFormat is returned when an entry bundle is not correctly encoded. It is wrapped by the more
+	// specific errors below, and callers should check for it using `errors.Is(e, ErrBundleFormat)`.
+	ErrBundleFormat = errors.New("malformed entry bundle")
+	// ErrBundleTruncated is a wrapped ErrBundleFormat. It is returned when an entry bundle ends part way
+	// through an entry, or contains fewer entries than expected, as may happen if it was only partially
+	// written or uploaded.
+	ErrBundleTruncated = fmt.Errorf("truncated %w", ErrBundleFormat)
+	// ErrBundleOverlong is a wrapped ErrBundleFormat. It is returned when an entry bundle contains more than
+	// layout.EntryBundleWidth entries.
+	ErrBundleOverlong = fmt.Errorf("overlong %w", ErrBundleFormat)
+)
+
 // EntryBundle represents a sequence of entries in the log.
 // These entries correspond to a leaf tile in the hash tree.
 type EntryBundle struct {
@@ -77,12 +91,12 @@ func (t *EntryBundle) UnmarshalText(raw []byte) error {
 	for index := 0; index < len(raw); {
 		dataIndex := index + 2
 		if dataIndex > len(raw) {
-			return fmt.Errorf("dangling bytes at byte index %d in data of %d bytes", index, len(raw))
+			return fmt.Errorf("%w: dangling bytes at byte index %d in data of %d bytes", ErrBundleTruncated, index, len(raw))
 		}
 		size := int(binary.BigEndian.Uint16(raw[index:dataIndex]))
 		dataEnd := dataIndex + size
 		if dataEnd > len(raw) {
-			return fmt.Errorf("require %d bytes from byte index %d, but size is %d", size, dataIndex, len(raw))
+			return fmt.Errorf("%w: require %d bytes from byte index %d, but size is %d", ErrBundleTruncated, size, dataIndex, len(raw))
 		}
 		data := raw[dataIndex:dataEnd]
 		nodes = append(nodes, data)
@@ -91,3 +105,19 @@ func (t *EntryBundle) UnmarshalText(raw []byte) error {
 	t.Entries = nodes
 	return nil
 }
+
+// ParseEntryBundle splits an entry bundle encoded using the tlog-tiles spec into the data of the entries
+// it contains, in order. This reverses tessera.Entry.MarshalBundleData.
+//
+// An error wrapping ErrBundleTruncated is returned if the bundle is truncated, or one wrapping ErrBundleOverlong
+// if it contains more than layout.EntryBundleWidth entries.
+func ParseEntryBundle(raw []byte) ([][]byte, error) {
+	eb := &EntryBundle{}
+	if err := eb.UnmarshalText(raw); err != nil {
+		return nil, err
+	}
+	if n := len(eb.Entries); n > layout.EntryBundleWidth {
+		return nil, fmt.Errorf("%w: bundle contains %d entries, more than the maximum of %d", ErrBundleOverlong, n, layout.EntryBundleWidth)
+	}
+	return eb.Entries, nil
+}
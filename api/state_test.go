@@ -19,6 +19,7 @@ import (
 	"bytes"
 	"crypto/rand"
 	"crypto/sha256"
+	"errors"
 	"fmt"
 	"strings"
 	"testing"
@@ -26,6 +27,7 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/transparency-dev/tessera"
 	"github.com/transparency-dev/tessera/api"
+	"github.com/transparency-dev/tessera/api/layout"
 )
 
 func TestHashTile_MarshalTileRoundtrip(t *testing.T) {
@@ -146,6 +148,74 @@ func TestLeafBundle_UnmarshalText(t *testing.T) {
 	}
 }
 
+func TestParseEntryBundle(t *testing.T) {
+	bundle := func(n int) []byte {
+		bs := bytes.Buffer{}
+		for i := range n {
+			_, _ = bs.Write(tessera.NewEntry(fmt.Appendf(nil, "entry %d", i)).MarshalBundleData(uint64(i)))
+		}
+		return bs.Bytes()
+	}
+	full := bundle(layout.EntryBundleWidth)
+
+	for _, test := range []struct {
+		desc    string
+		input   []byte
+		want    int
+		wantErr error
+	}{
+		{
+			desc:  "empty",
+			input: []byte{},
+		}, {
+			desc:  "partial",
+			input: bundle(3),
+			want:  3,
+		}, {
+			desc:  "full",
+			input: full,
+			want:  layout.EntryBundleWidth,
+		}, {
+			desc:    "too many entries",
+			input:   bundle(layout.EntryBundleWidth + 1),
+			wantErr: api.ErrBundleOverlong,
+		}, {
+			desc:    "truncated entry",
+			input:   full[:len(full)-1],
+			wantErr: api.ErrBundleTruncated,
+		}, {
+			desc:    "truncated length",
+			input:   append(bundle(2), 0x00),
+			wantErr: api.ErrBundleTruncated,
+		}, {
+			desc:    "length exceeds data",
+			input:   []byte{0xff, 0xff, 'a'},
+			wantErr: api.ErrBundleTruncated,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			got, err := api.ParseEntryBundle(test.input)
+			if test.wantErr != nil {
+				if !errors.Is(err, test.wantErr) || !errors.Is(err, api.ErrBundleFormat) {
+					t.Fatalf("ParseEntryBundle: %v, want error wrapping %v and %v", err, test.wantErr, api.ErrBundleFormat)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseEntryBundle: %v", err)
+			}
+			if len(got) != test.want {
+				t.Fatalf("ParseEntryBundle returned %d entries, want %d", len(got), test.want)
+			}
+			for i, e := range got {
+				if want := fmt.Sprintf("entry %d", i); string(e) != want {
+					t.Errorf("Entry %d = %q, want %q", i, e, want)
+				}
+			}
+		})
+	}
+}
+
 func BenchmarkLeafBundle_UnmarshalText(b *testing.B) {
 	bs := bytes.Buffer{}
 	for i := range 222 {
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -27,7 +28,9 @@ import (
 	"log/slog"
 
 	f_log "github.com/transparency-dev/formats/log"
+	"github.com/transparency-dev/merkle"
 	"github.com/transparency-dev/merkle/rfc6962"
+	"github.com/transparency-dev/tessera/api"
 	"github.com/transparency-dev/tessera/api/layout"
 	"github.com/transparency-dev/tessera/internal/otel"
 	"github.com/transparency-dev/tessera/internal/parse"
@@ -36,6 +39,7 @@ import (
 	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/mod/sumdb/note"
+	"golang.org/x/time/rate"
 )
 
 const (
@@ -60,6 +64,8 @@ const (
 
 	// DefaultEntrySizeLimit is the maximum possible size of data for a single entry, as specified by C2SP tlog-tiles.
 	DefaultEntrySizeLimit = 1<<16 - 1
+	// DefaultHasherName is the name of the RFC6962 Merkle tree hashing strategy used by logs by default.
+	DefaultHasherName = "rfc6962"
 )
 
 var (
@@ -249,6 +255,27 @@ type Index struct {
 // such as a Shutdown method for #341.
 type Appender struct {
 	Add AddFn
+
+	// idempotency, if set, is used by AddIdempotent.
+	idempotency *idempotentAdder
+}
+
+// AddIdempotent adds the entry to the log unless an entry has previously been added with the same
+// caller-defined key, in which case the index assigned to that earlier entry is returned with IsDup set.
+//
+// This allows clients which retry submissions to avoid adding the same logical entry to the log more than
+// once. Note that, unlike antispam, the key is not derived from the entry, so it's the caller's responsibility
+// to ensure that different entries are not submitted with the same key.
+//
+// The Appender must have been created with AppendOptions.WithIdempotency, otherwise the returned
+// future will resolve to an error.
+func (a *Appender) AddIdempotent(ctx context.Context, key string, e *Entry) IndexFuture {
+	if a.idempotency == nil {
+		return func() (Index, error) {
+			return Index{}, errors.New("appender is not configured for idempotent adds, see AppendOptions.WithIdempotency")
+		}
+	}
+	return a.idempotency.add(ctx, key, e, a.Add)
 }
 
 // NewAppender returns an Appender, which allows a personality to incrementally append new
@@ -281,6 +308,15 @@ func NewAppender(ctx context.Context, d Driver, opts *AppendOptions) (*Appender,
 	if err := opts.valid(); err != nil {
 		return nil, nil, nil, err
 	}
+	if opts.hasherName != DefaultHasherName {
+		// Drivers must opt in to building their trees with a hasher other than the default.
+		type customHasherSupport interface {
+			SupportsCustomHasher() bool
+		}
+		if hs, ok := d.(customHasherSupport); !ok || !hs.SupportsCustomHasher() {
+			return nil, nil, nil, fmt.Errorf("driver %T does not support hasher %q", d, opts.hasherName)
+		}
+	}
 	a, r, err := lc.Appender(ctx, opts)
 	if err != nil {
 		return nil, nil, nil, fmt.Errorf("failed to init appender lifecycle: %v", err)
@@ -288,7 +324,16 @@ func NewAppender(ctx context.Context, d Driver, opts *AppendOptions) (*Appender,
 	for i := len(opts.addDecorators) - 1; i >= 0; i-- {
 		a.Add = opts.addDecorators[i](a.Add)
 	}
+	if opts.rateLimit > 0 {
+		a.Add = rateLimitDecorator(a.Add, rate.NewLimiter(rate.Limit(opts.rateLimit), opts.rateBurst), opts.rateLimitBlock)
+	}
+	if len(opts.entryValidators) > 0 {
+		a.Add = entryValidatorDecorator(a.Add, opts.entryValidators)
+	}
 	a.Add = entrySizeLimitDecorator(a.Add, opts.maxEntrySize)
+	if opts.hasherName != DefaultHasherName {
+		a.Add = leafHasherDecorator(a.Add, opts.hasher)
+	}
 	sd := &integrationStats{}
 	a.Add = sd.statsDecorator(a.Add)
 	for _, f := range opts.followers {
@@ -316,6 +361,10 @@ func NewAppender(ctx context.Context, d Driver, opts *AppendOptions) (*Appender,
 		//		 this remains true.
 		return memoizeFuture(t.Add(ctx, entry))
 	}
+	if opts.idempotency != nil {
+		a.idempotency = newIdempotentAdder(opts.idempotency)
+		a.idempotency.now = opts.now
+	}
 	return a, t.Shutdown, r, nil
 }
 
@@ -332,6 +381,58 @@ func entrySizeLimitDecorator(d AddFn, maxSize uint) AddFn {
 	}
 }
 
+// entryValidatorDecorator wraps a delegate AddFn with logic which will return an error wrapping
+// ErrInvalidEntry if any of the provided validators rejects the entry.
+func entryValidatorDecorator(d AddFn, validators []func(*Entry) error) AddFn {
+	return func(ctx context.Context, entry *Entry) IndexFuture {
+		for _, v := range validators {
+			if err := v(entry); err != nil {
+				return func() (Index, error) {
+					return Index{}, fmt.Errorf("%w: %w", ErrInvalidEntry, err)
+				}
+			}
+		}
+		return d(ctx, entry)
+	}
+}
+
+// rateLimitDecorator wraps a delegate AddFn with logic which only passes entries on to it as fast as
+// the provided limiter allows. Entries which exceed the limit are rejected with an error wrapping
+// ErrRateLimited or, if block is true, are held until the limiter allows them or ctx becomes done.
+func rateLimitDecorator(d AddFn, l *rate.Limiter, block bool) AddFn {
+	return func(ctx context.Context, entry *Entry) IndexFuture {
+		if !block {
+			if !l.Allow() {
+				return func() (Index, error) {
+					return Index{}, ErrRateLimited
+				}
+			}
+			return d(ctx, entry)
+		}
+		if err := l.Wait(ctx); err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				err = ctxErr
+			} else {
+				// The wait would outlast ctx's deadline.
+				err = fmt.Errorf("%w: %v", ErrRateLimited, err)
+			}
+			return func() (Index, error) {
+				return Index{}, err
+			}
+		}
+		return d(ctx, entry)
+	}
+}
+
+// leafHasherDecorator wraps a delegate AddFn with logic which recalculates the leaf hash of entries
+// using the provided hasher.
+func leafHasherDecorator(d AddFn, h merkle.LogHasher) AddFn {
+	return func(ctx context.Context, entry *Entry) IndexFuture {
+		entry.internal.LeafHash = h.HashLeaf(entry.internal.Data)
+		return d(ctx, entry)
+	}
+}
+
 // memoizeFuture wraps an AddFn delegate with logic to ensure that the delegate is called at most
 // once.
 func memoizeFuture(delegate IndexFuture) IndexFuture {
@@ -461,6 +562,8 @@ func (i *integrationStats) statsDecorator(delegate AddFn) AddFn {
 					attr = append(attr, attribute.String("tessera.pushback", "antispam"))
 				case errors.Is(err, ErrPushbackIntegration):
 					attr = append(attr, attribute.String("tessera.pushback", "integration"))
+				case errors.Is(err, ErrRateLimited):
+					attr = append(attr, attribute.String("tessera.pushback", "ratelimit"))
 				case errors.Is(err, ErrPushback):
 					attr = append(attr, attribute.String("tessera.pushback", "other"))
 				default:
@@ -583,6 +686,8 @@ func NewAppendOptions() *AppendOptions {
 		addDecorators:               make([]func(AddFn) AddFn, 0),
 		pushbackMaxOutstanding:      DefaultPushbackMaxOutstanding,
 		garbageCollectionInterval:   DefaultGarbageCollectionInterval,
+		hasherName:                  DefaultHasherName,
+		hasher:                      rfc6962.DefaultHasher,
 	}
 }
 
@@ -590,12 +695,23 @@ func NewAppendOptions() *AppendOptions {
 type AppendOptions struct {
 	// newCP knows how to format and sign checkpoints.
 	newCP func(ctx context.Context, size uint64, hash []byte) ([]byte, error)
+	// checkpointExtraLines are additional body lines to be included in checkpoints created by newCP.
+	checkpointExtraLines []string
+	// checkpointTimestamp, if true, causes checkpoints created by newCP to include a timestamp line.
+	checkpointTimestamp bool
+	// clock, if set, is used in place of time.Now to get the current time.
+	clock func() time.Time
 
 	batchMaxAge  time.Duration
 	batchMaxSize uint
 
 	pushbackMaxOutstanding uint
 
+	// maxPendingEntries is the maximum number of entries waiting to be sequenced, or zero for no limit.
+	maxPendingEntries uint
+	// blockWhenPending controls whether Add waits for space, rather than failing, when maxPendingEntries is reached.
+	blockWhenPending bool
+
 	// EntriesPath knows how to format entry bundle paths.
 	entriesPath func(n uint64, p uint8) string
 
@@ -608,6 +724,18 @@ type AppendOptions struct {
 	// e.g. in their Add() function implementations.
 	maxEntrySize uint
 
+	// entryValidators are called, in order, with each entry passed to Add.
+	entryValidators []func(*Entry) error
+
+	// rateLimit and rateBurst, if rateLimit is non-zero, limit the rate at which entries may be passed to Add.
+	// If rateLimitBlock is true, Add waits for the limit to allow an entry rather than failing.
+	rateLimit      float64
+	rateBurst      int
+	rateLimitBlock bool
+
+	// idempotency, if set, is used to resolve the idempotency keys passed to Appender.AddIdempotent.
+	idempotency IdempotencyStore
+
 	// bundleIDHasher knows how to create antispam leaf identities for entries in a serialised bundle.
 	bundleIDHasher func([]byte) ([][]byte, error)
 
@@ -622,6 +750,10 @@ type AppendOptions struct {
 
 	// garbageCollectionInterval of zero should be interpreted as requesting garbage collection to be disabled.
 	garbageCollectionInterval time.Duration
+
+	// hasherName and hasher describe the Merkle tree hashing strategy used by the log.
+	hasherName string
+	hasher     merkle.LogHasher
 }
 
 // valid returns an error if an invalid combination of options has been set, or nil otherwise.
@@ -632,6 +764,14 @@ func (o AppendOptions) valid() error {
 	if o.checkpointRepublishInterval > 0 && o.checkpointRepublishInterval < o.checkpointInterval {
 		return fmt.Errorf("invalid AppendOptions: WithCheckpointRepublishInterval (%d) is smaller than WithCheckpointInterval (%d)", o.checkpointRepublishInterval, o.checkpointInterval)
 	}
+	if o.rateLimit < 0 || (o.rateLimit > 0 && o.rateBurst < 1) {
+		return fmt.Errorf("invalid AppendOptions: WithRateLimit rate (%v) must not be negative, and burst (%d) must be at least 1", o.rateLimit, o.rateBurst)
+	}
+	for i, l := range o.checkpointExtraLines {
+		if l == "" || strings.Contains(l, "\n") {
+			return fmt.Errorf("invalid AppendOptions: WithCheckpointExtraLines line %d is empty or contains a newline", i)
+		}
+	}
 	return nil
 }
 
@@ -724,6 +864,12 @@ func (o AppendOptions) PushbackMaxOutstanding() uint {
 	return o.pushbackMaxOutstanding
 }
 
+// MaxPendingEntries returns the maximum number of entries which may be waiting to be sequenced, or zero
+// for no limit, and whether Add should block rather than fail when this is reached.
+func (o AppendOptions) MaxPendingEntries() (uint, bool) {
+	return o.maxPendingEntries, o.blockWhenPending
+}
+
 func (o AppendOptions) EntriesPath() func(uint64, uint8) string {
 	return o.entriesPath
 }
@@ -740,6 +886,16 @@ func (o AppendOptions) GarbageCollectionInterval() time.Duration {
 	return o.garbageCollectionInterval
 }
 
+// HasherName returns the name of the Merkle tree hashing strategy used by the log.
+func (o AppendOptions) HasherName() string {
+	return o.hasherName
+}
+
+// Hasher returns the Merkle tree hasher used by the log.
+func (o AppendOptions) Hasher() merkle.LogHasher {
+	return o.hasher
+}
+
 // WithCheckpointSigner is an option for setting the note signer and verifier to use when creating and parsing checkpoints.
 // This option is mandatory for creating logs where the checkpoint is signed locally, e.g. in
 // the Appender mode. This does not need to be provided where the storage will be used to mirror
@@ -770,7 +926,7 @@ func (o *AppendOptions) WithCheckpointSigner(s note.Signer, additionalSigners ..
 			// If we're signing a zero-sized tree, the tlog-checkpoint spec says (via RFC6962) that
 			// the root must be SHA256 of the empty string, so we'll enforce that here:
 			if size == 0 {
-				emptyRoot := rfc6962.DefaultHasher.EmptyRoot()
+				emptyRoot := o.hasher.EmptyRoot()
 				hash = emptyRoot[:]
 			}
 			cpRaw := f_log.Checkpoint{
@@ -778,6 +934,14 @@ func (o *AppendOptions) WithCheckpointSigner(s note.Signer, additionalSigners ..
 				Size:   size,
 				Hash:   hash,
 			}.Marshal()
+			for _, l := range o.checkpointExtraLines {
+				cpRaw = append(cpRaw, l...)
+				cpRaw = append(cpRaw, '\n')
+			}
+			if o.checkpointTimestamp {
+				cpRaw = append(cpRaw, api.FormatCheckpointTimestamp(o.now())...)
+				cpRaw = append(cpRaw, '\n')
+			}
 
 			n, err := note.Sign(&note.Note{Text: string(cpRaw)}, append([]note.Signer{s}, additionalSigners...)...)
 			if err != nil {
@@ -790,6 +954,49 @@ func (o *AppendOptions) WithCheckpointSigner(s note.Signer, additionalSigners ..
 	return o
 }
 
+// WithCheckpointExtraLines configures additional body lines to be included in checkpoints created by the signer
+// provided via WithCheckpointSigner.
+//
+// The lines are emitted, in the order provided, immediately following the root hash line and before the
+// signature block. This supports ecosystem-specific conventions (e.g. key hints or log metadata); as per
+// https://c2sp.org/tlog-checkpoint, verifiers which do not expect these lines will ignore them.
+//
+// Lines must be non-empty and must not contain newline characters.
+func (o *AppendOptions) WithCheckpointExtraLines(lines ...string) *AppendOptions {
+	o.checkpointExtraLines = lines
+	return o
+}
+
+// WithCheckpointTimestamp configures whether checkpoints created by the signer provided via WithCheckpointSigner
+// include a line recording the time at which they were created, allowing monitors to detect a log which has
+// stopped publishing fresh checkpoints.
+//
+// The line follows any lines configured with WithCheckpointExtraLines, and can be read with
+// api.CheckpointTimestamp. As with other extension lines, verifiers which do not expect it will ignore it.
+//
+// By default, checkpoints don't include a timestamp.
+func (o *AppendOptions) WithCheckpointTimestamp(enabled bool) *AppendOptions {
+	o.checkpointTimestamp = enabled
+	return o
+}
+
+// WithClock configures the function used to get the current time, e.g. for the timestamps included in
+// checkpoints by WithCheckpointTimestamp. This is intended for tests.
+//
+// By default, time.Now is used.
+func (o *AppendOptions) WithClock(now func() time.Time) *AppendOptions {
+	o.clock = now
+	return o
+}
+
+// now returns the current time according to the configured clock.
+func (o *AppendOptions) now() time.Time {
+	if o.clock != nil {
+		return o.clock()
+	}
+	return time.Now()
+}
+
 // WithBatching configures the batching behaviour of leaves being sequenced.
 // A batch will be allowed to grow in memory until either:
 //   - the number of entries in the batch reach maxSize
@@ -818,6 +1025,56 @@ func (o *AppendOptions) WithPushback(maxOutstanding uint) *AppendOptions {
 	return o
 }
 
+// WithMaxPendingEntries limits the number of entries which may be waiting to be sequenced, so that memory
+// use stays bounded when entries are added faster than the storage can sequence them.
+//
+// Once n entries are waiting, further calls to Add return a future which resolves to ErrOverloaded or,
+// if block is true, wait until there's space for the entry or the context passed to Add becomes done.
+//
+// By default there is no limit.
+func (o *AppendOptions) WithMaxPendingEntries(n uint, block bool) *AppendOptions {
+	o.maxPendingEntries = n
+	o.blockWhenPending = block
+	return o
+}
+
+// WithRateLimit limits the rate at which entries may be added to the log to rps entries per second on
+// average, with bursts of up to burst entries, to protect the storage from being overwhelmed. The limit
+// is applied before entries are queued for sequencing, and after any validation of them.
+//
+// Once the limit is reached, further calls to Add return a future which resolves to ErrRateLimited or,
+// if block is true, wait until the limit allows the entry or the context passed to Add becomes done.
+//
+// By default there is no limit.
+func (o *AppendOptions) WithRateLimit(rps float64, burst int, block bool) *AppendOptions {
+	o.rateLimit = rps
+	o.rateBurst = burst
+	o.rateLimitBlock = block
+	return o
+}
+
+// WithIdempotency enables Appender.AddIdempotent, using the provided store to persist the mapping from
+// caller-defined idempotency keys to the indices assigned to their entries.
+//
+// By default, idempotent adds are disabled.
+func (o *AppendOptions) WithIdempotency(s IdempotencyStore) *AppendOptions {
+	o.idempotency = s
+	return o
+}
+
+// WithEntryValidator adds a function which checks each entry passed to Add before it's accepted, e.g. to
+// enforce a domain-specific schema.
+//
+// The validator is called synchronously by Add. If it returns an error, the entry is not added to the log,
+// and Add returns a future which resolves to an error wrapping both ErrInvalidEntry and the validator's error.
+//
+// This option may be used more than once, in which case the validators are called in the order they were added,
+// and the first to reject an entry determines the error.
+func (o *AppendOptions) WithEntryValidator(v func(*Entry) error) *AppendOptions {
+	o.entryValidators = append(o.entryValidators, v)
+	return o
+}
+
 // WithCheckpointInterval configures the frequency at which Tessera will attempt to create & publish
 // new checkpoints.
 //
@@ -894,3 +1151,19 @@ func (o *AppendOptions) WithGarbageCollectionInterval(interval time.Duration) *A
 	o.garbageCollectionInterval = interval
 	return o
 }
+
+// WithHasher configures the log to build its Merkle tree using the provided hasher, rather than the RFC6962
+// hasher required by https://c2sp.org/tlog-tiles. The name identifies the hashing strategy, and is recorded
+// by storage implementations which support it so that a log cannot later be reopened with a different hasher.
+//
+// The hasher is used for the root of the empty tree, the leaf hashes of entries, and all interior nodes.
+// Leaf hashes of added entries are recalculated by calling HashLeaf on their data, so this option is not
+// compatible with entry types which use a different leaf preimage, such as those for static-ct.
+//
+// Not all storage implementations support this option, in which case NewAppender will return an error.
+// Logs using a non-default hasher cannot be verified by standard tlog-tiles clients.
+func (o *AppendOptions) WithHasher(name string, h merkle.LogHasher) *AppendOptions {
+	o.hasherName = name
+	o.hasher = h
+	return o
+}
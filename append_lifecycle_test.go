@@ -16,11 +16,19 @@ package tessera
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
 	"strings"
 	"testing"
 	"time"
 
+	f_log "github.com/transparency-dev/formats/log"
+	"github.com/transparency-dev/merkle/rfc6962"
+	"github.com/transparency-dev/tessera/api"
 	"golang.org/x/mod/sumdb/note"
+	"golang.org/x/time/rate"
 )
 
 func TestMemoize(t *testing.T) {
@@ -87,6 +95,34 @@ func TestAppendOptionsValid(t *testing.T) {
 			name:            "Error: No CheckpointSigner",
 			opts:            NewAppendOptions(),
 			wantErrContains: "WithCheckpointSigner",
+		}, {
+			name: "Valid: CheckpointExtraLines",
+			opts: NewAppendOptions().
+				WithCheckpointSigner(mustCreateSigner(t, testSignerKey)).
+				WithCheckpointExtraLines("one", "two"),
+		}, {
+			name: "Error: CheckpointExtraLines contains newline",
+			opts: NewAppendOptions().
+				WithCheckpointSigner(mustCreateSigner(t, testSignerKey)).
+				WithCheckpointExtraLines("one\ntwo"),
+			wantErrContains: "WithCheckpointExtraLines",
+		}, {
+			name: "Error: CheckpointExtraLines contains empty line",
+			opts: NewAppendOptions().
+				WithCheckpointSigner(mustCreateSigner(t, testSignerKey)).
+				WithCheckpointExtraLines(""),
+			wantErrContains: "WithCheckpointExtraLines",
+		}, {
+			name: "Valid: RateLimit",
+			opts: NewAppendOptions().
+				WithCheckpointSigner(mustCreateSigner(t, testSignerKey)).
+				WithRateLimit(10, 1, false),
+		}, {
+			name: "Error: RateLimit with zero burst",
+			opts: NewAppendOptions().
+				WithCheckpointSigner(mustCreateSigner(t, testSignerKey)).
+				WithRateLimit(10, 0, false),
+			wantErrContains: "WithRateLimit",
 		},
 	} {
 		t.Run(test.name, func(t *testing.T) {
@@ -105,6 +141,118 @@ func TestAppendOptionsValid(t *testing.T) {
 	}
 }
 
+var errAppenderCalled = errors.New("Appender called")
+
+// appenderDriver is a Driver whose Appender lifecycle always fails with errAppenderCalled.
+type appenderDriver struct{}
+
+func (appenderDriver) Appender(context.Context, *AppendOptions) (*Appender, LogReader, error) {
+	return nil, nil, errAppenderCalled
+}
+
+// customHasherDriver is an appenderDriver which reports whether it supports custom hashers.
+type customHasherDriver struct {
+	appenderDriver
+	supported bool
+}
+
+func (d customHasherDriver) SupportsCustomHasher() bool {
+	return d.supported
+}
+
+func TestNewAppenderCustomHasher(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		d          Driver
+		hasherName string
+		wantCalled bool
+	}{
+		{
+			name:       "default hasher",
+			d:          appenderDriver{},
+			hasherName: DefaultHasherName,
+			wantCalled: true,
+		}, {
+			name:       "custom hasher, no support",
+			d:          appenderDriver{},
+			hasherName: "custom",
+		}, {
+			name:       "custom hasher, not supported",
+			d:          customHasherDriver{},
+			hasherName: "custom",
+		}, {
+			name:       "custom hasher, supported",
+			d:          customHasherDriver{supported: true},
+			hasherName: "custom",
+			wantCalled: true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			opts := NewAppendOptions().
+				WithCheckpointSigner(mustCreateSigner(t, testSignerKey)).
+				WithHasher(test.hasherName, rfc6962.DefaultHasher)
+			_, _, _, err := NewAppender(t.Context(), test.d, opts)
+			if err == nil {
+				t.Fatal("NewAppender succeeded, want error")
+			}
+			if gotCalled := strings.Contains(err.Error(), errAppenderCalled.Error()); gotCalled != test.wantCalled {
+				t.Errorf("NewAppender = %v, want Appender called %t", err, test.wantCalled)
+			}
+		})
+	}
+}
+
+func TestCheckpointExtraLines(t *testing.T) {
+	opts := NewAppendOptions().
+		WithCheckpointSigner(mustCreateSigner(t, testSignerKey)).
+		WithCheckpointExtraLines("extra one", "extra two")
+	cp, err := opts.newCP(t.Context(), 1, make([]byte, 32))
+	if err != nil {
+		t.Fatalf("newCP: %v", err)
+	}
+	body, _, ok := strings.Cut(string(cp), "\n\n")
+	if !ok {
+		t.Fatalf("Checkpoint has no signature block: %q", cp)
+	}
+	lines := strings.Split(body, "\n")
+	if got, want := lines[3:], []string{"extra one", "extra two"}; !slices.Equal(got, want) {
+		t.Fatalf("Got extra lines %q, want %q", got, want)
+	}
+}
+
+func TestCheckpointTimestamp(t *testing.T) {
+	sk, vk, err := note.GenerateKey(nil, "example.com/log")
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	now := time.UnixMilli(1700000000123)
+	opts := NewAppendOptions().
+		WithCheckpointSigner(mustCreateSigner(t, sk)).
+		WithCheckpointExtraLines("extra").
+		WithCheckpointTimestamp(true).
+		WithClock(func() time.Time { return now })
+	cp, err := opts.newCP(t.Context(), 1, make([]byte, 32))
+	if err != nil {
+		t.Fatalf("newCP: %v", err)
+	}
+
+	// The timestamp line must not prevent the checkpoint from being verified by clients which don't expect it.
+	v, err := note.NewVerifier(vk)
+	if err != nil {
+		t.Fatalf("NewVerifier: %v", err)
+	}
+	if _, _, _, err := f_log.ParseCheckpoint(cp, "example.com/log", v); err != nil {
+		t.Fatalf("ParseCheckpoint: %v", err)
+	}
+	ts, ok, err := api.CheckpointTimestamp(cp)
+	if err != nil || !ok {
+		t.Fatalf("CheckpointTimestamp: got (%v, %v), want timestamp", ok, err)
+	}
+	if !ts.Equal(now) {
+		t.Errorf("CheckpointTimestamp: got %v, want %v", ts, now)
+	}
+}
+
 func TestMaxEntrySize(t *testing.T) {
 	d := func(_ context.Context, e *Entry) IndexFuture {
 		return func() (Index, error) {
@@ -141,6 +289,130 @@ func TestMaxEntrySize(t *testing.T) {
 	}
 }
 
+func TestEntryValidator(t *testing.T) {
+	var delegated int
+	d := func(_ context.Context, e *Entry) IndexFuture {
+		delegated++
+		return func() (Index, error) {
+			return Index{}, nil
+		}
+	}
+
+	errNotJSON := errors.New("not JSON")
+	errEmpty := errors.New("empty")
+	add := entryValidatorDecorator(d, []func(*Entry) error{
+		func(e *Entry) error {
+			if len(e.Data()) == 0 {
+				return errEmpty
+			}
+			return nil
+		},
+		func(e *Entry) error {
+			if !json.Valid(e.Data()) {
+				return errNotJSON
+			}
+			return nil
+		},
+	})
+
+	for _, test := range []struct {
+		name    string
+		data    string
+		wantErr error
+	}{
+		{
+			name: "valid",
+			data: `{"a": 1}`,
+		}, {
+			name:    "rejected by first validator",
+			data:    "",
+			wantErr: errEmpty,
+		}, {
+			name:    "rejected by second validator",
+			data:    "{",
+			wantErr: errNotJSON,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			delegated = 0
+			_, err := add(t.Context(), NewEntry([]byte(test.data)))()
+			if test.wantErr == nil {
+				if err != nil {
+					t.Fatalf("Add: %v", err)
+				}
+				if delegated != 1 {
+					t.Fatalf("Valid entry was not passed to delegate")
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidEntry) || !errors.Is(err, test.wantErr) {
+				t.Fatalf("Add = %v, want error wrapping %v and %v", err, ErrInvalidEntry, test.wantErr)
+			}
+			if delegated != 0 {
+				t.Fatalf("Invalid entry was passed to delegate")
+			}
+		})
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	var delegated int
+	d := func(_ context.Context, e *Entry) IndexFuture {
+		delegated++
+		return func() (Index, error) {
+			return Index{}, nil
+		}
+	}
+
+	for _, block := range []bool{false, true} {
+		t.Run(fmt.Sprintf("block=%t", block), func(t *testing.T) {
+			delegated = 0
+			// Allow a burst of 2 entries, after which no more are allowed for the duration of the test.
+			add := rateLimitDecorator(d, rate.NewLimiter(rate.Every(time.Hour), 2), block)
+			for i := range 2 {
+				if _, err := add(t.Context(), NewEntry(nil))(); err != nil {
+					t.Fatalf("Add %d: %v", i, err)
+				}
+			}
+
+			ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+			defer cancel()
+			_, err := add(ctx, NewEntry(nil))()
+			if !errors.Is(err, ErrRateLimited) || !errors.Is(err, ErrPushback) {
+				t.Errorf("Add beyond limit = %v, want error wrapping %v and %v", err, ErrRateLimited, ErrPushback)
+			}
+			if delegated != 2 {
+				t.Errorf("%d entries passed to delegate, want 2", delegated)
+			}
+		})
+	}
+
+	t.Run("block until allowed", func(t *testing.T) {
+		delegated = 0
+		add := rateLimitDecorator(d, rate.NewLimiter(rate.Every(10*time.Millisecond), 1), true)
+		for i := range 3 {
+			if _, err := add(t.Context(), NewEntry(nil))(); err != nil {
+				t.Fatalf("Add %d: %v", i, err)
+			}
+		}
+		if delegated != 3 {
+			t.Errorf("%d entries passed to delegate, want 3", delegated)
+		}
+	})
+
+	t.Run("block until cancelled", func(t *testing.T) {
+		add := rateLimitDecorator(d, rate.NewLimiter(rate.Every(time.Hour), 1), true)
+		if _, err := add(t.Context(), NewEntry(nil))(); err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+		ctx, cancel := context.WithCancel(t.Context())
+		cancel()
+		if _, err := add(ctx, NewEntry(nil))(); !errors.Is(err, context.Canceled) {
+			t.Errorf("Add with cancelled context = %v, want %v", err, context.Canceled)
+		}
+	})
+}
+
 func mustCreateSigner(t *testing.T, k string) note.Signer {
 	t.Helper()
 	s, err := note.NewSigner(k)
@@ -125,12 +125,12 @@ func main() {
 
 // defaultMerkleLeafHasher parses a C2SP tlog-tile bundle and returns the Merkle leaf hashes of each entry it contains.
 func defaultMerkleLeafHasher(bundle []byte) ([][]byte, error) {
-	eb := &api.EntryBundle{}
-	if err := eb.UnmarshalText(bundle); err != nil {
+	entries, err := api.ParseEntryBundle(bundle)
+	if err != nil {
 		return nil, fmt.Errorf("unmarshal: %v", err)
 	}
-	r := make([][]byte, 0, len(eb.Entries))
-	for _, e := range eb.Entries {
+	r := make([][]byte, 0, len(entries))
+	for _, e := range entries {
 		h := rfc6962.DefaultHasher.HashLeaf(e)
 		r = append(r, h[:])
 	}
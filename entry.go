@@ -28,10 +28,11 @@ type Entry struct {
 	// it provides), while also keeping these fields private which allows us to deter bad practice
 	// by forcing use of the API to set these values to safe values.
 	internal struct {
-		Data     []byte
-		Identity []byte
-		LeafHash []byte
-		Index    *uint64
+		Data      []byte
+		Identity  []byte
+		LeafHash  []byte
+		Index     *uint64
+		ExtraData []byte
 	}
 
 	// marshalForBundle knows how to convert this entry's Data into a marshalled bundle entry.
@@ -48,6 +49,10 @@ func (e Entry) Identity() []byte { return e.internal.Identity }
 // Note that in almost all cases, this should be the RFC6962 definition of a leaf hash.
 func (e Entry) LeafHash() []byte { return e.internal.LeafHash }
 
+// ExtraData returns the extra data which is stored alongside the entry, but which isn't committed to by the log,
+// or nil if there is none.
+func (e Entry) ExtraData() []byte { return e.internal.ExtraData }
+
 // Index returns the index assigned to the entry in the log, or nil if no index has been assigned.
 func (e Entry) Index() *uint64 { return e.internal.Index }
 
@@ -78,3 +83,15 @@ func NewEntry(data []byte) *Entry {
 	}
 	return e
 }
+
+// NewEntryWithExtraData creates a new Entry object with leaf data, and some extra data which will be stored
+// alongside it.
+//
+// Only the leaf data is committed to by the log; the extra data plays no part in the entry's identity or
+// leaf hash, and so is not verifiable. This mirrors the way CT logs store certificate chains alongside their
+// leaves. Storage drivers which don't support extra data ignore it.
+func NewEntryWithExtraData(data, extra []byte) *Entry {
+	e := NewEntry(data)
+	e.internal.ExtraData = extra
+	return e
+}
@@ -36,3 +36,23 @@ func TestEntryMarshalBundleDelegates(t *testing.T) {
 		t.Fatalf("Got %q, want %q", got, want)
 	}
 }
+
+func TestEntryExtraDataNotCommitted(t *testing.T) {
+	data := []byte("this is data")
+	e, x := NewEntry(data), NewEntryWithExtraData(data, []byte("extra"))
+	if got, want := x.ExtraData(), []byte("extra"); !bytes.Equal(got, want) {
+		t.Errorf("ExtraData: got %q, want %q", got, want)
+	}
+	if e.ExtraData() != nil {
+		t.Errorf("ExtraData: got %q, want nil", e.ExtraData())
+	}
+	if !bytes.Equal(e.LeafHash(), x.LeafHash()) {
+		t.Error("extra data changed the leaf hash")
+	}
+	if !bytes.Equal(e.Identity(), x.Identity()) {
+		t.Error("extra data changed the identity")
+	}
+	if !bytes.Equal(e.MarshalBundleData(1), x.MarshalBundleData(1)) {
+		t.Error("extra data changed the bundle data")
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package http provides an HTTP handler which serves the read side of the [tlog-tiles API]
+// from a Tessera LogReader.
+//
+// [tlog-tiles API]: https://c2sp.org/tlog-tiles
+package http
+
+import (
+	"errors"
+	"log/slog"
+	nethttp "net/http"
+
+	"github.com/transparency-dev/tessera"
+	"github.com/transparency-dev/tessera/api/layout"
+)
+
+const (
+	// checkpointCacheControl prevents clients and caches from serving stale checkpoints.
+	checkpointCacheControl = "no-cache"
+	// tileCacheControl allows tiles and entry bundles, which never change once written, to be cached indefinitely.
+	tileCacheControl = "max-age=31536000, immutable"
+)
+
+// Handler returns an http.Handler which serves the checkpoint, tiles, and entry bundles of the log
+// from the provided LogReader, using the paths defined by the tlog-tiles API.
+//
+// Requests for resources which the LogReader reports as tessera.ErrNotFound are answered with 404,
+// and requests for malformed tile or entry bundle paths with 400.
+func Handler(r tessera.LogReader) nethttp.Handler {
+	mux := nethttp.NewServeMux()
+	mux.HandleFunc("GET /"+layout.CheckpointPath, func(w nethttp.ResponseWriter, req *nethttp.Request) {
+		cp, err := r.ReadCheckpoint(req.Context())
+		serve(w, req, cp, err, "text/plain; charset=utf-8", checkpointCacheControl)
+	})
+	mux.HandleFunc("GET /tile/entries/", func(w nethttp.ResponseWriter, req *nethttp.Request) {
+		index, p, err := layout.ParseEntriesPath(req.URL.Path)
+		if err != nil {
+			nethttp.Error(w, err.Error(), nethttp.StatusBadRequest)
+			return
+		}
+		b, err := r.ReadEntryBundle(req.Context(), index, p)
+		serve(w, req, b, err, "application/octet-stream", tileCacheControl)
+	})
+	mux.HandleFunc("GET /tile/", func(w nethttp.ResponseWriter, req *nethttp.Request) {
+		level, index, p, err := layout.ParseTilePath(req.URL.Path)
+		if err != nil {
+			nethttp.Error(w, err.Error(), nethttp.StatusBadRequest)
+			return
+		}
+		t, err := r.ReadTile(req.Context(), level, index, p)
+		serve(w, req, t, err, "application/octet-stream", tileCacheControl)
+	})
+	return mux
+}
+
+// serve writes the result of reading a log resource to w.
+func serve(w nethttp.ResponseWriter, req *nethttp.Request, b []byte, err error, contentType, cacheControl string) {
+	if err != nil {
+		if errors.Is(err, tessera.ErrNotFound) {
+			nethttp.NotFound(w, req)
+			return
+		}
+		slog.ErrorContext(req.Context(), "Failed to read log resource", slog.String("path", req.URL.Path), slog.Any("error", err))
+		nethttp.Error(w, nethttp.StatusText(nethttp.StatusInternalServerError), nethttp.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", cacheControl)
+	if _, err := w.Write(b); err != nil {
+		slog.WarnContext(req.Context(), "Failed to write response", slog.String("path", req.URL.Path), slog.Any("error", err))
+	}
+}
@@ -0,0 +1,178 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	nethttp "net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/transparency-dev/tessera"
+	"github.com/transparency-dev/tessera/api/layout"
+)
+
+// fakeReader serves resources from a map keyed by their tlog-tiles path.
+type fakeReader struct {
+	resources map[string][]byte
+	err       error
+}
+
+func (f *fakeReader) read(p string) ([]byte, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	r, ok := f.resources[p]
+	if !ok {
+		return nil, fmt.Errorf("%q: %w", p, tessera.ErrNotFound)
+	}
+	return r, nil
+}
+
+func (f *fakeReader) ReadCheckpoint(_ context.Context) ([]byte, error) {
+	return f.read(layout.CheckpointPath)
+}
+
+func (f *fakeReader) ReadTile(_ context.Context, level, index uint64, p uint8) ([]byte, error) {
+	return f.read(layout.TilePath(level, index, p))
+}
+
+func (f *fakeReader) ReadEntryBundle(_ context.Context, index uint64, p uint8) ([]byte, error) {
+	return f.read(layout.EntriesPath(index, p))
+}
+
+func (f *fakeReader) IntegratedSize(_ context.Context) (uint64, error) {
+	return 0, nil
+}
+
+func (f *fakeReader) NextIndex(_ context.Context) (uint64, error) {
+	return 0, nil
+}
+
+func TestHandler(t *testing.T) {
+	r := &fakeReader{
+		resources: map[string][]byte{
+			layout.CheckpointPath:          []byte("checkpoint"),
+			layout.TilePath(0, 1234067, 0): []byte("full tile"),
+			layout.TilePath(1, 3, 8):       []byte("partial tile"),
+			layout.EntriesPath(1234067, 0): []byte("full bundle"),
+			layout.EntriesPath(0, 255):     []byte("partial bundle"),
+		},
+	}
+	srv := httptest.NewServer(Handler(r))
+	defer srv.Close()
+
+	for _, test := range []struct {
+		path             string
+		wantCode         int
+		wantBody         string
+		wantContentType  string
+		wantCacheControl string
+	}{
+		{
+			path:             "/checkpoint",
+			wantCode:         nethttp.StatusOK,
+			wantBody:         "checkpoint",
+			wantContentType:  "text/plain; charset=utf-8",
+			wantCacheControl: "no-cache",
+		}, {
+			path:             "/tile/0/x001/x234/067",
+			wantCode:         nethttp.StatusOK,
+			wantBody:         "full tile",
+			wantContentType:  "application/octet-stream",
+			wantCacheControl: "max-age=31536000, immutable",
+		}, {
+			path:             "/tile/1/003.p/8",
+			wantCode:         nethttp.StatusOK,
+			wantBody:         "partial tile",
+			wantContentType:  "application/octet-stream",
+			wantCacheControl: "max-age=31536000, immutable",
+		}, {
+			path:             "/tile/entries/x001/x234/067",
+			wantCode:         nethttp.StatusOK,
+			wantBody:         "full bundle",
+			wantContentType:  "application/octet-stream",
+			wantCacheControl: "max-age=31536000, immutable",
+		}, {
+			path:             "/tile/entries/000.p/255",
+			wantCode:         nethttp.StatusOK,
+			wantBody:         "partial bundle",
+			wantContentType:  "application/octet-stream",
+			wantCacheControl: "max-age=31536000, immutable",
+		}, {
+			path:     "/tile/0/001",
+			wantCode: nethttp.StatusNotFound,
+		}, {
+			path:     "/tile/entries/001",
+			wantCode: nethttp.StatusNotFound,
+		}, {
+			path:     "/tile/64/001",
+			wantCode: nethttp.StatusBadRequest,
+		}, {
+			path:     "/tile/0/1",
+			wantCode: nethttp.StatusBadRequest,
+		}, {
+			path:     "/tile/entries/001.p/256",
+			wantCode: nethttp.StatusBadRequest,
+		}, {
+			path:     "/tile/entries/x1/001",
+			wantCode: nethttp.StatusBadRequest,
+		},
+	} {
+		t.Run(test.path, func(t *testing.T) {
+			resp, err := nethttp.Get(srv.URL + test.path)
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			defer func() { _ = resp.Body.Close() }()
+			if got, want := resp.StatusCode, test.wantCode; got != want {
+				t.Fatalf("got status %d, want %d", got, want)
+			}
+			if test.wantCode != nethttp.StatusOK {
+				return
+			}
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("ReadAll: %v", err)
+			}
+			if got, want := string(body), test.wantBody; got != want {
+				t.Errorf("got body %q, want %q", got, want)
+			}
+			if got, want := resp.Header.Get("Content-Type"), test.wantContentType; got != want {
+				t.Errorf("got Content-Type %q, want %q", got, want)
+			}
+			if got, want := resp.Header.Get("Cache-Control"), test.wantCacheControl; got != want {
+				t.Errorf("got Cache-Control %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestHandlerReadError(t *testing.T) {
+	srv := httptest.NewServer(Handler(&fakeReader{err: errors.New("boom")}))
+	defer srv.Close()
+
+	resp, err := nethttp.Get(srv.URL + "/checkpoint")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	_ = resp.Body.Close()
+	if got, want := resp.StatusCode, nethttp.StatusInternalServerError; got != want {
+		t.Errorf("got status %d, want %d", got, want)
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tessera
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// maxUnstoredKeys is the maximum number of keys which couldn't be stored that are remembered in memory.
+	maxUnstoredKeys = 10000
+	// unstoredKeyTTL is how long a key which couldn't be stored is remembered in memory.
+	unstoredKeyTTL = time.Hour
+)
+
+// idempotentAdder implements Appender.AddIdempotent on top of an IdempotencyStore.
+type idempotentAdder struct {
+	store IdempotencyStore
+	now   func() time.Time
+
+	mu sync.Mutex
+	// inFlight holds futures for keys which are currently being added, so that concurrent
+	// calls with the same key within this process don't each add an entry.
+	inFlight map[string]IndexFuture
+	// unstored holds the indices of entries whose keys couldn't be stored, so that retries handled by this
+	// process don't add them again. Since nothing else removes them if the store doesn't recover, its entries
+	// expire after unstoredKeyTTL, and it holds at most maxUnstoredKeys of them.
+	unstored map[string]unstoredKey
+}
+
+// unstoredKey is an entry in idempotentAdder.unstored.
+type unstoredKey struct {
+	idx   uint64
+	added time.Time
+}
+
+func newIdempotentAdder(s IdempotencyStore) *idempotentAdder {
+	return &idempotentAdder{
+		store:    s,
+		now:      time.Now,
+		inFlight: make(map[string]IndexFuture),
+		unstored: make(map[string]unstoredKey),
+	}
+}
+
+func (d *idempotentAdder) add(ctx context.Context, key string, e *Entry, delegate AddFn) IndexFuture {
+	ctx, span := tracer.Start(ctx, "tessera.Appender.AddIdempotent")
+	defer span.End()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if f, ok := d.inFlight[key]; ok {
+		span.AddEvent("tessera.inflight")
+		return dupFuture(f)
+	}
+	if u, ok := d.unstored[key]; ok && d.now().Sub(u.added) >= unstoredKeyTTL {
+		delete(d.unstored, key)
+	} else if ok {
+		span.AddEvent("tessera.unstored")
+		// The store may have recovered, in which case there's no longer any need to remember the key here.
+		if err := d.store.Store(ctx, key, u.idx); err == nil {
+			delete(d.unstored, key)
+		}
+		return func() (Index, error) { return Index{Index: u.idx, IsDup: true}, nil }
+	}
+
+	idx, err := d.store.Index(ctx, key)
+	if err != nil {
+		return func() (Index, error) { return Index{}, fmt.Errorf("failed to look up idempotency key: %v", err) }
+	}
+	if idx != nil {
+		span.AddEvent("tessera.hit")
+		return func() (Index, error) { return Index{Index: *idx, IsDup: true}, nil }
+	}
+	span.AddEvent("tessera.miss")
+
+	df := delegate(ctx, e)
+	// The key is stored as soon as the entry is assigned an index, whether or not the returned future is
+	// ever evaluated, and even if ctx is done by then.
+	storeCtx := context.WithoutCancel(ctx)
+	done := make(chan struct{})
+	var i Index
+	var iErr error
+	go func() {
+		defer close(done)
+		i, iErr = d.complete(storeCtx, key, df)
+	}()
+	f := func() (Index, error) {
+		<-done
+		return i, iErr
+	}
+	d.inFlight[key] = f
+	return f
+}
+
+// complete waits for the entry added with key to be assigned an index, and stores the key.
+func (d *idempotentAdder) complete(ctx context.Context, key string, df IndexFuture) (Index, error) {
+	i, err := df()
+	if err != nil {
+		// Don't remember the failure, the caller may retry with the same key.
+		d.mu.Lock()
+		delete(d.inFlight, key)
+		d.mu.Unlock()
+		return i, err
+	}
+	storeErr := d.store.Store(ctx, key, i.Index)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.inFlight, key)
+	if storeErr != nil {
+		// The entry is in the log, so remember its index in memory for a while to prevent any retries
+		// handled by this process from adding it again.
+		d.rememberUnstored(key, i.Index)
+		return i, fmt.Errorf("entry assigned index %d but failed to store idempotency key: %v", i.Index, storeErr)
+	}
+	return i, nil
+}
+
+// rememberUnstored records the index of the entry added with a key which couldn't be stored, making
+// room for it if necessary by dropping expired keys and then, if there's still no room, the oldest one.
+//
+// d.mu must be held.
+func (d *idempotentAdder) rememberUnstored(key string, idx uint64) {
+	now := d.now()
+	if len(d.unstored) >= maxUnstoredKeys {
+		var oldest string
+		var oldestAdded time.Time
+		for k, u := range d.unstored {
+			if now.Sub(u.added) >= unstoredKeyTTL {
+				delete(d.unstored, k)
+				continue
+			}
+			if oldestAdded.IsZero() || u.added.Before(oldestAdded) {
+				oldest, oldestAdded = k, u.added
+			}
+		}
+		if len(d.unstored) >= maxUnstoredKeys {
+			delete(d.unstored, oldest)
+		}
+	}
+	d.unstored[key] = unstoredKey{idx: idx, added: now}
+}
+
+// dupFuture returns a future which resolves to the same index as f, but marked as a duplicate.
+func dupFuture(f IndexFuture) IndexFuture {
+	return func() (Index, error) {
+		i, err := f()
+		i.IsDup = true
+		return i, err
+	}
+}
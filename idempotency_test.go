@@ -0,0 +1,206 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tessera
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+type mapIdempotencyStore struct {
+	mu       sync.Mutex
+	m        map[string]uint64
+	storeErr error
+}
+
+func (s *mapIdempotencyStore) Index(_ context.Context, key string) (*uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i, ok := s.m[key]; ok {
+		return &i, nil
+	}
+	return nil, nil
+}
+
+func (s *mapIdempotencyStore) Store(_ context.Context, key string, idx uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.storeErr != nil {
+		return s.storeErr
+	}
+	s.m[key] = idx
+	return nil
+}
+
+func TestAddIdempotent(t *testing.T) {
+	ctx := t.Context()
+	var next uint64
+	var mu sync.Mutex
+	add := func(_ context.Context, _ *Entry) IndexFuture {
+		mu.Lock()
+		defer mu.Unlock()
+		i := next
+		next++
+		return func() (Index, error) { return Index{Index: i}, nil }
+	}
+
+	s := &mapIdempotencyStore{m: map[string]uint64{"old": 100}}
+	a := &Appender{Add: add, idempotency: newIdempotentAdder(s)}
+
+	for _, test := range []struct {
+		key  string
+		want Index
+	}{
+		{key: "a", want: Index{Index: 0}},
+		{key: "b", want: Index{Index: 1}},
+		{key: "a", want: Index{Index: 0, IsDup: true}},
+		{key: "old", want: Index{Index: 100, IsDup: true}},
+		{key: "c", want: Index{Index: 2}},
+	} {
+		got, err := a.AddIdempotent(ctx, test.key, NewEntry([]byte(test.key)))()
+		if err != nil {
+			t.Fatalf("AddIdempotent(%q): %v", test.key, err)
+		}
+		if got != test.want {
+			t.Errorf("AddIdempotent(%q): got %+v, want %+v", test.key, got, test.want)
+		}
+	}
+
+	// Concurrent adds with the same key which haven't yet resolved should only add a single entry.
+	f1 := a.AddIdempotent(ctx, "d", NewEntry([]byte("d")))
+	f2 := a.AddIdempotent(ctx, "d", NewEntry([]byte("d")))
+	i1, err1 := f1()
+	i2, err2 := f2()
+	if err1 != nil || err2 != nil {
+		t.Fatalf("AddIdempotent: %v, %v", err1, err2)
+	}
+	if i1.Index != i2.Index || i1.IsDup || !i2.IsDup {
+		t.Errorf("AddIdempotent with in-flight key: got %+v and %+v, want same index with second marked dup", i1, i2)
+	}
+
+	// If the key can't be stored, retries from this process should still find the original index.
+	s.storeErr = errors.New("bang")
+	if _, err := a.AddIdempotent(ctx, "e", NewEntry([]byte("e")))(); err == nil {
+		t.Fatal("AddIdempotent with failing store: got nil error")
+	}
+	got, err := a.AddIdempotent(ctx, "e", NewEntry([]byte("e")))()
+	if err != nil {
+		t.Fatalf("AddIdempotent retry: %v", err)
+	}
+	if want := (Index{Index: 4, IsDup: true}); got != want {
+		t.Errorf("AddIdempotent retry: got %+v, want %+v", got, want)
+	}
+}
+
+func TestAddIdempotentStoresWithoutAwait(t *testing.T) {
+	ctx := t.Context()
+	assigned := make(chan struct{})
+	add := func(context.Context, *Entry) IndexFuture {
+		return func() (Index, error) {
+			<-assigned
+			return Index{Index: 7}, nil
+		}
+	}
+	s := &mapIdempotencyStore{m: map[string]uint64{}}
+	a := &Appender{Add: add, idempotency: newIdempotentAdder(s)}
+
+	// The returned future is never evaluated, but the key must still be stored once the entry is assigned an index.
+	_ = a.AddIdempotent(ctx, "a", NewEntry([]byte("a")))
+	close(assigned)
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(time.Millisecond) {
+		if i, err := s.Index(ctx, "a"); err != nil || i != nil {
+			if err != nil || *i != 7 {
+				t.Fatalf("Index(a) = %v, %v, want 7", i, err)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Key was not stored")
+		}
+	}
+}
+
+func TestAddIdempotentUnstored(t *testing.T) {
+	ctx := t.Context()
+	var next uint64
+	add := func(context.Context, *Entry) IndexFuture {
+		i := next
+		next++
+		return func() (Index, error) { return Index{Index: i}, nil }
+	}
+	s := &mapIdempotencyStore{m: map[string]uint64{}, storeErr: errors.New("bang")}
+	d := newIdempotentAdder(s)
+	now := time.Now()
+	d.now = func() time.Time { return now }
+	a := &Appender{Add: add, idempotency: d}
+
+	if _, err := a.AddIdempotent(ctx, "a", NewEntry([]byte("a")))(); err == nil {
+		t.Fatal("AddIdempotent with failing store: got nil error")
+	}
+	if _, ok := d.inFlight["a"]; ok {
+		t.Error("Key which couldn't be stored is still in flight")
+	}
+
+	// Once the store recovers, a retry stores the key, and it's no longer needed in memory.
+	s.mu.Lock()
+	s.storeErr = nil
+	s.mu.Unlock()
+	if got, err := a.AddIdempotent(ctx, "a", NewEntry([]byte("a")))(); err != nil || got != (Index{Index: 0, IsDup: true}) {
+		t.Errorf("AddIdempotent retry = %+v, %v, want index 0 dup", got, err)
+	}
+	if i, err := s.Index(ctx, "a"); err != nil || i == nil || *i != 0 {
+		t.Errorf("Index(a) = %v, %v, want 0", i, err)
+	}
+	if len(d.unstored) != 0 {
+		t.Errorf("%d unstored keys remembered, want 0", len(d.unstored))
+	}
+
+	// Keys which couldn't be stored are forgotten once they expire.
+	s.mu.Lock()
+	s.storeErr = errors.New("bang")
+	s.mu.Unlock()
+	if _, err := a.AddIdempotent(ctx, "b", NewEntry([]byte("b")))(); err == nil {
+		t.Fatal("AddIdempotent with failing store: got nil error")
+	}
+	now = now.Add(unstoredKeyTTL)
+	if got, _ := a.AddIdempotent(ctx, "b", NewEntry([]byte("b")))(); got.IsDup {
+		t.Errorf("AddIdempotent after expiry = %+v, want new entry", got)
+	}
+
+	// And at most maxUnstoredKeys are remembered, dropping the oldest first.
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i := range maxUnstoredKeys + 1 {
+		now = now.Add(time.Millisecond)
+		d.rememberUnstored(fmt.Sprintf("k%d", i), uint64(i))
+	}
+	if got := len(d.unstored); got != maxUnstoredKeys {
+		t.Errorf("%d unstored keys remembered, want %d", got, maxUnstoredKeys)
+	}
+	if _, ok := d.unstored["k0"]; ok {
+		t.Error("Oldest unstored key was not dropped")
+	}
+}
+
+func TestAddIdempotentNotConfigured(t *testing.T) {
+	a := &Appender{Add: func(context.Context, *Entry) IndexFuture { return nil }}
+	if _, err := a.AddIdempotent(t.Context(), "a", NewEntry([]byte("a")))(); err == nil {
+		t.Error("AddIdempotent without WithIdempotency: got nil error")
+	}
+}
@@ -26,10 +26,11 @@ import (
 // LogReader provides read-only access to the log.
 type LogReader interface {
 	// ReadCheckpoint returns the latest checkpoint available.
-	// If no checkpoint is available then os.ErrNotExist should be returned.
+	// If no checkpoint is available then an error wrapping both ErrNotFound and os.ErrNotExist should be returned.
 	ReadCheckpoint(ctx context.Context) ([]byte, error)
 
-	// ReadTile returns the raw marshalled tile at the given coordinates, if it exists.
+	// ReadTile returns the raw marshalled tile at the given coordinates, if it exists, or an error
+	// wrapping both ErrNotFound and os.ErrNotExist if not.
 	// The expected usage for this method is to derive the parameters from a tree size
 	// that has been committed to by a checkpoint returned by this log. Whenever such a
 	// tree size is used, this method will behave as per the https://c2sp.org/tlog-tiles
@@ -98,6 +99,15 @@ type Antispam interface {
 	Follower(func(entryBundle []byte) ([][]byte, error)) Follower
 }
 
+// IdempotencyStore persists a mapping between caller-defined idempotency keys and the indices
+// assigned to the entries added with them, for use by Appender.AddIdempotent.
+type IdempotencyStore interface {
+	// Index returns the index previously stored for the provided key, or nil if there is none.
+	Index(ctx context.Context, key string) (*uint64, error)
+	// Store durably associates the provided key with the index assigned to its entry.
+	Store(ctx context.Context, key string, idx uint64) error
+}
+
 // identityHash calculates the antispam identity hash for the provided (single) leaf entry data.
 func identityHash(data []byte) []byte {
 	h := sha256.Sum256(data)
@@ -107,12 +117,12 @@ func identityHash(data []byte) []byte {
 // defaultIDHasher returns a list of identity hashes corresponding to entries in the provided bundle.
 // Currently, these are simply SHA256 hashes of the raw byte of each entry.
 func defaultIDHasher(bundle []byte) ([][]byte, error) {
-	eb := &api.EntryBundle{}
-	if err := eb.UnmarshalText(bundle); err != nil {
-		return nil, fmt.Errorf("unmarshal: %v", err)
+	entries, err := api.ParseEntryBundle(bundle)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal: %w", err)
 	}
-	r := make([][]byte, 0, len(eb.Entries))
-	for _, e := range eb.Entries {
+	r := make([][]byte, 0, len(entries))
+	for _, e := range entries {
 		h := identityHash(e)
 		r = append(r, h[:])
 	}
@@ -121,12 +131,12 @@ func defaultIDHasher(bundle []byte) ([][]byte, error) {
 
 // defaultMerkleLeafHasher parses a C2SP tlog-tile bundle and returns the Merkle leaf hashes of each entry it contains.
 func defaultMerkleLeafHasher(bundle []byte) ([][]byte, error) {
-	eb := &api.EntryBundle{}
-	if err := eb.UnmarshalText(bundle); err != nil {
-		return nil, fmt.Errorf("unmarshal: %v", err)
+	entries, err := api.ParseEntryBundle(bundle)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal: %w", err)
 	}
-	r := make([][]byte, 0, len(eb.Entries))
-	for _, e := range eb.Entries {
+	r := make([][]byte, 0, len(entries))
+	for _, e := range entries {
 		h := rfc6962.DefaultHasher.HashLeaf(e)
 		r = append(r, h[:])
 	}
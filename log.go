@@ -39,6 +39,25 @@ var (
 	// when an entry cannot be accepted becasue there are too many "in-flight" add requests - i.e. entries
 	// with sequence numbers assigned, but which are not yet integrated into the log.
 	ErrPushbackIntegration = fmt.Errorf("integration %w", ErrPushback)
+	// ErrOverloaded is a wrapped ErrPushback. It is returned when a new entry cannot be accepted because
+	// the number of entries waiting to be sequenced has reached the limit set via AppendOptions.WithMaxPendingEntries.
+	ErrOverloaded = fmt.Errorf("overloaded %w", ErrPushback)
+	// ErrRateLimited is a wrapped ErrPushback. It is returned when a new entry cannot be accepted because
+	// entries are being added faster than the limit set via AppendOptions.WithRateLimit.
+	ErrRateLimited = fmt.Errorf("rate limited %w", ErrPushback)
+	// ErrInvalidEntry is returned, wrapped together with the validator's error, when an entry passed to Add
+	// is rejected by a validator set via AppendOptions.WithEntryValidator.
+	ErrInvalidEntry = errors.New("invalid entry")
+	// ErrRootMismatch is returned by storage implementations in migration mode when the root hash of the
+	// locally built tree does not match the expected root configured via MigrationOptions.WithExpectedRoot.
+	// This indicates that one or more migrated entry bundles are corrupt, and is not retryable.
+	ErrRootMismatch = errors.New("migrated root does not match expected root")
+	// ErrNotFound is returned by the LogReader implementations of all storage implementations when the
+	// requested checkpoint, tile, or entry bundle does not exist. It's always returned wrapped together
+	// with the underlying error, which will in turn wrap os.ErrNotExist.
+	//
+	// Callers should check for this error using `errors.Is(e, ErrNotFound)`.
+	ErrNotFound = errors.New("not found")
 )
 
 // Driver is the implementation-specific parts of Tessera. No methods are on here as this is not for public use.
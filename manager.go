@@ -0,0 +1,143 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tessera
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var (
+	// ErrManagerClosed is returned by Manager.Create once the Manager has been closed.
+	ErrManagerClosed = errors.New("manager closed")
+	// ErrDuplicateOrigin is returned by Manager.Create if a log with the requested origin is already managed.
+	ErrDuplicateOrigin = errors.New("duplicate origin")
+)
+
+// Manager hosts a set of logs, keyed by origin, within a single process.
+//
+// All logs created by a Manager share a single parent context, which is cancelled once
+// the Manager is closed. It is safe to use a Manager from multiple goroutines.
+type Manager struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu     sync.RWMutex
+	logs   map[string]*managedLog
+	closed bool
+}
+
+type managedLog struct {
+	appender *Appender
+	reader   LogReader
+	shutdown func(context.Context) error
+}
+
+// NewManager returns a Manager whose logs will all derive their context from the provided one.
+func NewManager(ctx context.Context) *Manager {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Manager{
+		ctx:    ctx,
+		cancel: cancel,
+		logs:   make(map[string]*managedLog),
+	}
+}
+
+// Create constructs a new Appender for the log with the given origin using the provided driver
+// and options, and registers it with the Manager.
+//
+// An error wrapping ErrDuplicateOrigin is returned if a log with this origin is already managed.
+func (m *Manager) Create(origin string, d Driver, opts *AppendOptions) (*Appender, LogReader, error) {
+	if origin == "" {
+		return nil, nil, errors.New("origin cannot be empty")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return nil, nil, ErrManagerClosed
+	}
+	if _, ok := m.logs[origin]; ok {
+		return nil, nil, fmt.Errorf("log %q: %w", origin, ErrDuplicateOrigin)
+	}
+	a, shutdown, r, err := NewAppender(m.ctx, d, opts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create log %q: %w", origin, err)
+	}
+	m.logs[origin] = &managedLog{appender: a, reader: r, shutdown: shutdown}
+	return a, r, nil
+}
+
+// Get returns the Appender and LogReader for the log with the given origin.
+//
+// The final return value is false if no such log is managed.
+func (m *Manager) Get(origin string) (*Appender, LogReader, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	l, ok := m.logs[origin]
+	if !ok {
+		return nil, nil, false
+	}
+	return l.appender, l.reader, true
+}
+
+// Origins returns the origins of all logs currently managed.
+func (m *Manager) Origins() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	r := make([]string, 0, len(m.logs))
+	for o := range m.logs {
+		r = append(r, o)
+	}
+	return r
+}
+
+// Close gracefully shuts down all managed logs, waiting for entries which have already been
+// assigned indices to be integrated and published, before cancelling the context shared by
+// the logs.
+//
+// The provided context bounds how long to wait for logs to shut down.
+// After Close has been called, calls to Create will fail.
+func (m *Manager) Close(ctx context.Context) error {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil
+	}
+	m.closed = true
+	logs := m.logs
+	m.mu.Unlock()
+	defer m.cancel()
+
+	var (
+		wg   sync.WaitGroup
+		errM sync.Mutex
+		errs []error
+	)
+	for o, l := range logs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := l.shutdown(ctx); err != nil {
+				errM.Lock()
+				errs = append(errs, fmt.Errorf("log %q: %v", o, err))
+				errM.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
@@ -0,0 +1,99 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tessera_test
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/transparency-dev/tessera"
+	"github.com/transparency-dev/tessera/storage/memory"
+	"golang.org/x/mod/sumdb/note"
+)
+
+func TestManager(t *testing.T) {
+	ctx := t.Context()
+	m := tessera.NewManager(ctx)
+
+	origins := []string{"example.com/log1", "example.com/log2"}
+	for _, o := range origins {
+		if _, _, err := m.Create(o, memory.New(), managerTestOpts(t, o)); err != nil {
+			t.Fatalf("Create(%q): %v", o, err)
+		}
+	}
+	if _, _, err := m.Create(origins[0], memory.New(), managerTestOpts(t, origins[0])); !errors.Is(err, tessera.ErrDuplicateOrigin) {
+		t.Errorf("Create(duplicate): got %v, want %v", err, tessera.ErrDuplicateOrigin)
+	}
+	if _, _, ok := m.Get("example.com/missing"); ok {
+		t.Error("Get(missing): got ok, want !ok")
+	}
+	got := m.Origins()
+	slices.Sort(got)
+	if !slices.Equal(got, origins) {
+		t.Errorf("Origins: got %v, want %v", got, origins)
+	}
+
+	futures := []tessera.IndexFuture{}
+	for _, o := range origins {
+		a, _, ok := m.Get(o)
+		if !ok {
+			t.Fatalf("Get(%q): !ok", o)
+		}
+		for i := range 10 {
+			futures = append(futures, a.Add(ctx, tessera.NewEntry(fmt.Appendf(nil, "%s %d", o, i))))
+		}
+	}
+	for _, f := range futures {
+		if _, err := f(); err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+	}
+
+	if err := m.Close(ctx); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	for _, o := range origins {
+		_, r, _ := m.Get(o)
+		s, err := r.IntegratedSize(ctx)
+		if err != nil {
+			t.Fatalf("IntegratedSize(%q): %v", o, err)
+		}
+		if s != 10 {
+			t.Errorf("IntegratedSize(%q): got %d, want 10", o, s)
+		}
+	}
+	if _, _, err := m.Create("example.com/log3", memory.New(), managerTestOpts(t, "example.com/log3")); !errors.Is(err, tessera.ErrManagerClosed) {
+		t.Errorf("Create after Close: got %v, want %v", err, tessera.ErrManagerClosed)
+	}
+}
+
+func managerTestOpts(t *testing.T, origin string) *tessera.AppendOptions {
+	t.Helper()
+	sk, _, err := note.GenerateKey(nil, origin)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	s, err := note.NewSigner(sk)
+	if err != nil {
+		t.Fatalf("NewSigner: %v", err)
+	}
+	return tessera.NewAppendOptions().
+		WithCheckpointSigner(s).
+		WithCheckpointInterval(100*time.Millisecond).
+		WithBatching(10, 10*time.Millisecond)
+}
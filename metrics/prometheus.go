@@ -0,0 +1,48 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package metrics provides helpers for exporting the metrics recorded by Tessera.
+package metrics
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	otelprom "go.opentelemetry.io/otel/exporters/prometheus"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+)
+
+// NewPrometheusMeterProvider returns an OpenTelemetry MeterProvider which exports all metrics recorded
+// with it to the provided Prometheus registerer. Metric names are translated to follow Prometheus
+// conventions, e.g. the "tessera.appender.add.calls" counter is exported as "tessera_appender_add_calls_total".
+//
+// Tessera records its metrics (e.g. queue depth, integration and publication durations, read counts, and
+// errors) via the global OpenTelemetry MeterProvider, so callers wishing to collect them should install the
+// returned provider with otel.SetMeterProvider, early in the life of the process.
+//
+// To distinguish metrics from multiple logs scraped by the same Prometheus instance, wrap reg with
+// per-origin labels before passing it in, e.g.:
+//
+//	mp, err := metrics.NewPrometheusMeterProvider(prometheus.WrapRegistererWith(prometheus.Labels{"origin": origin}, reg))
+//	if err != nil {
+//		...
+//	}
+//	otel.SetMeterProvider(mp)
+func NewPrometheusMeterProvider(reg prometheus.Registerer) (*sdkmetric.MeterProvider, error) {
+	e, err := otelprom.New(otelprom.WithRegisterer(reg), otelprom.WithoutScopeInfo())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Prometheus exporter: %v", err)
+	}
+	return sdkmetric.NewMeterProvider(sdkmetric.WithReader(e)), nil
+}
@@ -0,0 +1,59 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestNewPrometheusMeterProvider(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	mp, err := NewPrometheusMeterProvider(prometheus.WrapRegistererWith(prometheus.Labels{"origin": "example.com/log"}, reg))
+	if err != nil {
+		t.Fatalf("NewPrometheusMeterProvider: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := mp.Shutdown(t.Context()); err != nil {
+			t.Errorf("Shutdown: %v", err)
+		}
+	})
+
+	// The provider is used directly, rather than being installed globally, so as not to affect other tests.
+	c, err := mp.Meter("tessera").Int64Counter("tessera.appender.add.calls")
+	if err != nil {
+		t.Fatalf("Int64Counter: %v", err)
+	}
+	c.Add(t.Context(), 1)
+
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("Gather: %v", err)
+	}
+	const want = "tessera_appender_add_calls_total"
+	for _, mf := range mfs {
+		if mf.GetName() != want {
+			continue
+		}
+		for _, l := range mf.GetMetric()[0].GetLabel() {
+			if l.GetName() == "origin" && l.GetValue() == "example.com/log" {
+				return
+			}
+		}
+		t.Fatalf("Metric %q missing origin label: %v", want, mf)
+	}
+	t.Fatalf("Metric %q not found", want)
+}
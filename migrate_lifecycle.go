@@ -69,6 +69,16 @@ type MigrationOptions struct {
 	// This field's value must not be updated once configured or weird and probably unwanted integration behaviour is likely to occur.
 	bundleLeafHasher func([]byte) ([][]byte, error)
 	followers        []Follower
+	// progress, if set, is called by the storage implementation after each attempt to integrate migrated entries.
+	progress func(current, target uint64)
+	// maxBundlesPerPass is the maximum number of entry bundles to integrate in each pass, or zero to use the storage default.
+	maxBundlesPerPass uint
+	// fetchConcurrency is the maximum number of entry bundles to fetch concurrently, or zero to use the storage default.
+	fetchConcurrency uint
+	// expectedRoot, if set, is the root hash which the migrated tree must have once it reaches the source size.
+	expectedRoot []byte
+	// resumeVerification, if true, causes the storage implementation to check an existing partially migrated tree before resuming.
+	resumeVerification bool
 }
 
 func (o MigrationOptions) EntriesPath() func(uint64, uint8) string {
@@ -79,6 +89,82 @@ func (o *MigrationOptions) LeafHasher() func([]byte) ([][]byte, error) {
 	return o.bundleLeafHasher
 }
 
+func (o *MigrationOptions) ProgressCallback() func(current, target uint64) {
+	return o.progress
+}
+
+func (o *MigrationOptions) MaxBundlesPerPass() uint {
+	return o.maxBundlesPerPass
+}
+
+func (o *MigrationOptions) FetchConcurrency() uint {
+	return o.fetchConcurrency
+}
+
+// WithBundleFetching configures how migrated entry bundles are read back by the storage implementation
+// in order to integrate them into the local tree.
+//
+// maxBundlesPerPass limits the number of entry bundles which will be integrated in each pass of the
+// integration loop, and fetchConcurrency limits the number of those bundles which will be fetched
+// concurrently. Larger values can substantially increase migration throughput when the storage has
+// high latency. A value of zero for either parameter leaves the storage implementation's default in place.
+//
+// Leaf hashes are always integrated in log order, regardless of the order in which bundles are fetched.
+func (o *MigrationOptions) WithBundleFetching(maxBundlesPerPass, fetchConcurrency uint) *MigrationOptions {
+	o.maxBundlesPerPass = maxBundlesPerPass
+	o.fetchConcurrency = fetchConcurrency
+	return o
+}
+
+func (o *MigrationOptions) ExpectedRoot() []byte {
+	return o.expectedRoot
+}
+
+// WithExpectedRoot configures the root hash of the source log at the size being migrated.
+//
+// When set, the storage implementation will verify that the root hash of the locally built tree matches
+// this value as soon as integration reaches the source size, and before that tree state is committed.
+// If the roots differ, integration fails with an error wrapping ErrRootMismatch rather than continuing
+// to retry. This catches corruption of entry bundles during the copy as early as possible.
+func (o *MigrationOptions) WithExpectedRoot(root []byte) *MigrationOptions {
+	o.expectedRoot = root
+	return o
+}
+
+func (o *MigrationOptions) ResumeVerification() bool {
+	return o.resumeVerification
+}
+
+// WithResumeVerification controls whether a migration which is resumed, e.g. after the migrating process
+// was restarted, first checks that the tree it has already built is consistent with the data it has stored.
+//
+// By default, the tree state persisted by an earlier run is trusted and integration simply continues
+// from that size. When verification is enabled, the storage implementation recalculates the persisted root
+// hash from the stored tiles, and checks the most recently integrated entry bundle against them, before
+// continuing, failing with an error wrapping ErrRootMismatch if not. This only reads a handful of tiles and
+// a single entry bundle, regardless of the size of the tree. Whether or not this is enabled, the tree is
+// checked against any root configured with WithExpectedRoot once it reaches the source size.
+//
+// Storage implementations which don't support resume verification ignore this option.
+func (o *MigrationOptions) WithResumeVerification(verify bool) *MigrationOptions {
+	o.resumeVerification = verify
+	return o
+}
+
+// WithProgressCallback configures a function which will be called after each attempt by the storage
+// implementation to integrate migrated entries into the local tree, with the current integrated size
+// of the local tree and the target size of the migration.
+//
+// This can be used to log or export metrics about the progress of long running migrations, e.g. to
+// estimate completion time or to detect stalls.
+//
+// The callback is called synchronously from the integration loop, but not while holding any locks,
+// and so it should return quickly.
+func (o *MigrationOptions) WithProgressCallback(f func(current, target uint64)) *MigrationOptions {
+	o.progress = f
+	return o
+}
+
 // WithAntispam configures the migration target to *populate* the provided antispam storage using
 // the data being migrated into the target tree.
 //
@@ -94,6 +94,9 @@ const (
 	defaultPublicationTimeout = 5 * time.Second
 	// defaultGCTimeout is the default context timeout applied when undertaking a garbage collection task.
 	defaultGCTimeout = 30 * time.Second
+
+	// defaultMigrationMaxBundles is the default maximum number of entry bundles integrated in each pass during migration.
+	defaultMigrationMaxBundles = 300
 )
 
 // Storage is an AWS based storage implementation for Tessera.
@@ -237,10 +240,11 @@ func (s *Storage) newAppender(ctx context.Context, o objStore, seq sequencer, op
 	r := &Appender{
 		logStore:    logStore,
 		sequencer:   seq,
-		queue:       storage.NewQueue(ctx, opts.BatchMaxAge(), opts.BatchMaxSize(), seq.assignEntries),
+		queue:       storage.NewQueue(ctx, opts.BatchMaxAge(), opts.BatchMaxSize(), nil, seq.assignEntries),
 		newCP:       opts.CheckpointPublisher(logStore, s.cfg.HTTPClient),
 		treeUpdated: make(chan struct{}),
 	}
+	r.queue.SetMaxPending(opts.MaxPendingEntries())
 
 	if err := r.init(ctx); err != nil {
 		return nil, nil, fmt.Errorf("failed to initialise log storage: %v", err)
@@ -533,8 +537,13 @@ func (s *Storage) MigrationWriter(ctx context.Context, opts *tessera.MigrationOp
 		s:            s,
 		dbPool:       seq.dbPool,
 		bundleHasher: opts.LeafHasher(),
+		progress:     opts.ProgressCallback(),
+		expectedRoot: opts.ExpectedRoot(),
 		sequencer:    seq,
 		logStore:     logStore,
+		// Zero values for these fields select the defaults.
+		maxBundles:       opts.MaxBundlesPerPass(),
+		fetchConcurrency: opts.FetchConcurrency(),
 	}
 
 	return m, logStore, nil
@@ -545,8 +554,15 @@ type MigrationStorage struct {
 	s            *Storage
 	dbPool       *sql.DB
 	bundleHasher func([]byte) ([][]byte, error)
+	progress     func(current, target uint64)
+	expectedRoot []byte
 	sequencer    sequencer
 	logStore     *logResourceStore
+
+	// maxBundles is the maximum number of entry bundles to integrate in each pass, or zero for the default.
+	maxBundles uint
+	// fetchConcurrency is the maximum number of entry bundles to fetch concurrently, or zero for no limit.
+	fetchConcurrency uint
 }
 
 var _ migrate.MigrationWriter = &MigrationStorage{}
@@ -566,8 +582,13 @@ func (m *MigrationStorage) AwaitIntegration(ctx context.Context, sourceSize uint
 			}
 			slog.InfoContext(ctx, "Integrating", slog.Uint64("from", from), slog.Uint64("target", sourceSize))
 			newSize, newRoot, err := m.buildTree(ctx, sourceSize)
+			if errors.Is(err, tessera.ErrRootMismatch) {
+				return nil, err
+			}
 			if err != nil {
 				slog.WarnContext(ctx, "integrate", slog.Any("error", err))
+			} else if m.progress != nil {
+				m.progress(newSize, sourceSize)
 			}
 			if newSize == sourceSize {
 				slog.InfoContext(ctx, "Integration complete", slog.Uint64("size", newSize), slog.String("root", fmt.Sprintf("%x", newRoot)))
@@ -587,22 +608,34 @@ func (m *MigrationStorage) IntegratedSize(ctx context.Context) (uint64, error) {
 }
 
 func (m *MigrationStorage) fetchLeafHashes(ctx context.Context, from, to, sourceSize uint64) ([][]byte, error) {
-	// TODO(al): Make this configurable.
-	const maxBundles = 300
+	maxBundles := m.maxBundles
+	if maxBundles == 0 {
+		maxBundles = defaultMigrationMaxBundles
+	}
 
 	toBeAdded := sync.Map{}
-	eg := errgroup.Group{}
-	n := 0
+	// Once one fetch fails, the context passed to the others is cancelled and no more are started.
+	eg, ctx := errgroup.WithContext(ctx)
+	if m.fetchConcurrency > 0 {
+		eg.SetLimit(int(m.fetchConcurrency))
+	}
+	n := uint(0)
 	for ri := range layout.Range(from, to, sourceSize) {
 		eg.Go(func() error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
 			b, err := m.logStore.getEntryBundle(ctx, ri.Index, ri.Partial)
 			if err != nil {
-				return fmt.Errorf("getEntryBundle(%d.%d): %v", ri.Index, ri.Partial, err)
+				return fmt.Errorf("getEntryBundle(%d.%d): %w", ri.Index, ri.Partial, err)
 			}
 
 			bh, err := m.bundleHasher(b)
 			if err != nil {
-				return fmt.Errorf("bundleHasherFunc for bundle index %d: %v", ri.Index, err)
+				return fmt.Errorf("bundleHasherFunc for bundle index %d: %w", ri.Index, err)
+			}
+			if want := int(ri.First + ri.N); len(bh) < want {
+				return fmt.Errorf("bundle index %d has %d entries, want at least %d: %w", ri.Index, len(bh), want, api.ErrBundleTruncated)
 			}
 			toBeAdded.Store(ri.Index, bh[ri.First:ri.First+ri.N])
 			return nil
@@ -662,6 +695,10 @@ func (m *MigrationStorage) buildTree(ctx context.Context, sourceSize uint64) (ui
 
 	if len(lh) == 0 {
 		slog.InfoContext(ctx, "Integrate: nothing to do, nothing done")
+		// A resumed migration may find the tree already complete, in which case it must still match the source.
+		if from == sourceSize && m.expectedRoot != nil && !bytes.Equal(rootHash, m.expectedRoot) {
+			return 0, nil, fmt.Errorf("root %x at size %d, want %x: %w", rootHash, from, m.expectedRoot, tessera.ErrRootMismatch)
+		}
 		return from, rootHash, nil
 	}
 
@@ -674,6 +711,9 @@ func (m *MigrationStorage) buildTree(ctx context.Context, sourceSize uint64) (ui
 	}
 	newSize = from + added
 	slog.InfoContext(ctx, "Integrate: added entries", slog.Uint64("count", added))
+	if newSize == sourceSize && m.expectedRoot != nil && !bytes.Equal(newRoot, m.expectedRoot) {
+		return 0, nil, fmt.Errorf("root %x at size %d, want %x: %w", newRoot, newSize, m.expectedRoot, tessera.ErrRootMismatch)
+	}
 
 	if _, err := tx.ExecContext(ctx, "UPDATE IntCoord SET seq=?, rootHash=? WHERE id=?", newSize, newRoot, 0); err != nil {
 		return 0, nil, fmt.Errorf("update intcoord: %v", err)
@@ -699,22 +739,24 @@ func (lr *logResourceStore) ReadCheckpoint(ctx context.Context) ([]byte, error)
 	if err != nil {
 		var nske *types.NoSuchKey
 		if errors.As(err, &nske) {
-			return r, os.ErrNotExist
+			return r, storage.WrapNotFound(fmt.Errorf("%v: %w", err, os.ErrNotExist))
 		}
 	}
 	return r, err
 }
 
 func (lr *logResourceStore) ReadTile(ctx context.Context, l, i uint64, p uint8) ([]byte, error) {
-	return fetcher.PartialOrFullResource(ctx, p, func(ctx context.Context, p uint8) ([]byte, error) {
+	r, err := fetcher.PartialOrFullResource(ctx, p, func(ctx context.Context, p uint8) ([]byte, error) {
 		return lr.get(ctx, layout.TilePath(l, i, p))
 	})
+	return r, storage.WrapNotFound(err)
 }
 
 func (lr *logResourceStore) ReadEntryBundle(ctx context.Context, i uint64, p uint8) ([]byte, error) {
-	return fetcher.PartialOrFullResource(ctx, p, func(ctx context.Context, p uint8) ([]byte, error) {
+	r, err := fetcher.PartialOrFullResource(ctx, p, func(ctx context.Context, p uint8) ([]byte, error) {
 		return lr.get(ctx, lr.entriesPath(i, p))
 	})
+	return r, storage.WrapNotFound(err)
 }
 
 func (lr *logResourceStore) IntegratedSize(ctx context.Context) (uint64, error) {
@@ -1246,7 +1288,7 @@ func (s *mySQLSequencer) publishCheckpoint(ctx context.Context, minStaleActive,
 		// Detect any errors and update metrics accordingly.
 		// Non-error cases are explicitly handled in the body of the function below.
 		if errR != nil {
-			publishCount.Add(ctx, 1, metric.WithAttributes(errorTypeKey.String("error")))
+			publishCount.Add(ctx, 1, metric.WithAttributes(outcomeTypeKey.String("error")))
 		}
 	}()
 
@@ -1269,7 +1311,7 @@ func (s *mySQLSequencer) publishCheckpoint(ctx context.Context, minStaleActive,
 	cpAge := time.Since(time.Unix(pubAt, 0))
 	if cpAge < minStaleActive {
 		slog.DebugContext(ctx, "publishCheckpoint: last checkpoint published too recently, not publishing new checkpoint", slog.Duration("age", cpAge), slog.Duration("minstaleactive", minStaleActive))
-		publishCount.Add(ctx, 1, metric.WithAttributes(errorTypeKey.String("skipped")))
+		publishCount.Add(ctx, 1, metric.WithAttributes(outcomeTypeKey.String("skipped")))
 		return nil
 	}
 
@@ -1294,7 +1336,7 @@ func (s *mySQLSequencer) publishCheckpoint(ctx context.Context, minStaleActive,
 
 	if !shouldPublish {
 		slog.DebugContext(ctx, "publishCheckpoint: skipping publish because tree hasn't grown and previous checkpoint is too recent")
-		publishCount.Add(ctx, 1, metric.WithAttributes(errorTypeKey.String("skipped_no_growth")))
+		publishCount.Add(ctx, 1, metric.WithAttributes(outcomeTypeKey.String("skipped_no_growth")))
 		return nil
 	}
 
@@ -1311,7 +1353,7 @@ func (s *mySQLSequencer) publishCheckpoint(ctx context.Context, minStaleActive,
 		return err
 	}
 	opsHistogram.Record(ctx, time.Since(start).Milliseconds(), metric.WithAttributes(opNameKey.String("publishCheckpoint")))
-	publishCount.Add(ctx, 1)
+	publishCount.Add(ctx, 1, metric.WithAttributes(outcomeTypeKey.String("success")))
 	tx = nil
 
 	return nil
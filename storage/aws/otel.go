@@ -32,10 +32,10 @@ var (
 )
 
 var (
-	errorTypeKey  = attribute.Key("error.type")
-	numEntriesKey = attribute.Key("tessera.numEntries")
-	objectPathKey = attribute.Key("tessera.objectPath")
-	opNameKey     = attribute.Key("op_name")
+	numEntriesKey  = attribute.Key("tessera.numEntries")
+	objectPathKey  = attribute.Key("tessera.objectPath")
+	opNameKey      = attribute.Key("op_name")
+	outcomeTypeKey = attribute.Key("tessera.publishCheckpoint.outcome")
 
 	opsHistogram metric.Int64Histogram
 	publishCount metric.Int64Counter
@@ -59,7 +59,7 @@ func init() {
 
 	publishCount, err = meter.Int64Counter(
 		"tessera.appender.checkpoint.publication.counter",
-		metric.WithDescription("Number of checkpoint publication attempts by result"),
+		metric.WithDescription("Number of checkpoint publication attempts by outcome (success, skipped, skipped_no_growth, or error)"),
 		metric.WithUnit("{call}"))
 	if err != nil {
 		slog.ErrorContext(context.Background(), "Failed to create checkpoint publication counter metric", slog.Any("error", err))
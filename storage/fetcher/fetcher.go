@@ -0,0 +1,205 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fetcher contains a read-only Tessera storage implementation which reads the static resources of
+// a log served over HTTP in the https://c2sp.org/tlog-tiles layout.
+//
+// This allows tools which consume a log via a tessera.LogReader, e.g. to migrate or mirror it, to read from
+// any live tlog-tiles log rather than only from local storage.
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/transparency-dev/tessera"
+	"github.com/transparency-dev/tessera/api/layout"
+	"github.com/transparency-dev/tessera/internal/fetcher"
+	"github.com/transparency-dev/tessera/internal/parse"
+	storage "github.com/transparency-dev/tessera/storage/internal"
+)
+
+const (
+	// defaultTimeout is the default value of Config.Timeout.
+	defaultTimeout = 30 * time.Second
+	// defaultRetryBaseDelay is the default value of Config.RetryBaseDelay.
+	defaultRetryBaseDelay = 100 * time.Millisecond
+)
+
+// Config holds configuration for a fetcher storage.
+type Config struct {
+	// URL is the root URL of the log, under which its checkpoint, tiles, and entry bundles are served.
+	URL *url.URL
+
+	// HTTPClient is used to make requests. If unset, http.DefaultClient is used.
+	HTTPClient *http.Client
+
+	// Timeout is the maximum duration of each request. If unset, 30 seconds is used.
+	Timeout time.Duration
+
+	// RetryMaxAttempts is the maximum number of times a request which fails with a transient error, i.e. a
+	// network error or a 429 or 5xx response, is attempted. If zero or one, requests are not retried.
+	RetryMaxAttempts uint
+
+	// RetryBaseDelay is the delay before the first retry of a failed request, which doubles with each
+	// subsequent retry. If unset, 100ms is used.
+	RetryBaseDelay time.Duration
+
+	// EntriesPath, if set, is used to format the paths of entry bundles in place of the tlog-tiles layout,
+	// for logs which store their entries elsewhere.
+	EntriesPath func(n uint64, p uint8) string
+}
+
+// Storage reads the resources of a log served over HTTP. It implements tessera.LogReader.
+type Storage struct {
+	cfg Config
+}
+
+// New creates a new read-only storage for the log served at cfg.URL.
+func New(cfg Config) (*Storage, error) {
+	if cfg.URL == nil {
+		return nil, errors.New("URL must be set")
+	}
+	u := *cfg.URL
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+	cfg.URL = &u
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = http.DefaultClient
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	if cfg.RetryBaseDelay <= 0 {
+		cfg.RetryBaseDelay = defaultRetryBaseDelay
+	}
+	if cfg.EntriesPath == nil {
+		cfg.EntriesPath = tessera.NewAppendOptions().EntriesPath()
+	}
+	return &Storage{cfg: cfg}, nil
+}
+
+// ReadCheckpoint returns the log's latest checkpoint.
+func (s *Storage) ReadCheckpoint(ctx context.Context) ([]byte, error) {
+	r, err := s.fetch(ctx, layout.CheckpointPath)
+	return r, storage.WrapNotFound(err)
+}
+
+// ReadTile returns the tile at the given level and index, falling back to the full tile if the requested
+// partial tile isn't found.
+func (s *Storage) ReadTile(ctx context.Context, level, index uint64, p uint8) ([]byte, error) {
+	r, err := fetcher.PartialOrFullResource(ctx, p, func(ctx context.Context, p uint8) ([]byte, error) {
+		return s.fetch(ctx, layout.TilePath(level, index, p))
+	})
+	return r, storage.WrapNotFound(err)
+}
+
+// ReadEntryBundle returns the entry bundle at the given index, falling back to the full bundle if the
+// requested partial bundle isn't found.
+func (s *Storage) ReadEntryBundle(ctx context.Context, index uint64, p uint8) ([]byte, error) {
+	r, err := fetcher.PartialOrFullResource(ctx, p, func(ctx context.Context, p uint8) ([]byte, error) {
+		return s.fetch(ctx, s.cfg.EntriesPath(index, p))
+	})
+	return r, storage.WrapNotFound(err)
+}
+
+// IntegratedSize returns the size of the tree committed to by the log's latest checkpoint, which is the
+// largest tree whose resources are known to be served.
+func (s *Storage) IntegratedSize(ctx context.Context) (uint64, error) {
+	cp, err := s.ReadCheckpoint(ctx)
+	if err != nil {
+		return 0, err
+	}
+	_, size, _, err := parse.CheckpointUnsafe(cp)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse checkpoint: %v", err)
+	}
+	return size, nil
+}
+
+// NextIndex returns the size of the tree committed to by the log's latest checkpoint, since entries which
+// have been sequenced but not yet published can't be seen over HTTP.
+func (s *Storage) NextIndex(ctx context.Context) (uint64, error) {
+	return s.IntegratedSize(ctx)
+}
+
+// errRetriable wraps errors from requests which may succeed if retried.
+var errRetriable = errors.New("retriable")
+
+// fetch returns the resource at path p relative to the log's root URL, retrying transient failures as
+// configured.
+func (s *Storage) fetch(ctx context.Context, p string) ([]byte, error) {
+	u, err := s.cfg.URL.Parse(p)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %v", err)
+	}
+	delay := s.cfg.RetryBaseDelay
+	for attempt := uint(1); ; attempt++ {
+		r, err := s.get(ctx, u.String())
+		if err == nil || !errors.Is(err, errRetriable) || attempt >= s.cfg.RetryMaxAttempts || ctx.Err() != nil {
+			return r, err
+		}
+		slog.DebugContext(ctx, "Retrying request", slog.String("url", u.String()), slog.Uint64("attempt", uint64(attempt)), slog.Any("error", err))
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// get makes a single request for the resource at u.
+func (s *Storage) get(ctx context.Context, u string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("NewRequestWithContext(%q): %v", u, err)
+	}
+	resp, err := s.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("get(%q): %w: %w", u, errRetriable, err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			slog.ErrorContext(ctx, "resp.Body.Close", slog.Any("error", err))
+		}
+	}()
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		// All good, continue below
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("get(%q): %w", u, os.ErrNotExist)
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return nil, fmt.Errorf("get(%q): %w: %d", u, errRetriable, resp.StatusCode)
+	default:
+		return nil, fmt.Errorf("get(%q): %d", u, resp.StatusCode)
+	}
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("get(%q): %w: failed to read body: %w", u, errRetriable, err)
+	}
+	return b, nil
+}
@@ -0,0 +1,175 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fetcher
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/transparency-dev/tessera"
+	"github.com/transparency-dev/tessera/api/layout"
+)
+
+// testServer serves the given resources, failing the first failures requests for each with the given status.
+type testServer struct {
+	mu        sync.Mutex
+	resources map[string][]byte
+	failures  map[string]int
+	status    int
+	requests  map[string]int
+}
+
+func (s *testServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := strings.TrimPrefix(r.URL.Path, "/log/")
+	s.requests[p]++
+	if s.failures[p] > 0 {
+		s.failures[p]--
+		w.WriteHeader(s.status)
+		return
+	}
+	b, ok := s.resources[p]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	_, _ = w.Write(b)
+}
+
+func newTestStorage(t *testing.T, ts *testServer, cfg Config) *Storage {
+	t.Helper()
+	ts.requests = map[string]int{}
+	srv := httptest.NewServer(ts)
+	t.Cleanup(srv.Close)
+	u, err := url.Parse(srv.URL + "/log")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	cfg.URL = u
+	cfg.RetryBaseDelay = time.Millisecond
+	s, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return s
+}
+
+func TestRead(t *testing.T) {
+	ctx := t.Context()
+	ts := &testServer{resources: map[string][]byte{
+		layout.CheckpointPath:                []byte("example.com/log\n300\nAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=\n\n— sig\n"),
+		layout.TilePath(0, 0, 0):             []byte("full tile"),
+		layout.TilePath(0, 1, 44):            []byte("partial tile"),
+		layout.EntriesPath(1, 44):            []byte("partial bundle"),
+		layout.EntriesPath(0, 0):             []byte("full bundle"),
+		"custom/" + layout.TilePath(0, 0, 0): []byte("custom bundle"),
+	}}
+	s := newTestStorage(t, ts, Config{})
+
+	for _, test := range []struct {
+		name string
+		read func() ([]byte, error)
+		want string
+	}{
+		{name: "checkpoint", read: func() ([]byte, error) { return s.ReadCheckpoint(ctx) }, want: string(ts.resources[layout.CheckpointPath])},
+		{name: "full tile", read: func() ([]byte, error) { return s.ReadTile(ctx, 0, 0, 0) }, want: "full tile"},
+		{name: "partial tile", read: func() ([]byte, error) { return s.ReadTile(ctx, 0, 1, 44) }, want: "partial tile"},
+		{name: "partial tile from full tile", read: func() ([]byte, error) { return s.ReadTile(ctx, 0, 0, 12) }, want: "full tile"},
+		{name: "partial bundle", read: func() ([]byte, error) { return s.ReadEntryBundle(ctx, 1, 44) }, want: "partial bundle"},
+		{name: "partial bundle from full bundle", read: func() ([]byte, error) { return s.ReadEntryBundle(ctx, 0, 3) }, want: "full bundle"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := test.read()
+			if err != nil {
+				t.Fatalf("Read: %v", err)
+			}
+			if !bytes.Equal(got, []byte(test.want)) {
+				t.Errorf("Read = %q, want %q", got, test.want)
+			}
+		})
+	}
+
+	if size, err := s.IntegratedSize(ctx); err != nil || size != 300 {
+		t.Errorf("IntegratedSize = %d, %v, want 300", size, err)
+	}
+	_, err := s.ReadTile(ctx, 1, 0, 0)
+	if !errors.Is(err, tessera.ErrNotFound) || !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("ReadTile for missing tile = %v, want error wrapping %v and %v", err, tessera.ErrNotFound, os.ErrNotExist)
+	}
+
+	s = newTestStorage(t, ts, Config{EntriesPath: func(n uint64, p uint8) string { return "custom/" + layout.TilePath(0, n, p) }})
+	if got, err := s.ReadEntryBundle(ctx, 0, 0); err != nil || string(got) != "custom bundle" {
+		t.Errorf("ReadEntryBundle with EntriesPath = %q, %v, want %q", got, err, "custom bundle")
+	}
+}
+
+func TestRetry(t *testing.T) {
+	ctx := t.Context()
+	for _, test := range []struct {
+		name         string
+		status       int
+		failures     int
+		maxAttempts  uint
+		wantErr      bool
+		wantRequests int
+	}{
+		{name: "no retries", status: http.StatusServiceUnavailable, failures: 1, maxAttempts: 0, wantErr: true, wantRequests: 1},
+		{name: "retried to success", status: http.StatusServiceUnavailable, failures: 2, maxAttempts: 3, wantRequests: 3},
+		{name: "too many failures", status: http.StatusTooManyRequests, failures: 3, maxAttempts: 3, wantErr: true, wantRequests: 3},
+		{name: "not retriable", status: http.StatusForbidden, failures: 1, maxAttempts: 3, wantErr: true, wantRequests: 1},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ts := &testServer{
+				resources: map[string][]byte{layout.CheckpointPath: []byte("checkpoint")},
+				failures:  map[string]int{layout.CheckpointPath: test.failures},
+				status:    test.status,
+			}
+			s := newTestStorage(t, ts, Config{RetryMaxAttempts: test.maxAttempts})
+			_, err := s.ReadCheckpoint(ctx)
+			if gotErr := err != nil; gotErr != test.wantErr {
+				t.Errorf("ReadCheckpoint = %v, want error: %t", err, test.wantErr)
+			}
+			if got := ts.requests[layout.CheckpointPath]; got != test.wantRequests {
+				t.Errorf("Made %d requests, want %d", got, test.wantRequests)
+			}
+		})
+	}
+}
+
+func TestContextCancelled(t *testing.T) {
+	ts := &testServer{
+		resources: map[string][]byte{layout.CheckpointPath: []byte("checkpoint")},
+		failures:  map[string]int{layout.CheckpointPath: 100},
+		status:    http.StatusServiceUnavailable,
+	}
+	s := newTestStorage(t, ts, Config{RetryMaxAttempts: 100})
+	// Retry slowly enough that the deadline passes while waiting to retry.
+	s.cfg.RetryBaseDelay = time.Hour
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := s.ReadCheckpoint(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("ReadCheckpoint = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
@@ -104,6 +104,9 @@ const (
 	defaultPublicationTimeout = 5 * time.Second
 	// defaultGCTimeout is the default context timeout applied when undertaking a garbage collection task.
 	defaultGCTimeout = 30 * time.Second
+
+	// defaultMigrationMaxBundles is the default maximum number of entry bundles integrated in each pass during migration.
+	defaultMigrationMaxBundles = 100
 )
 
 // Storage is a GCP based storage implementation for Tessera.
@@ -178,7 +181,7 @@ func (lr *LogReader) ReadCheckpoint(ctx context.Context) ([]byte, error) {
 		r, err := lr.lrs.getCheckpoint(ctx)
 		if err != nil {
 			if errors.Is(err, gcs.ErrObjectNotExist) {
-				return r, os.ErrNotExist
+				return r, storage.WrapNotFound(fmt.Errorf("%v: %w", err, os.ErrNotExist))
 			}
 		}
 		return r, err
@@ -187,17 +190,19 @@ func (lr *LogReader) ReadCheckpoint(ctx context.Context) ([]byte, error) {
 
 func (lr *LogReader) ReadTile(ctx context.Context, l, i uint64, p uint8) ([]byte, error) {
 	return otel.Trace(ctx, "tessera.storage.gcp.ReadTile", tracer, func(ctx context.Context, span trace.Span) ([]byte, error) {
-		return fetcher.PartialOrFullResource(ctx, p, func(ctx context.Context, p uint8) ([]byte, error) {
+		r, err := fetcher.PartialOrFullResource(ctx, p, func(ctx context.Context, p uint8) ([]byte, error) {
 			return lr.lrs.getTile(ctx, l, i, p)
 		})
+		return r, storage.WrapNotFound(err)
 	})
 }
 
 func (lr *LogReader) ReadEntryBundle(ctx context.Context, i uint64, p uint8) ([]byte, error) {
 	return otel.Trace(ctx, "tessera.storage.gcp.ReadEntryBundle", tracer, func(ctx context.Context, span trace.Span) ([]byte, error) {
-		return fetcher.PartialOrFullResource(ctx, p, func(ctx context.Context, p uint8) ([]byte, error) {
+		r, err := fetcher.PartialOrFullResource(ctx, p, func(ctx context.Context, p uint8) ([]byte, error) {
 			return lr.lrs.getEntryBundle(ctx, i, p)
 		})
+		return r, storage.WrapNotFound(err)
 	})
 }
 
@@ -267,7 +272,8 @@ func (s *Storage) newAppender(ctx context.Context, o objStore, seq *spannerCoord
 		sequencer: seq,
 		cpUpdated: make(chan struct{}),
 	}
-	a.queue = storage.NewQueue(ctx, opts.BatchMaxAge(), opts.BatchMaxSize(), a.sequencer.assignEntries)
+	a.queue = storage.NewQueue(ctx, opts.BatchMaxAge(), opts.BatchMaxSize(), nil, a.sequencer.assignEntries)
+	a.queue.SetMaxPending(opts.MaxPendingEntries())
 
 	reader := &LogReader{
 		lrs: *a.logStore,
@@ -1414,6 +1420,8 @@ func (s *Storage) MigrationWriter(ctx context.Context, opts *tessera.MigrationOp
 		s:            s,
 		dbPool:       seq.dbPool,
 		bundleHasher: opts.LeafHasher(),
+		progress:     opts.ProgressCallback(),
+		expectedRoot: opts.ExpectedRoot(),
 		sequencer:    seq,
 		logStore: &logResourceStore{
 			objStore: &gcsStorage{
@@ -1423,6 +1431,9 @@ func (s *Storage) MigrationWriter(ctx context.Context, opts *tessera.MigrationOp
 			},
 			entriesPath: opts.EntriesPath(),
 		},
+		// Zero values for these fields select the defaults.
+		maxBundles:       opts.MaxBundlesPerPass(),
+		fetchConcurrency: opts.FetchConcurrency(),
 	}
 
 	r := &LogReader{
@@ -1443,8 +1454,15 @@ type MigrationStorage struct {
 	s            *Storage
 	dbPool       *spanner.Client
 	bundleHasher func([]byte) ([][]byte, error)
+	progress     func(current, target uint64)
+	expectedRoot []byte
 	sequencer    sequencer
 	logStore     *logResourceStore
+
+	// maxBundles is the maximum number of entry bundles to integrate in each pass, or zero for the default.
+	maxBundles uint
+	// fetchConcurrency is the maximum number of entry bundles to fetch concurrently, or zero for no limit.
+	fetchConcurrency uint
 }
 
 var _ migrate.MigrationWriter = &MigrationStorage{}
@@ -1464,8 +1482,13 @@ func (m *MigrationStorage) AwaitIntegration(ctx context.Context, sourceSize uint
 			}
 			slog.InfoContext(ctx, "Integrate", slog.Uint64("from", from), slog.Uint64("sourceSize", sourceSize))
 			newSize, newRoot, err := m.buildTree(ctx, sourceSize)
+			if errors.Is(err, tessera.ErrRootMismatch) {
+				return nil, err
+			}
 			if err != nil {
 				slog.WarnContext(ctx, "integrate failed", slog.Any("error", err))
+			} else if m.progress != nil {
+				m.progress(newSize, sourceSize)
 			}
 			if newSize == sourceSize {
 				slog.InfoContext(ctx, "Integrated", slog.Uint64("newSize", newSize), slog.String("newRoot", fmt.Sprintf("%x", newRoot)))
@@ -1485,22 +1508,34 @@ func (m *MigrationStorage) IntegratedSize(ctx context.Context) (uint64, error) {
 }
 
 func (m *MigrationStorage) fetchLeafHashes(ctx context.Context, from, to, sourceSize uint64) ([][]byte, error) {
-	// TODO(al): Make this configurable.
-	const maxBundles = 100
+	maxBundles := m.maxBundles
+	if maxBundles == 0 {
+		maxBundles = defaultMigrationMaxBundles
+	}
 
 	toBeAdded := sync.Map{}
-	eg := errgroup.Group{}
-	n := 0
+	// Once one fetch fails, the context passed to the others is cancelled and no more are started.
+	eg, ctx := errgroup.WithContext(ctx)
+	if m.fetchConcurrency > 0 {
+		eg.SetLimit(int(m.fetchConcurrency))
+	}
+	n := uint(0)
 	for ri := range layout.Range(from, to, sourceSize) {
 		eg.Go(func() error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
 			b, err := m.logStore.getEntryBundle(ctx, ri.Index, ri.Partial)
 			if err != nil {
-				return fmt.Errorf("getEntryBundle(%d.%d): %v", ri.Index, ri.Partial, err)
+				return fmt.Errorf("getEntryBundle(%d.%d): %w", ri.Index, ri.Partial, err)
 			}
 
 			bh, err := m.bundleHasher(b)
 			if err != nil {
-				return fmt.Errorf("bundleHasherFunc for bundle index %d: %v", ri.Index, err)
+				return fmt.Errorf("bundleHasherFunc for bundle index %d: %w", ri.Index, err)
+			}
+			if want := int(ri.First + ri.N); len(bh) < want {
+				return fmt.Errorf("bundle index %d has %d entries, want at least %d: %w", ri.Index, len(bh), want, api.ErrBundleTruncated)
 			}
 			toBeAdded.Store(ri.Index, bh[ri.First:ri.First+ri.N])
 			return nil
@@ -1552,6 +1587,10 @@ func (m *MigrationStorage) buildTree(ctx context.Context, sourceSize uint64) (ui
 
 		if len(lh) == 0 {
 			slog.InfoContext(ctx, "Integrate: nothing to do, nothing done")
+			// A resumed migration may find the tree already complete, in which case it must still match the source.
+			if from == sourceSize && m.expectedRoot != nil && !bytes.Equal(rootHash, m.expectedRoot) {
+				return fmt.Errorf("root %x at size %d, want %x: %w", rootHash, from, m.expectedRoot, tessera.ErrRootMismatch)
+			}
 			// Set these to the current state of the tree so we reflect that in buildTree's return values.
 			newSize, newRoot = from, rootHash
 			return nil
@@ -1566,6 +1605,9 @@ func (m *MigrationStorage) buildTree(ctx context.Context, sourceSize uint64) (ui
 		}
 		newSize = from + added
 		slog.InfoContext(ctx, "Integrate: added entries", slog.Uint64("added", added))
+		if newSize == sourceSize && m.expectedRoot != nil && !bytes.Equal(newRoot, m.expectedRoot) {
+			return fmt.Errorf("root %x at size %d, want %x: %w", newRoot, newSize, m.expectedRoot, tessera.ErrRootMismatch)
+		}
 
 		// integration was successful, so we can update our coordination row
 		m := make([]*spanner.Mutation, 0)
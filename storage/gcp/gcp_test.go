@@ -452,6 +452,63 @@ func TestSpannerSequencerAssignEntriesBatchSplitting(t *testing.T) {
 	}
 }
 
+func TestMigrationFetchLeafHashesStopsOnError(t *testing.T) {
+	ctx := t.Context()
+	m := &MigrationStorage{
+		logStore: &logResourceStore{
+			objStore:    newMemObjStore(),
+			entriesPath: layout.EntriesPath,
+		},
+		maxBundles:       10,
+		fetchConcurrency: 1,
+	}
+
+	const size = 10 * layout.EntryBundleWidth
+	for i := range uint64(size / layout.EntryBundleWidth) {
+		if err := m.logStore.setEntryBundle(ctx, i, 0, []byte("bundle")); err != nil {
+			t.Fatalf("setEntryBundle: %v", err)
+		}
+	}
+	calls := 0
+	m.bundleHasher = func([]byte) ([][]byte, error) {
+		calls++
+		return nil, errors.New("bad bundle")
+	}
+	if _, err := m.fetchLeafHashes(ctx, 0, size, size); err == nil {
+		t.Fatal("fetchLeafHashes succeeded, want error")
+	}
+	if calls != 1 {
+		t.Errorf("Bundle hasher called %d times after the first failure, want 0", calls-1)
+	}
+}
+
+func TestMigrationBuildTreeCompleteChecksExpectedRoot(t *testing.T) {
+	ctx := t.Context()
+	db, closeDB := newSpannerDB(t)
+	defer closeDB()
+
+	for _, test := range []struct {
+		name         string
+		expectedRoot []byte
+		wantErr      error
+	}{
+		{name: "match", expectedRoot: rfc6962.DefaultHasher.EmptyRoot()},
+		{name: "mismatch", expectedRoot: []byte("not the root"), wantErr: tessera.ErrRootMismatch},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			m := &MigrationStorage{
+				dbPool:       db,
+				logStore:     &logResourceStore{objStore: newMemObjStore(), entriesPath: layout.EntriesPath},
+				expectedRoot: test.expectedRoot,
+			}
+			// The (empty) tree is already complete, so there's nothing to integrate.
+			if _, _, err := m.buildTree(ctx, 0); !errors.Is(err, test.wantErr) {
+				t.Fatalf("buildTree: %v, want %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
 func TestPublishTree(t *testing.T) {
 	ctx := t.Context()
 	for _, test := range []struct {
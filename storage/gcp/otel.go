@@ -72,7 +72,7 @@ func init() {
 
 	publishCount, err = meter.Int64Counter(
 		"tessera.appender.checkpoint.publication.counter",
-		metric.WithDescription("Number of checkpoint publication attempts by result"),
+		metric.WithDescription("Number of checkpoint publication attempts by outcome (success, aborted, skipped, skipped_no_growth, or error)"),
 		metric.WithUnit("{call}"))
 	if err != nil {
 		slog.ErrorContext(context.Background(), "Failed to create checkpoint publication counter metric", slog.Any("error", err))
@@ -24,6 +24,7 @@ import (
 
 	"log/slog"
 
+	"github.com/transparency-dev/merkle"
 	"github.com/transparency-dev/merkle/compact"
 	"github.com/transparency-dev/merkle/rfc6962"
 	"github.com/transparency-dev/tessera/api"
@@ -82,7 +83,12 @@ type SequencedEntry struct {
 }
 
 func Integrate(ctx context.Context, getTiles func(ctx context.Context, tileIDs []TileID, treeSize uint64) ([]*api.HashTile, error), fromSize uint64, leafHashes [][]byte) (newSize uint64, rootHash []byte, tiles map[TileID]*api.HashTile, err error) {
-	tb := newTreeBuilder(getTiles)
+	return IntegrateWithHasher(ctx, rfc6962.DefaultHasher, getTiles, fromSize, leafHashes)
+}
+
+// IntegrateWithHasher is like Integrate, but builds the tree using the provided hasher rather than the RFC6962 hasher.
+func IntegrateWithHasher(ctx context.Context, h merkle.LogHasher, getTiles func(ctx context.Context, tileIDs []TileID, treeSize uint64) ([]*api.HashTile, error), fromSize uint64, leafHashes [][]byte) (newSize uint64, rootHash []byte, tiles map[TileID]*api.HashTile, err error) {
+	tb := newTreeBuilder(getTiles, h)
 	return tb.integrate(ctx, fromSize, leafHashes)
 }
 
@@ -98,6 +104,7 @@ type getPopulatedTileFunc func(ctx context.Context, tileID TileID, treeSize uint
 // to leak memory.
 type treeBuilder struct {
 	readCache *tileReadCache
+	hasher    merkle.LogHasher
 	rf        *compact.RangeFactory
 }
 
@@ -105,11 +112,15 @@ type treeBuilder struct {
 //
 // The getTiles param must know how to fetch the specified tiles from storage. It must return tiles in the same order as the
 // provided tileIDs, substituing nil for any tiles which were not found.
-func newTreeBuilder(getTiles func(ctx context.Context, tileIDs []TileID, treeSize uint64) ([]*api.HashTile, error)) *treeBuilder {
-	readCache := newTileReadCache(getTiles)
+//
+// The tree is built using the provided hasher.
+func newTreeBuilder(getTiles func(ctx context.Context, tileIDs []TileID, treeSize uint64) ([]*api.HashTile, error), h merkle.LogHasher) *treeBuilder {
+	rf := &compact.RangeFactory{Hash: h.HashChildren}
+	readCache := newTileReadCache(getTiles, rf)
 	r := &treeBuilder{
 		readCache: &readCache,
-		rf:        &compact.RangeFactory{Hash: rfc6962.DefaultHasher.HashChildren},
+		hasher:    h,
+		rf:        rf,
 	}
 
 	return r
@@ -163,7 +174,7 @@ func (t *treeBuilder) integrate(ctx context.Context, fromSize uint64, leafHashes
 			// C2SP.org/log-tiles says all Merkle operations are those from RFC6962, we need to override
 			// the root of the empty tree to match (compact.Range will return an empty slice).
 			if fromSize == 0 {
-				r = rfc6962.DefaultHasher.EmptyRoot()
+				r = t.hasher.EmptyRoot()
 			}
 			// Nothing to do, nothing done.
 			return fromSize, r, nil, nil
@@ -222,12 +233,14 @@ func (t *treeBuilder) integrate(ctx context.Context, fromSize uint64, leafHashes
 type tileReadCache struct {
 	entries  map[string]*populatedTile
 	getTiles func(ctx context.Context, tileIDs []TileID, treeSize uint64) ([]*api.HashTile, error)
+	rf       *compact.RangeFactory
 }
 
-func newTileReadCache(getTiles func(ctx context.Context, tileIDs []TileID, treeSize uint64) ([]*api.HashTile, error)) tileReadCache {
+func newTileReadCache(getTiles func(ctx context.Context, tileIDs []TileID, treeSize uint64) ([]*api.HashTile, error), rf *compact.RangeFactory) tileReadCache {
 	return tileReadCache{
 		entries:  make(map[string]*populatedTile),
 		getTiles: getTiles,
+		rf:       rf,
 	}
 }
 
@@ -245,7 +258,7 @@ func (r *tileReadCache) Get(ctx context.Context, tileID TileID, treeSize uint64)
 			if err != nil {
 				return nil, err
 			}
-			e, err = newPopulatedTile(t[0])
+			e, err = newPopulatedTile(t[0], r.rf)
 			if err != nil {
 				return nil, fmt.Errorf("failed to create fulltile: %v", err)
 			}
@@ -265,7 +278,7 @@ func (r *tileReadCache) Prewarm(ctx context.Context, tileIDs []TileID, treeSize
 			return err
 		}
 		for i, tile := range t {
-			e, err := newPopulatedTile(tile)
+			e, err := newPopulatedTile(tile, r.rf)
 			if err != nil {
 				return fmt.Errorf("failed to create fulltile: %v", err)
 			}
@@ -341,7 +354,7 @@ func (tc *tileWriteCache) Visitor(ctx context.Context) compact.VisitFn {
 			}
 			if tile == nil {
 				// No tile found in storage: this is a brand new tile being created due to tree growth.
-				tile, err = newPopulatedTile(nil)
+				tile, err = newPopulatedTile(nil, nil)
 				if err != nil {
 					tc.err = append(tc.err, err)
 					return
@@ -372,7 +385,9 @@ type populatedTile struct {
 }
 
 // newPopulatedTile creates and populates a fullTile struct based on the passed in HashTile data.
-func newPopulatedTile(h *api.HashTile) (*populatedTile, error) {
+//
+// The internal nodes of the tile are calculated using ranges created by rf, which may be nil if h is nil.
+func newPopulatedTile(h *api.HashTile, rf *compact.RangeFactory) (*populatedTile, error) {
 	ft := &populatedTile{
 		inner:  make(map[compact.NodeID][]byte),
 		leaves: make([][]byte, 0, layout.TileWidth),
@@ -380,7 +395,7 @@ func newPopulatedTile(h *api.HashTile) (*populatedTile, error) {
 
 	if h != nil {
 		// TODO: it might be better if we calculate (and cache) nodes in get, so we don't do more work that necessary.
-		r := (&compact.RangeFactory{Hash: rfc6962.DefaultHasher.HashChildren}).NewEmptyRange(0)
+		r := rf.NewEmptyRange(0)
 		for _, h := range h.Nodes {
 			if err := r.Append(h, ft.Set); err != nil {
 				return nil, fmt.Errorf("failed to append to range: %v", err)
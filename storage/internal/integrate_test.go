@@ -34,7 +34,7 @@ import (
 func TestNewRangeFetchesTiles(t *testing.T) {
 	ctx := context.Background()
 	m := newMemTileStore[api.HashTile]()
-	tb := newTreeBuilder(m.getTiles)
+	tb := newTreeBuilder(m.getTiles, rfc6962.DefaultHasher)
 
 	treeSize := uint64(0x102030)
 	wantIDs := []TileID{
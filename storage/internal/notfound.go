@@ -0,0 +1,34 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/transparency-dev/tessera"
+)
+
+// WrapNotFound returns err wrapped with tessera.ErrNotFound if it indicates that the requested
+// resource does not exist, or err unchanged otherwise.
+//
+// Storage implementations should use this on errors returned by their LogReader methods.
+func WrapNotFound(err error) error {
+	if errors.Is(err, os.ErrNotExist) && !errors.Is(err, tessera.ErrNotFound) {
+		return fmt.Errorf("%w: %w", tessera.ErrNotFound, err)
+	}
+	return err
+}
@@ -35,12 +35,33 @@ import (
 type Queue struct {
 	maxSize uint
 	maxAge  time.Duration
+	tracer  trace.Tracer
 
 	timer *time.Timer
-	work  chan []queueItem
+	// oldest is the time at which the oldest item currently in the queue was added.
+	oldest time.Time
+	work   chan *batch
 
 	mu    sync.Mutex
 	items []queueItem
+	// inFlight holds the batches which have been taken from the queue, but not yet fully processed.
+	inFlight map[*batch]struct{}
+
+	// maxPending is the maximum number of entries which may be queued or in flight, or zero for no limit.
+	maxPending uint
+	// blockWhenFull controls whether Add waits for space, rather than failing, when maxPending is reached.
+	blockWhenFull bool
+	// pending is the number of entries which are currently queued or in flight.
+	pending uint
+	// space is closed, and replaced, whenever pending decreases.
+	space chan struct{}
+}
+
+// batch is a set of queued entries which are flushed together.
+type batch struct {
+	items []queueItem
+	// done is closed once the batch has been processed.
+	done chan struct{}
 }
 
 // FlushFunc is the signature of a function which will receive the slice of queued entries.
@@ -55,12 +76,20 @@ type FlushFunc func(ctx context.Context, entries []*tessera.Entry) error
 // The provided FlushFunc will be called with a slice containing the contents of the queue, in
 // the same order as they were added, when either the oldest entry in the queue has been there
 // for maxAge, or the size of the queue reaches maxSize.
-func NewQueue(ctx context.Context, maxAge time.Duration, maxSize uint, f FlushFunc) *Queue {
+//
+// Flushes are traced using the provided tracer or, if it's nil, a tracer from the global TracerProvider.
+func NewQueue(ctx context.Context, maxAge time.Duration, maxSize uint, tr trace.Tracer, f FlushFunc) *Queue {
+	if tr == nil {
+		tr = tracer
+	}
 	q := &Queue{
-		maxSize: maxSize,
-		maxAge:  maxAge,
-		work:    make(chan []queueItem, 1),
-		items:   make([]queueItem, 0, maxSize),
+		maxSize:  maxSize,
+		maxAge:   maxAge,
+		tracer:   tr,
+		work:     make(chan *batch, 1),
+		items:    make([]queueItem, 0, maxSize),
+		inFlight: make(map[*batch]struct{}),
+		space:    make(chan struct{}),
 	}
 
 	// Spin off a worker thread to write the queue flushes to storage.
@@ -69,8 +98,15 @@ func NewQueue(ctx context.Context, maxAge time.Duration, maxSize uint, f FlushFu
 			select {
 			case <-ctx.Done():
 				return
-			case entries := <-q.work:
-				q.doFlush(ctx, f, entries)
+			case b := <-q.work:
+				q.doFlush(ctx, f, b.items)
+				q.mu.Lock()
+				delete(q.inFlight, b)
+				q.pending -= uint(len(b.items))
+				close(q.space)
+				q.space = make(chan struct{})
+				q.mu.Unlock()
+				close(b.done)
 			}
 		}
 	}(ctx)
@@ -78,46 +114,160 @@ func NewQueue(ctx context.Context, maxAge time.Duration, maxSize uint, f FlushFu
 }
 
 // Add places e into the queue, and returns a func which should be called to retrieve the assigned index.
+//
+// If the queue has a limit on the number of pending entries which has been reached, the returned func
+// will return tessera.ErrOverloaded, unless the queue is configured to block until there's space for
+// the entry, or ctx becomes done.
 func (q *Queue) Add(ctx context.Context, e *tessera.Entry) tessera.IndexFuture {
-	qi := newEntry(e)
+	return q.AddBatch(ctx, []*tessera.Entry{e})[0]
+}
+
+// AddBatch places all of entries into the queue, in order, and returns a func for each entry which
+// should be called to retrieve its assigned index.
+//
+// The entries will be sequenced in the order they appear in the slice, and before any entries which
+// are added after this call returns. The limit on pending entries is applied to each entry in turn, as
+// described for Add.
+func (q *Queue) AddBatch(ctx context.Context, entries []*tessera.Entry) []tessera.IndexFuture {
+	spanCtx := trace.SpanContextFromContext(ctx)
+	r := make([]tessera.IndexFuture, len(entries))
 
 	q.mu.Lock()
+	for i, e := range entries {
+		if err := q.waitForSpaceLocked(ctx); err != nil {
+			q.mu.Unlock()
+			for j := i; j < len(entries); j++ {
+				r[j] = func() (tessera.Index, error) { return tessera.Index{}, err }
+			}
+			return r
+		}
 
-	q.items = append(q.items, qi)
+		qi := newEntry(e)
+		qi.spanCtx = spanCtx
+		q.items = append(q.items, qi)
+		q.pending++
+		r[i] = qi.f
 
-	// If this is the first item, start the timer.
-	if len(q.items) == 1 {
-		q.timer = time.AfterFunc(q.maxAge, q.flush)
+		// If this is the first item, start the timer.
+		if len(q.items) == 1 {
+			q.oldest = time.Now()
+			q.timer = time.AfterFunc(q.maxAge, q.flush)
+		}
+
+		// If we've reached max size, flush.
+		if len(q.items) >= int(q.maxSize) {
+			toFlush := q.flushLocked()
+			q.mu.Unlock()
+			q.work <- toFlush
+			q.mu.Lock()
+		}
+	}
+	q.mu.Unlock()
+
+	return r
+}
+
+// waitForSpaceLocked returns once there is space in the queue for another entry, or an error if the
+// queue is full and either isn't configured to block, or ctx becomes done while waiting.
+//
+// q.mu must be held when calling this method, and will be held again when it returns, although it may
+// have been released in the meantime.
+func (q *Queue) waitForSpaceLocked(ctx context.Context) error {
+	for q.maxPending > 0 && q.pending >= q.maxPending {
+		if !q.blockWhenFull {
+			return tessera.ErrOverloaded
+		}
+		space := q.space
+		q.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			q.mu.Lock()
+			return ctx.Err()
+		case <-space:
+		}
+		q.mu.Lock()
 	}
+	return nil
+}
+
+// SetBatchParams updates the maximum size and age of batches.
+//
+// Entries which are already queued are retained, in order, and will be flushed according to the new
+// parameters; if they already exceed the new maximum size or age, they're flushed immediately.
+func (q *Queue) SetBatchParams(maxSize uint, maxAge time.Duration) {
+	q.mu.Lock()
+	q.maxSize, q.maxAge = maxSize, maxAge
 
-	// If we've reached max size, flush.
-	var itemsToFlush []queueItem
+	var toFlush *batch
 	if len(q.items) >= int(q.maxSize) {
-		itemsToFlush = q.flushLocked()
+		toFlush = q.flushLocked()
+	} else if q.timer != nil {
+		q.timer.Stop()
+		q.timer = time.AfterFunc(max(0, maxAge-time.Since(q.oldest)), q.flush)
 	}
 	q.mu.Unlock()
 
-	if itemsToFlush != nil {
-		q.work <- itemsToFlush
+	if toFlush != nil {
+		q.work <- toFlush
 	}
+}
 
-	return qi.f
+// SetMaxPending limits the number of entries which may be queued or in the process of being flushed.
+// Once the limit is reached, calls to Add either fail with tessera.ErrOverloaded or, if block is true,
+// wait until there's space for the new entry.
+//
+// A maxPending of zero removes the limit.
+func (q *Queue) SetMaxPending(maxPending uint, block bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.maxPending, q.blockWhenFull = maxPending, block
+	// Wake any blocked callers so they re-check against the new limit.
+	close(q.space)
+	q.space = make(chan struct{})
+}
+
+// Flush causes any currently queued entries to be flushed immediately, and blocks until they,
+// along with any entries previously taken from the queue for flushing, have been processed.
+//
+// Returns an error if the context becomes done before this happens, in which case the entries
+// will continue to be processed in the background.
+func (q *Queue) Flush(ctx context.Context) error {
+	q.mu.Lock()
+	toFlush := q.flushLocked()
+	waitFor := make([]chan struct{}, 0, len(q.inFlight))
+	for b := range q.inFlight {
+		waitFor = append(waitFor, b.done)
+	}
+	q.mu.Unlock()
+
+	if toFlush != nil {
+		q.work <- toFlush
+	}
+
+	for _, done := range waitFor {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-done:
+		}
+	}
+	return nil
 }
 
 // flush is called by the timer to flush the buffer.
 func (q *Queue) flush() {
 	q.mu.Lock()
-	itemsToFlush := q.flushLocked()
+	toFlush := q.flushLocked()
 	q.mu.Unlock()
 
-	if itemsToFlush != nil {
-		q.work <- itemsToFlush
+	if toFlush != nil {
+		q.work <- toFlush
 	}
 }
 
 // flushLocked must be called with q.mu held.
-// It prepares items for flushing and returns them.
-func (q *Queue) flushLocked() []queueItem {
+// It prepares items for flushing and returns them as an in-flight batch.
+func (q *Queue) flushLocked() *batch {
 	if len(q.items) == 0 {
 		return nil
 	}
@@ -127,22 +277,33 @@ func (q *Queue) flushLocked() []queueItem {
 		q.timer = nil
 	}
 
-	itemsToFlush := q.items
+	b := &batch{
+		items: q.items,
+		done:  make(chan struct{}),
+	}
 	q.items = make([]queueItem, 0, q.maxSize)
+	q.inFlight[b] = struct{}{}
 
-	return itemsToFlush
+	return b
 }
 
 // doFlush handles the queue flush, and sending notifications of assigned log indices.
 func (q *Queue) doFlush(ctx context.Context, f FlushFunc, entries []queueItem) {
-	err := otel.TraceErr(ctx, "tessera.storage.queue.doFlush", tracer, func(ctx context.Context, span trace.Span) error {
+	// Link the span for this flush to the spans of the calls which added the entries it contains.
+	links := make([]trace.Link, 0, len(entries))
+	for _, e := range entries {
+		if e.spanCtx.IsValid() {
+			links = append(links, trace.Link{SpanContext: e.spanCtx})
+		}
+	}
+	err := otel.TraceErr(ctx, "tessera.storage.queue.doFlush", q.tracer, func(ctx context.Context, span trace.Span) error {
 		entriesData := make([]*tessera.Entry, 0, len(entries))
 		for _, e := range entries {
 			entriesData = append(entriesData, e.entry)
 		}
 
 		return f(ctx, entriesData)
-	})
+	}, trace.WithLinks(links...))
 
 	// Send assigned indices to all the waiting Add() requests
 	for _, e := range entries {
@@ -158,6 +319,8 @@ type queueItem struct {
 	entry *tessera.Entry
 	f     tessera.IndexFuture
 	set   func(tessera.Index, error)
+	// spanCtx is the span context of the call which added this item, if any.
+	spanCtx trace.SpanContext
 }
 
 // newEntry creates a new entry for the provided data.
@@ -72,7 +72,7 @@ func TestQueue(t *testing.T) {
 			}
 
 			// Create the Queue
-			q := storage.NewQueue(ctx, test.maxWait, uint(test.maxEntries), flushFunc)
+			q := storage.NewQueue(ctx, test.maxWait, uint(test.maxEntries), nil, flushFunc)
 
 			// Now submit a bunch of entries
 			adds := make([]tessera.IndexFuture, test.numItems)
@@ -137,7 +137,7 @@ func TestNotify(t *testing.T) {
 			}
 
 			// Create the Queue
-			q := storage.NewQueue(ctx, time.Second, uint(1), flushFunc)
+			q := storage.NewQueue(ctx, time.Second, uint(1), nil, flushFunc)
 
 			// Now submit the entry
 			added := q.Add(ctx, tessera.NewEntry([]byte(test.name)))
@@ -160,7 +160,7 @@ func BenchmarkQueue(b *testing.B) {
 			}
 			return nil
 		}
-		q := storage.NewQueue(ctx, time.Second, 256, flushFn)
+		q := storage.NewQueue(ctx, time.Second, 256, nil, flushFn)
 
 		adds := make([]tessera.IndexFuture, 0, count)
 		for leafIndex := range count {
@@ -177,3 +177,154 @@ func BenchmarkQueue(b *testing.B) {
 		}
 	}
 }
+
+func TestQueueFlush(t *testing.T) {
+	ctx := t.Context()
+	flushed := make(chan int, 10)
+	release := make(chan struct{})
+	flushFunc := func(_ context.Context, entries []*tessera.Entry) error {
+		<-release
+		for i, e := range entries {
+			_ = e.MarshalBundleData(uint64(i))
+		}
+		flushed <- len(entries)
+		return nil
+	}
+
+	// Use a long max age and large max size so that nothing is flushed unless we ask for it.
+	q := storage.NewQueue(ctx, time.Hour, 100, nil, flushFunc)
+	for i := range 3 {
+		q.Add(ctx, tessera.NewEntry(fmt.Appendf(nil, "item %d", i)))
+	}
+
+	// Flush should block until the flushFunc has completed, so should time out here.
+	cctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	if err := q.Flush(cctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Flush with blocked flushFunc: got %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// Once the flushFunc is unblocked, a further Flush should wait for the in-flight batch to complete.
+	close(release)
+	if err := q.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	select {
+	case n := <-flushed:
+		if n != 3 {
+			t.Fatalf("Flushed %d entries, want 3", n)
+		}
+	default:
+		t.Fatal("Flush returned before entries were flushed")
+	}
+}
+
+func TestQueueSetBatchParams(t *testing.T) {
+	ctx := t.Context()
+	flushed := make(chan int, 10)
+	next := uint64(0)
+	flushFunc := func(_ context.Context, entries []*tessera.Entry) error {
+		for _, e := range entries {
+			_ = e.MarshalBundleData(next)
+			next++
+		}
+		flushed <- len(entries)
+		return nil
+	}
+	wantFlush := func(want int) {
+		t.Helper()
+		select {
+		case n := <-flushed:
+			if n != want {
+				t.Fatalf("Flushed %d entries, want %d", n, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timed out waiting for %d entries to be flushed", want)
+		}
+	}
+
+	// Use a long max age and large max size so that nothing is flushed until the parameters are changed.
+	q := storage.NewQueue(ctx, time.Hour, 100, nil, flushFunc)
+	var futures []tessera.IndexFuture
+	add := func(n int) {
+		for range n {
+			futures = append(futures, q.Add(ctx, tessera.NewEntry(fmt.Appendf(nil, "item %d", len(futures)))))
+		}
+	}
+
+	// Reducing the max age should cause already queued entries to be flushed once they reach it.
+	add(3)
+	q.SetBatchParams(100, 10*time.Millisecond)
+	wantFlush(3)
+
+	// Reducing the max size below the number of queued entries should flush them immediately.
+	q.SetBatchParams(100, time.Hour)
+	add(5)
+	q.SetBatchParams(4, time.Hour)
+	wantFlush(5)
+
+	// Subsequent batches should use the new max size.
+	add(4)
+	wantFlush(4)
+
+	for i, f := range futures {
+		idx, err := f()
+		if err != nil {
+			t.Fatalf("Future %d: %v", i, err)
+		}
+		if idx.Index != uint64(i) {
+			t.Errorf("Entry %d was assigned index %d", i, idx.Index)
+		}
+	}
+}
+
+func TestQueueMaxPending(t *testing.T) {
+	ctx := t.Context()
+	release := make(chan struct{})
+	var idx uint64
+	flushFunc := func(_ context.Context, entries []*tessera.Entry) error {
+		<-release
+		for _, e := range entries {
+			_ = e.MarshalBundleData(idx)
+			idx++
+		}
+		return nil
+	}
+
+	// A max size of 1 sends each entry to the flush func on its own, where it's held until released.
+	q := storage.NewQueue(ctx, time.Hour, 1, nil, flushFunc)
+	q.SetMaxPending(2, false)
+
+	f1 := q.Add(ctx, tessera.NewEntry([]byte("one")))
+	f2 := q.Add(ctx, tessera.NewEntry([]byte("two")))
+	if _, err := q.Add(ctx, tessera.NewEntry([]byte("three")))(); !errors.Is(err, tessera.ErrOverloaded) {
+		t.Fatalf("Add over limit: %v, want %v", err, tessera.ErrOverloaded)
+	}
+
+	// In blocking mode, Add should wait until there's space.
+	q.SetMaxPending(2, true)
+	added := make(chan tessera.IndexFuture)
+	go func() {
+		added <- q.Add(ctx, tessera.NewEntry([]byte("four")))
+	}()
+	select {
+	case <-added:
+		t.Fatal("Add returned before there was space")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Blocked callers should give up when their context is done.
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := q.Add(cctx, tessera.NewEntry([]byte("five")))(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Add with cancelled context: %v, want %v", err, context.Canceled)
+	}
+
+	close(release)
+	f4 := <-added
+	for i, f := range []tessera.IndexFuture{f1, f2, f4} {
+		if _, err := f(); err != nil {
+			t.Errorf("Future %d: %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package memory contains a Tessera storage implementation which holds the log entirely in memory.
+//
+// This is intended for use in tests of personalities and other code built on Tessera: it needs no
+// external resources, and the tiles and entry bundles it produces are identical to those produced by
+// the other storage implementations. The log is lost when the process exits.
+package memory
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/transparency-dev/merkle/rfc6962"
+	"github.com/transparency-dev/tessera"
+	"github.com/transparency-dev/tessera/api"
+	"github.com/transparency-dev/tessera/api/layout"
+	"github.com/transparency-dev/tessera/internal/fetcher"
+	storage "github.com/transparency-dev/tessera/storage/internal"
+)
+
+// Storage implements an in-memory storage for Tessera logs.
+type Storage struct {
+	mu sync.Mutex
+	// resources holds the static resources of the log, keyed by their https://c2sp.org/tlog-tiles path.
+	resources map[string][]byte
+	// size and root describe the integrated tree.
+	size uint64
+	root []byte
+}
+
+// appender implements the Tessera append lifecycle.
+type appender struct {
+	s           *Storage
+	entriesPath func(uint64, uint8) string
+	queue       *storage.Queue
+	newCP       func(context.Context, uint64, []byte) ([]byte, error)
+
+	cpUpdated chan struct{}
+
+	// publishedSize and publishedAt describe the most recently published checkpoint.
+	publishedSize uint64
+	publishedAt   time.Time
+}
+
+// New creates a new, empty, in-memory storage.
+func New() tessera.Driver {
+	return &Storage{
+		resources: make(map[string][]byte),
+		root:      rfc6962.DefaultHasher.EmptyRoot(),
+	}
+}
+
+func (s *Storage) Appender(ctx context.Context, opts *tessera.AppendOptions) (*tessera.Appender, tessera.LogReader, error) {
+	a := &appender{
+		s:           s,
+		entriesPath: opts.EntriesPath(),
+		cpUpdated:   make(chan struct{}, 1),
+	}
+	a.newCP = opts.CheckpointPublisher(a, http.DefaultClient)
+	if err := a.publishCheckpoint(ctx, 0, 0); err != nil {
+		return nil, nil, fmt.Errorf("failed to publish checkpoint: %v", err)
+	}
+	a.queue = storage.NewQueue(ctx, opts.BatchMaxAge(), opts.BatchMaxSize(), nil, a.sequenceBatch)
+	a.queue.SetMaxPending(opts.MaxPendingEntries())
+	go a.publishCheckpointJob(ctx, opts.CheckpointInterval(), opts.CheckpointRepublishInterval())
+
+	return &tessera.Appender{
+		Add: a.queue.Add,
+	}, a, nil
+}
+
+// read returns the resource stored at the given path, or os.ErrNotExist if there is none.
+func (s *Storage) read(p string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.resources[p]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return r, nil
+}
+
+func (a *appender) ReadCheckpoint(ctx context.Context) ([]byte, error) {
+	r, err := a.s.read(layout.CheckpointPath)
+	return r, storage.WrapNotFound(err)
+}
+
+func (a *appender) ReadTile(ctx context.Context, level, index uint64, p uint8) ([]byte, error) {
+	r, err := fetcher.PartialOrFullResource(ctx, p, func(ctx context.Context, p uint8) ([]byte, error) {
+		return a.s.read(layout.TilePath(level, index, p))
+	})
+	return r, storage.WrapNotFound(err)
+}
+
+func (a *appender) ReadEntryBundle(ctx context.Context, index uint64, p uint8) ([]byte, error) {
+	r, err := fetcher.PartialOrFullResource(ctx, p, func(ctx context.Context, p uint8) ([]byte, error) {
+		return a.s.read(a.entriesPath(index, p))
+	})
+	return r, storage.WrapNotFound(err)
+}
+
+func (a *appender) IntegratedSize(ctx context.Context) (uint64, error) {
+	a.s.mu.Lock()
+	defer a.s.mu.Unlock()
+	return a.s.size, nil
+}
+
+func (a *appender) NextIndex(ctx context.Context) (uint64, error) {
+	return a.IntegratedSize(ctx)
+}
+
+// sequenceBatch writes the entries from the provided batch into the entry bundles, and integrates
+// them into the tree.
+func (a *appender) sequenceBatch(ctx context.Context, entries []*tessera.Entry) error {
+	a.s.mu.Lock()
+	defer a.s.mu.Unlock()
+
+	if len(entries) == 0 {
+		return nil
+	}
+	seq := a.s.size
+	leafHashes := make([][]byte, 0, len(entries))
+	bundleIndex, entriesInBundle := seq/layout.EntryBundleWidth, seq%layout.EntryBundleWidth
+	currBundle := &bytes.Buffer{}
+	if entriesInBundle > 0 {
+		// If the latest bundle is partial, we need to extend the data it contains with our new entries.
+		currBundle.Write(a.s.resources[a.entriesPath(bundleIndex, uint8(entriesInBundle))])
+	}
+	for i, e := range entries {
+		currBundle.Write(e.MarshalBundleData(seq + uint64(i)))
+		leafHashes = append(leafHashes, e.LeafHash())
+
+		entriesInBundle++
+		if entriesInBundle == layout.EntryBundleWidth {
+			a.s.resources[a.entriesPath(bundleIndex, 0)] = currBundle.Bytes()
+			bundleIndex++
+			entriesInBundle = 0
+			currBundle = &bytes.Buffer{}
+		}
+	}
+	if entriesInBundle > 0 {
+		a.s.resources[a.entriesPath(bundleIndex, uint8(entriesInBundle))] = currBundle.Bytes()
+	}
+
+	newSize, newRoot, tiles, err := storage.Integrate(ctx, a.s.readTilesLocked, seq, leafHashes)
+	if err != nil {
+		return fmt.Errorf("error in Integrate: %v", err)
+	}
+	for k, v := range tiles {
+		t, err := v.MarshalText()
+		if err != nil {
+			return fmt.Errorf("failed to marshal tile: %v", err)
+		}
+		a.s.resources[layout.TilePath(uint64(k.Level), k.Index, layout.PartialTileSize(uint64(k.Level), k.Index, newSize))] = t
+	}
+	a.s.size, a.s.root = newSize, newRoot
+
+	// Notify that there's a new tree to publish, but don't block if there's already an outstanding notification.
+	select {
+	case a.cpUpdated <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// readTilesLocked returns the tiles with the given IDs for a tree of the given size, with nil in place of
+// any which don't exist. The caller must hold s.mu.
+func (s *Storage) readTilesLocked(ctx context.Context, tileIDs []storage.TileID, treeSize uint64) ([]*api.HashTile, error) {
+	r := make([]*api.HashTile, len(tileIDs))
+	for i, id := range tileIDs {
+		t, ok := s.resources[layout.TilePath(uint64(id.Level), id.Index, layout.PartialTileSize(uint64(id.Level), id.Index, treeSize))]
+		if !ok {
+			// Fall back to the full tile, in case the partial tile has since been completed.
+			if t, ok = s.resources[layout.TilePath(uint64(id.Level), id.Index, 0)]; !ok {
+				continue
+			}
+		}
+		r[i] = &api.HashTile{}
+		if err := r[i].UnmarshalText(t); err != nil {
+			return nil, fmt.Errorf("failed to parse tile: %v", err)
+		}
+	}
+	return r, nil
+}
+
+func (a *appender) publishCheckpointJob(ctx context.Context, pubInterval, republishInterval time.Duration) {
+	t := time.NewTicker(pubInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-a.cpUpdated:
+		case <-t.C:
+		}
+		if err := a.publishCheckpoint(ctx, pubInterval, republishInterval); err != nil {
+			slog.WarnContext(ctx, "publishCheckpoint failed", slog.Any("error", err))
+		}
+	}
+}
+
+// publishCheckpoint creates and stores a checkpoint for the integrated tree, unless the current checkpoint
+// is too recent to be replaced.
+func (a *appender) publishCheckpoint(ctx context.Context, minStalenessActive, minStalenessRepub time.Duration) error {
+	a.s.mu.Lock()
+	size, root := a.s.size, a.s.root
+	a.s.mu.Unlock()
+
+	if !a.publishedAt.IsZero() {
+		age := time.Since(a.publishedAt)
+		if age < minStalenessActive {
+			return nil
+		}
+		if size == a.publishedSize && (minStalenessRepub == 0 || age < minStalenessRepub) {
+			return nil
+		}
+	}
+
+	cpRaw, err := a.newCP(ctx, size, root)
+	if err != nil {
+		return fmt.Errorf("newCP: %v", err)
+	}
+	a.s.mu.Lock()
+	a.s.resources[layout.CheckpointPath] = cpRaw
+	a.s.mu.Unlock()
+	a.publishedSize, a.publishedAt = size, time.Now()
+	return nil
+}
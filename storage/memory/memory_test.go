@@ -0,0 +1,167 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/transparency-dev/formats/log"
+	"github.com/transparency-dev/merkle/rfc6962"
+	"github.com/transparency-dev/tessera"
+	"github.com/transparency-dev/tessera/api"
+	"github.com/transparency-dev/tessera/api/layout"
+	"github.com/transparency-dev/tessera/fsck"
+	"github.com/transparency-dev/tessera/storage/posix"
+	"golang.org/x/mod/sumdb/note"
+)
+
+func TestMatchesPOSIX(t *testing.T) {
+	ctx := t.Context()
+	sk, vk := mustGenerateKeys(t)
+	opts := tessera.NewAppendOptions().
+		WithCheckpointInterval(100*time.Millisecond).
+		WithBatching(100, 10*time.Millisecond).
+		WithCheckpointSigner(sk)
+
+	pd, err := posix.New(ctx, posix.Config{Path: t.TempDir()})
+	if err != nil {
+		t.Fatalf("posix.New: %v", err)
+	}
+	var readers []tessera.LogReader
+	var appenders []*tessera.Appender
+	for _, d := range []tessera.Driver{New(), pd} {
+		a, _, r, err := tessera.NewAppender(ctx, d, opts)
+		if err != nil {
+			t.Fatalf("NewAppender(%T): %v", d, err)
+		}
+		appenders = append(appenders, a)
+		readers = append(readers, r)
+	}
+	memReader, posixReader := readers[0], readers[1]
+
+	// Add entries in batches of varying sizes, so that the trees are built from many partial tiles and bundles.
+	const size = 2*layout.TileWidth + 13
+	for i := uint64(0); i < size; {
+		n := min(i%97+1, size-i)
+		for _, a := range appenders {
+			futures := make([]tessera.IndexFuture, 0, n)
+			for j := range n {
+				futures = append(futures, a.Add(ctx, tessera.NewEntry(fmt.Appendf(nil, "entry %d", i+j))))
+			}
+			for _, f := range futures {
+				if _, err := f(); err != nil {
+					t.Fatalf("Add: %v", err)
+				}
+			}
+		}
+		i += n
+	}
+
+	for ri := range layout.Range(0, size, size) {
+		want, err := posixReader.ReadEntryBundle(ctx, ri.Index, layout.PartialTileSize(0, ri.Index, size))
+		if err != nil {
+			t.Fatalf("posix ReadEntryBundle(%d): %v", ri.Index, err)
+		}
+		got, err := memReader.ReadEntryBundle(ctx, ri.Index, layout.PartialTileSize(0, ri.Index, size))
+		if err != nil {
+			t.Fatalf("ReadEntryBundle(%d): %v", ri.Index, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("Entry bundle %d differs from posix", ri.Index)
+		}
+	}
+	for level, n := uint64(0), uint64(size); n > 0; level, n = level+1, n>>layout.TileHeight {
+		for index := range (n + layout.TileWidth - 1) / layout.TileWidth {
+			p := layout.PartialTileSize(level, index, size)
+			want, err := posixReader.ReadTile(ctx, level, index, p)
+			if err != nil {
+				t.Fatalf("posix ReadTile(%d, %d, %d): %v", level, index, p, err)
+			}
+			got, err := memReader.ReadTile(ctx, level, index, p)
+			if err != nil {
+				t.Fatalf("ReadTile(%d, %d, %d): %v", level, index, p, err)
+			}
+			// Full tiles may be read in place of a partial tile, so only compare the requested nodes.
+			var gotTile, wantTile api.HashTile
+			if err := gotTile.UnmarshalText(got); err != nil {
+				t.Fatalf("UnmarshalText: %v", err)
+			}
+			if err := wantTile.UnmarshalText(want); err != nil {
+				t.Fatalf("UnmarshalText: %v", err)
+			}
+			if p == 0 && !bytes.Equal(got, want) {
+				t.Errorf("Tile(%d, %d) differs from posix", level, index)
+			}
+			for i := range int(p) {
+				if !bytes.Equal(gotTile.Nodes[i], wantTile.Nodes[i]) {
+					t.Errorf("Tile(%d, %d, %d) node %d differs from posix", level, index, p, i)
+				}
+			}
+		}
+	}
+
+	// Wait for a checkpoint committing to all the entries, and check that everything it implies is present.
+	for {
+		cpRaw, err := memReader.ReadCheckpoint(ctx)
+		if err != nil {
+			t.Fatalf("ReadCheckpoint: %v", err)
+		}
+		cp, _, _, err := log.ParseCheckpoint(cpRaw, vk.Name(), vk)
+		if err != nil {
+			t.Fatalf("ParseCheckpoint: %v", err)
+		}
+		if cp.Size == size {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	f := fsck.New(vk.Name(), vk, memReader, defaultMerkleLeafHasher, fsck.Opts{N: 1})
+	if err := f.Check(ctx); err != nil {
+		t.Fatalf("FSCK failed: %v", err)
+	}
+}
+
+func mustGenerateKeys(t *testing.T) (note.Signer, note.Verifier) {
+	sk, vk, err := note.GenerateKey(nil, "testlog")
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	s, err := note.NewSigner(sk)
+	if err != nil {
+		t.Fatalf("NewSigner: %v", err)
+	}
+	v, err := note.NewVerifier(vk)
+	if err != nil {
+		t.Fatalf("NewVerifier: %v", err)
+	}
+	return s, v
+}
+
+// defaultMerkleLeafHasher parses a C2SP tlog-tile bundle and returns the Merkle leaf hashes of each entry it contains.
+func defaultMerkleLeafHasher(bundle []byte) ([][]byte, error) {
+	eb := &api.EntryBundle{}
+	if err := eb.UnmarshalText(bundle); err != nil {
+		return nil, fmt.Errorf("unmarshal: %v", err)
+	}
+	r := make([][]byte, 0, len(eb.Entries))
+	for _, e := range eb.Entries {
+		h := rfc6962.DefaultHasher.HashLeaf(e)
+		r = append(r, h[:])
+	}
+	return r, nil
+}
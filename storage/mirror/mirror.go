@@ -0,0 +1,298 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package mirror contains a Tessera storage implementation which wraps another, duplicating the log's
+// static resources to one or more secondary targets as they're written.
+//
+// All reads are served by the primary storage, and writes to the log only succeed once the primary has
+// succeeded. The secondary targets are written to from the resources published by the primary, so they
+// can be any location which is able to store files, e.g. a different cloud provider, or a local disk.
+package mirror
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/transparency-dev/formats/log"
+	"github.com/transparency-dev/tessera"
+	"github.com/transparency-dev/tessera/api"
+	"github.com/transparency-dev/tessera/api/layout"
+)
+
+// Target is a secondary location to which the log's resources are copied.
+//
+// Implementations should overwrite any existing resource, as the same resource may be written more than
+// once, e.g. after an earlier failure.
+type Target interface {
+	// WriteCheckpoint stores the log's latest checkpoint.
+	WriteCheckpoint(ctx context.Context, data []byte) error
+	// WriteTile stores the tile at the given level and index, with the given partial size, or 0 for a full tile.
+	WriteTile(ctx context.Context, level, index uint64, p uint8, data []byte) error
+	// WriteEntryBundle stores the entry bundle at the given index, with the given partial size, or 0 for a full bundle.
+	WriteEntryBundle(ctx context.Context, index uint64, p uint8, data []byte) error
+}
+
+// Policy determines how failures to write to a secondary target are handled.
+type Policy int
+
+const (
+	// LogFailures causes failures to write to a secondary target to be logged, without affecting the caller.
+	// Resources which couldn't be written are retried when the log next grows.
+	LogFailures Policy = iota
+	// FailOnError causes the future returned by Add to fail if the resources containing the new entry can't be
+	// written to every secondary target, even though the entry has been added to the primary storage.
+	FailOnError
+)
+
+// Driver is a Tessera storage implementation which mirrors the resources of a primary storage.
+type Driver struct {
+	primary tessera.Driver
+	policy  Policy
+	targets []Target
+}
+
+// New creates a new storage which duplicates the resources written by the primary storage to the given targets.
+//
+// Only the append lifecycle of the primary storage is supported.
+func New(primary tessera.Driver, policy Policy, targets ...Target) tessera.Driver {
+	return &Driver{
+		primary: primary,
+		policy:  policy,
+		targets: targets,
+	}
+}
+
+func (d *Driver) Appender(ctx context.Context, opts *tessera.AppendOptions) (*tessera.Appender, tessera.LogReader, error) {
+	type appendLifecycle interface {
+		Appender(context.Context, *tessera.AppendOptions) (*tessera.Appender, tessera.LogReader, error)
+	}
+	lc, ok := d.primary.(appendLifecycle)
+	if !ok {
+		return nil, nil, fmt.Errorf("primary driver %T does not implement Appender lifecycle", d.primary)
+	}
+	a, r, err := lc.Appender(ctx, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	m := &mirror{
+		r:       r,
+		policy:  d.policy,
+		targets: d.targets,
+	}
+	a.Add = m.addDecorator(a.Add)
+	go m.mirrorCheckpointJob(ctx, opts.CheckpointInterval())
+
+	return a, r, nil
+}
+
+// mirror copies the resources of a log from the primary storage to the secondary targets.
+type mirror struct {
+	r       tessera.LogReader
+	policy  Policy
+	targets []Target
+
+	mu sync.Mutex
+	// size is the number of entries whose bundles and tiles have been written to every target.
+	size uint64
+	// cpRaw is the checkpoint most recently written to every target.
+	cpRaw []byte
+}
+
+// addDecorator wraps the primary storage's Add function so that the resources containing each new entry
+// are mirrored before the future returned for it resolves.
+func (m *mirror) addDecorator(delegate tessera.AddFn) tessera.AddFn {
+	return func(ctx context.Context, entry *tessera.Entry) tessera.IndexFuture {
+		f := delegate(ctx, entry)
+		return func() (tessera.Index, error) {
+			idx, err := f()
+			if err != nil {
+				return idx, err
+			}
+			if err := m.syncTo(ctx, idx.Index+1); err != nil {
+				slog.WarnContext(ctx, "Failed to mirror entry", slog.Uint64("index", idx.Index), slog.Any("error", err))
+				if m.policy == FailOnError {
+					return idx, fmt.Errorf("failed to mirror entry %d: %v", idx.Index, err)
+				}
+			}
+			return idx, nil
+		}
+	}
+}
+
+// syncTo writes the bundles and tiles of a tree of at least the given size to the targets, if they haven't
+// been already.
+//
+// Entries which the primary storage hasn't yet integrated are left to be mirrored later.
+func (m *mirror) syncTo(ctx context.Context, size uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if size <= m.size {
+		return nil
+	}
+	n, err := m.r.IntegratedSize(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read integrated size: %v", err)
+	}
+	if n <= m.size {
+		return nil
+	}
+	if err := m.copyRange(ctx, m.size, n); err != nil {
+		return err
+	}
+	m.size = n
+	return nil
+}
+
+// mirrorCheckpointJob periodically copies the primary storage's checkpoint to the targets, along with the
+// resources it commits to.
+func (m *mirror) mirrorCheckpointJob(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		if err := m.mirrorCheckpoint(ctx); err != nil {
+			slog.WarnContext(ctx, "Failed to mirror checkpoint", slog.Any("error", err))
+		}
+	}
+}
+
+// mirrorCheckpoint writes the primary storage's current checkpoint to the targets if it has changed.
+//
+// The resources committed to by the checkpoint are written first, so that the targets never hold a
+// checkpoint for a tree they can't serve.
+func (m *mirror) mirrorCheckpoint(ctx context.Context) error {
+	cpRaw, err := m.r.ReadCheckpoint(ctx)
+	if err != nil {
+		if errors.Is(err, tessera.ErrNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to read checkpoint: %v", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if bytes.Equal(cpRaw, m.cpRaw) {
+		return nil
+	}
+	cp := &log.Checkpoint{}
+	if _, err := cp.Unmarshal(cpRaw); err != nil {
+		return fmt.Errorf("failed to parse checkpoint: %v", err)
+	}
+	if cp.Size > 0 {
+		// Even if the tree has already been mirrored beyond the checkpoint, the partial resources at its
+		// right-hand edge are still needed by clients which have it.
+		if err := m.copyRange(ctx, min(m.size, cp.Size-1), cp.Size); err != nil {
+			return err
+		}
+		m.size = max(m.size, cp.Size)
+	}
+	if err := m.forEachTarget(func(t Target) error { return t.WriteCheckpoint(ctx, cpRaw) }); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %v", err)
+	}
+	m.cpRaw = cpRaw
+	return nil
+}
+
+// copyRange writes the bundles and tiles which contain the entries in [from, size) in a tree of the given
+// size to the targets.
+//
+// m.mu must be held.
+func (m *mirror) copyRange(ctx context.Context, from, size uint64) error {
+	for ri := range layout.Range(from, size-from, size) {
+		p := layout.PartialTileSize(0, ri.Index, size)
+		b, err := m.r.ReadEntryBundle(ctx, ri.Index, p)
+		if err != nil {
+			return fmt.Errorf("failed to read entry bundle %d: %v", ri.Index, err)
+		}
+		// The primary may return the full bundle in place of a partial one if it's since been completed.
+		if b, err = trimBundle(b, p); err != nil {
+			return fmt.Errorf("invalid entry bundle %d: %v", ri.Index, err)
+		}
+		if err := m.forEachTarget(func(t Target) error { return t.WriteEntryBundle(ctx, ri.Index, p, b) }); err != nil {
+			return fmt.Errorf("failed to write entry bundle %d: %v", ri.Index, err)
+		}
+	}
+	for level, n := uint64(0), size; n > 0; level, n = level+1, n>>layout.TileHeight {
+		first := (from >> (level * layout.TileHeight)) / layout.TileWidth
+		for index := first; index < (n+layout.TileWidth-1)/layout.TileWidth; index++ {
+			p := layout.PartialTileSize(level, index, size)
+			raw, err := m.r.ReadTile(ctx, level, index, p)
+			if err != nil {
+				return fmt.Errorf("failed to read tile(%d, %d): %v", level, index, err)
+			}
+			if raw, err = trimTile(raw, p); err != nil {
+				return fmt.Errorf("invalid tile(%d, %d): %v", level, index, err)
+			}
+			if err := m.forEachTarget(func(t Target) error { return t.WriteTile(ctx, level, index, p, raw) }); err != nil {
+				return fmt.Errorf("failed to write tile(%d, %d): %v", level, index, err)
+			}
+		}
+	}
+	return nil
+}
+
+// forEachTarget calls f for every target, returning the errors from any which failed.
+func (m *mirror) forEachTarget(f func(Target) error) error {
+	errs := make([]error, 0, len(m.targets))
+	for _, t := range m.targets {
+		errs = append(errs, f(t))
+	}
+	return errors.Join(errs...)
+}
+
+// trimTile returns the first p hashes of the given tile, or the whole tile if p is 0.
+func trimTile(raw []byte, p uint8) ([]byte, error) {
+	if p == 0 {
+		return raw, nil
+	}
+	t := api.HashTile{}
+	if err := t.UnmarshalText(raw); err != nil {
+		return nil, err
+	}
+	if len(t.Nodes) < int(p) {
+		return nil, fmt.Errorf("tile has %d hashes, want at least %d", len(t.Nodes), p)
+	}
+	t.Nodes = t.Nodes[:p]
+	return t.MarshalText()
+}
+
+// trimBundle returns the first p entries of the given bundle, or the whole bundle if p is 0.
+func trimBundle(raw []byte, p uint8) ([]byte, error) {
+	if p == 0 {
+		return raw, nil
+	}
+	end := 0
+	for range p {
+		if end+2 > len(raw) {
+			return nil, fmt.Errorf("bundle has fewer than %d entries", p)
+		}
+		end += 2 + int(binary.BigEndian.Uint16(raw[end:]))
+		if end > len(raw) {
+			return nil, errors.New("bundle is truncated")
+		}
+	}
+	return raw[:end], nil
+}
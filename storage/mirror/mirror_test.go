@@ -0,0 +1,201 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mirror
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/transparency-dev/tessera"
+	"github.com/transparency-dev/tessera/api/layout"
+	"github.com/transparency-dev/tessera/storage/memory"
+	"golang.org/x/mod/sumdb/note"
+)
+
+// memTarget is a Target which stores resources in memory, keyed by their path.
+type memTarget struct {
+	mu        sync.Mutex
+	resources map[string][]byte
+	err       error
+}
+
+func newMemTarget() *memTarget {
+	return &memTarget{resources: make(map[string][]byte)}
+}
+
+func (m *memTarget) write(p string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	m.resources[p] = bytes.Clone(data)
+	return nil
+}
+
+func (m *memTarget) get(p string) ([]byte, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r, ok := m.resources[p]
+	return r, ok
+}
+
+func (m *memTarget) WriteCheckpoint(_ context.Context, data []byte) error {
+	return m.write(layout.CheckpointPath, data)
+}
+
+func (m *memTarget) WriteTile(_ context.Context, level, index uint64, p uint8, data []byte) error {
+	return m.write(layout.TilePath(level, index, p), data)
+}
+
+func (m *memTarget) WriteEntryBundle(_ context.Context, index uint64, p uint8, data []byte) error {
+	return m.write(layout.EntriesPath(index, p), data)
+}
+
+func TestMirror(t *testing.T) {
+	ctx := t.Context()
+	sk, _ := mustGenerateKeys(t)
+	opts := tessera.NewAppendOptions().
+		WithCheckpointInterval(100*time.Millisecond).
+		WithBatching(100, 10*time.Millisecond).
+		WithCheckpointSigner(sk)
+
+	targets := []*memTarget{newMemTarget(), newMemTarget()}
+	a, _, r, err := tessera.NewAppender(ctx, New(memory.New(), FailOnError, targets[0], targets[1]), opts)
+	if err != nil {
+		t.Fatalf("NewAppender: %v", err)
+	}
+
+	// Add entries in batches of varying sizes, so that many partial tiles and bundles are mirrored.
+	const size = layout.TileWidth + 13
+	for i := uint64(0); i < size; {
+		n := min(i%37+1, size-i)
+		futures := make([]tessera.IndexFuture, 0, n)
+		for j := range n {
+			futures = append(futures, a.Add(ctx, tessera.NewEntry(fmt.Appendf(nil, "entry %d", i+j))))
+		}
+		for _, f := range futures {
+			if _, err := f(); err != nil {
+				t.Fatalf("Add: %v", err)
+			}
+		}
+		i += n
+	}
+
+	for _, target := range targets {
+		for _, ri := range []struct {
+			index uint64
+			p     uint8
+		}{{0, 0}, {1, 13}} {
+			want, err := r.ReadEntryBundle(ctx, ri.index, ri.p)
+			if err != nil {
+				t.Fatalf("ReadEntryBundle(%d, %d): %v", ri.index, ri.p, err)
+			}
+			if got, ok := target.get(layout.EntriesPath(ri.index, ri.p)); !bytes.Equal(got, want) {
+				t.Errorf("Mirrored entry bundle(%d, %d) (present: %t) differs from primary", ri.index, ri.p, ok)
+			}
+			want, err = r.ReadTile(ctx, 0, ri.index, ri.p)
+			if err != nil {
+				t.Fatalf("ReadTile(0, %d, %d): %v", ri.index, ri.p, err)
+			}
+			if got, ok := target.get(layout.TilePath(0, ri.index, ri.p)); !bytes.Equal(got, want) {
+				t.Errorf("Mirrored tile(0, %d, %d) (present: %t) differs from primary", ri.index, ri.p, ok)
+			}
+		}
+		if _, ok := target.get(layout.TilePath(1, 0, 1)); !ok {
+			t.Errorf("Tile(1, 0, 1) was not mirrored")
+		}
+	}
+
+	// Wait for the checkpoint committing to all the entries to be mirrored.
+	for {
+		cpRaw, err := r.ReadCheckpoint(ctx)
+		if err != nil {
+			t.Fatalf("ReadCheckpoint: %v", err)
+		}
+		got0, _ := targets[0].get(layout.CheckpointPath)
+		got1, _ := targets[1].get(layout.CheckpointPath)
+		if bytes.Contains(cpRaw, fmt.Appendf(nil, "\n%d\n", size)) && bytes.Equal(got0, cpRaw) && bytes.Equal(got1, cpRaw) {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatal("Timed out waiting for checkpoint to be mirrored")
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+func TestMirrorPolicy(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		policy  Policy
+		wantErr bool
+	}{
+		{name: "log failures", policy: LogFailures},
+		{name: "fail on error", policy: FailOnError, wantErr: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := t.Context()
+			sk, _ := mustGenerateKeys(t)
+			opts := tessera.NewAppendOptions().
+				WithCheckpointInterval(time.Minute).
+				WithBatching(1, time.Millisecond).
+				WithCheckpointSigner(sk)
+
+			target := newMemTarget()
+			target.err = errors.New("bang")
+			a, _, _, err := tessera.NewAppender(ctx, New(memory.New(), test.policy, target), opts)
+			if err != nil {
+				t.Fatalf("NewAppender: %v", err)
+			}
+			if _, err := a.Add(ctx, tessera.NewEntry([]byte("one")))(); (err != nil) != test.wantErr {
+				t.Fatalf("Add with failing target: %v, wantErr %t", err, test.wantErr)
+			}
+
+			// Once the target recovers, the entries it missed are mirrored with the next one.
+			target.mu.Lock()
+			target.err = nil
+			target.mu.Unlock()
+			if _, err := a.Add(ctx, tessera.NewEntry([]byte("two")))(); err != nil {
+				t.Fatalf("Add: %v", err)
+			}
+			if _, ok := target.get(layout.EntriesPath(0, 2)); !ok {
+				t.Errorf("Entry bundle(0, 2) was not mirrored after target recovered")
+			}
+		})
+	}
+}
+
+func mustGenerateKeys(t *testing.T) (note.Signer, note.Verifier) {
+	sk, vk, err := note.GenerateKey(nil, "testlog")
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	s, err := note.NewSigner(sk)
+	if err != nil {
+		t.Fatalf("NewSigner: %v", err)
+	}
+	v, err := note.NewVerifier(vk)
+	if err != nil {
+		t.Fatalf("NewVerifier: %v", err)
+	}
+	return s, v
+}
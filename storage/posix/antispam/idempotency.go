@@ -0,0 +1,75 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package badger
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+// IdempotencyStorage uses Badger to persist the mapping between caller-defined idempotency keys
+// and the indices assigned to their entries.
+//
+// This implements tessera.IdempotencyStore.
+type IdempotencyStorage struct {
+	db *badger.DB
+}
+
+// NewIdempotencyStore returns an idempotency store backed by a Badger DB at badgerPath, which will be
+// created if it doesn't exist.
+//
+// The path must not be shared with any antispam storage.
+func NewIdempotencyStore(badgerPath string) (*IdempotencyStorage, error) {
+	db, err := badger.Open(badger.DefaultOptions(badgerPath).WithLogger(&slogger{}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open badger: %v", err)
+	}
+	return &IdempotencyStorage{db: db}, nil
+}
+
+// Index returns the index previously stored for the provided key, or nil if there is none.
+func (s *IdempotencyStorage) Index(_ context.Context, key string) (*uint64, error) {
+	var idx *uint64
+	err := s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(key))
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		return item.Value(func(v []byte) error {
+			i := binary.BigEndian.Uint64(v)
+			idx = &i
+			return nil
+		})
+	})
+	return idx, err
+}
+
+// Store durably associates the provided key with idx.
+func (s *IdempotencyStorage) Store(_ context.Context, key string, idx uint64) error {
+	return s.db.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte(key), binary.BigEndian.AppendUint64(nil, idx))
+	})
+}
+
+// Close releases the underlying Badger DB.
+func (s *IdempotencyStorage) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,56 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package badger
+
+import (
+	"testing"
+
+	"github.com/transparency-dev/tessera"
+)
+
+func TestIdempotencyStorage(t *testing.T) {
+	ctx := t.Context()
+	dir := t.TempDir()
+	var s tessera.IdempotencyStore
+	is, err := NewIdempotencyStore(dir)
+	if err != nil {
+		t.Fatalf("NewIdempotencyStore: %v", err)
+	}
+	s = is
+
+	if idx, err := s.Index(ctx, "key"); err != nil || idx != nil {
+		t.Fatalf("Index(missing): got (%v, %v), want (nil, nil)", idx, err)
+	}
+	if err := s.Store(ctx, "key", 42); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+	if err := is.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	// The mapping should survive reopening the store.
+	is, err = NewIdempotencyStore(dir)
+	if err != nil {
+		t.Fatalf("NewIdempotencyStore: %v", err)
+	}
+	defer func() { _ = is.Close() }()
+	idx, err := is.Index(ctx, "key")
+	if err != nil {
+		t.Fatalf("Index: %v", err)
+	}
+	if idx == nil || *idx != 42 {
+		t.Errorf("Index: got %v, want 42", idx)
+	}
+}
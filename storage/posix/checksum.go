@@ -0,0 +1,63 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package posix
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// checksumSuffix is added to the path of a tile to form the path of its checksum sidecar file.
+const checksumSuffix = ".sha256"
+
+// ErrTileCorrupt is returned when a tile read from disk does not match its stored checksum.
+var ErrTileCorrupt = errors.New("tile does not match its checksum")
+
+// isChecksumPath returns true if p is the path of a checksum sidecar file.
+func isChecksumPath(p string) bool {
+	return strings.HasSuffix(p, checksumSuffix)
+}
+
+// writeTileChecksum stores the checksum of the serialised tile t alongside the tile at path p.
+//
+// Like tiles themselves, this is idempotent: an existing sidecar with the expected checksum is left untouched.
+func (s *Storage) writeTileChecksum(p string, t []byte) error {
+	h := sha256.Sum256(t)
+	if existing, err := s.readAll(p + checksumSuffix); err == nil && bytes.Equal(existing, h[:]) {
+		return nil
+	}
+	return s.createOverwrite(p+checksumSuffix, h[:])
+}
+
+// verifyTileChecksum checks the serialised tile t, read from path p, against its stored checksum.
+//
+// Tiles with no checksum sidecar, e.g. those written before checksums were enabled, are not verified.
+func (s *Storage) verifyTileChecksum(p string, t []byte) error {
+	want, err := s.readAll(p + checksumSuffix)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to read checksum for tile %q: %v", p, err)
+	}
+	if h := sha256.Sum256(t); !bytes.Equal(h[:], want) {
+		return fmt.Errorf("%w: %q", ErrTileCorrupt, p)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package posix
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/transparency-dev/merkle/rfc6962"
+	"github.com/transparency-dev/tessera"
+	"github.com/transparency-dev/tessera/api/layout"
+)
+
+func TestTileChecksums(t *testing.T) {
+	ctx := t.Context()
+	dir := t.TempDir()
+	s := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: dir, TileChecksums: true}}
+	sk, _ := mustGenerateKeys(t)
+	opts := tessera.NewAppendOptions().
+		WithCheckpointInterval(10*time.Minute).
+		WithBatching(1000, time.Hour).
+		WithCheckpointSigner(sk)
+	logStorage := &logResourceStorage{
+		s:           s,
+		entriesPath: opts.EntriesPath(),
+	}
+	if _, _, err := s.newAppender(ctx, logStorage, opts); err != nil {
+		t.Fatalf("Appender: %v", err)
+	}
+
+	lh := make([][]byte, 0, layout.TileWidth+10)
+	for i := range layout.TileWidth + 10 {
+		lh = append(lh, tessera.NewEntry(fmt.Appendf(nil, "entry %d", i)).LeafHash())
+	}
+	if _, _, err := doIntegrate(ctx, rfc6962.DefaultHasher, 0, lh, logStorage); err != nil {
+		t.Fatalf("doIntegrate: %v", err)
+	}
+
+	for _, p := range []string{layout.TilePath(0, 0, 0), layout.TilePath(0, 1, 10)} {
+		if _, err := os.Stat(filepath.Join(dir, p+checksumSuffix)); err != nil {
+			t.Errorf("Checksum for tile %q: %v", p, err)
+		}
+	}
+	if _, err := logStorage.readTile(ctx, 0, 1, 10); err != nil {
+		t.Fatalf("readTile: %v", err)
+	}
+
+	// Truncate the partial tile so that it still parses, but contains fewer hashes.
+	tPath := filepath.Join(dir, layout.TilePath(0, 1, 10))
+	raw, err := os.ReadFile(tPath)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if err := os.WriteFile(tPath, raw[:len(raw)-32], filePerm); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	if _, err := logStorage.readTile(ctx, 0, 1, 10); !errors.Is(err, ErrTileCorrupt) {
+		t.Errorf("readTile of truncated tile: %v, want %v", err, ErrTileCorrupt)
+	}
+	if _, err := logStorage.ReadTile(ctx, 0, 1, 10); !errors.Is(err, ErrTileCorrupt) {
+		t.Errorf("ReadTile of truncated tile: %v, want %v", err, ErrTileCorrupt)
+	}
+
+	// Without checksums, the truncated tile goes undetected.
+	s.cfg.TileChecksums = false
+	if _, err := logStorage.readTile(ctx, 0, 1, 10); err != nil {
+		t.Errorf("readTile without checksums: %v", err)
+	}
+}
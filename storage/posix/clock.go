@@ -0,0 +1,88 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package posix
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+)
+
+// Clock is the source of time used to schedule and pace checkpoint publication, and to time leader leases.
+//
+// This exists so that tests can control the passage of time; most users should leave Config.Clock unset
+// to use the system clock.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// NewTicker returns a Ticker which ticks with the given period.
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker delivers ticks at intervals, like time.Ticker.
+type Ticker interface {
+	// C returns the channel on which the ticks are delivered.
+	C() <-chan time.Time
+	// Stop turns off the ticker.
+	Stop()
+}
+
+// systemClock is a Clock backed by the time package.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) NewTicker(d time.Duration) Ticker { return systemTicker{time.NewTicker(d)} }
+
+// systemTicker is a Ticker backed by a time.Ticker.
+type systemTicker struct {
+	t *time.Ticker
+}
+
+func (t systemTicker) C() <-chan time.Time { return t.t.C }
+
+func (t systemTicker) Stop() { t.t.Stop() }
+
+// clock returns the clock which should be used by this storage.
+func (s *Storage) clock() Clock {
+	if s.cfg.Clock != nil {
+		return s.cfg.Clock
+	}
+	return systemClock{}
+}
+
+// sleep blocks for the duration d according to the storage's clock, returning false if ctx becomes done first.
+func (s *Storage) sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+	t := s.clock().NewTicker(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C():
+		return true
+	}
+}
+
+// jitter returns a random duration in the range [0, f*d).
+func jitter(d time.Duration, f float64) time.Duration {
+	n := int64(f * float64(d))
+	if n <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int64N(n))
+}
@@ -0,0 +1,165 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package posix
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/transparency-dev/tessera"
+)
+
+// fakeClock is a Clock whose time only changes when advanced, and whose tickers only tick when told to.
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	tickers []*fakeTicker
+	// started is closed when the first ticker is created.
+	started chan struct{}
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Now(), started: make(chan struct{})}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) NewTicker(time.Duration) Ticker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &fakeTicker{c: make(chan time.Time)}
+	c.tickers = append(c.tickers, t)
+	if len(c.tickers) == 1 {
+		close(c.started)
+	}
+	return t
+}
+
+// Advance moves the clock forward by d.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Tick delivers a tick to every ticker created by the clock, blocking until there is at least one ticker,
+// and each has received the tick.
+func (c *fakeClock) Tick(ctx context.Context) {
+	select {
+	case <-c.started:
+	case <-ctx.Done():
+		return
+	}
+	c.mu.Lock()
+	tickers, now := c.tickers, c.now
+	c.mu.Unlock()
+	for _, t := range tickers {
+		select {
+		case t.c <- now:
+		case <-ctx.Done():
+		}
+	}
+}
+
+type fakeTicker struct {
+	c chan time.Time
+}
+
+func (t *fakeTicker) C() <-chan time.Time { return t.c }
+
+func (t *fakeTicker) Stop() {}
+
+func TestPublishCheckpointJobClock(t *testing.T) {
+	ctx := t.Context()
+	clk := newFakeClock()
+	s := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: t.TempDir(), Clock: clk}}
+	sk, _ := mustGenerateKeys(t)
+	opts := tessera.NewAppendOptions().
+		WithCheckpointInterval(10*time.Minute).
+		WithBatching(1, minCheckpointInterval).
+		WithCheckpointSigner(sk)
+	a, lr, err := s.newAppender(ctx, &logResourceStorage{s: s, entriesPath: opts.EntriesPath()}, opts)
+	if err != nil {
+		t.Fatalf("Appender: %v", err)
+	}
+	cpOld, err := lr.ReadCheckpoint(ctx)
+	if err != nil {
+		t.Fatalf("ReadCheckpoint: %v", err)
+	}
+
+	// Grow the tree without notifying the publisher, so that only ticks prompt it to publish.
+	if err := a.s.writeTreeState(ctx, 1, []byte("root")); err != nil {
+		t.Fatalf("writeTreeState: %v", err)
+	}
+	clk.Tick(ctx)
+	// A second tick can only be received once the first has been handled.
+	clk.Tick(ctx)
+	if cp, err := lr.ReadCheckpoint(ctx); err != nil {
+		t.Fatalf("ReadCheckpoint: %v", err)
+	} else if !bytes.Equal(cp, cpOld) {
+		t.Fatalf("Checkpoint published before the checkpoint interval had elapsed:\n%s", cp)
+	}
+
+	// Only tick once more, so that no further publication is in progress when the test ends.
+	clk.Advance(11 * time.Minute)
+	clk.Tick(ctx)
+	for {
+		cp, err := lr.ReadCheckpoint(ctx)
+		if err != nil {
+			t.Fatalf("ReadCheckpoint: %v", err)
+		}
+		if bytes.Contains(cp, fmt.Appendf(nil, "\n1\n")) {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatal("Checkpoint not published for grown tree once checkpoint interval had elapsed")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestJitter(t *testing.T) {
+	const d = time.Minute
+	if got := jitter(d, 0); got != 0 {
+		t.Errorf("jitter(%v, 0) = %v, want 0", d, got)
+	}
+	seen := map[time.Duration]bool{}
+	for range 100 {
+		got := jitter(d, 0.5)
+		if got < 0 || got >= d/2 {
+			t.Fatalf("jitter(%v, 0.5) = %v, want in [0, %v)", d, got, d/2)
+		}
+		seen[got] = true
+	}
+	if len(seen) < 2 {
+		t.Errorf("jitter(%v, 0.5) returned the same offset every time", d)
+	}
+
+	for _, f := range []float64{-0.1, 1.1} {
+		if _, err := New(t.Context(), Config{Path: t.TempDir(), PublishJitter: f}); err == nil {
+			t.Errorf("New with PublishJitter %v succeeded", f)
+		}
+	}
+}
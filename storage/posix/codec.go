@@ -0,0 +1,154 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package posix
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// BundleCodec encodes entry bundles for storage on disk.
+//
+// Regardless of the codec used to store them, entry bundles are always returned by the LogReader in the
+// form described by https://c2sp.org/tlog-tiles, i.e. as the concatenation of the entries they contain,
+// each as marshalled by tessera.Entry.MarshalBundleData.
+type BundleCodec interface {
+	// Name identifies the codec, and is recorded in the log's state directory when the log is created.
+	Name() string
+	// Append returns the encoded bundle b extended with the provided marshalled entries.
+	// b is empty when a new bundle is being started.
+	Append(b []byte, entries ...[]byte) ([]byte, error)
+	// Decode returns the concatenation of the marshalled entries held in the encoded bundle b.
+	Decode(b []byte) ([]byte, error)
+}
+
+// TLogTilesCodec is the default BundleCodec, which stores entry bundles exactly as described by
+// https://c2sp.org/tlog-tiles: the marshalled entries are simply concatenated, with no header.
+type TLogTilesCodec struct{}
+
+// Name returns "tlog-tiles".
+func (TLogTilesCodec) Name() string { return "tlog-tiles" }
+
+// Append appends the entries to b.
+func (TLogTilesCodec) Append(b []byte, entries ...[]byte) ([]byte, error) {
+	for _, e := range entries {
+		b = append(b, e...)
+	}
+	return b, nil
+}
+
+// Decode returns b unchanged.
+func (TLogTilesCodec) Decode(b []byte) ([]byte, error) { return b, nil }
+
+// BinaryV2Codec is a BundleCodec which stores entry bundles in a self-describing binary format, making
+// the boundaries between entries explicit regardless of how they were marshalled, for consumers which
+// don't know the log's entry format.
+//
+// An encoded bundle comprises a 7 byte header followed by the entries:
+//
+//	bundle = magic version count entry*
+//	magic   = "TSBN"          ; 4 bytes
+//	version = %x02            ; 1 byte
+//	count   = uint16          ; number of entries which follow, big-endian
+//	entry   = length data
+//	length  = uint32          ; length of data, big-endian
+//	data    = length bytes    ; the entry as marshalled by tessera.Entry.MarshalBundleData
+//
+// There must be exactly count entries, and no trailing data. Since entries are marshalled before being
+// encoded, entries in the default tlog-tiles format retain their own uint16 length prefix within data.
+type BinaryV2Codec struct{}
+
+const (
+	binaryV2Magic      = "TSBN"
+	binaryV2Version    = 2
+	binaryV2HeaderSize = len(binaryV2Magic) + 1 + 2
+)
+
+// Name returns "binary-v2".
+func (BinaryV2Codec) Name() string { return "binary-v2" }
+
+// Append appends the entries to b, updating the count in its header, which is created if b is empty.
+func (c BinaryV2Codec) Append(b []byte, entries ...[]byte) ([]byte, error) {
+	if len(b) == 0 {
+		b = append([]byte(binaryV2Magic), binaryV2Version, 0, 0)
+	}
+	n, err := c.header(b)
+	if err != nil {
+		return nil, err
+	}
+	if n+len(entries) > math.MaxUint16 {
+		return nil, fmt.Errorf("too many entries (%d) for bundle", n+len(entries))
+	}
+	binary.BigEndian.PutUint16(b[len(binaryV2Magic)+1:], uint16(n+len(entries)))
+	for i, e := range entries {
+		if uint64(len(e)) > math.MaxUint32 {
+			return nil, fmt.Errorf("entry %d too large (%d bytes)", i, len(e))
+		}
+		b = binary.BigEndian.AppendUint32(b, uint32(len(e)))
+		b = append(b, e...)
+	}
+	return b, nil
+}
+
+// Decode checks the header of b, and returns the concatenation of the entries it contains.
+func (c BinaryV2Codec) Decode(b []byte) ([]byte, error) {
+	n, err := c.header(b)
+	if err != nil {
+		return nil, err
+	}
+	r := &bytes.Buffer{}
+	b = b[binaryV2HeaderSize:]
+	for i := range n {
+		if len(b) < 4 {
+			return nil, fmt.Errorf("truncated length prefix for entry %d", i)
+		}
+		l := uint64(binary.BigEndian.Uint32(b))
+		b = b[4:]
+		if uint64(len(b)) < l {
+			return nil, fmt.Errorf("entry %d has length %d, but only %d bytes remain", i, l, len(b))
+		}
+		r.Write(b[:l])
+		b = b[l:]
+	}
+	if len(b) > 0 {
+		return nil, fmt.Errorf("%d bytes of trailing data after %d entries", len(b), n)
+	}
+	return r.Bytes(), nil
+}
+
+// header validates the header of the encoded bundle b, and returns the number of entries it declares.
+func (BinaryV2Codec) header(b []byte) (int, error) {
+	if len(b) < binaryV2HeaderSize {
+		return 0, errors.New("truncated bundle header")
+	}
+	if string(b[:len(binaryV2Magic)]) != binaryV2Magic {
+		return 0, fmt.Errorf("invalid bundle magic %q", b[:len(binaryV2Magic)])
+	}
+	if v := b[len(binaryV2Magic)]; v != binaryV2Version {
+		return 0, fmt.Errorf("unsupported bundle version %d", v)
+	}
+	return int(binary.BigEndian.Uint16(b[len(binaryV2Magic)+1:])), nil
+}
+
+// bundleCodec returns the codec configured for the log, or the default if none is.
+func (s *Storage) bundleCodec() BundleCodec {
+	if s.cfg.BundleCodec == nil {
+		return TLogTilesCodec{}
+	}
+	return s.cfg.BundleCodec
+}
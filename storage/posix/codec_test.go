@@ -0,0 +1,191 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package posix
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/transparency-dev/tessera"
+	"github.com/transparency-dev/tessera/api/layout"
+)
+
+func TestBundleCodecRoundTrip(t *testing.T) {
+	entries := [][]byte{[]byte("one"), {}, []byte("three"), []byte("four")}
+	want := bytes.Join(entries, nil)
+	for _, c := range []BundleCodec{TLogTilesCodec{}, BinaryV2Codec{}} {
+		t.Run(c.Name(), func(t *testing.T) {
+			// Bundles are built up over several calls, as partial bundles are extended.
+			b, err := c.Append(nil, entries[:1]...)
+			if err != nil {
+				t.Fatalf("Append: %v", err)
+			}
+			if b, err = c.Append(b, entries[1:]...); err != nil {
+				t.Fatalf("Append: %v", err)
+			}
+			got, err := c.Decode(b)
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("Decode: got %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestBinaryV2CodecFormat(t *testing.T) {
+	c := BinaryV2Codec{}
+	b, err := c.Append(nil, []byte("ab"), []byte("c"))
+	if err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	want := []byte("TSBN\x02\x00\x02\x00\x00\x00\x02ab\x00\x00\x00\x01c")
+	if !bytes.Equal(b, want) {
+		t.Fatalf("Append: got %x, want %x", b, want)
+	}
+
+	for _, test := range []struct {
+		desc string
+		b    []byte
+		// badHeader is set if appending to b must also fail.
+		badHeader bool
+	}{
+		{desc: "bad magic", b: append([]byte("XSBN"), want[4:]...), badHeader: true},
+		{desc: "bad version", b: append([]byte("TSBN\x01"), want[5:]...), badHeader: true},
+		{desc: "truncated header", b: want[:6], badHeader: true},
+		{desc: "truncated length", b: want[:len(want)-3]},
+		{desc: "truncated entry", b: want[:len(want)-1]},
+		{desc: "trailing data", b: append(bytes.Clone(want), 0)},
+		{desc: "too few entries", b: append([]byte("TSBN\x02\x00\x03"), want[7:]...)},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			if _, err := c.Decode(test.b); err == nil {
+				t.Error("Decode succeeded, want error")
+			}
+			if _, err := c.Append(bytes.Clone(test.b), []byte("x")); (err != nil) != test.badHeader {
+				t.Errorf("Append: got err %v, want err %t", err, test.badHeader)
+			}
+		})
+	}
+}
+
+func TestBundleCodecBinaryV2(t *testing.T) {
+	ctx := t.Context()
+	dir := t.TempDir()
+	cfg := Config{HTTPClient: http.DefaultClient, Path: dir, BundleCodec: BinaryV2Codec{}, BundleCompression: BundleCompressionGzip}
+	s := &Storage{cfg: cfg}
+	sk, _ := mustGenerateKeys(t)
+	opts := tessera.NewAppendOptions().
+		WithCheckpointInterval(10*time.Minute).
+		WithBatching(100, time.Hour).
+		WithCheckpointSigner(sk)
+	logStorage := &logResourceStorage{s: s, entriesPath: opts.EntriesPath()}
+	appender, _, err := s.newAppender(ctx, logStorage, opts)
+	if err != nil {
+		t.Fatalf("Appender: %v", err)
+	}
+
+	// Add entries in several batches, so that partial bundles are read back and extended.
+	const size = layout.EntryBundleWidth + 10
+	for i := range size {
+		appender.Add(ctx, tessera.NewEntry(fmt.Appendf(nil, "entry %d", i)))
+		if i%100 == 0 {
+			if err := s.Flush(ctx); err != nil {
+				t.Fatalf("Flush: %v", err)
+			}
+		}
+	}
+	if err := s.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	i := uint64(0)
+	for e, err := range s.StreamEntries(ctx, 0, size) {
+		if err != nil {
+			t.Fatalf("StreamEntries: %v", err)
+		}
+		if want := fmt.Appendf(nil, "entry %d", i); !bytes.Equal(e, want) {
+			t.Fatalf("Entry %d = %q, want %q", i, e, want)
+		}
+		i++
+	}
+
+	want, err := logStorage.ReadEntryBundle(ctx, 1, 10)
+	if err != nil {
+		t.Fatalf("ReadEntryBundle: %v", err)
+	}
+	r, err := s.OpenEntryBundle(ctx, 1, 10)
+	if err != nil {
+		t.Fatalf("OpenEntryBundle: %v", err)
+	}
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Error("OpenEntryBundle returned different data to ReadEntryBundle")
+	}
+
+	// Bundles are encoded before being compressed.
+	raw, err := os.ReadFile(filepath.Join(dir, layout.EntriesPath(0, 0)+BundleCompressionGzip.suffix()))
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if raw, err = BundleCompressionGzip.decompress(raw); err != nil {
+		t.Fatalf("decompress: %v", err)
+	}
+	if !bytes.HasPrefix(raw, []byte(binaryV2Magic)) {
+		t.Errorf("Stored bundle starts with %q, want %q", raw[:min(len(raw), 4)], binaryV2Magic)
+	}
+
+	// Reopening the log with a different codec must fail.
+	cfg.BundleCodec = nil
+	s2 := &Storage{cfg: cfg}
+	if _, _, err := s2.newAppender(ctx, &logResourceStorage{s: s2, entriesPath: opts.EntriesPath()}, opts); err == nil {
+		t.Error("newAppender with default bundle codec succeeded on binary-v2 log, want error")
+	}
+	if _, _, err := s.MigrationWriter(ctx, tessera.NewMigrationOptions()); err == nil {
+		t.Error("MigrationWriter succeeded with binary-v2 codec, want error")
+	}
+}
+
+func TestBundleCodecExistingLog(t *testing.T) {
+	ctx := t.Context()
+	dir := t.TempDir()
+	s := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: dir}}
+	// Simulate a log which was created before bundle codecs were supported.
+	if err := os.MkdirAll(filepath.Join(dir, defaultStateDir), dirPerm); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	if err := s.writeTreeState(ctx, 0, []byte("root")); err != nil {
+		t.Fatalf("writeTreeState: %v", err)
+	}
+
+	s.cfg.BundleCodec = BinaryV2Codec{}
+	if err := s.ensureVersion(compatibilityVersion, tessera.DefaultHasherName); err == nil {
+		t.Fatal("ensureVersion with binary-v2 codec on existing log succeeded, want error")
+	}
+	s.cfg.BundleCodec = nil
+	if err := s.ensureVersion(compatibilityVersion, tessera.DefaultHasherName); err != nil {
+		t.Fatalf("ensureVersion with tlog-tiles codec: %v", err)
+	}
+}
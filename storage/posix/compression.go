@@ -0,0 +1,120 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package posix
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// BundleCompression identifies the codec used to compress entry bundles stored on disk.
+type BundleCompression string
+
+const (
+	// BundleCompressionNone stores entry bundles uncompressed, exactly as described by https://c2sp.org/tlog-tiles.
+	BundleCompressionNone BundleCompression = ""
+	// BundleCompressionGzip stores entry bundles compressed with gzip, with a ".gz" suffix added to their paths.
+	BundleCompressionGzip BundleCompression = "gzip"
+	// BundleCompressionZstd stores entry bundles compressed with zstd, with a ".zst" suffix added to their paths.
+	BundleCompressionZstd BundleCompression = "zstd"
+)
+
+var (
+	// zstdEncoder and zstdDecoder are safe for concurrent use via EncodeAll and DecodeAll respectively.
+	zstdEncoder, _ = zstd.NewWriter(nil)
+	zstdDecoder, _ = zstd.NewReader(nil)
+)
+
+// validate returns an error if c is not a known codec.
+func (c BundleCompression) validate() error {
+	switch c {
+	case BundleCompressionNone, BundleCompressionGzip, BundleCompressionZstd:
+		return nil
+	}
+	return fmt.Errorf("unknown bundle compression %q", c)
+}
+
+// suffix returns the suffix added to the paths of entry bundles compressed with c.
+func (c BundleCompression) suffix() string {
+	switch c {
+	case BundleCompressionGzip:
+		return ".gz"
+	case BundleCompressionZstd:
+		return ".zst"
+	}
+	return ""
+}
+
+// compress returns the data compressed with c.
+func (c BundleCompression) compress(data []byte) ([]byte, error) {
+	switch c {
+	case BundleCompressionNone:
+		return data, nil
+	case BundleCompressionGzip:
+		b := &bytes.Buffer{}
+		w := gzip.NewWriter(b)
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return b.Bytes(), nil
+	case BundleCompressionZstd:
+		return zstdEncoder.EncodeAll(data, nil), nil
+	}
+	return nil, c.validate()
+}
+
+// decompress returns the data decompressed with c.
+func (c BundleCompression) decompress(data []byte) ([]byte, error) {
+	switch c {
+	case BundleCompressionNone:
+		return data, nil
+	case BundleCompressionGzip:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = r.Close() }()
+		return io.ReadAll(r)
+	case BundleCompressionZstd:
+		return zstdDecoder.DecodeAll(data, nil)
+	}
+	return nil, c.validate()
+}
+
+// newReader returns a reader which decompresses data compressed with c as it's read from r.
+//
+// Closing the returned reader does not close r.
+func (c BundleCompression) newReader(r io.Reader) (io.ReadCloser, error) {
+	switch c {
+	case BundleCompressionNone:
+		return io.NopCloser(r), nil
+	case BundleCompressionGzip:
+		return gzip.NewReader(r)
+	case BundleCompressionZstd:
+		d, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return d.IOReadCloser(), nil
+	}
+	return nil, c.validate()
+}
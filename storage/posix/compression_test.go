@@ -0,0 +1,152 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package posix
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/transparency-dev/tessera"
+	"github.com/transparency-dev/tessera/api/layout"
+)
+
+func TestBundleCompression(t *testing.T) {
+	for _, c := range []BundleCompression{BundleCompressionNone, BundleCompressionGzip, BundleCompressionZstd} {
+		t.Run(string(c), func(t *testing.T) {
+			ctx := t.Context()
+			dir := t.TempDir()
+			s := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: dir, BundleCompression: c}}
+			sk, _ := mustGenerateKeys(t)
+			opts := tessera.NewAppendOptions().
+				WithCheckpointInterval(10*time.Minute).
+				WithBatching(100, time.Hour).
+				WithCheckpointSigner(sk)
+			logStorage := &logResourceStorage{
+				s:           s,
+				entriesPath: opts.EntriesPath(),
+			}
+			appender, _, err := s.newAppender(ctx, logStorage, opts)
+			if err != nil {
+				t.Fatalf("Appender: %v", err)
+			}
+
+			// Add entries in several batches, so that partial bundles are read back and extended.
+			const size = layout.EntryBundleWidth + 10
+			for i := range size {
+				appender.Add(ctx, tessera.NewEntry(fmt.Appendf(nil, "entry %d", i)))
+				if i%100 == 0 {
+					if err := s.Flush(ctx); err != nil {
+						t.Fatalf("Flush: %v", err)
+					}
+				}
+			}
+			if err := s.Flush(ctx); err != nil {
+				t.Fatalf("Flush: %v", err)
+			}
+
+			i := uint64(0)
+			for e, err := range s.StreamEntries(ctx, 0, size) {
+				if err != nil {
+					t.Fatalf("StreamEntries: %v", err)
+				}
+				if want := fmt.Appendf(nil, "entry %d", i); !bytes.Equal(e, want) {
+					t.Fatalf("Entry %d = %q, want %q", i, e, want)
+				}
+				i++
+			}
+
+			// Streaming bundles must give the same result as reading them, including falling back from a
+			// partial bundle to the full bundle which replaced it.
+			for _, b := range []struct {
+				index uint64
+				p     uint8
+			}{{0, 0}, {0, 1}, {1, 10}} {
+				want, err := logStorage.ReadEntryBundle(ctx, b.index, b.p)
+				if err != nil {
+					t.Fatalf("ReadEntryBundle(%d, %d): %v", b.index, b.p, err)
+				}
+				r, err := s.OpenEntryBundle(ctx, b.index, b.p)
+				if err != nil {
+					t.Fatalf("OpenEntryBundle(%d, %d): %v", b.index, b.p, err)
+				}
+				got, err := io.ReadAll(r)
+				if err != nil {
+					t.Fatalf("ReadAll(%d, %d): %v", b.index, b.p, err)
+				}
+				if err := r.Close(); err != nil {
+					t.Errorf("Close(%d, %d): %v", b.index, b.p, err)
+				}
+				if !bytes.Equal(got, want) {
+					t.Errorf("OpenEntryBundle(%d, %d) returned different data to ReadEntryBundle", b.index, b.p)
+				}
+			}
+			if _, err := s.OpenEntryBundle(ctx, 2, 0); !errors.Is(err, tessera.ErrNotFound) {
+				t.Errorf("OpenEntryBundle(2, 0) = %v, want ErrNotFound", err)
+			}
+
+			raw, err := os.ReadFile(filepath.Join(dir, layout.EntriesPath(0, 0)+c.suffix()))
+			if err != nil {
+				t.Fatalf("ReadFile: %v", err)
+			}
+			if isPlain := bytes.Contains(raw, []byte("entry 0")); isPlain != (c == BundleCompressionNone) {
+				t.Errorf("Stored bundle contains plaintext entries: %t, want %t", isPlain, c == BundleCompressionNone)
+			}
+
+			// Reopening the log with a different codec must fail.
+			other := BundleCompressionGzip
+			if c == other {
+				other = BundleCompressionNone
+			}
+			s2 := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: dir, BundleCompression: other}}
+			if _, _, err := s2.newAppender(ctx, &logResourceStorage{s: s2, entriesPath: opts.EntriesPath()}, opts); err == nil {
+				t.Errorf("newAppender with bundle compression %q succeeded on log with %q, want error", other, c)
+			}
+		})
+	}
+}
+
+func TestBundleCompressionExistingLog(t *testing.T) {
+	ctx := t.Context()
+	dir := t.TempDir()
+	s := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: dir}}
+	// Simulate a log which was created before bundle compression was supported.
+	if err := os.MkdirAll(filepath.Join(dir, defaultStateDir), dirPerm); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	if err := s.writeTreeState(ctx, 0, []byte("root")); err != nil {
+		t.Fatalf("writeTreeState: %v", err)
+	}
+
+	for _, test := range []struct {
+		c       BundleCompression
+		wantErr bool
+	}{
+		{c: BundleCompressionZstd, wantErr: true},
+		{c: BundleCompressionNone},
+		{c: "lz4", wantErr: true},
+	} {
+		s.cfg.BundleCompression = test.c
+		if err := s.ensureVersion(compatibilityVersion, tessera.DefaultHasherName); (err != nil) != test.wantErr {
+			t.Errorf("ensureVersion with bundle compression %q: got err %v, want err %t", test.c, err, test.wantErr)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package posix
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/transparency-dev/tessera/api/layout"
+	"github.com/transparency-dev/tessera/internal/otel"
+	storage "github.com/transparency-dev/tessera/storage/internal"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// checkpointCache holds the most recently read checkpoint, along with the file info it was read with.
+type checkpointCache struct {
+	mu   sync.Mutex
+	info os.FileInfo
+	raw  []byte
+}
+
+// get returns the cached checkpoint if it was read from the file described by info, which must not
+// have been modified since.
+func (c *checkpointCache) get(info os.FileInfo) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.info == nil || !os.SameFile(c.info, info) || !c.info.ModTime().Equal(info.ModTime()) || c.info.Size() != info.Size() {
+		return nil, false
+	}
+	return c.raw, true
+}
+
+func (c *checkpointCache) set(info os.FileInfo, raw []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.info, c.raw = info, raw
+}
+
+// ReadCheckpointWithModTime returns the latest published checkpoint, along with the time at which it was
+// last modified. This is intended to allow HTTP handlers to set the Last-Modified and ETag headers.
+//
+// If Config.CheckpointCache is set, the checkpoint is only read from disk when its file has changed since
+// it was last read.
+func (s *Storage) ReadCheckpointWithModTime(ctx context.Context) ([]byte, time.Time, error) {
+	return otel.Trace2(ctx, "tessera.storage.posix.ReadCheckpointWithModTime", s.tracer(), func(ctx context.Context, span trace.Span) ([]byte, time.Time, error) {
+		info, err := s.stat(layout.CheckpointPath)
+		if err != nil {
+			return nil, time.Time{}, storage.WrapNotFound(err)
+		}
+		if s.cfg.CheckpointCache {
+			if r, ok := s.cpCache.get(info); ok {
+				span.SetAttributes(cacheHitKey.Bool(true))
+				return r, info.ModTime(), nil
+			}
+		}
+		// The checkpoint may have been replaced since the stat above, in which case the newer checkpoint
+		// is returned and cached alongside the older file info; the next read will notice the change.
+		r, err := s.readAll(layout.CheckpointPath)
+		if err != nil {
+			return nil, time.Time{}, storage.WrapNotFound(err)
+		}
+		if s.cfg.CheckpointCache {
+			s.cpCache.set(info, r)
+		}
+		return r, info.ModTime(), nil
+	})
+}
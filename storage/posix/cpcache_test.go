@@ -0,0 +1,73 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package posix
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/transparency-dev/tessera"
+	"github.com/transparency-dev/tessera/api/layout"
+)
+
+func TestCheckpointCache(t *testing.T) {
+	ctx := t.Context()
+	dir := t.TempDir()
+	s := &Storage{cfg: Config{Path: dir, CheckpointCache: true}}
+	lr := s.logReader()
+
+	if _, err := lr.ReadCheckpoint(ctx); !errors.Is(err, tessera.ErrNotFound) {
+		t.Fatalf("ReadCheckpoint without checkpoint = %v, want %v", err, tessera.ErrNotFound)
+	}
+
+	cpPath := filepath.Join(dir, layout.CheckpointPath)
+	mtime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.WriteFile(cpPath, []byte("one"), filePerm); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := os.Chtimes(cpPath, mtime, mtime); err != nil {
+		t.Fatalf("Chtimes: %v", err)
+	}
+	got, gotMTime, err := s.ReadCheckpointWithModTime(ctx)
+	if err != nil {
+		t.Fatalf("ReadCheckpointWithModTime: %v", err)
+	}
+	if !bytes.Equal(got, []byte("one")) || !gotMTime.Equal(mtime) {
+		t.Errorf("ReadCheckpointWithModTime = %q, %v, want %q, %v", got, gotMTime, "one", mtime)
+	}
+
+	// Modifying the file in place without changing its size or modification time isn't noticed...
+	if err := os.WriteFile(cpPath, []byte("two"), filePerm); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := os.Chtimes(cpPath, mtime, mtime); err != nil {
+		t.Fatalf("Chtimes: %v", err)
+	}
+	if got, err := lr.ReadCheckpoint(ctx); err != nil || !bytes.Equal(got, []byte("one")) {
+		t.Errorf("ReadCheckpoint of unchanged file = %q, %v, want cached %q", got, err, "one")
+	}
+
+	// ...but publishing a new checkpoint is.
+	if err := s.createOverwrite(layout.CheckpointPath, []byte("three")); err != nil {
+		t.Fatalf("createOverwrite: %v", err)
+	}
+	if got, err := lr.ReadCheckpoint(ctx); err != nil || !bytes.Equal(got, []byte("three")) {
+		t.Errorf("ReadCheckpoint of new checkpoint = %q, %v, want %q", got, err, "three")
+	}
+}
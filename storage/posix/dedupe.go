@@ -0,0 +1,403 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package posix
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/transparency-dev/tessera/api/layout"
+	"github.com/transparency-dev/tessera/internal/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// leafIndexFile is the name of the file in the state directory which holds the leaf hash of every entry
+	// in the log, in order, when Config.Dedupe is set.
+	leafIndexFile = "leafIndex"
+	// leafIndexTableFile is the name of the file in the state directory which holds the hash table used to
+	// find leaf hashes in leafIndexFile.
+	leafIndexTableFile = "leafIndex.table"
+
+	// leafIndexMinSlots is the number of slots in a new leaf index table. The number of slots is always a
+	// power of two, and is doubled whenever the table would otherwise become more than half full.
+	leafIndexMinSlots = 1024
+	// leafIndexHeaderSize is the size of the table's header, which holds the number of leaf hashes in it.
+	leafIndexHeaderSize = 8
+	// leafIndexSlotSize is the size of each slot in the table, which holds one more than the index of the
+	// leaf hash stored in it, or zero if it's empty.
+	leafIndexSlotSize = 8
+)
+
+// leafIndex maps the leaf hashes of the entries in the log to their indices.
+//
+// It's backed by two files: one containing the concatenated leaf hashes of the log's entries, so the index
+// of an entry is implied by the position of its hash, and an open-addressed hash table whose slots hold
+// the indices of those hashes. Neither is held in memory, so the index uses a constant amount of memory
+// regardless of the size of the log, and lookups rely on the OS page cache.
+//
+// The files are only appended to once the entries have been integrated, so they may lag behind the tree
+// after a crash; they're brought up to date from the level 0 tiles when the appender is created.
+type leafIndex struct {
+	path      string
+	tablePath string
+	hashSize  int
+
+	mu sync.Mutex
+	// size is the number of leaf hashes in the index.
+	size uint64
+}
+
+// openLeafIndex opens the leaf index for a tree of the given size, bringing it up to date from the tree's
+// level 0 tiles if necessary.
+//
+// The caller must hold the tree state lock.
+func (a *appender) openLeafIndex(ctx context.Context, treeSize uint64) (*leafIndex, error) {
+	dir := filepath.Join(a.s.cfg.Path, a.s.stateDir())
+	li := &leafIndex{
+		path:      filepath.Join(dir, leafIndexFile),
+		tablePath: filepath.Join(dir, leafIndexTableFile),
+		hashSize:  a.hasher.Size(),
+	}
+	fi, err := os.Stat(li.path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return nil, fmt.Errorf("failed to stat leaf index: %v", err)
+	default:
+		li.size = uint64(fi.Size()) / uint64(li.hashSize)
+		if li.size > treeSize {
+			// The tree must have been rolled back, so forget the entries which are no longer in it.
+			a.s.logger().WarnContext(ctx, "Leaf index is ahead of tree, truncating", slog.Uint64("indexSize", li.size), slog.Uint64("treeSize", treeSize))
+			li.size = treeSize
+		}
+		// This also drops any trailing partial hash, which was being written when the log was last closed.
+		if want := int64(li.size) * int64(li.hashSize); fi.Size() != want {
+			if err := os.Truncate(li.path, want); err != nil {
+				return nil, fmt.Errorf("failed to truncate leaf index: %v", err)
+			}
+		}
+	}
+	// The table is rebuilt if it doesn't hold exactly the hashes in the index, e.g. because the index was
+	// truncated, or the process crashed while updating it.
+	if n, err := li.tableSize(); err != nil || n != li.size {
+		if err := li.rebuild(a.s); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := a.catchUpLeafIndex(ctx, li, treeSize); err != nil {
+		return nil, err
+	}
+	return li, nil
+}
+
+// catchUpLeafIndex adds the leaf hashes of any entries in a tree of the given size which are missing from
+// the leaf index, reading them from the tree's level 0 tiles.
+//
+// The caller must hold the tree state lock.
+func (a *appender) catchUpLeafIndex(ctx context.Context, li *leafIndex, treeSize uint64) error {
+	for size := li.loaded(); size < treeSize; size = li.loaded() {
+		tileIndex := size / layout.TileWidth
+		t, err := a.logStorage.readTile(ctx, 0, tileIndex, layout.PartialTileSize(0, tileIndex, treeSize))
+		if err != nil {
+			return fmt.Errorf("failed to read tile %d to populate leaf index: %v", tileIndex, err)
+		}
+		first := size % layout.TileWidth
+		if t == nil || uint64(len(t.Nodes)) <= first {
+			return fmt.Errorf("tile %d is missing leaf hashes needed to populate leaf index", tileIndex)
+		}
+		if err := li.extend(a.s, t.Nodes[first:]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateLeafIndex adds the leaf hashes of entries newly integrated at fromSize to the leaf index.
+//
+// The caller must hold the tree state lock.
+func (a *appender) updateLeafIndex(ctx context.Context, fromSize uint64, leafHashes [][]byte) error {
+	if a.leafIndex.loaded() != fromSize {
+		// A previous update must have failed, so fill in the gap from the tiles, which now include these entries too.
+		return a.catchUpLeafIndex(ctx, a.leafIndex, fromSize+uint64(len(leafHashes)))
+	}
+	return a.leafIndex.extend(a.s, leafHashes)
+}
+
+// extend adds the leaf hashes of newly integrated entries to the index.
+//
+// The caller must hold the tree state lock.
+func (li *leafIndex) extend(s *Storage, leafHashes [][]byte) error {
+	li.mu.Lock()
+	defer li.mu.Unlock()
+
+	buf := make([]byte, 0, len(leafHashes)*li.hashSize)
+	for _, h := range leafHashes {
+		if len(h) != li.hashSize {
+			return fmt.Errorf("leaf hash has length %d, want %d", len(h), li.hashSize)
+		}
+		buf = append(buf, h...)
+	}
+	if err := s.retry(func() error {
+		f, err := os.OpenFile(li.path, os.O_WRONLY|os.O_CREATE, filePerm)
+		if err != nil {
+			return err
+		}
+		// Write at the offset we expect, rather than appending, so that a failed partial write is overwritten
+		// when retried.
+		_, err = f.WriteAt(buf, int64(li.size)*int64(li.hashSize))
+		if err == nil && !s.cfg.DisableSyncWrites {
+			err = f.Sync()
+		}
+		return errors.Join(err, f.Close())
+	}); err != nil {
+		return fmt.Errorf("failed to write leaf index: %v", err)
+	}
+
+	// The hashes are written before being added to the table, so the table never refers to a missing hash.
+	newSize := li.size + uint64(len(leafHashes))
+	if err := li.addToTable(s, li.size, leafHashes); err != nil {
+		return err
+	}
+	li.size = newSize
+	return nil
+}
+
+// addToTable adds the given leaf hashes, the first of which is at index from, to the table, growing the
+// table if necessary.
+//
+// li.mu must be held.
+func (li *leafIndex) addToTable(s *Storage, from uint64, leafHashes [][]byte) error {
+	newSize := from + uint64(len(leafHashes))
+	t, err := os.OpenFile(li.tablePath, os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open leaf index table: %v", err)
+	}
+	nSlots, err := tableSlots(t)
+	if err != nil {
+		return errors.Join(err, t.Close())
+	}
+	if 2*newSize > nSlots {
+		if err := t.Close(); err != nil {
+			return fmt.Errorf("failed to close leaf index table: %v", err)
+		}
+		li.size = newSize
+		if err := li.rebuild(s); err != nil {
+			li.size = from
+			return err
+		}
+		return nil
+	}
+
+	err = func() error {
+		hashes, err := os.Open(li.path)
+		if err != nil {
+			return fmt.Errorf("failed to open leaf index: %v", err)
+		}
+		defer func() { _ = hashes.Close() }()
+		for i, h := range leafHashes {
+			if err := li.insert(t, hashes, nSlots, h, from+uint64(i)); err != nil {
+				return err
+			}
+		}
+		return li.writeTableSize(t, newSize, !s.cfg.DisableSyncWrites)
+	}()
+	return errors.Join(err, t.Close())
+}
+
+// rebuild replaces the table with a new one holding all of the hashes in the index.
+//
+// li.mu must be held, or li must not yet be shared.
+func (li *leafIndex) rebuild(s *Storage) error {
+	nSlots := uint64(leafIndexMinSlots)
+	for 2*li.size > nSlots {
+		nSlots *= 2
+	}
+	dir := filepath.Dir(li.tablePath)
+	t, err := os.CreateTemp(dir, leafIndexTableFile+"-*")
+	if err != nil {
+		return fmt.Errorf("failed to create leaf index table: %v", err)
+	}
+	defer func() { _ = os.Remove(t.Name()) }()
+
+	err = func() error {
+		if err := t.Truncate(leafIndexHeaderSize + int64(nSlots)*leafIndexSlotSize); err != nil {
+			return fmt.Errorf("failed to size leaf index table: %v", err)
+		}
+		if li.size > 0 {
+			hashes, err := os.Open(li.path)
+			if err != nil {
+				return fmt.Errorf("failed to open leaf index: %v", err)
+			}
+			defer func() { _ = hashes.Close() }()
+			r := bufio.NewReader(io.NewSectionReader(hashes, 0, int64(li.size)*int64(li.hashSize)))
+			h := make([]byte, li.hashSize)
+			for i := range li.size {
+				if _, err := io.ReadFull(r, h); err != nil {
+					return fmt.Errorf("failed to read leaf index: %v", err)
+				}
+				if err := li.insert(t, hashes, nSlots, h, i); err != nil {
+					return err
+				}
+			}
+		}
+		return li.writeTableSize(t, li.size, !s.cfg.DisableSyncWrites)
+	}()
+	if err := errors.Join(err, t.Close()); err != nil {
+		return err
+	}
+	return maybeSyncDir(dir, !s.cfg.DisableSyncWrites, func() error {
+		if err := os.Rename(t.Name(), li.tablePath); err != nil {
+			return fmt.Errorf("failed to replace leaf index table: %v", err)
+		}
+		return nil
+	})
+}
+
+// insert stores the index i of leaf hash h in the table t, which has nSlots slots, unless h is already
+// present at a lower index.
+func (li *leafIndex) insert(t, hashes *os.File, nSlots uint64, h []byte, i uint64) error {
+	slot, _, found, err := li.probe(t, hashes, nSlots, h)
+	if err != nil || found {
+		return err
+	}
+	b := binary.BigEndian.AppendUint64(nil, i+1)
+	if _, err := t.WriteAt(b, leafIndexHeaderSize+int64(slot)*leafIndexSlotSize); err != nil {
+		return fmt.Errorf("failed to write leaf index table: %v", err)
+	}
+	return nil
+}
+
+// probe searches the table t, which has nSlots slots, for the leaf hash h. It returns the index of h if
+// it's found, and otherwise the empty slot in which it should be stored.
+func (li *leafIndex) probe(t, hashes *os.File, nSlots uint64, h []byte) (slot uint64, idx uint64, found bool, err error) {
+	fh := fnv.New64a()
+	_, _ = fh.Write(h)
+	slot = fh.Sum64() & (nSlots - 1)
+	b := make([]byte, leafIndexSlotSize)
+	got := make([]byte, li.hashSize)
+	// The table is never more than half full, so this always finds an empty slot.
+	for ; ; slot = (slot + 1) & (nSlots - 1) {
+		if _, err := t.ReadAt(b, leafIndexHeaderSize+int64(slot)*leafIndexSlotSize); err != nil {
+			return 0, 0, false, fmt.Errorf("failed to read leaf index table: %v", err)
+		}
+		v := binary.BigEndian.Uint64(b)
+		if v == 0 {
+			return slot, 0, false, nil
+		}
+		if _, err := hashes.ReadAt(got, int64(v-1)*int64(li.hashSize)); err != nil {
+			if errors.Is(err, io.EOF) {
+				// The index has been truncated since this slot was written.
+				continue
+			}
+			return 0, 0, false, fmt.Errorf("failed to read leaf index: %v", err)
+		}
+		if bytes.Equal(got, h) {
+			return slot, v - 1, true, nil
+		}
+	}
+}
+
+// tableSize returns the number of leaf hashes held in the table.
+func (li *leafIndex) tableSize() (uint64, error) {
+	t, err := os.Open(li.tablePath)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = t.Close() }()
+	b := make([]byte, leafIndexHeaderSize)
+	if _, err := t.ReadAt(b, 0); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(b), nil
+}
+
+// writeTableSize records the number of leaf hashes held in the table t, syncing it if sync is true.
+func (li *leafIndex) writeTableSize(t *os.File, size uint64, sync bool) error {
+	if _, err := t.WriteAt(binary.BigEndian.AppendUint64(nil, size), 0); err != nil {
+		return fmt.Errorf("failed to write leaf index table: %v", err)
+	}
+	if sync {
+		if err := t.Sync(); err != nil {
+			return fmt.Errorf("failed to sync leaf index table: %v", err)
+		}
+	}
+	return nil
+}
+
+// tableSlots returns the number of slots in the table t.
+func tableSlots(t *os.File) (uint64, error) {
+	fi, err := t.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat leaf index table: %v", err)
+	}
+	return uint64(fi.Size()-leafIndexHeaderSize) / leafIndexSlotSize, nil
+}
+
+// loaded returns the number of leaf hashes in the index.
+func (li *leafIndex) loaded() uint64 {
+	li.mu.Lock()
+	defer li.mu.Unlock()
+	return li.size
+}
+
+// lookup returns the lowest index at which the given leaf hash appears in the log, if it does.
+//
+// This reads the index from disk, so it also finds entries added by other processes appending to the log.
+func (li *leafIndex) lookup(h []byte) (uint64, bool, error) {
+	t, err := os.Open(li.tablePath)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to open leaf index table: %v", err)
+	}
+	defer func() { _ = t.Close() }()
+	hashes, err := os.Open(li.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, false, nil
+	} else if err != nil {
+		return 0, false, fmt.Errorf("failed to open leaf index: %v", err)
+	}
+	defer func() { _ = hashes.Close() }()
+	nSlots, err := tableSlots(t)
+	if err != nil {
+		return 0, false, err
+	}
+	_, idx, found, err := li.probe(t, hashes, nSlots, h)
+	return idx, found, err
+}
+
+// LookupByLeafHash returns the lowest index of an entry in the log with the given Merkle leaf hash, if
+// there is one.
+//
+// This is only available if Config.Dedupe is set, and an appender has been created with this storage.
+func (s *Storage) LookupByLeafHash(ctx context.Context, h []byte) (uint64, bool, error) {
+	return otel.Trace2(ctx, "tessera.storage.posix.LookupByLeafHash", s.tracer(), func(ctx context.Context, span trace.Span) (uint64, bool, error) {
+		a := s.appender.Load()
+		if a == nil || a.leafIndex == nil {
+			return 0, false, errors.New("leaf index is not enabled")
+		}
+		return a.leafIndex.lookup(h)
+	})
+}
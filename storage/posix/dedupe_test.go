@@ -0,0 +1,145 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package posix
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/transparency-dev/tessera"
+	"github.com/transparency-dev/tessera/api/layout"
+)
+
+func TestLookupByLeafHash(t *testing.T) {
+	ctx := t.Context()
+	dir := t.TempDir()
+	sk, _ := mustGenerateKeys(t)
+	opts := tessera.NewAppendOptions().
+		WithCheckpointInterval(10*time.Minute).
+		WithBatching(100, time.Hour).
+		WithCheckpointSigner(sk)
+	newStorage := func() *Storage {
+		t.Helper()
+		s := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: dir, Dedupe: true, DisableAutoPublish: true}}
+		if _, _, err := s.newAppender(ctx, &logResourceStorage{s: s, entriesPath: opts.EntriesPath()}, opts); err != nil {
+			t.Fatalf("newAppender: %v", err)
+		}
+		return s
+	}
+
+	s := newStorage()
+	// Add enough entries to span more than one tile, including a duplicate.
+	const n = layout.TileWidth + 10
+	entries := make([]*tessera.Entry, 0, n+1)
+	for i := range n {
+		entries = append(entries, tessera.NewEntry(fmt.Appendf(nil, "entry %d", i)))
+	}
+	entries = append(entries, tessera.NewEntry([]byte("entry 3")))
+	s.AddBatch(ctx, entries)
+	if err := s.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	check := func(s *Storage) {
+		t.Helper()
+		for _, i := range []uint64{0, 3, layout.TileWidth, n - 1} {
+			got, found, err := s.LookupByLeafHash(ctx, entries[i].LeafHash())
+			if err != nil || !found || got != i {
+				t.Errorf("LookupByLeafHash(entry %d) = %d, %t, %v, want %d, true, nil", i, got, found, err, i)
+			}
+		}
+		if _, found, err := s.LookupByLeafHash(ctx, tessera.NewEntry([]byte("missing")).LeafHash()); err != nil || found {
+			t.Errorf("LookupByLeafHash(missing) = %t, %v, want false, nil", found, err)
+		}
+	}
+	check(s)
+
+	// Truncating the index, leaving a partial hash, should cause it to be rebuilt from the tiles when reopened.
+	if err := os.Truncate(filepath.Join(dir, defaultStateDir, leafIndexFile), 100); err != nil {
+		t.Fatalf("Truncate: %v", err)
+	}
+	check(newStorage())
+
+	// The index should also be built when enabled on an existing log.
+	if err := os.Remove(filepath.Join(dir, defaultStateDir, leafIndexFile)); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	check(newStorage())
+}
+
+func TestLookupByLeafHashDisabled(t *testing.T) {
+	s := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: t.TempDir()}}
+	if _, _, err := s.LookupByLeafHash(t.Context(), make([]byte, 32)); err == nil {
+		t.Error("LookupByLeafHash succeeded without the leaf index enabled")
+	}
+}
+
+func TestLeafIndexTable(t *testing.T) {
+	dir := t.TempDir()
+	s := &Storage{cfg: Config{Path: dir, DisableSyncWrites: true}}
+	li := &leafIndex{
+		path:      filepath.Join(dir, leafIndexFile),
+		tablePath: filepath.Join(dir, leafIndexTableFile),
+		hashSize:  sha256.Size,
+	}
+	if err := li.rebuild(s); err != nil {
+		t.Fatalf("rebuild: %v", err)
+	}
+
+	// Enough entries to force the table to grow several times, with every entry added twice.
+	const n = 3000
+	hash := func(i int) []byte {
+		h := sha256.Sum256([]byte(fmt.Sprintf("leaf %d", i%(n/2))))
+		return h[:]
+	}
+	for i := 0; i < n; i += 100 {
+		batch := make([][]byte, 0, 100)
+		for j := i; j < i+100; j++ {
+			batch = append(batch, hash(j))
+		}
+		if err := li.extend(s, batch); err != nil {
+			t.Fatalf("extend(%d): %v", i, err)
+		}
+	}
+	if got := li.loaded(); got != n {
+		t.Fatalf("loaded() = %d, want %d", got, n)
+	}
+	fi, err := os.Stat(li.tablePath)
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if got, want := fi.Size(), int64(leafIndexHeaderSize+2*n*leafIndexSlotSize); got < want {
+		t.Errorf("table size = %d, want at least %d", got, want)
+	}
+
+	for i := 0; i < n/2; i++ {
+		idx, ok, err := li.lookup(hash(i))
+		if err != nil {
+			t.Fatalf("lookup(%d): %v", i, err)
+		}
+		if !ok || idx != uint64(i) {
+			t.Errorf("lookup(%d) = %d, %t, want %d, true", i, idx, ok, i)
+		}
+	}
+	missing := sha256.Sum256([]byte("missing"))
+	if _, ok, err := li.lookup(missing[:]); err != nil || ok {
+		t.Errorf("lookup(missing) = _, %t, %v, want false, nil", ok, err)
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package posix
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/transparency-dev/tessera/internal/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// extraDataSuffix is added to the path of an entry bundle to form the path of the file holding the extra
+// data for the entries in that bundle.
+//
+// Extra data files are not compressed, and hold one uint32 length-prefixed blob for each entry in the
+// corresponding bundle, in the same order. Entries without extra data have an empty blob.
+const extraDataSuffix = ".x"
+
+// appendExtraData appends the framed extra data blob d to b.
+func appendExtraData(b []byte, d []byte) ([]byte, error) {
+	if uint64(len(d)) > math.MaxUint32 {
+		return nil, fmt.Errorf("extra data too large (%d bytes)", len(d))
+	}
+	b = binary.BigEndian.AppendUint32(b, uint32(len(d)))
+	return append(b, d...), nil
+}
+
+// parseExtraData splits the contents of an extra data file into the per-entry blobs it contains.
+func parseExtraData(b []byte) ([][]byte, error) {
+	r := [][]byte{}
+	for len(b) > 0 {
+		if len(b) < 4 {
+			return nil, fmt.Errorf("truncated length prefix for extra data %d", len(r))
+		}
+		l := uint64(binary.BigEndian.Uint32(b))
+		b = b[4:]
+		if uint64(len(b)) < l {
+			return nil, fmt.Errorf("extra data %d has length %d, but only %d bytes remain", len(r), l, len(b))
+		}
+		r = append(r, b[:l])
+		b = b[l:]
+	}
+	return r, nil
+}
+
+// ReadExtraData returns the extra data stored alongside each of the entries in the specified entry bundle,
+// in the same order as the entries in the bundle. Entries which were added without extra data have an empty
+// blob.
+//
+// As with ReadEntryBundle, index is the index of the bundle, and p its partial width. The full bundle's extra
+// data is returned in place of a partial bundle's which no longer exists. An error wrapping os.ErrNotExist is
+// returned if none of the entries in the bundle were added with extra data.
+func (s *Storage) ReadExtraData(ctx context.Context, index uint64, p uint8) ([][]byte, error) {
+	return otel.Trace(ctx, "tessera.storage.posix.ReadExtraData", s.tracer(), func(ctx context.Context, span trace.Span) ([][]byte, error) {
+		b, err := s.logReader().readExtraData(ctx, index, p)
+		if errors.Is(err, os.ErrNotExist) && p > 0 {
+			b, err = s.logReader().readExtraData(ctx, index, 0)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read extra data for entry bundle %d: %w", index, err)
+		}
+		r, err := parseExtraData(b)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse extra data for entry bundle %d: %v", index, err)
+		}
+		return r, nil
+	})
+}
+
+// readExtraData returns the raw contents of the extra data file for the specified entry bundle.
+func (lrs *logResourceStorage) readExtraData(_ context.Context, index uint64, p uint8) ([]byte, error) {
+	return lrs.s.readAll(lrs.bundlePath(index, p) + extraDataSuffix)
+}
+
+// writeExtraData stores the framed extra data for the specified entry bundle.
+//
+// As with writeBundleBatched, if batch is non-nil the file is not synced as it's written, and is instead added
+// to batch, which the caller must sync.
+func (lrs *logResourceStorage) writeExtraData(ctx context.Context, index uint64, partial uint8, d []byte, batch *syncBatch) error {
+	return otel.TraceErr(ctx, "tessera.storage.posix.writeExtraData", lrs.s.tracer(), func(ctx context.Context, span trace.Span) error {
+		p := lrs.bundlePath(index, partial) + extraDataSuffix
+		if batch == nil {
+			return lrs.s.createOverwrite(p, d)
+		}
+		p = filepath.Join(lrs.s.cfg.Path, p)
+		if err := lrs.s.retry(func() error { return overwrite(p, d, false) }); err != nil {
+			return err
+		}
+		batch.add(p)
+		return nil
+	})
+}
@@ -0,0 +1,139 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package posix
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/transparency-dev/tessera"
+	"github.com/transparency-dev/tessera/api/layout"
+)
+
+func TestParseExtraDataRoundTrip(t *testing.T) {
+	want := [][]byte{[]byte("one"), {}, []byte("three")}
+	var b []byte
+	for _, d := range want {
+		var err error
+		if b, err = appendExtraData(b, d); err != nil {
+			t.Fatalf("appendExtraData: %v", err)
+		}
+	}
+	got, err := parseExtraData(b)
+	if err != nil {
+		t.Fatalf("parseExtraData: %v", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d blobs, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !bytes.Equal(got[i], want[i]) {
+			t.Errorf("blob %d: got %q, want %q", i, got[i], want[i])
+		}
+	}
+	for _, l := range []int{1, 3, 6} {
+		if _, err := parseExtraData(b[:len(b)-l]); err == nil {
+			t.Errorf("parseExtraData(truncated by %d) succeeded", l)
+		}
+	}
+}
+
+func TestExtraData(t *testing.T) {
+	ctx := t.Context()
+	sk, _ := mustGenerateKeys(t)
+	opts := tessera.NewAppendOptions().
+		WithCheckpointInterval(10*time.Minute).
+		WithBatching(1000, time.Hour).
+		WithCheckpointSigner(sk)
+	newLog := func() *Storage {
+		s := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: t.TempDir(), DisableAutoPublish: true}}
+		if _, _, err := s.newAppender(ctx, &logResourceStorage{s: s, entriesPath: opts.EntriesPath()}, opts); err != nil {
+			t.Fatalf("Appender: %v", err)
+		}
+		return s
+	}
+	withExtra, withoutExtra := newLog(), newLog()
+	extra := func(i int) []byte {
+		if i < 3 || i%2 == 0 {
+			return nil
+		}
+		return fmt.Appendf(nil, "extra %d", i)
+	}
+	add := func(from, to int) {
+		t.Helper()
+		for i := from; i < to; i++ {
+			d := fmt.Appendf(nil, "entry %d", i)
+			withExtra.appender.Load().Add(ctx, tessera.NewEntryWithExtraData(d, extra(i)))
+			withoutExtra.appender.Load().Add(ctx, tessera.NewEntry(d))
+		}
+		for _, s := range []*Storage{withExtra, withoutExtra} {
+			if err := s.Flush(ctx); err != nil {
+				t.Fatalf("Flush: %v", err)
+			}
+		}
+	}
+
+	// No extra data is stored for bundles whose entries don't have any.
+	add(0, 3)
+	if _, err := withExtra.ReadExtraData(ctx, 0, 3); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("ReadExtraData(0, 3): got %v, want %v", err, os.ErrNotExist)
+	}
+
+	// Fill the partial bundle, and spill over into the next.
+	const size = layout.EntryBundleWidth + 5
+	add(3, size)
+	for i := range 2 {
+		p := layout.PartialTileSize(0, uint64(i), size)
+		got, err := withExtra.ReadExtraData(ctx, uint64(i), p)
+		if err != nil {
+			t.Fatalf("ReadExtraData(%d, %d): %v", i, p, err)
+		}
+		want := int(p)
+		if p == 0 {
+			want = layout.EntryBundleWidth
+		}
+		if len(got) != want {
+			t.Fatalf("ReadExtraData(%d, %d): got %d blobs, want %d", i, p, len(got), want)
+		}
+		for j, x := range got {
+			idx := i*layout.EntryBundleWidth + j
+			if !bytes.Equal(x, extra(idx)) {
+				t.Errorf("Extra data for entry %d: got %q, want %q", idx, x, extra(idx))
+			}
+		}
+	}
+	// The partial bundle's extra data is still available once the bundle is full.
+	if _, err := withExtra.ReadExtraData(ctx, 0, 3); err != nil {
+		t.Errorf("ReadExtraData(0, 3): %v", err)
+	}
+
+	// The tree commits only to the leaves.
+	gotSize, gotRoot, err := withExtra.TreeState(ctx)
+	if err != nil {
+		t.Fatalf("TreeState: %v", err)
+	}
+	wantSize, wantRoot, err := withoutExtra.TreeState(ctx)
+	if err != nil {
+		t.Fatalf("TreeState: %v", err)
+	}
+	if gotSize != wantSize || !bytes.Equal(gotRoot, wantRoot) {
+		t.Errorf("TreeState: got (%d, %x), want (%d, %x)", gotSize, gotRoot, wantSize, wantRoot)
+	}
+}
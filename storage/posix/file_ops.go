@@ -30,6 +30,11 @@ import (
 const (
 	dirPerm  = 0o755
 	filePerm = 0o644
+
+	// tempSuffix is the suffix of the names of temporary files created by createTemp.
+	tempSuffix = ".temp"
+	// linkSuffix is the suffix of the names of temporary symlinks used when relinking partial tiles.
+	linkSuffix = ".link"
 )
 
 // syncDir opens the specified directory and calls op before syncing and closing the handle on the directory.
@@ -105,18 +110,29 @@ func mkdirAll(name string, perm os.FileMode) error {
 	}
 }
 
-// createEx atomically creates a file at the given path containing the provided data, and syncs the
-// directory containing the newly created file.
+// maybeSyncDir calls syncDir if sync is true, otherwise it simply calls op.
+func maybeSyncDir(dir string, sync bool, op func() error) error {
+	if !sync {
+		return op()
+	}
+	return syncDir(dir, op)
+}
+
+// createEx atomically creates a file at the given path containing the provided data.
+//
+// If sync is true, the file data is durably written before the file is linked into place, and the directory
+// containing the newly created file is synced before returning, so once this function returns successfully
+// the file and its contents will survive a crash or power loss.
 //
 // Returns an error if a file already exists at the specified location, or it's unable to fully write the
 // data & close the file.
-func createEx(name string, d []byte) error {
+func createEx(name string, d []byte, sync bool) error {
 	dir := filepath.Dir(name)
 	if err := mkdirAll(dir, dirPerm); err != nil {
 		return fmt.Errorf("failed to make directory structure: %w", err)
 	}
-	return syncDir(dir, func() error {
-		tmpName, err := createTemp(name, d)
+	return maybeSyncDir(dir, sync, func() error {
+		tmpName, err := createTemp(name, d, sync)
 		if err != nil {
 			return fmt.Errorf("failed to create temp file: %w", err)
 		}
@@ -134,20 +150,23 @@ func createEx(name string, d []byte) error {
 	})
 }
 
-// overwrite atomically creates/overwrites a file at the given path containing the provided data, and syncs
-// the directory containing the overwritten/created file.
-func overwrite(name string, d []byte) error {
+// overwrite atomically creates/overwrites a file at the given path containing the provided data.
+//
+// If sync is true, the file data is durably written before the file is renamed into place, and the
+// directory containing the overwritten/created file is synced before returning, with the same durability
+// guarantee as createEx.
+func overwrite(name string, d []byte, sync bool) error {
 	dir := filepath.Dir(name)
 	if err := mkdirAll(dir, dirPerm); err != nil {
 		return fmt.Errorf("failed to make directory structure: %w", err)
 	}
-	return syncDir(dir, func() error {
+	return maybeSyncDir(dir, sync, func() error {
 		dir, _ := filepath.Split(name)
 		if err := mkdirAll(dir, dirPerm); err != nil {
 			return fmt.Errorf("failed to make entries directory structure: %w", err)
 		}
 
-		tmpName, err := createTemp(name, d)
+		tmpName, err := createTemp(name, d, sync)
 		if err != nil {
 			return fmt.Errorf("failed to create temp file: %w", err)
 		}
@@ -159,28 +178,32 @@ func overwrite(name string, d []byte) error {
 	})
 }
 
-// createTemp creates a new temporary file in the directory dir, with a name based on the provided prefix,
-// and writes the provided data to it.
+// createTemp creates a new temporary file in the directory dir, with a name based on the provided prefix
+// and ending with tempSuffix, and writes the provided data to it.
 //
 // Multiple programs or goroutines calling CreateTemp simultaneously will not choose the same file.
 // It is the caller's responsibility to remove the file when it is no longer needed.
 //
-// Ths file data is written with O_SYNC, however the containing directory is NOT sync'd on the assumption
-// that this temporary file will be linked/renamed by the caller who will also sync the directory.
-func createTemp(prefix string, d []byte) (name string, err error) {
+// If sync is true, the file data is written with O_SYNC, however the containing directory is NOT sync'd on
+// the assumption that this temporary file will be linked/renamed by the caller who will also sync the directory.
+func createTemp(prefix string, d []byte, sync bool) (name string, err error) {
 	try := 0
 	var f *os.File
 
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if sync {
+		flags |= os.O_SYNC
+	}
 	for {
-		name = prefix + strconv.Itoa(int(rand.Int32()))
-		f, err = os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL|os.O_SYNC, filePerm)
+		name = prefix + "." + strconv.Itoa(int(rand.Int32())) + tempSuffix
+		f, err = os.OpenFile(name, flags, filePerm)
 		if err == nil {
 			break
 		} else if os.IsExist(err) {
 			if try++; try < 10000 {
 				continue
 			}
-			return "", &os.PathError{Op: "createtemp", Path: prefix + "*", Err: os.ErrExist}
+			return "", &os.PathError{Op: "createtemp", Path: prefix + ".*" + tempSuffix, Err: os.ErrExist}
 		}
 	}
 
@@ -198,3 +221,48 @@ func createTemp(prefix string, d []byte) (name string, err error) {
 
 	return name, nil
 }
+
+// syncBatch collects files which have been written without being synced, so that they can be synced together.
+type syncBatch struct {
+	files []string
+}
+
+// add records that the named file needs to be synced.
+func (b *syncBatch) add(name string) {
+	b.files = append(b.files, name)
+}
+
+// sync durably writes the data of every file in the batch, and then syncs each of the directories containing
+// them once, so that once this returns successfully the files will survive a crash or power loss.
+func (b *syncBatch) sync() error {
+	dirs := make(map[string]bool)
+	for _, name := range b.files {
+		if err := syncFile(name); err != nil {
+			return err
+		}
+		dirs[filepath.Dir(name)] = true
+	}
+	for dir := range dirs {
+		if err := syncDir(dir, func() error { return nil }); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncFile durably writes the data of the named file.
+func syncFile(name string) (err error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return fmt.Errorf("failed to open %q: %w", name, err)
+	}
+	defer func() {
+		if e := f.Close(); err == nil {
+			err = e
+		}
+	}()
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("failed to sync %q: %w", name, err)
+	}
+	return nil
+}
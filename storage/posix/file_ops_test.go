@@ -0,0 +1,82 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package posix
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCreateAndOverwrite(t *testing.T) {
+	for _, sync := range []bool{true, false} {
+		name := "sync"
+		if !sync {
+			name = "nosync"
+		}
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			p := filepath.Join(dir, "a", "b", "file")
+
+			if err := createEx(p, []byte("one"), sync); err != nil {
+				t.Fatalf("createEx: %v", err)
+			}
+			if err := createEx(p, []byte("two"), sync); !errors.Is(err, os.ErrExist) {
+				t.Fatalf("createEx of existing file: got %v, want %v", err, os.ErrExist)
+			}
+			if err := overwrite(p, []byte("three"), sync); err != nil {
+				t.Fatalf("overwrite: %v", err)
+			}
+			got, err := os.ReadFile(p)
+			if err != nil {
+				t.Fatalf("ReadFile: %v", err)
+			}
+			if want := []byte("three"); !bytes.Equal(got, want) {
+				t.Fatalf("Got %q, want %q", got, want)
+			}
+
+			// No temporary files should be left behind.
+			entries, err := os.ReadDir(filepath.Dir(p))
+			if err != nil {
+				t.Fatalf("ReadDir: %v", err)
+			}
+			if len(entries) != 1 {
+				t.Fatalf("Found %d files in directory, want 1", len(entries))
+			}
+		})
+	}
+}
+
+func TestSyncBatch(t *testing.T) {
+	dir := t.TempDir()
+	b := &syncBatch{}
+	for _, n := range []string{"a/one", "a/two", "b/three"} {
+		p := filepath.Join(dir, n)
+		if err := overwrite(p, []byte(n), false); err != nil {
+			t.Fatalf("overwrite: %v", err)
+		}
+		b.add(p)
+	}
+	if err := b.sync(); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+
+	b.add(filepath.Join(dir, "missing"))
+	if err := b.sync(); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("sync with missing file: got %v, want %v", err, os.ErrNotExist)
+	}
+}
@@ -16,21 +16,28 @@ package posix
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
+	"encoding/binary"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"io/fs"
+	"iter"
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"log/slog"
+	"math"
 
+	"github.com/transparency-dev/merkle"
 	"github.com/transparency-dev/merkle/rfc6962"
 	"github.com/transparency-dev/tessera"
 	"github.com/transparency-dev/tessera/api"
@@ -42,28 +49,37 @@ import (
 	storage "github.com/transparency-dev/tessera/storage/internal"
 	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/errgroup"
 )
 
 const (
 	// compatibilityVersion is the required version of the log state directory.
 	// This should be bumped whenever a change is made that would break compatibility with old versions.
-	// When this is bumped, ensure that the version file is only written when a new log is being
+	// When this is bumped, register a migration from the previous version in upgrades so that
+	// existing logs can be upgraded, and ensure that the version file is only written when a new log is being
 	// created. Currently, this version is written whenever it is missing in order to upgrade logs
 	// that were created before we introduced this.
 	compatibilityVersion = 1
 
-	// stateDir holds any private (but not secret) internal state needed to maintain/operate the log.
-	stateDir = ".state"
+	// defaultStateDir is the default directory, relative to the log root, holding any private (but not secret)
+	// internal state needed to maintain/operate the log.
+	defaultStateDir = ".state"
+	// settingsFile records the settings, fixed when the log is created, which determine how it's stored.
+	settingsFile = "settings"
 	// gcStateFile contains the state of the garbage collection operations.
 	gcStateFile = "gcState"
 	// gcStateLock must be held when performing GC operations and updating the gcState file.
 	gcStateLock = gcStateFile + ".lock"
 	// publishLock must be held when checking/updating the published checkpoint.
 	publishLock = "publish.lock"
+	// publishStateFile records when the published checkpoint was published, according to Config.Clock.
+	publishStateFile = "publishState"
 	// treeStateFile contains the integrated (but not necessarily published) state of the tree.
 	treeStateFile = "treeState"
 	// treeStateLock must be held when integrating entries into the tree or writing to the treeState file.
 	treeStateLock = treeStateFile + ".lock"
+	// prevTreeStateFile holds the tree state which treeStateFile last replaced, if Config.RetainPreviousTreeState is set.
+	prevTreeStateFile = treeStateFile + ".prev"
 
 	minCheckpointInterval = 100 * time.Millisecond
 
@@ -73,13 +89,44 @@ const (
 	defaultPublicationTimeout = 5 * time.Second
 	// defaultGCTimeout is the default context timeout applied when undertaking a garbage collection task.
 	defaultGCTimeout = 30 * time.Second
+
+	// minLockRetryInterval and maxLockRetryInterval bound the backoff used between attempts to take a file lock
+	// which is currently held by another process.
+	minLockRetryInterval = 5 * time.Millisecond
+	maxLockRetryInterval = 250 * time.Millisecond
+
+	// defaultMigrationMaxBundles is the default maximum number of entry bundles integrated in each pass during migration.
+	defaultMigrationMaxBundles = 300
+	// defaultMigrationFetchConcurrency is the default maximum number of entry bundles read concurrently during migration.
+	defaultMigrationFetchConcurrency = 1
+
+	// tileReadConcurrency is the maximum number of tiles read concurrently by a single call to readTiles.
+	tileReadConcurrency = 16
 )
 
 // Storage implements storage functions for a POSIX filesystem.
 // It leverages the POSIX atomic operations where needed.
 type Storage struct {
-	mu  sync.Mutex
-	cfg Config
+	mu sync.Mutex
+	// seqMu serialises sequencing within this process; see lockSequence.
+	seqMu sync.Mutex
+	cfg   Config
+
+	// heatmap tracks tile reads, or is nil if tracking is disabled.
+	heatmap *tileHeatmap
+	// tileCache holds recently read tiles, or is nil if caching is disabled.
+	tileCache *tileCache
+	// cpCache holds the most recently read checkpoint, if Config.CheckpointCache is set.
+	cpCache checkpointCache
+	// signer tracks the health of checkpoint creation.
+	signer signerHealth
+	// appender is the most recently created appender lifecycle instance, if any.
+	appender atomic.Pointer[appender]
+
+	// leaseID identifies this instance as the owner of the leader lease.
+	leaseID string
+	// leaseExpiry is the time, in nanoseconds since the Unix epoch, until which this instance holds the leader lease.
+	leaseExpiry atomic.Int64
 }
 
 // appender implements the Tessera append lifecycle.
@@ -87,11 +134,41 @@ type appender struct {
 	s          *Storage
 	logStorage *logResourceStorage
 	queue      *storage.Queue
+	// public is the tessera.Appender returned by Storage.Appender, if any. Its Add function is decorated
+	// in place by tessera.NewAppender with the behaviours configured via tessera.AppendOptions.
+	public atomic.Pointer[tessera.Appender]
 
 	curSize uint64
-	newCP   func(context.Context, uint64, []byte) ([]byte, error) // May be nil for mirrored logs.
+	newCP   func(context.Context, uint64, []byte) ([]byte, error) // May be nil for mirrored logs, or if Config.DisableAutoPublish is set.
+
+	// leafIndex maps leaf hashes to the indices of the entries in the log, or is nil if Config.Dedupe is not set.
+	leafIndex *leafIndex
+
+	// hasherName and hasher describe the Merkle tree hashing strategy used by the log.
+	hasherName string
+	hasher     merkle.LogHasher
 
 	cpUpdated chan struct{}
+	// publishInterval is the interval at which the checkpoint publication job runs, or zero if it isn't started.
+	publishInterval time.Duration
+	// publishHeartbeat is the time, in nanoseconds since the Unix epoch according to the storage's clock, at
+	// which the checkpoint publication job was last seen to be running, or zero if it has stopped.
+	publishHeartbeat atomic.Int64
+
+	// integrate prompts the integrator to integrate newly sequenced entries when Config.AsyncIntegration is set.
+	integrate chan struct{}
+	// grown is closed, and replaced, whenever this appender grows the tree.
+	grownMu sync.Mutex
+	grown   chan struct{}
+
+	// stop cancels the context of the appender's background jobs, which are tracked by jobs so that Close can
+	// wait for them to exit.
+	stop context.CancelFunc
+	jobs sync.WaitGroup
+	// closed is set by Close, after which entries can no longer be added. closeMu is held for reading while
+	// entries are being queued, so that Close can't miss any.
+	closeMu sync.RWMutex
+	closed  bool
 }
 
 // logResourceStorage knows how to read and write tiled log resources via a
@@ -104,12 +181,244 @@ type logResourceStorage struct {
 // NewTreeFunc is the signature of a function which receives information about newly integrated trees.
 type NewTreeFunc func(size uint64, root []byte) error
 
+// SyncMode describes how entry bundles are synced to stable storage as they're written.
+type SyncMode int
+
+const (
+	// SyncPerFile syncs each entry bundle, and the directory containing it, as soon as it's written.
+	SyncPerFile SyncMode = iota
+	// SyncPerBatch writes all of the entry bundles for a batch of sequenced entries before syncing them,
+	// syncing each directory containing them only once. This is considerably faster when batches span many
+	// bundles, e.g. during a bulk import. Bundles are still durable before any tiles or tree state which
+	// commit to them are written.
+	SyncPerBatch
+)
+
+// CheckpointPublishedFunc is the signature of a function which receives newly published checkpoints.
+type CheckpointPublishedFunc func(ctx context.Context, cpRaw []byte) error
+
 type Config struct {
 	// HTTPClient will be used for outgoing HTTP requests. If unset, Tessera will use the net/http DefaultClient.
 	HTTPClient *http.Client
 
-	// Path is the path to a directory in which the log should be stored.
+	// Path is the path to a directory in which the log should be stored. All of the log's files, including
+	// its state directory, are stored within it, so several logs can be hosted under one root directory by
+	// giving each its own subdirectory, e.g. <root>/<log>.
 	Path string
+
+	// TileReadSampleRate, if non-zero, enables tracking of the number of reads of each tile, which
+	// can be retrieved via Storage.TileHeatmap. One in every TileReadSampleRate reads will be recorded,
+	// so setting this to 1 records every read.
+	//
+	// This is intended to help with identifying read hotspots, and is disabled by default.
+	TileReadSampleRate uint
+
+	// TileCacheMaxBytes, if non-zero, enables an in-memory cache of tiles returned by the LogReader, which
+	// holds up to this many bytes of the most recently read tiles. This reduces disk reads for workloads,
+	// such as serving proofs, which repeatedly read the same tiles.
+	TileCacheMaxBytes int
+
+	// CheckpointCache, if true, causes the checkpoint returned by the LogReader to be cached in memory, and
+	// only read again once its file has been modified. This reduces load on shared storage when the
+	// checkpoint is polled frequently.
+	CheckpointCache bool
+
+	// MaxSignerOutage controls what happens when checkpoints cannot be created (e.g. because the
+	// signer is unavailable).
+	//
+	// If zero (the default), new entries will continue to be integrated into the tree even though
+	// no new checkpoints can be published. Otherwise, once checkpoint creation has been failing for
+	// longer than this duration, requests to add new entries will be rejected with
+	// tessera.ErrPushback until a checkpoint is successfully created, so that the integrated tree
+	// cannot grow too far beyond what has been published.
+	MaxSignerOutage time.Duration
+
+	// BundleCompression selects the codec used to compress entry bundles on disk. Compressed bundles are
+	// stored with a suffix identifying the codec added to their usual paths, so they are not directly
+	// servable as https://c2sp.org/tlog-tiles resources, but the LogReader returned by this storage will
+	// transparently decompress them.
+	//
+	// The codec is recorded in the log's state directory when it is created, and cannot be changed
+	// afterwards. Logs created before this option existed are always uncompressed.
+	BundleCompression BundleCompression
+
+	// BundleCodec selects how entry bundles are encoded on disk, before any compression. If nil, bundles
+	// are stored in the https://c2sp.org/tlog-tiles format, which is required for the log to be served
+	// directly as a tlog-tiles log. The LogReader returned by this storage always returns bundles in
+	// the tlog-tiles format, regardless of the codec used to store them.
+	//
+	// The codec is recorded in the log's state directory when it is created, and cannot be changed
+	// afterwards. Logs created before this option existed always use the default codec.
+	BundleCodec BundleCodec
+
+	// DisableSyncWrites, if true, stops the storage from syncing files and their containing directories
+	// to stable storage as they are written.
+	//
+	// By default, every file written by the storage (entry bundles, tiles, tree state, and checkpoints)
+	// has its data synced before being atomically moved into place, and its directory synced afterwards.
+	// Since tiles are written before the tree state which commits to them, and checkpoints are only
+	// created from the stored tree state, this guarantees that a crash or power loss can never leave
+	// a published checkpoint which commits to tiles or entry bundles which were not durably written.
+	//
+	// Disabling this removes that guarantee, and should only be done for logs whose contents can be
+	// discarded, e.g. in tests or on ephemeral storage.
+	DisableSyncWrites bool
+
+	// SyncMode controls how entry bundles are synced to stable storage when DisableSyncWrites is not set.
+	// Defaults to SyncPerFile.
+	SyncMode SyncMode
+
+	// LeaderLease, if non-zero, enables leader election between multiple processes appending to the same log.
+	//
+	// Only the process holding the leader lease will sequence and integrate entries, publish checkpoints, and
+	// garbage collect; other processes stand by, and entries added via them fail with ErrNotLeader.
+	// The leader renews its lease several times during each lease period, and if it fails to do so
+	// (e.g. because it has crashed), another process will take over once the lease has expired.
+	LeaderLease time.Duration
+
+	// TileChecksums, if true, causes a checksum of each tile to be stored alongside it in a sidecar file,
+	// which is verified whenever the tile is read. Reading a tile which does not match its checksum, e.g.
+	// because it was truncated by a failing disk, fails with ErrTileCorrupt rather than allowing a tree
+	// with an incorrect root to be integrated and published.
+	//
+	// Tiles written while this option was disabled have no checksum, and so are not verified.
+	TileChecksums bool
+
+	// TempFileMaxAge is the age beyond which CleanupTempFiles considers temporary files left behind by
+	// interrupted writes to be abandoned. If zero, a default of one hour is used.
+	TempFileMaxAge time.Duration
+
+	// NewTreeFunc, if set, is called with the size and root hash of each newly integrated tree, as soon
+	// as it has been stored and before a checkpoint for it is published. Errors returned by the function
+	// are logged, but otherwise ignored.
+	//
+	// The function is called while the tree is locked, so it must return quickly; implementations which need
+	// to do slow work (e.g. pushing the tree to a remote service) should hand it off asynchronously.
+	NewTreeFunc NewTreeFunc
+
+	// CheckpointHistory, if non-zero, is the number of recently published checkpoints, one per tree size, to
+	// retain in the checkpoints/ directory of the log, from where they can be read with ReadCheckpointAtSize.
+	// When the history grows beyond this number, the checkpoints for the smallest tree sizes are removed.
+	CheckpointHistory uint
+
+	// CheckpointPublishedFunc, if set, is called with the raw bytes of each checkpoint once it has been
+	// published. Errors returned by the function are logged, but the checkpoint remains published.
+	//
+	// The function is called while the publish lock is held, so it must return quickly; implementations which
+	// need to do slow work (e.g. pushing the checkpoint to an external aggregator) should hand it off asynchronously.
+	CheckpointPublishedFunc CheckpointPublishedFunc
+
+	// RetryMaxAttempts is the maximum number of times that reads and writes of files which fail with transient
+	// errors (EAGAIN, ESTALE, or EINTR), as seen on network filesystems such as NFS, are attempted.
+	// Other errors are returned immediately. If zero or one, operations are not retried.
+	RetryMaxAttempts uint
+
+	// RetryBaseDelay is the delay before the first retry of a failed filesystem operation, which is
+	// doubled for each subsequent retry. If zero, a default of 10ms is used.
+	RetryBaseDelay time.Duration
+
+	// RepublishCheckpointOnMismatch, if true, causes the published checkpoint to be replaced with one for the stored
+	// tree state if, when an appender is created, the checkpoint is found to commit to a larger tree. Otherwise, this
+	// situation causes creating the appender to fail with ErrCheckpointAhead.
+	//
+	// The replacement checkpoint is inconsistent with the one it replaces, so this should only be enabled once the
+	// cause of the mismatch is understood.
+	RepublishCheckpointOnMismatch bool
+
+	// DisableAutoPublish, if true, stops the storage from publishing checkpoints itself, for deployments where
+	// checkpoints are published out-of-band, e.g. by a separate signer service which reads the tree state via
+	// Storage.TreeState. Entries are still sequenced and integrated as usual, but no checkpoint is ever written,
+	// and the checkpoint signer configured via tessera.AppendOptions is not used.
+	DisableAutoPublish bool
+
+	// Dedupe, if true, maintains an index from the Merkle leaf hash of each entry in the log to its index,
+	// which can be queried with Storage.LookupByLeafHash, e.g. to find an earlier copy of a resubmitted entry.
+	// The index is stored on disk in the log's state directory, rather than in memory, and costs one leaf hash
+	// plus up to four table slots (32 bytes) of storage, and a little write overhead, per entry. It has no
+	// effect on the tree.
+	//
+	// When enabled on an existing log, the index is populated from the log's tiles when the appender is created.
+	Dedupe bool
+
+	// MaxIntegrateLeaves, if non-zero, is the maximum number of entries integrated into the tree at once. Larger
+	// batches of entries, e.g. when AppendFramedBundle is used to import a large backlog, are integrated in chunks
+	// of this size, with the tree state stored after each, which bounds the memory used and makes progress
+	// observable via Storage.TreeState. Checkpoints are published for the fully integrated tree as usual.
+	//
+	// If integrating a chunk fails, the earlier chunks remain integrated even though the batch as a whole fails.
+	MaxIntegrateLeaves uint
+
+	// Metadata, if set, is descriptive information about the log (e.g. a human-readable name, environment, or
+	// contact) which is stored in the log's state directory when it is created, and can be read back with
+	// Storage.Metadata. It has no effect on the log itself, and is ignored when opening an existing log.
+	Metadata map[string]string
+
+	// StateDir is the name of the directory, relative to Path, in which the log's private internal state (e.g. lock
+	// files, tree state, and version information) is stored. If unset, ".state" is used.
+	//
+	// This must be the same every time the log is opened.
+	StateDir string
+
+	// TracerProvider, if set, is used to create the OpenTelemetry tracer for spans created by this storage.
+	// If unset, the global TracerProvider is used.
+	TracerProvider trace.TracerProvider
+
+	// Logger, if set, is used for the messages logged by this storage, e.g. about sequencing, integration, and
+	// checkpoint publication. If unset, the default slog logger is used.
+	Logger *slog.Logger
+
+	// AsyncIntegration, if true, decouples integration of entries into the tree from their sequencing.
+	// Batches of added entries are written to the entry bundles and recorded in the state directory, and a
+	// background integrator brings the tree up to date, integrating all the entries sequenced since it last
+	// ran at once. Sequencing and integration take separate locks, so sequencing can proceed while integration
+	// is in progress, and integration is more efficient under heavy load.
+	//
+	// Futures returned by Add still only resolve once their entries have been integrated.
+	AsyncIntegration bool
+
+	// AutoUpgrade, if true, upgrades the state directory of a log created by an older version of this storage
+	// to the current version when an appender is created, as Storage.Upgrade does. If unset, opening such a log
+	// fails, and it must be upgraded explicitly.
+	AutoUpgrade bool
+
+	// FlatLayout, if true, stores tiles and entry bundles in the flat layout described by layout.FlatTilePath
+	// and layout.FlatEntriesPath, rather than in the nested directories of the tlog-tiles layout, for
+	// filesystems on which deeply nested directories perform poorly. Logs stored this way can't be served
+	// statically, but can be served via tessera.LogReader, e.g. with the http package. Entry bundles are stored
+	// in the flat layout even if a different path is configured via tessera.AppendOptions.WithEntriesPath.
+	//
+	// The layout is recorded in the log's state directory when it is created, and opening an existing log with
+	// a different layout fails. This must be the same every time the log is opened.
+	FlatLayout bool
+
+	// ShardDigits sets the number of decimal digits in each directory name of the paths of tiles and entry
+	// bundles, as described by layout.ShardedTilePath and layout.ShardedEntriesPath, and may be 2, 3, or 4.
+	// Fewer digits give deeper trees of smaller directories, and more digits give shallower trees of larger
+	// directories. If zero, the tlog-tiles default of layout.DefaultShardDigits is used, which is required for
+	// the log to be served statically. As with FlatLayout, entry bundles are stored in the sharded layout even if
+	// a different path is configured via tessera.AppendOptions.WithEntriesPath, and this can't be combined
+	// with FlatLayout.
+	//
+	// This is recorded in the log's state directory as part of its path layout when the log is created, and
+	// must be the same every time the log is opened.
+	ShardDigits int
+
+	// RetainPreviousTreeState, if true, keeps a copy of the tree state in the state directory each time it's
+	// replaced, so that it can be restored with Storage.RollbackTreeState, e.g. if a bug causes a wrong root
+	// to be stored. This costs an extra read and write of the small tree state file per integration.
+	RetainPreviousTreeState bool
+
+	// PublishJitter, if non-zero, is the fraction of the checkpoint interval, between 0 and 1, by which the
+	// start of this log's checkpoint publication schedule is randomly offset. When many logs with the same
+	// checkpoint interval run in one process, this spreads their publication out across the interval, rather
+	// than having them all publish at once.
+	//
+	// The first checkpoint publication is delayed by the offset, even if entries are integrated before then.
+	PublishJitter float64
+
+	// Clock, if set, is used in place of the system clock to schedule checkpoint publication, to determine
+	// the age of the published checkpoint, and to measure checkpoint signer outages. This is intended for tests.
+	Clock Clock
 }
 
 // New creates a new POSIX storage.
@@ -118,9 +427,116 @@ func New(ctx context.Context, cfg Config) (tessera.Driver, error) {
 		cfg.HTTPClient = http.DefaultClient
 	}
 
-	return &Storage{
-		cfg: cfg,
-	}, nil
+	if cfg.StateDir != "" && !filepath.IsLocal(cfg.StateDir) {
+		return nil, fmt.Errorf("StateDir %q must be a local path within the log directory", cfg.StateDir)
+	}
+	if cfg.PublishJitter < 0 || cfg.PublishJitter > 1 {
+		return nil, fmt.Errorf("PublishJitter %v must be between 0 and 1", cfg.PublishJitter)
+	}
+	if cfg.SyncMode != SyncPerFile && cfg.SyncMode != SyncPerBatch {
+		return nil, fmt.Errorf("unknown SyncMode %d", cfg.SyncMode)
+	}
+	if cfg.ShardDigits != 0 && !layout.ValidShardDigits(cfg.ShardDigits) {
+		return nil, fmt.Errorf("ShardDigits %d must be 2, 3, or 4", cfg.ShardDigits)
+	}
+	if cfg.FlatLayout && cfg.ShardDigits != 0 && cfg.ShardDigits != layout.DefaultShardDigits {
+		return nil, errors.New("ShardDigits can't be combined with FlatLayout")
+	}
+
+	s := &Storage{
+		cfg:     cfg,
+		leaseID: rand.Text(),
+	}
+	if cfg.TileReadSampleRate > 0 {
+		s.heatmap = newTileHeatmap(cfg.TileReadSampleRate)
+	}
+	if cfg.TileCacheMaxBytes > 0 {
+		s.tileCache = newTileCache(cfg.TileCacheMaxBytes)
+	}
+	return s, nil
+}
+
+// ErrLogExists is returned by InitializeLog when there is already a log at the given location.
+var ErrLogExists = errors.New("log already exists")
+
+// ErrClosed is returned when entries are added to a log after its storage has been closed.
+var ErrClosed = errors.New("storage closed")
+
+// InitializeLog creates a new, empty, log at the location described by cfg, and publishes its initial checkpoint
+// using the signer configured in opts. This is intended for provisioning tools which need to create a log without
+// going on to append to it; no background jobs are started.
+//
+// Returns an error wrapping ErrLogExists if there is already a log at this location.
+func InitializeLog(ctx context.Context, cfg Config, opts *tessera.AppendOptions) error {
+	d, err := New(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	s := d.(*Storage)
+	if _, _, err := s.readTreeState(ctx); err == nil {
+		return fmt.Errorf("%w at %q", ErrLogExists, s.cfg.Path)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read tree state: %v", err)
+	}
+
+	o := &logResourceStorage{s: s, entriesPath: opts.EntriesPath()}
+	a := &appender{
+		s:          s,
+		logStorage: o,
+		cpUpdated:  make(chan struct{}),
+		newCP:      opts.CheckpointPublisher(o, s.cfg.HTTPClient),
+		hasherName: opts.HasherName(),
+		hasher:     opts.Hasher(),
+	}
+	if s.cfg.DisableAutoPublish {
+		a.newCP = nil
+	}
+	return a.initialise(ctx)
+}
+
+// ErrPartiallyInitialized is returned by Exists when the state directory of a log has been created, but the log
+// has not been fully initialized, e.g. because the process creating it stopped part way through.
+var ErrPartiallyInitialized = errors.New("log is partially initialized")
+
+// Exists reports whether there is already a log at the location described by cfg, without creating anything.
+//
+// Returns an error if the log there was written with an incompatible version of this storage, or an error
+// wrapping ErrPartiallyInitialized if only some of its state is present. Opening such a log with an appender,
+// or calling InitializeLog, will complete its initialization.
+func Exists(ctx context.Context, cfg Config) (bool, error) {
+	d, err := New(ctx, cfg)
+	if err != nil {
+		return false, err
+	}
+	s := d.(*Storage)
+	exists := func(f string) (bool, error) {
+		if _, err := s.stat(filepath.Join(s.stateDir(), f)); errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		} else if err != nil {
+			return false, fmt.Errorf("stat(%s): %v", f, err)
+		}
+		return true, nil
+	}
+	hasVersion, err := exists("version")
+	if err != nil {
+		return false, err
+	}
+	hasTreeState, err := exists(treeStateFile)
+	if err != nil {
+		return false, err
+	}
+	switch {
+	case !hasVersion && !hasTreeState:
+		return false, nil
+	case !hasTreeState:
+		return false, fmt.Errorf("%w: %q has a version but no tree state", ErrPartiallyInitialized, s.cfg.Path)
+	case !hasVersion:
+		return false, fmt.Errorf("%w: %q has a tree state but no version", ErrPartiallyInitialized, s.cfg.Path)
+	}
+	if err := s.checkVersion(compatibilityVersion); err != nil {
+		return false, err
+	}
+	return true, nil
 }
 
 func (s *Storage) Appender(ctx context.Context, opts *tessera.AppendOptions) (*tessera.Appender, tessera.LogReader, error) {
@@ -134,9 +550,11 @@ func (s *Storage) Appender(ctx context.Context, opts *tessera.AppendOptions) (*t
 		return nil, nil, err
 	}
 
-	return &tessera.Appender{
+	ta := &tessera.Appender{
 		Add: a.Add,
-	}, lr, nil
+	}
+	a.public.Store(ta)
+	return ta, lr, nil
 }
 
 func (s *Storage) newAppender(ctx context.Context, o *logResourceStorage, opts *tessera.AppendOptions) (*appender, tessera.LogReader, error) {
@@ -148,35 +566,358 @@ func (s *Storage) newAppender(ctx context.Context, o *logResourceStorage, opts *
 		s:          s,
 		logStorage: o,
 		cpUpdated:  make(chan struct{}),
+		integrate:  make(chan struct{}, 1),
+		grown:      make(chan struct{}),
 		newCP:      opts.CheckpointPublisher(o, s.cfg.HTTPClient),
+		hasherName: opts.HasherName(),
+		hasher:     opts.Hasher(),
+	}
+	if s.cfg.DisableAutoPublish {
+		a.newCP = nil
 	}
 	if err := a.initialise(ctx); err != nil {
 		return nil, nil, err
 	}
-	a.queue = storage.NewQueue(ctx, opts.BatchMaxAge(), opts.BatchMaxSize(), func(ctx context.Context, entries []*tessera.Entry) error {
+	// Integrate any entries which were sequenced, but not integrated, before the log was last closed.
+	if err := a.integratePending(ctx); err != nil {
+		return nil, nil, fmt.Errorf("failed to integrate previously sequenced entries: %v", err)
+	}
+	ctx, a.stop = context.WithCancel(ctx)
+	a.queue = storage.NewQueue(ctx, opts.BatchMaxAge(), opts.BatchMaxSize(), s.tracer(), func(ctx context.Context, entries []*tessera.Entry) error {
 		ctx, cancel := context.WithTimeout(ctx, defaultIntegrationTimeout)
 		defer cancel()
 		return a.sequenceBatch(ctx, entries)
 	})
+	a.queue.SetMaxPending(opts.MaxPendingEntries())
+
+	if s.cfg.LeaderLease > 0 {
+		if _, err := s.renewLease(ctx); err != nil {
+			a.stop()
+			return nil, nil, fmt.Errorf("failed to take leader lease: %v", err)
+		}
+		a.jobs.Go(func() { s.leaseJob(ctx) })
+	}
 
-	go a.publishCheckpointJob(ctx, opts.CheckpointInterval(), opts.CheckpointRepublishInterval())
+	if a.newCP != nil {
+		a.publishInterval = opts.CheckpointInterval()
+		a.publishHeartbeat.Store(s.clock().Now().UnixNano())
+		a.jobs.Go(func() { a.publishCheckpointJob(ctx, opts.CheckpointInterval(), opts.CheckpointRepublishInterval()) })
+	}
 	if i := opts.GarbageCollectionInterval(); i > 0 {
-		go a.garbageCollectorJob(ctx, i)
+		a.jobs.Go(func() { a.garbageCollectorJob(ctx, i) })
+	}
+	if s.cfg.AsyncIntegration {
+		a.jobs.Go(func() { a.integrationJob(ctx) })
 	}
+	s.appender.Store(a)
 
 	return a, a.logStorage, nil
 }
 
+// Flush causes any entries which have been passed to Add but not yet sequenced to be sequenced
+// immediately, and blocks until they, along with any other in-flight entries, have been integrated
+// into the tree.
+//
+// Note that this does not wait for a checkpoint committing to these entries to be published.
+//
+// Returns an error if no appender has been created with this storage, or if ctx becomes done
+// before the entries have been integrated.
+func (s *Storage) Flush(ctx context.Context) error {
+	a := s.appender.Load()
+	if a == nil {
+		return errors.New("no appender has been created")
+	}
+	if err := a.queue.Flush(ctx); err != nil {
+		return err
+	}
+	if !s.cfg.AsyncIntegration {
+		return nil
+	}
+	treeSize, _, err := s.readTreeState(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read tree state: %v", err)
+	}
+	size, _, err := s.readSequencedState(ctx, treeSize, 0)
+	if err != nil {
+		return err
+	}
+	return a.awaitIntegration(ctx, size)
+}
+
+// Close shuts down the appender created with this storage in an orderly way: entries which have been passed
+// to Add are integrated, a checkpoint committing to them is published (unless Config.DisableAutoPublish is set),
+// and the appender's background jobs are stopped, with Close waiting for them to exit.
+//
+// Entries can't be added once Close has been called. Calling Close when no appender has been created, or when
+// it has already been closed, does nothing.
+func (s *Storage) Close(ctx context.Context) error {
+	return otel.TraceErr(ctx, "tessera.storage.posix.Close", s.tracer(), func(ctx context.Context, span trace.Span) error {
+		a := s.appender.Load()
+		if a == nil {
+			return nil
+		}
+		a.closeMu.Lock()
+		closed := a.closed
+		a.closed = true
+		a.closeMu.Unlock()
+		if closed {
+			return nil
+		}
+		var errs []error
+		if err := s.Flush(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to flush: %v", err))
+		} else if a.newCP != nil && s.IsLeader() {
+			if err := a.publishCheckpoint(ctx, 0, 0); err != nil {
+				errs = append(errs, fmt.Errorf("failed to publish final checkpoint: %v", err))
+			}
+		}
+
+		a.stop()
+		done := make(chan struct{})
+		go func() {
+			a.jobs.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-ctx.Done():
+			errs = append(errs, fmt.Errorf("background jobs did not exit: %v", ctx.Err()))
+		}
+		return errors.Join(errs...)
+	})
+}
+
+// SetBatchParams updates the maximum size and age of the batches in which added entries are sequenced,
+// which were initially configured via tessera.AppendOptions.WithBatching.
+//
+// This can be used to adapt to changing traffic without restarting the process, e.g. by allowing larger
+// batches under heavy load, and reducing the maximum age to lower latency when the log is quiet. Entries
+// which have already been added are neither lost nor reordered.
+//
+// Returns an error if no appender has been created with this storage.
+func (s *Storage) SetBatchParams(maxSize uint, maxAge time.Duration) error {
+	a := s.appender.Load()
+	if a == nil {
+		return errors.New("no appender has been created")
+	}
+	if maxSize == 0 {
+		return errors.New("maxSize must be greater than zero")
+	}
+	a.queue.SetBatchParams(maxSize, maxAge)
+	return nil
+}
+
+// stateDir returns the directory, relative to the log root, in which the log's private internal state is stored.
+func (s *Storage) stateDir() string {
+	if s.cfg.StateDir != "" {
+		return s.cfg.StateDir
+	}
+	return defaultStateDir
+}
+
+// logger returns the logger which should be used for messages logged by this storage.
+func (s *Storage) logger() *slog.Logger {
+	if s.cfg.Logger != nil {
+		return s.cfg.Logger
+	}
+	return slog.Default()
+}
+
+// tracer returns the tracer which should be used for spans created by this storage.
+func (s *Storage) tracer() trace.Tracer {
+	if s.cfg.TracerProvider != nil {
+		return s.cfg.TracerProvider.Tracer(name)
+	}
+	return tracer
+}
+
+// TreeState returns the size and root hash of the latest integrated tree.
+//
+// This may be ahead of the latest published checkpoint, and so can be used, e.g. by health checks,
+// to detect that checkpoint publication has stalled.
+func (s *Storage) TreeState(ctx context.Context) (uint64, []byte, error) {
+	// Wait for any integration being undertaken by this process to complete.
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.readTreeState(ctx)
+}
+
+// logReader returns a logResourceStorage for reading this log's resources.
+//
+// The entry bundle layout configured on the appender is used if one has been created, otherwise the
+// default tlog-tiles layout is assumed.
+func (s *Storage) logReader() *logResourceStorage {
+	if a := s.appender.Load(); a != nil {
+		return a.logStorage
+	}
+	return &logResourceStorage{s: s, entriesPath: tessera.NewAppendOptions().EntriesPath()}
+}
+
+// StreamEntries returns an iterator over the individual entries in the range [start, end) of the log,
+// in log order.
+//
+// Entries are read from the entry bundles covering the range, which must be in the tlog-tiles format,
+// and only entries which have been integrated into the tree can be streamed.
+// Iteration stops after the first error is yielded, including ctx becoming done between bundles.
+func (s *Storage) StreamEntries(ctx context.Context, start, end uint64) iter.Seq2[[]byte, error] {
+	return func(yield func([]byte, error) bool) {
+		size, _, err := s.readTreeState(ctx)
+		if err != nil {
+			yield(nil, fmt.Errorf("failed to read tree state: %v", err))
+			return
+		}
+		if start > end || end > size {
+			yield(nil, fmt.Errorf("invalid range [%d, %d) for tree size %d", start, end, size))
+			return
+		}
+		lrs := s.logReader()
+		for ri := range layout.Range(start, end-start, size) {
+			if err := ctx.Err(); err != nil {
+				yield(nil, err)
+				return
+			}
+			entries, err := readBundleEntries(ctx, lrs, ri.Index, ri.Partial)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if uint(len(entries)) < ri.First+ri.N {
+				yield(nil, fmt.Errorf("entry bundle %d has %d entries, want at least %d", ri.Index, len(entries), ri.First+ri.N))
+				return
+			}
+			for _, e := range entries[ri.First : ri.First+ri.N] {
+				if !yield(e, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// ReadEntries returns the count entries in the log starting at index start, as stored in their entry bundles.
+//
+// Returns an error if any of the entries have not yet been integrated into the tree.
+func (s *Storage) ReadEntries(ctx context.Context, start, count uint64) ([][]byte, error) {
+	return otel.Trace(ctx, "tessera.storage.posix.ReadEntries", s.tracer(), func(ctx context.Context, span trace.Span) ([][]byte, error) {
+		span.SetAttributes(numEntriesKey.Int64(otel.Clamp64(count)))
+		end := start + count
+		if end < start {
+			return nil, fmt.Errorf("range of %d entries from %d overflows", count, start)
+		}
+		r := make([][]byte, 0, min(count, layout.EntryBundleWidth))
+		for e, err := range s.StreamEntries(ctx, start, end) {
+			if err != nil {
+				return nil, err
+			}
+			r = append(r, e)
+		}
+		return r, nil
+	})
+}
+
+// ReadEntry returns the entry at the given index in the log, as stored in its entry bundle.
+//
+// Returns an error if the entry has not yet been integrated into the tree.
+func (s *Storage) ReadEntry(ctx context.Context, index uint64) ([]byte, error) {
+	return otel.Trace(ctx, "tessera.storage.posix.ReadEntry", s.tracer(), func(ctx context.Context, span trace.Span) ([]byte, error) {
+		size, _, err := s.readTreeState(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tree state: %v", err)
+		}
+		if index >= size {
+			return nil, fmt.Errorf("index %d is beyond tree size %d", index, size)
+		}
+		bundleIndex := index / layout.EntryBundleWidth
+		entries, err := readBundleEntries(ctx, s.logReader(), bundleIndex, layout.PartialTileSize(0, bundleIndex, size))
+		if err != nil {
+			return nil, err
+		}
+		offset := index % layout.EntryBundleWidth
+		if offset >= uint64(len(entries)) {
+			return nil, fmt.Errorf("entry bundle %d has %d entries, want more than %d", bundleIndex, len(entries), offset)
+		}
+		return entries[offset], nil
+	})
+}
+
+// OpenEntryBundle returns a reader for the contents of the specified entry bundle, allowing large bundles to
+// be parsed as they're streamed from disk rather than being read into memory in their entirety.
+//
+// As with ReadEntryBundle, the full bundle is returned in place of a partial bundle which no longer exists.
+// The caller must close the returned reader.
+func (s *Storage) OpenEntryBundle(ctx context.Context, index uint64, p uint8) (io.ReadCloser, error) {
+	_, span := s.tracer().Start(ctx, "tessera.storage.posix.OpenEntryBundle")
+	defer span.End()
+
+	lrs := s.logReader()
+	if _, ok := s.bundleCodec().(TLogTilesCodec); !ok {
+		// Other codecs need the whole bundle in order to decode it.
+		b, err := lrs.ReadEntryBundle(ctx, index, p)
+		if err != nil {
+			return nil, err
+		}
+		return io.NopCloser(bytes.NewReader(b)), nil
+	}
+	c := s.cfg.BundleCompression
+	f, err := os.Open(filepath.Join(s.cfg.Path, lrs.bundlePath(index, p)+c.suffix()))
+	if errors.Is(err, os.ErrNotExist) && p > 0 {
+		// The partial bundle may have been removed as the tree has grown, so fall back to the full bundle.
+		f, err = os.Open(filepath.Join(s.cfg.Path, lrs.bundlePath(index, 0)+c.suffix()))
+	}
+	if err != nil {
+		return nil, storage.WrapNotFound(err)
+	}
+	r, err := c.newReader(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("failed to decompress entry bundle %d: %v", index, err)
+	}
+	return &bundleReader{ReadCloser: r, f: f}, nil
+}
+
+// bundleReader reads an entry bundle, closing the underlying file once it's closed.
+type bundleReader struct {
+	io.ReadCloser
+	f *os.File
+}
+
+func (b *bundleReader) Close() error {
+	return errors.Join(b.ReadCloser.Close(), b.f.Close())
+}
+
+// readBundleEntries reads the specified entry bundle and returns the entries it contains.
+func readBundleEntries(ctx context.Context, lrs *logResourceStorage, index uint64, p uint8) ([][]byte, error) {
+	b, err := lrs.ReadEntryBundle(ctx, index, p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read entry bundle %d: %w", index, err)
+	}
+	entries, err := api.ParseEntryBundle(b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse entry bundle %d: %v", index, err)
+	}
+	return entries, nil
+}
+
 func (a *appender) publishCheckpointJob(ctx context.Context, pubInterval, republishInterval time.Duration) {
-	t := time.NewTicker(pubInterval)
+	defer a.publishHeartbeat.Store(0)
+	// Offset the schedule, so that logs started at the same time don't all publish at once.
+	if !a.s.sleep(ctx, jitter(pubInterval, a.s.cfg.PublishJitter)) {
+		return
+	}
+	t := a.s.clock().NewTicker(pubInterval)
+	defer t.Stop()
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		case <-a.cpUpdated:
-		case <-t.C:
+		case <-t.C():
+		}
+		a.publishHeartbeat.Store(a.s.clock().Now().UnixNano())
+		if !a.s.IsLeader() {
+			continue
 		}
-		if err := otel.TraceErr(ctx, "tessera.storage.posix.publishCheckpointJob", tracer, func(ctx context.Context, span trace.Span) error {
+		if err := otel.TraceErr(ctx, "tessera.storage.posix.publishCheckpointJob", a.s.tracer(), func(ctx context.Context, span trace.Span) error {
 			ctx, cancel := context.WithTimeout(ctx, defaultPublicationTimeout)
 			defer cancel()
 			if err := a.publishCheckpoint(ctx, pubInterval, republishInterval); err != nil {
@@ -184,7 +925,7 @@ func (a *appender) publishCheckpointJob(ctx context.Context, pubInterval, republ
 			}
 			return nil
 		}, trace.WithAttributes(otel.PeriodicKey.Bool(true))); err != nil {
-			slog.WarnContext(ctx, "publishCheckpoint failed", slog.Any("error", err))
+			a.s.logger().WarnContext(ctx, "publishCheckpoint failed", slog.Any("error", err))
 		}
 	}
 }
@@ -197,13 +938,22 @@ func (a *appender) publishCheckpointJob(ctx context.Context, pubInterval, republ
 // (e.g. <something>.lock>) to avoid inherent brittleness of the `fcntrl` API
 // (*any* `Close` operation on this file (even if it's a different FD) from
 // this PID, or overwriting of the file by *any* process breaks the lock.)
+//
+// If the lock is held by another process, this func will keep retrying until either the lock is
+// acquired or the provided context is done, in which case ctx.Err() is returned.
 func (s *Storage) lockFile(ctx context.Context, p string) (func() error, error) {
-	return otel.Trace(ctx, "tessera.storage.posix.lockFile", tracer, func(ctx context.Context, span trace.Span) (func() error, error) {
+	return otel.Trace(ctx, "tessera.storage.posix.lockFile", s.tracer(), func(ctx context.Context, span trace.Span) (func() error, error) {
 		span.SetAttributes(filenameKey.String(p))
 		now := time.Now()
 
+		// Don't create the lock file if we're already shutting down, e.g. when a background job wakes up
+		// at the same time as its context is cancelled.
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
 		span.AddEvent("Open file")
-		p = filepath.Join(s.cfg.Path, stateDir, p)
+		p = filepath.Join(s.cfg.Path, s.stateDir(), p)
 		f, err := os.OpenFile(p, syscall.O_CREAT|syscall.O_RDWR|syscall.O_CLOEXEC, filePerm)
 		if err != nil {
 			return nil, err
@@ -215,25 +965,68 @@ func (s *Storage) lockFile(ctx context.Context, p string) (func() error, error)
 			Start:  0,
 			Len:    0,
 		}
-		// Keep trying until we manage to get an answer without being interrupted.
+		// We use the non-blocking F_SETLK here rather than F_SETLKW so that we're able to give up
+		// if the context becomes done while another process is holding the lock.
 		span.AddEvent("Lock attempt")
+		retry := minLockRetryInterval
 		for {
-			if err := syscall.FcntlFlock(f.Fd(), syscall.F_SETLKW, &flockT); err != syscall.EINTR {
-				if err == nil {
-					span.AddEvent("Lock taken")
-					posixOpsHistogram.Record(ctx, time.Since(now).Milliseconds(), metric.WithAttributes(opNameKey.String(fmt.Sprintf("lock-%s", p))))
-				}
+			err := syscall.FcntlFlock(f.Fd(), syscall.F_SETLK, &flockT)
+			switch {
+			case err == nil:
+				span.AddEvent("Lock taken")
+				posixOpsHistogram.Record(ctx, time.Since(now).Milliseconds(), metric.WithAttributes(opNameKey.String(fmt.Sprintf("lock-%s", p))))
 				c := func() error {
 					span.AddEvent("Lock released")
 					return f.Close()
 				}
-				return c, err
+				return c, nil
+			case errors.Is(err, syscall.EINTR):
+				// Interrupted, just try again.
+				continue
+			case errors.Is(err, syscall.EAGAIN), errors.Is(err, syscall.EACCES):
+				// Another process holds the lock, so back off below before trying again.
+			default:
+				_ = f.Close()
+				return nil, err
+			}
+
+			select {
+			case <-ctx.Done():
+				span.AddEvent("Lock abandoned")
+				_ = f.Close()
+				return nil, ctx.Err()
+			case <-time.After(retry):
 			}
+			retry = min(2*retry, maxLockRetryInterval)
 		}
 	})
 
 }
 
+// lockTreeState takes the locks which must be held when integrating entries into the tree or
+// writing to the treeState file.
+//
+// Double locking:
+// - The mutex `Lock()` ensures that multiple concurrent calls within a task are serialised.
+// - The POSIX `lockFile()` ensures that distinct tasks are serialised.
+//
+// The returned function must be called to release both locks.
+func (s *Storage) lockTreeState(ctx context.Context) (func() error, error) {
+	s.mu.Lock()
+	unlock, err := s.lockFile(ctx, treeStateLock)
+	if err != nil {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("lockFile(%s): %w", treeStateLock, err)
+	}
+	return func() error {
+		defer s.mu.Unlock()
+		if err := unlock(); err != nil {
+			return fmt.Errorf("unlock(%s): %w", treeStateLock, err)
+		}
+		return nil
+	}, nil
+}
+
 // Add takes an entry and queues it for inclusion in the log.
 // Upon placing the entry in an in-memory queue to be sequenced, it returns a future that will
 // evaluate to either the sequence number assigned to this entry, or an error.
@@ -251,46 +1044,159 @@ func (s *Storage) lockFile(ctx context.Context, p string) (func() error, error)
 // mean that some of the entries added are not committed to by a checkpoint, and thus are
 // not considered to be in the log.
 func (a *appender) Add(ctx context.Context, e *tessera.Entry) tessera.IndexFuture {
-	return a.queue.Add(ctx, e)
+	// The span context of this span is recorded alongside the queued entry, so that the span for the
+	// batch in which it's eventually sequenced can be linked back to it.
+	ctx, span := a.s.tracer().Start(ctx, "tessera.storage.posix.Add")
+	defer span.End()
+
+	a.closeMu.RLock()
+	defer a.closeMu.RUnlock()
+	if a.closed {
+		return func() (tessera.Index, error) { return tessera.Index{}, ErrClosed }
+	}
+	return a.awaitIntegrationDecorator(ctx, a.queue.Add(ctx, e))
+}
+
+// AddSync adds an entry to the log and waits for it to be sequenced and integrated, returning the
+// index assigned to it. This is equivalent to calling Add and then evaluating the returned future.
+//
+// As with AddBatch, the entry is passed through the appender's Add function, including any decoration
+// of it configured via tessera.AppendOptions, such as antispam, so the index returned may be that of an
+// earlier duplicate of the entry.
+func (s *Storage) AddSync(ctx context.Context, e *tessera.Entry) (uint64, error) {
+	a := s.appender.Load()
+	if a == nil {
+		return 0, errors.New("no appender has been created")
+	}
+	idx, err := a.decoratedAdd()(ctx, e)()
+	if err != nil {
+		return 0, err
+	}
+	return idx.Index, nil
+}
+
+// AddBatch adds each of the provided entries to the log, in order, and returns a future for each
+// of them which behaves as described for Add.
+//
+// Each entry is passed through the appender's Add function, including any decoration of it configured
+// via tessera.AppendOptions, such as antispam, so entries may be rejected or deduplicated individually.
+// Entries which are added will appear in the log in the order they're provided, and before any entries
+// added after this call returns.
+func (s *Storage) AddBatch(ctx context.Context, entries []*tessera.Entry) []tessera.IndexFuture {
+	ctx, span := s.tracer().Start(ctx, "tessera.storage.posix.AddBatch")
+	defer span.End()
+	span.SetAttributes(numEntriesKey.Int(len(entries)))
+
+	r := make([]tessera.IndexFuture, len(entries))
+	a := s.appender.Load()
+	if a == nil {
+		for i := range r {
+			r[i] = func() (tessera.Index, error) { return tessera.Index{}, errors.New("no appender has been created") }
+		}
+		return r
+	}
+	add := a.decoratedAdd()
+	for i, e := range entries {
+		r[i] = add(ctx, e)
+	}
+	return r
+}
+
+// decoratedAdd returns the Add function of the tessera.Appender created for this appender, which
+// includes any decoration configured via tessera.AppendOptions, or the undecorated Add function if
+// the appender wasn't created via Storage.Appender.
+func (a *appender) decoratedAdd() tessera.AddFn {
+	if ta := a.public.Load(); ta != nil {
+		return ta.Add
+	}
+	return a.Add
 }
 
 func (l *logResourceStorage) ReadCheckpoint(ctx context.Context) ([]byte, error) {
-	return otel.Trace(ctx, "tessera.storage.posix.ReadCheckpoint", tracer, func(ctx context.Context, span trace.Span) ([]byte, error) {
-		r, err := os.ReadFile(filepath.Join(l.s.cfg.Path, layout.CheckpointPath))
-		if errors.Is(err, fs.ErrNotExist) {
-			return r, os.ErrNotExist
+	return otel.Trace(ctx, "tessera.storage.posix.ReadCheckpoint", l.s.tracer(), func(ctx context.Context, span trace.Span) ([]byte, error) {
+		if l.s.cfg.CheckpointCache {
+			r, _, err := l.s.ReadCheckpointWithModTime(ctx)
+			return r, err
 		}
-		return r, err
+		r, err := l.s.readAll(layout.CheckpointPath)
+		return r, storage.WrapNotFound(err)
 	})
 }
 
 // ReadEntryBundle retrieves the Nth entries bundle for a log of the given size.
 func (l *logResourceStorage) ReadEntryBundle(ctx context.Context, index uint64, p uint8) ([]byte, error) {
-	return otel.Trace(ctx, "tessera.storage.posix.EntryBundle", tracer, func(ctx context.Context, span trace.Span) ([]byte, error) {
-		return fetcher.PartialOrFullResource(ctx, p, func(ctx context.Context, p uint8) ([]byte, error) {
-			return os.ReadFile(filepath.Join(l.s.cfg.Path, l.entriesPath(index, p)))
+	return otel.Trace(ctx, "tessera.storage.posix.EntryBundle", l.s.tracer(), func(ctx context.Context, span trace.Span) ([]byte, error) {
+		r, err := fetcher.PartialOrFullResource(ctx, p, func(ctx context.Context, p uint8) ([]byte, error) {
+			b, err := l.readEncodedBundle(index, p)
+			if err != nil {
+				return nil, err
+			}
+			return l.s.bundleCodec().Decode(b)
 		})
+		return r, storage.WrapNotFound(err)
 	})
 }
 
+// readEncodedBundle returns the specified entry bundle as encoded by the log's BundleCodec.
+func (l *logResourceStorage) readEncodedBundle(index uint64, p uint8) ([]byte, error) {
+	c := l.s.cfg.BundleCompression
+	b, err := l.s.readAll(l.bundlePath(index, p) + c.suffix())
+	if err != nil {
+		return nil, err
+	}
+	return c.decompress(b)
+}
+
 func (l *logResourceStorage) ReadTile(ctx context.Context, level, index uint64, p uint8) ([]byte, error) {
-	return otel.Trace(ctx, "tessera.storage.posix.ReadTile", tracer, func(ctx context.Context, span trace.Span) ([]byte, error) {
-		return fetcher.PartialOrFullResource(ctx, p, func(ctx context.Context, p uint8) ([]byte, error) {
-			return os.ReadFile(filepath.Join(l.s.cfg.Path, layout.TilePath(level, index, p)))
+	return otel.Trace(ctx, "tessera.storage.posix.ReadTile", l.s.tracer(), func(ctx context.Context, span trace.Span) ([]byte, error) {
+		if l.s.heatmap != nil {
+			l.s.heatmap.record(level, index)
+		}
+		k := tileCacheKey{level: level, index: index, p: p}
+		if l.s.tileCache != nil {
+			if r, ok := l.s.tileCache.get(k); ok {
+				span.SetAttributes(cacheHitKey.Bool(true))
+				return r, nil
+			}
+		}
+		r, err := fetcher.PartialOrFullResource(ctx, p, func(ctx context.Context, p uint8) ([]byte, error) {
+			tPath := l.s.tilePath(level, index, p)
+			t, err := l.s.readAll(tPath)
+			if err != nil {
+				return nil, err
+			}
+			if l.s.cfg.TileChecksums {
+				if err := l.s.verifyTileChecksum(tPath, t); err != nil {
+					return nil, err
+				}
+			}
+			return t, nil
 		})
+		if err != nil {
+			return nil, storage.WrapNotFound(err)
+		}
+		if l.s.tileCache != nil {
+			l.s.tileCache.add(k, r)
+		}
+		return r, nil
 	})
 }
 
 func (l *logResourceStorage) IntegratedSize(ctx context.Context) (uint64, error) {
-	return otel.Trace(ctx, "tessera.storage.posix.IntegratedSize", tracer, func(ctx context.Context, span trace.Span) (uint64, error) {
+	return otel.Trace(ctx, "tessera.storage.posix.IntegratedSize", l.s.tracer(), func(ctx context.Context, span trace.Span) (uint64, error) {
 		size, _, err := l.s.readTreeState(ctx)
 		return size, err
 	})
 }
 
 func (l *logResourceStorage) NextIndex(ctx context.Context) (uint64, error) {
-	return otel.Trace(ctx, "tessera.storage.posix.NextIndex", tracer, func(ctx context.Context, span trace.Span) (uint64, error) {
-		return l.IntegratedSize(ctx)
+	return otel.Trace(ctx, "tessera.storage.posix.NextIndex", l.s.tracer(), func(ctx context.Context, span trace.Span) (uint64, error) {
+		size, err := l.IntegratedSize(ctx)
+		if err != nil || !l.s.cfg.AsyncIntegration {
+			return size, err
+		}
+		size, _, err = l.s.readSequencedState(ctx, size, 0)
+		return size, err
 	})
 }
 
@@ -301,22 +1207,28 @@ func (l *logResourceStorage) NextIndex(ctx context.Context) (uint64, error) {
 // We try to minimise the number of partially complete entry bundles by writing entries in chunks rather
 // than one-by-one.
 func (a *appender) sequenceBatch(ctx context.Context, entries []*tessera.Entry) error {
-	return otel.TraceErr(ctx, "tessera.storage.posix.assignEntries", tracer, func(ctx context.Context, span trace.Span) error {
+	return otel.TraceErr(ctx, "tessera.storage.posix.assignEntries", a.s.tracer(), func(ctx context.Context, span trace.Span) (errR error) {
 		span.SetAttributes(numEntriesKey.Int(len(entries)))
+		batchSizeHistogram.Record(ctx, int64(len(entries)))
+
+		if !a.s.IsLeader() {
+			return ErrNotLeader
+		}
 
-		// Double locking:
-		// - The mutex `Lock()` ensures that multiple concurrent calls to this function within a task are serialised.
-		// - The POSIX `lockFile()` ensures that distinct tasks are serialised.
-		a.s.mu.Lock()
-		unlock, err := a.s.lockFile(ctx, treeStateLock)
+		if maxOutage := a.s.cfg.MaxSignerOutage; maxOutage > 0 {
+			if since, err := a.s.signer.failure(); !since.IsZero() && a.s.clock().Now().Sub(since) > maxOutage {
+				return fmt.Errorf("checkpoint creation has been failing since %v (last error: %v): %w", since, err, tessera.ErrPushback)
+			}
+		}
+
+		unlock, err := a.lockForSequencing(ctx)
 		if err != nil {
-			panic(err)
+			return err
 		}
 		defer func() {
-			if err := unlock(); err != nil {
-				panic(err)
+			if err := unlock(); err != nil && errR == nil {
+				errR = err
 			}
-			a.s.mu.Unlock()
 		}()
 
 		size, _, err := a.s.readTreeState(ctx)
@@ -326,87 +1238,310 @@ func (a *appender) sequenceBatch(ctx context.Context, entries []*tessera.Entry)
 			}
 			size = 0
 		}
+		// The lease may have been lost while we were waiting for the lock.
+		if !a.s.IsLeader() {
+			return ErrNotLeader
+		}
+		if size, err = a.nextSequence(ctx, size); err != nil {
+			return err
+		}
 		a.curSize = size
-		slog.DebugContext(ctx, "Sequencing", slog.Uint64("from", a.curSize))
+		span.SetAttributes(fromSizeKey.Int64(otel.Clamp64(size)))
+		a.s.logger().DebugContext(ctx, "Sequencing", slog.Uint64("fromSeq", a.curSize), slog.Int("batch", len(entries)))
 
 		if len(entries) == 0 {
 			return nil
 		}
-		currTile := &bytes.Buffer{}
-		seq := a.curSize
-		bundleIndex, entriesInBundle := seq/layout.EntryBundleWidth, seq%layout.EntryBundleWidth
-		if entriesInBundle > 0 {
-			// If the latest bundle is partial, we need to read the data it contains in for our newer, larger, bundle.
-			part, err := a.logStorage.ReadEntryBundle(ctx, bundleIndex, uint8(a.curSize%layout.EntryBundleWidth))
-			if err != nil {
-				return err
-			}
-			if _, err := currTile.Write(part); err != nil {
-				return fmt.Errorf("failed to write partial bundle into buffer: %v", err)
+		bundleData := make([][]byte, 0, len(entries))
+		leafHashes := make([][]byte, 0, len(entries))
+		var extraData [][]byte
+		for i, e := range entries {
+			bundleData = append(bundleData, e.MarshalBundleData(a.curSize+uint64(i)))
+			leafHashes = append(leafHashes, e.LeafHash())
+			if x := e.ExtraData(); x != nil {
+				if extraData == nil {
+					extraData = make([][]byte, len(entries))
+				}
+				extraData[i] = x
 			}
 		}
-		writeBundle := func(bundleIndex uint64, partialSize uint8) error {
-			return a.logStorage.writeBundle(ctx, bundleIndex, partialSize, currTile.Bytes())
+		return a.appendBundleData(ctx, bundleData, leafHashes, extraData)
+	}, trace.WithAttributes(otel.PeriodicKey.Bool(true)))
+}
+
+// AppendFramedBundle appends entries which have already been framed for inclusion in an entry bundle,
+// and integrates the provided corresponding leaf hashes into the tree.
+//
+// This is a fast-path intended for use when replicating or importing from a source which already frames
+// entries exactly as the Entry.MarshalBundleData method used by this log would, and so avoids the cost of
+// marshalling each entry individually. It is the caller's responsibility to ensure that the framing is
+// correct for the indices at which the entries will be appended.
+//
+// framed must contain exactly len(leafHashes) length-prefixed entries, as described by
+// https://c2sp.org/tlog-tiles, in the same order as leafHashes.
+//
+// Returns the index assigned to the first entry in framed.
+func (s *Storage) AppendFramedBundle(ctx context.Context, framed []byte, leafHashes [][]byte) (uint64, error) {
+	return otel.Trace(ctx, "tessera.storage.posix.AppendFramedBundle", s.tracer(), func(ctx context.Context, span trace.Span) (firstIndex uint64, errR error) {
+		span.SetAttributes(numEntriesKey.Int(len(leafHashes)))
+
+		a := s.appender.Load()
+		if a == nil {
+			return 0, errors.New("no appender has been created")
+		}
+		bundleData, err := splitFramedEntries(framed)
+		if err != nil {
+			return 0, fmt.Errorf("invalid framed bundle data: %v", err)
+		}
+		if len(bundleData) != len(leafHashes) {
+			return 0, fmt.Errorf("framed bundle data contains %d entries, but %d leaf hashes were provided", len(bundleData), len(leafHashes))
 		}
 
-		leafHashes := make([][]byte, 0, len(entries))
-		// Add new entries to the bundle
-		for i, e := range entries {
-			bundleData := e.MarshalBundleData(seq + uint64(i))
-			if _, err := currTile.Write(bundleData); err != nil {
-				return fmt.Errorf("failed to write entry %d to currTile: %v", i, err)
+		unlock, err := a.lockForSequencing(ctx)
+		if err != nil {
+			return 0, err
+		}
+		defer func() {
+			if err := unlock(); err != nil && errR == nil {
+				errR = err
 			}
-			leafHashes = append(leafHashes, e.LeafHash())
+		}()
+		if !s.IsLeader() {
+			return 0, ErrNotLeader
+		}
 
-			entriesInBundle++
-			if entriesInBundle == layout.EntryBundleWidth {
-				//  This bundle is full, so we need to write it out...
-				// ... and prepare the next entry bundle for any remaining entries in the batch
-				if err := writeBundle(bundleIndex, 0); err != nil {
-					return err
-				}
-				bundleIndex++
-				entriesInBundle = 0
-				currTile = &bytes.Buffer{}
+		size, _, err := s.readTreeState(ctx)
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				return 0, err
 			}
+			size = 0
 		}
-		// If we have a partial bundle remaining once we've added all the entries from the batch,
-		// this needs writing out too.
-		if entriesInBundle > 0 {
-			// This check should be redundant since this is [currently] checked above, but an overflow around the uint8 below could
-			// potentially be bad news if that check was broken/defeated as we'd be writing invalid bundle data, so do a belt-and-braces
-			// check and bail if need be.
-			if entriesInBundle > layout.EntryBundleWidth {
-				return fmt.Errorf("logic error: entriesInBundle(%d) > max bundle size %d", entriesInBundle, layout.EntryBundleWidth)
-			}
-			if err := writeBundle(bundleIndex, uint8(entriesInBundle)); err != nil {
+		if size, err = a.nextSequence(ctx, size); err != nil {
+			return 0, err
+		}
+		a.curSize = size
+		if len(bundleData) == 0 {
+			return size, nil
+		}
+		if err := a.appendBundleData(ctx, bundleData, leafHashes, nil); err != nil {
+			return 0, err
+		}
+		return size, nil
+	})
+}
+
+// splitFramedEntries splits a sequence of length-prefixed entries into its constituent entries, each of
+// which retains its length prefix.
+func splitFramedEntries(framed []byte) ([][]byte, error) {
+	r := [][]byte{}
+	for len(framed) > 0 {
+		if len(framed) < 2 {
+			return nil, fmt.Errorf("truncated length prefix for entry %d", len(r))
+		}
+		l := 2 + int(binary.BigEndian.Uint16(framed))
+		if len(framed) < l {
+			return nil, fmt.Errorf("entry %d has length %d, but only %d bytes remain", len(r), l-2, len(framed)-2)
+		}
+		r = append(r, framed[:l])
+		framed = framed[l:]
+	}
+	return r, nil
+}
+
+// appendBundleData writes the provided bundle-framed entries into the log's entry bundles starting at
+// a.curSize, and integrates the corresponding leaf hashes into the tree.
+//
+// extraData holds the extra data for each of the entries, and may be nil if none of them have any. Extra data
+// files are only written for bundles which contain at least one entry with extra data.
+//
+// The caller must hold the locks taken by lockForSequencing, and must have set a.curSize to the index at which
+// the entries are to be sequenced.
+func (a *appender) appendBundleData(ctx context.Context, bundleData [][]byte, leafHashes [][]byte, extraData [][]byte) error {
+	codec := a.s.bundleCodec()
+	// currTile holds the bundle being built, as encoded by codec.
+	var currTile []byte
+	// currExtra holds the extra data for the bundle in currTile, and is nil if there's none to write.
+	var currExtra []byte
+	seq := a.curSize
+	bundleIndex, entriesInBundle := seq/layout.EntryBundleWidth, seq%layout.EntryBundleWidth
+	if entriesInBundle > 0 {
+		// If the latest bundle is partial, we need to read the data it contains in for our newer, larger, bundle.
+		part, err := a.logStorage.readEncodedBundle(bundleIndex, uint8(a.curSize%layout.EntryBundleWidth))
+		if err != nil {
+			return storage.WrapNotFound(err)
+		}
+		currTile = part
+		// Likewise for its extra data, if it has any.
+		currExtra, err = a.logStorage.readExtraData(ctx, bundleIndex, uint8(entriesInBundle))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to read extra data for partial bundle: %v", err)
+		}
+	}
+	// newExtra starts the extra data for the bundle in currTile if it doesn't already have any, padding it with
+	// empty blobs for the entries it already contains.
+	newExtra := func() []byte {
+		r := make([]byte, 0, 4*entriesInBundle)
+		for range entriesInBundle {
+			r = binary.BigEndian.AppendUint32(r, 0)
+		}
+		return r
+	}
+	// In SyncPerBatch mode, the bundles are synced together once they've all been written.
+	var batch *syncBatch
+	if a.s.cfg.SyncMode == SyncPerBatch && !a.s.cfg.DisableSyncWrites {
+		batch = &syncBatch{}
+	}
+	writeBundle := func(bundleIndex uint64, partialSize uint8) error {
+		// The extra data is written first so that it's always present for an entry bundle which has been written.
+		if currExtra != nil {
+			if err := a.logStorage.writeExtraData(ctx, bundleIndex, partialSize, currExtra, batch); err != nil {
 				return err
 			}
 		}
+		return a.logStorage.writeBundleBatched(ctx, bundleIndex, partialSize, currTile, batch)
+	}
 
-		// For simplicity, in-line the integration of these new entries into the Merkle structure too.
-		// If this is broken out into an async process, we'll need to update the implementation of NextIndex, too.
-		newSize, newRoot, err := doIntegrate(ctx, seq, leafHashes, a.logStorage)
-		if err != nil {
-			slog.ErrorContext(ctx, "Integrate failed", slog.Any("error", err))
+	// Add new entries to the bundle
+	for i, d := range bundleData {
+		var err error
+		if extraData != nil && currExtra == nil {
+			currExtra = newExtra()
+		}
+		if currExtra != nil {
+			var x []byte
+			if extraData != nil {
+				x = extraData[i]
+			}
+			if currExtra, err = appendExtraData(currExtra, x); err != nil {
+				return fmt.Errorf("entry %d: %v", i, err)
+			}
+		}
+		if currTile, err = codec.Append(currTile, d); err != nil {
+			return fmt.Errorf("failed to write entry %d to currTile: %v", i, err)
+		}
+
+		entriesInBundle++
+		if entriesInBundle == layout.EntryBundleWidth {
+			//  This bundle is full, so we need to write it out...
+			// ... and prepare the next entry bundle for any remaining entries in the batch
+			if err := writeBundle(bundleIndex, 0); err != nil {
+				return err
+			}
+			bundleIndex++
+			entriesInBundle = 0
+			currTile = nil
+			currExtra = nil
+		}
+	}
+	// If we have a partial bundle remaining once we've added all the entries from the batch,
+	// this needs writing out too.
+	if entriesInBundle > 0 {
+		// This check should be redundant since this is [currently] checked above, but an overflow around the uint8 below could
+		// potentially be bad news if that check was broken/defeated as we'd be writing invalid bundle data, so do a belt-and-braces
+		// check and bail if need be.
+		if entriesInBundle > layout.EntryBundleWidth {
+			return fmt.Errorf("logic error: entriesInBundle(%d) > max bundle size %d", entriesInBundle, layout.EntryBundleWidth)
+		}
+		if err := writeBundle(bundleIndex, uint8(entriesInBundle)); err != nil {
 			return err
 		}
-		if err := a.s.writeTreeState(ctx, newSize, newRoot); err != nil {
-			return fmt.Errorf("failed to write new tree state: %v", err)
+	}
+	if batch != nil {
+		if err := a.s.retry(batch.sync); err != nil {
+			return fmt.Errorf("failed to sync entry bundles: %w", err)
 		}
-		// Notify that we know for sure there's a new checkpoint, but don't block if there's already
-		// an outstanding notification in the channel.
-		select {
-		case a.cpUpdated <- struct{}{}:
-		default:
+	}
+
+	// Unless integration is asynchronous, in-line the integration of these new entries into the Merkle
+	// structure too.
+	if a.s.cfg.AsyncIntegration {
+		return a.recordSequenced(ctx, seq, leafHashes)
+	}
+	return a.integrateLeaves(ctx, seq, leafHashes)
+}
+
+// integrateLeaves integrates the provided leaf hashes into the tree of the given size.
+//
+// The caller must hold the tree state lock.
+func (a *appender) integrateLeaves(ctx context.Context, seq uint64, leafHashes [][]byte) error {
+	// The bundles written above, and the tiles written during integration, are durable before the tree state
+	// is updated to include them. Checkpoints are only ever created from the tree state, so every resource
+	// committed to by a published checkpoint is guaranteed to exist before the checkpoint does.
+	//
+	// If configured, the entries are integrated in chunks, with the tree state stored after each, so that
+	// integrating a large backlog progresses incrementally rather than in a single large step.
+	chunkSize := len(leafHashes)
+	if m := a.s.cfg.MaxIntegrateLeaves; m > 0 && int(m) < chunkSize {
+		chunkSize = int(m)
+	}
+	for start := 0; start < len(leafHashes); start += chunkSize {
+		chunk := leafHashes[start:min(start+chunkSize, len(leafHashes))]
+		if err := a.integrateChunk(ctx, seq+uint64(start), chunk); err != nil {
+			return err
 		}
-		return nil
-	}, trace.WithAttributes(otel.PeriodicKey.Bool(true)))
+	}
+	// Notify that we know for sure there's a new checkpoint, but don't block if there's already
+	// an outstanding notification in the channel.
+	select {
+	case a.cpUpdated <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// integrateChunk integrates the provided leaf hashes into the tree of the given size, and stores the new tree state.
+//
+// The caller must hold the tree state lock.
+func (a *appender) integrateChunk(ctx context.Context, fromSize uint64, leafHashes [][]byte) error {
+	newSize, newRoot, err := doIntegrate(ctx, a.hasher, fromSize, leafHashes, a.logStorage)
+	if err != nil {
+		a.s.logger().ErrorContext(ctx, "Integrate failed", slog.Uint64("fromSeq", fromSize), slog.Int("batch", len(leafHashes)), slog.Any("error", err))
+		return err
+	}
+	if err := a.s.writeTreeState(ctx, newSize, newRoot); err != nil {
+		return fmt.Errorf("failed to write new tree state: %v", err)
+	}
+	a.s.notifyNewTree(ctx, newSize, newRoot)
+	a.notifyTreeGrown()
+	if a.leafIndex != nil {
+		// The entries are already in the log, so failing to index them isn't fatal; the gap is filled in by the next update.
+		if err := a.updateLeafIndex(ctx, fromSize, leafHashes); err != nil {
+			a.s.logger().WarnContext(ctx, "Failed to update leaf index", slog.Uint64("from", fromSize), slog.Any("error", err))
+		}
+	}
+	return nil
+}
+
+// IntegrateLeafHashes integrates the provided leaf hashes into the tree of size fromSeq, writing the resulting
+// tiles, and returns the size and root hash of the new tree.
+//
+// This only grows the tree: the entry bundles for the leaves are not written, and the log's tree state is not
+// updated, both of which are left to the caller. It's intended for benchmarking integration, and for importers
+// which already have the leaf hashes and manage the rest of the log themselves; callers must ensure that nothing
+// else integrates into the log concurrently.
+//
+// The hasher configured on the appender is used if one has been created, otherwise the RFC 6962 hasher, in
+// which case an error wrapping ErrHasherMismatch is returned if the log was created with a different hasher.
+func (s *Storage) IntegrateLeafHashes(ctx context.Context, fromSeq uint64, leafHashes [][]byte) (uint64, []byte, error) {
+	var h merkle.LogHasher = rfc6962.DefaultHasher
+	if a := s.appender.Load(); a != nil {
+		h = a.hasher
+	} else if err := s.checkDefaultHasher(); err != nil {
+		return 0, nil, err
+	}
+	return doIntegrate(ctx, h, fromSeq, leafHashes, s.logReader())
+}
+
+// SupportsCustomHasher returns true, as the storage builds its tree using the hasher configured via
+// tessera.AppendOptions.WithHasher. The hasher is recorded when the log is created.
+func (s *Storage) SupportsCustomHasher() bool {
+	return true
 }
 
-// doIntegrate handles integrating new leaf hashes into the log, and returns the new state.
-func doIntegrate(ctx context.Context, fromSeq uint64, leafHashes [][]byte, ls *logResourceStorage) (uint64, []byte, error) {
-	return otel.Trace2(ctx, "tessera.storage.posix.integrate", tracer, func(ctx context.Context, span trace.Span) (uint64, []byte, error) {
+// doIntegrate handles integrating new leaf hashes into the log using the provided hasher, and returns the new state.
+func doIntegrate(ctx context.Context, h merkle.LogHasher, fromSeq uint64, leafHashes [][]byte, ls *logResourceStorage) (uint64, []byte, error) {
+	return otel.Trace2(ctx, "tessera.storage.posix.integrate", ls.s.tracer(), func(ctx context.Context, span trace.Span) (uint64, []byte, error) {
 		getTiles := func(ctx context.Context, tileIDs []storage.TileID, treeSize uint64) ([]*api.HashTile, error) {
 			n, err := ls.readTiles(ctx, tileIDs, treeSize)
 			if err != nil {
@@ -415,32 +1550,107 @@ func doIntegrate(ctx context.Context, fromSeq uint64, leafHashes [][]byte, ls *l
 			return n, nil
 		}
 
-		newSize, newRoot, tiles, err := storage.Integrate(ctx, getTiles, fromSeq, leafHashes)
+		span.SetAttributes(fromSizeKey.Int64(otel.Clamp64(fromSeq)), numEntriesKey.Int(len(leafHashes)))
+		newSize, newRoot, tiles, err := storage.IntegrateWithHasher(ctx, h, getTiles, fromSeq, leafHashes)
 		if err != nil {
-			slog.ErrorContext(ctx, "Integrate", slog.Any("error", err))
+			ls.s.logger().ErrorContext(ctx, "Integrate", slog.Uint64("fromSeq", fromSeq), slog.Any("error", err))
 			return 0, nil, fmt.Errorf("error in Integrate: %v", err)
 		}
+		span.SetAttributes(treeSizeKey.Int64(otel.Clamp64(newSize)))
 		for k, v := range tiles {
 			if err := ls.storeTile(ctx, uint64(k.Level), k.Index, newSize, v); err != nil {
 				return 0, nil, fmt.Errorf("failed to set tile(%v): %v", k, err)
 			}
 		}
 
-		slog.DebugContext(ctx, "New tree", slog.Uint64("size", newSize), slog.String("hash", fmt.Sprintf("%x", newRoot)))
+		ls.s.logger().DebugContext(ctx, "New tree", slog.Uint64("size", newSize), slog.String("hash", fmt.Sprintf("%x", newRoot)))
 
 		return newSize, newRoot, nil
 	}, trace.WithAttributes(otel.PeriodicKey.Bool(true)))
 }
 
-func (lrs *logResourceStorage) readTiles(ctx context.Context, tileIDs []storage.TileID, treeSize uint64) ([]*api.HashTile, error) {
-	return otel.Trace(ctx, "tessera.storage.posix.readTiles", tracer, func(ctx context.Context, span trace.Span) ([]*api.HashTile, error) {
-		r := make([]*api.HashTile, 0, len(tileIDs))
-		for _, id := range tileIDs {
-			t, err := lrs.readTile(ctx, id.Level, id.Index, layout.PartialTileSize(id.Level, id.Index, treeSize))
+// TileID identifies a tile by its level and index.
+type TileID = storage.TileID
+
+// ReadTiles returns the parsed tiles with the given IDs, for a tree of the given size.
+//
+// Tiles are read concurrently, and returned in the same order as the provided IDs. Tiles which
+// do not exist are returned as nil.
+func (s *Storage) ReadTiles(ctx context.Context, ids []TileID, treeSize uint64) ([]*api.HashTile, error) {
+	return s.logReader().readTiles(ctx, ids, treeSize)
+}
+
+// ReadTileByID returns the parsed tile with the given ID, as it is in a tree of the given size.
+//
+// If the tile does not exist, nil is returned.
+func (s *Storage) ReadTileByID(ctx context.Context, id TileID, treeSize uint64) (*api.HashTile, error) {
+	return s.logReader().readTile(ctx, id.Level, id.Index, layout.PartialTileSize(id.Level, id.Index, treeSize))
+}
+
+// TileWithID is a tile of the tree, along with its ID, as yielded by Storage.IterateTiles.
+type TileWithID struct {
+	ID   TileID
+	Tile *api.HashTile
+}
+
+// IterateTiles returns an iterator over all of the tiles at the given level of the tree, in index order,
+// for the current tree size. This includes the partial tile on the right-hand edge of the level, if any.
+//
+// Tiles are read lazily as the iteration proceeds. Iteration stops after the first error is yielded,
+// including a tile which can't be read, or ctx becoming done between tiles.
+func (s *Storage) IterateTiles(ctx context.Context, level uint64) iter.Seq2[TileWithID, error] {
+	return func(yield func(TileWithID, error) bool) {
+		size, _, err := s.readTreeState(ctx)
+		if err != nil {
+			yield(TileWithID{}, fmt.Errorf("failed to read tree state: %v", err))
+			return
+		}
+		lrs := s.logReader()
+		n := size >> (level * layout.TileHeight)
+		for index := range (n + layout.TileWidth - 1) / layout.TileWidth {
+			if err := ctx.Err(); err != nil {
+				yield(TileWithID{}, err)
+				return
+			}
+			id := TileID{Level: level, Index: index}
+			t, err := lrs.readTile(ctx, level, index, layout.PartialTileSize(level, index, size))
+			if err == nil && t == nil {
+				err = os.ErrNotExist
+			}
 			if err != nil {
-				return nil, err
+				yield(TileWithID{}, fmt.Errorf("failed to read tile %v: %w", id, err))
+				return
+			}
+			if !yield(TileWithID{ID: id, Tile: t}, nil) {
+				return
 			}
-			r = append(r, t)
+		}
+	}
+}
+
+func (lrs *logResourceStorage) readTiles(ctx context.Context, tileIDs []storage.TileID, treeSize uint64) ([]*api.HashTile, error) {
+	return otel.Trace(ctx, "tessera.storage.posix.readTiles", lrs.s.tracer(), func(ctx context.Context, span trace.Span) ([]*api.HashTile, error) {
+		span.SetAttributes(numTilesKey.Int(len(tileIDs)))
+		r := make([]*api.HashTile, len(tileIDs))
+		eg, ctx := errgroup.WithContext(ctx)
+		eg.SetLimit(tileReadConcurrency)
+		for i, id := range tileIDs {
+			eg.Go(func() error {
+				// A transient failure to read a single tile is retried rather than failing the whole batch.
+				// Corrupt tiles are not retried, and missing tiles are not errors: readTile returns nil for
+				// a tile which hasn't been written yet, which the caller treats as an empty tile.
+				return lrs.s.retryContext(ctx, tileReadMaxAttempts, func() error {
+					t, err := lrs.readTile(ctx, id.Level, id.Index, layout.PartialTileSize(id.Level, id.Index, treeSize))
+					if err != nil {
+						return err
+					}
+					r[i] = t
+					return nil
+				})
+			})
+		}
+		if err := eg.Wait(); err != nil {
+			return nil, err
 		}
 		return r, nil
 	})
@@ -450,7 +1660,7 @@ func (lrs *logResourceStorage) readTiles(ctx context.Context, tileIDs []storage.
 // If no complete tile exists at that location, it will attempt to find a
 // partial tile for the given tree size at that location.
 func (lrs *logResourceStorage) readTile(ctx context.Context, level, index uint64, p uint8) (*api.HashTile, error) {
-	return otel.Trace(ctx, "tessera.storage.posix.readTile", tracer, func(ctx context.Context, span trace.Span) (*api.HashTile, error) {
+	return otel.Trace(ctx, "tessera.storage.posix.readTile", lrs.s.tracer(), func(ctx context.Context, span trace.Span) (*api.HashTile, error) {
 		now := time.Now()
 
 		t, err := lrs.ReadTile(ctx, level, index, p)
@@ -477,9 +1687,9 @@ func (lrs *logResourceStorage) readTile(ctx context.Context, level, index uint64
 // index parameters, partially populated (i.e. right-hand edge) tiles are
 // stored with a .xx suffix where xx is the number of "tile leaves" in hex.
 func (lrs *logResourceStorage) storeTile(ctx context.Context, level, index, logSize uint64, tile *api.HashTile) error {
-	return otel.TraceErr(ctx, "tessera.storage.posix.storeTile", tracer, func(ctx context.Context, span trace.Span) error {
+	return otel.TraceErr(ctx, "tessera.storage.posix.storeTile", lrs.s.tracer(), func(ctx context.Context, span trace.Span) error {
 		tileSize := uint64(len(tile.Nodes))
-		slog.DebugContext(ctx, "StoreTile", slog.Uint64("level", level), slog.String("index", fmt.Sprintf("%x", index)), slog.String("tilesize", fmt.Sprintf("%x", tileSize)))
+		lrs.s.logger().DebugContext(ctx, "StoreTile", slog.Uint64("level", level), slog.String("index", fmt.Sprintf("%x", index)), slog.String("tilesize", fmt.Sprintf("%x", tileSize)))
 		if tileSize == 0 || tileSize > layout.TileWidth {
 			return fmt.Errorf("tileSize %d must be > 0 and <= %d", tileSize, layout.TileWidth)
 		}
@@ -492,34 +1702,49 @@ func (lrs *logResourceStorage) storeTile(ctx context.Context, level, index, logS
 	})
 }
 
+// writeTile stores the provided serialised tile at the location implied by the level, index, and partial size.
+//
+// Writes are idempotent: if a tile with identical content already exists at that location (e.g. because a
+// previous integration attempt failed after writing some tiles but before updating the tree state) then
+// it is left untouched. A tile with different content is overwritten since, in that case, the existing
+// tile was written by an integration attempt which was never committed to the tree state.
+//
+// Tiles are normally written exactly once, so the existing tile is only read when it's found to exist.
 func (lrs *logResourceStorage) writeTile(ctx context.Context, level, index uint64, partial uint8, t []byte) error {
-	return otel.TraceErr(ctx, "tessera.storage.posix.writeTile", tracer, func(ctx context.Context, span trace.Span) error {
+	return otel.TraceErr(ctx, "tessera.storage.posix.writeTile", lrs.s.tracer(), func(ctx context.Context, span trace.Span) error {
 		now := time.Now()
 
-		tPath := layout.TilePath(level, index, partial)
+		tPath := lrs.s.tilePath(level, index, partial)
 
-		if err := lrs.s.createOverwrite(tPath, t); err != nil {
-			return err
+		if err := lrs.s.createExclusive(tPath, t); err != nil {
+			if !errors.Is(err, os.ErrExist) {
+				return err
+			}
+			if existing, err := lrs.s.readAll(tPath); err == nil && bytes.Equal(existing, t) {
+				lrs.s.logger().DebugContext(ctx, "Tile already exists with identical content", slog.String("tpath", tPath))
+			} else if err := lrs.s.createOverwrite(tPath, t); err != nil {
+				return err
+			}
+		}
+		if lrs.s.tileCache != nil {
+			lrs.s.tileCache.evict(level, index, partial)
+		}
+		if lrs.s.cfg.TileChecksums {
+			if err := lrs.s.writeTileChecksum(tPath, t); err != nil {
+				return fmt.Errorf("failed to write tile checksum: %v", err)
+			}
 		}
 
 		if partial == 0 {
-			partials, err := filepath.Glob(fmt.Sprintf("%s.p/*", tPath))
+			fullPath := filepath.Join(lrs.s.cfg.Path, tPath)
+			partials, err := filepath.Glob(fmt.Sprintf("%s.p/*", fullPath))
 			if err != nil {
 				return fmt.Errorf("failed to list partial tiles for clean up; %w", err)
 			}
 			// Clean up old partial tiles by symlinking them to the new full tile.
 			for _, p := range partials {
-				slog.DebugContext(ctx, "relink partial", slog.String("p", p), slog.String("tpath", tPath))
-				// We have to do a little dance here to get POSIX atomicity:
-				// 1. Create a new temporary symlink to the full tile
-				// 2. Rename the temporary symlink over the top of the old partial tile
-				tmp := fmt.Sprintf("%s.link", tPath)
-				_ = os.Remove(tmp)
-				if err := os.Symlink(tPath, tmp); err != nil {
-					return fmt.Errorf("failed to create temp link to full tile: %w", err)
-				}
-				if err := os.Rename(tmp, p); err != nil {
-					return fmt.Errorf("failed to rename temp link over partial tile: %w", err)
+				if err := lrs.s.relinkPartialTile(ctx, p, fullPath); err != nil {
+					return err
 				}
 			}
 		}
@@ -529,42 +1754,91 @@ func (lrs *logResourceStorage) writeTile(ctx context.Context, level, index uint6
 	})
 }
 
+// relinkPartialTile atomically replaces the partial tile at path p with a symlink to the full tile at path
+// full, so that readers of the partial tile continue to see a valid tile.
+//
+// Checksum sidecars of partial tiles are relinked to the sidecar of the full tile so that they continue to
+// match the content their tiles now link to. Temporary links are left untouched.
+func (s *Storage) relinkPartialTile(ctx context.Context, p, full string) error {
+	if strings.HasSuffix(p, linkSuffix) {
+		return nil
+	}
+	if isChecksumPath(p) {
+		full += checksumSuffix
+	}
+	// Partial tiles live in a directory alongside the full tile, so link relative to that.
+	target := filepath.Join("..", filepath.Base(full))
+	s.logger().DebugContext(ctx, "relink partial", slog.String("p", p), slog.String("tpath", full))
+	// We have to do a little dance here to get POSIX atomicity:
+	// 1. Create a new temporary symlink to the full tile
+	// 2. Rename the temporary symlink over the top of the old partial tile
+	tmp := p + linkSuffix
+	_ = os.Remove(tmp)
+	if err := os.Symlink(target, tmp); err != nil {
+		return fmt.Errorf("failed to create temp link to full tile: %w", err)
+	}
+	if err := os.Rename(tmp, p); err != nil {
+		return fmt.Errorf("failed to rename temp link over partial tile: %w", err)
+	}
+	return nil
+}
+
 // writeBundle takes care of writing out the serialised entry bundle file.
 func (lrs *logResourceStorage) writeBundle(ctx context.Context, index uint64, partial uint8, bundle []byte) error {
-	return otel.TraceErr(ctx, "tessera.storage.posix.writeBundle", tracer, func(ctx context.Context, span trace.Span) error {
-		bf := lrs.entriesPath(index, partial)
-		if err := lrs.s.createOverwrite(bf, bundle); err != nil {
-			if !errors.Is(err, os.ErrExist) {
+	return lrs.writeBundleBatched(ctx, index, partial, bundle, nil)
+}
+
+// writeBundleBatched is like writeBundle but, if batch is non-nil, the bundle is not synced as it's written,
+// and is instead added to batch, which the caller must sync.
+func (lrs *logResourceStorage) writeBundleBatched(ctx context.Context, index uint64, partial uint8, bundle []byte, batch *syncBatch) error {
+	return otel.TraceErr(ctx, "tessera.storage.posix.writeBundle", lrs.s.tracer(), func(ctx context.Context, span trace.Span) error {
+		c := lrs.s.cfg.BundleCompression
+		bf := lrs.bundlePath(index, partial) + c.suffix()
+		bundle, err := c.compress(bundle)
+		if err != nil {
+			return fmt.Errorf("failed to compress entry bundle: %v", err)
+		}
+		if batch == nil {
+			if err := lrs.s.createOverwrite(bf, bundle); err != nil {
+				if !errors.Is(err, os.ErrExist) {
+					return err
+				}
+			}
+		} else {
+			p := filepath.Join(lrs.s.cfg.Path, bf)
+			if err := lrs.s.retry(func() error { return overwrite(p, bundle, false) }); err != nil {
 				return err
 			}
+			batch.add(p)
 		}
+		bundleWriteCount.Add(ctx, 1, metric.WithAttributes(partialKey.Bool(partial > 0)))
 		return nil
 	})
 }
 
 // initialise ensures that the storage location is valid by loading the checkpoint from this location, or
 // creating a zero-sized one if it doesn't already exist.
-func (a *appender) initialise(ctx context.Context) error {
+func (a *appender) initialise(ctx context.Context) (errR error) {
 	// Idempotent: If folder exists, nothing happens.
-	if err := mkdirAll(filepath.Join(a.s.cfg.Path, stateDir), dirPerm); err != nil {
+	if err := a.s.retry(func() error { return mkdirAll(filepath.Join(a.s.cfg.Path, a.s.stateDir()), dirPerm) }); err != nil {
 		return fmt.Errorf("failed to create log directory: %q", err)
 	}
-	// Double locking:
-	// - The mutex `Lock()` ensures that multiple concurrent calls to this function within a task are serialised.
-	// - The POSIX `lockFile()` ensures that distinct tasks are serialised.
-	a.s.mu.Lock()
-	unlock, err := a.s.lockFile(ctx, treeStateLock)
+	unlock, err := a.s.lockTreeState(ctx)
 	if err != nil {
-		panic(err)
+		return err
 	}
 	defer func() {
-		if err := unlock(); err != nil {
-			panic(err)
+		if err := unlock(); err != nil && errR == nil {
+			errR = err
 		}
-		a.s.mu.Unlock()
 	}()
 
-	if err := a.s.ensureVersion(compatibilityVersion); err != nil {
+	if a.s.cfg.AutoUpgrade {
+		if err := a.s.autoUpgrade(ctx); err != nil {
+			return err
+		}
+	}
+	if err := a.s.ensureVersion(compatibilityVersion, a.hasherName); err != nil {
 		return err
 	}
 	curSize, _, err := a.s.readTreeState(ctx)
@@ -573,8 +1847,11 @@ func (a *appender) initialise(ctx context.Context) error {
 			return fmt.Errorf("failed to load checkpoint for log: %v", err)
 		}
 		// Create the directory structure and write out an empty checkpoint
-		slog.InfoContext(ctx, "Initializing directory for POSIX log (this should only happen ONCE per log!)", slog.String("path", a.s.cfg.Path))
-		if err := a.s.writeTreeState(ctx, 0, rfc6962.DefaultHasher.EmptyRoot()); err != nil {
+		a.s.logger().InfoContext(ctx, "Initializing directory for POSIX log (this should only happen ONCE per log!)", slog.String("path", a.s.cfg.Path))
+		if err := a.s.writeMetadata(); err != nil {
+			return err
+		}
+		if err := a.s.writeTreeState(ctx, 0, a.hasher.EmptyRoot()); err != nil {
 			return fmt.Errorf("failed to write tree-state checkpoint: %v", err)
 		}
 		if a.newCP != nil {
@@ -582,59 +1859,308 @@ func (a *appender) initialise(ctx context.Context) error {
 				return fmt.Errorf("failed to publish checkpoint: %v", err)
 			}
 		}
-		return nil
+		curSize = 0
 	}
 	a.curSize = curSize
+	if a.s.cfg.Dedupe {
+		if a.leafIndex, err = a.openLeafIndex(ctx, curSize); err != nil {
+			return fmt.Errorf("failed to open leaf index: %v", err)
+		}
+	}
+
+	return a.reconcileCheckpoint(ctx, curSize)
+}
 
+// reconcileCheckpoint checks that the published checkpoint, if any, doesn't commit to a larger tree than the
+// stored tree state of the given size. If it does, the checkpoint is replaced with one for the stored tree state
+// if Config.RepublishCheckpointOnMismatch is set, otherwise an error wrapping ErrCheckpointAhead is returned.
+//
+// Appenders which don't publish checkpoints, as for mirrored logs, are not checked.
+//
+// The caller must hold the tree state lock.
+func (a *appender) reconcileCheckpoint(ctx context.Context, size uint64) error {
+	if a.newCP == nil {
+		return nil
+	}
+	cpSize, err := a.publishedSize(ctx)
+	if err != nil {
+		return err
+	}
+	if cpSize <= size {
+		return nil
+	}
+	if !a.s.cfg.RepublishCheckpointOnMismatch {
+		return fmt.Errorf("%w: checkpoint has size %d, but tree state has size %d", ErrCheckpointAhead, cpSize, size)
+	}
+	a.s.logger().WarnContext(ctx, "Published checkpoint is ahead of tree state, republishing", slog.Uint64("checkpointSize", cpSize), slog.Uint64("treeSize", size))
+	if err := a.publishCheckpoint(ctx, 0, 0); err != nil {
+		return fmt.Errorf("failed to republish checkpoint: %v", err)
+	}
 	return nil
 }
 
 type treeState struct {
 	Size uint64 `json:"size"`
 	Root []byte `json:"root"`
+	// Integrated is the time, according to Config.Clock, at which the tree state was written, in nanoseconds
+	// since the Unix epoch. It's zero for tree states written by older versions.
+	Integrated int64 `json:"integrated,omitempty"`
+}
+
+// publishState is the content of the publishStateFile.
+type publishState struct {
+	// Published is the time, according to Config.Clock, at which the checkpoint was published, in nanoseconds
+	// since the Unix epoch.
+	Published int64 `json:"published"`
 }
 
-// ensureVersion will fail if the compatibility version stored in the state directory
-// is not the expected version. If no file exists, then it is created with the expected version.
-func (s *Storage) ensureVersion(version uint16) error {
-	versionFile := filepath.Join(stateDir, "version")
+// ensureVersion will fail if the compatibility version stored in the state directory is not the expected
+// version, or if the settings recorded for the log don't match those it's configured with, given the name of
+// the hasher in use. If no version file exists, then it is created with the expected version, along with the
+// settings record if the log is being created.
+func (s *Storage) ensureVersion(version uint16, hasherName string) error {
+	want, err := s.settings(hasherName)
+	if err != nil {
+		return err
+	}
+	versionFile := filepath.Join(s.stateDir(), "version")
 
 	if _, err := s.stat(versionFile); errors.Is(err, os.ErrNotExist) {
-		slog.DebugContext(context.Background(), "No version file exists, creating")
+		// Logs which predate the version file have a tree state, and use the default settings.
+		if _, err := s.stat(filepath.Join(s.stateDir(), treeStateFile)); errors.Is(err, os.ErrNotExist) {
+			if err := s.writeSettings(want); err != nil {
+				return err
+			}
+		} else if err != nil {
+			return fmt.Errorf("stat(%s): %v", treeStateFile, err)
+		}
+		s.logger().DebugContext(context.Background(), "No version file exists, creating")
 		data := fmt.Appendf(nil, "%d", version)
 		if err := s.createExclusive(versionFile, data); err != nil {
 			return fmt.Errorf("failed to create version file: %v", err)
 		}
-		return nil
 	} else if err != nil {
 		return fmt.Errorf("stat(%s): %v", versionFile, err)
 	}
+	if err := s.checkVersion(version); err != nil {
+		return err
+	}
+	return s.checkSettings(want)
+}
+
+// checkVersion will fail if the compatibility version stored in the state directory is missing or
+// is not the expected version.
+func (s *Storage) checkVersion(version uint16) error {
+	got, err := s.readVersion()
+	if err != nil {
+		return err
+	}
+	if got != version {
+		return fmt.Errorf("wanted version %d but found %d", version, got)
+	}
+	return nil
+}
 
-	data, err := s.readAll(versionFile)
+// readVersion returns the compatibility version stored in the state directory.
+func (s *Storage) readVersion() (uint16, error) {
+	data, err := s.readAll(filepath.Join(s.stateDir(), "version"))
 	if err != nil {
-		return fmt.Errorf("failed to read version file: %v", err)
+		return 0, fmt.Errorf("failed to read version file: %w", err)
 	}
 	parsed, err := strconv.ParseUint(string(data), 10, 16)
 	if err != nil {
-		return fmt.Errorf("failed to parse version: %v", err)
+		return 0, fmt.Errorf("failed to parse version: %v", err)
+	}
+	return uint16(parsed), nil
+}
+
+// ErrGeometryMismatch is returned when the geometry recorded in the state directory of an existing log
+// differs from the geometry this binary is configured to use.
+var ErrGeometryMismatch = errors.New("log geometry mismatch")
+
+// ErrCheckpointAhead is returned when opening a log whose published checkpoint commits to a larger tree than
+// its stored tree state, and Config.RepublishCheckpointOnMismatch is not set.
+var ErrCheckpointAhead = errors.New("published checkpoint is ahead of tree state")
+
+// ErrHasherMismatch is returned when the hasher recorded in the state directory of an existing log differs
+// from the hasher this binary is configured to use.
+var ErrHasherMismatch = errors.New("log hasher mismatch")
+
+// settingsVersion is the version of the format of the settings record.
+const settingsVersion = 1
+
+// logSettings describes the settings, fixed when a log is created, which determine how its tiles and entry
+// bundles are stored.
+type logSettings struct {
+	// Version is the version of the format of this record.
+	Version           uint16            `json:"version"`
+	TileWidth         uint64            `json:"tileWidth"`
+	EntryBundleWidth  uint64            `json:"entryBundleWidth"`
+	Hasher            string            `json:"hasher"`
+	PathLayout        string            `json:"pathLayout"`
+	BundleCompression BundleCompression `json:"bundleCompression"`
+	BundleCodec       string            `json:"bundleCodec"`
+}
+
+// legacySettings are the settings of logs created before the settings record was introduced.
+var legacySettings = logSettings{
+	Version:           settingsVersion,
+	TileWidth:         layout.TileWidth,
+	EntryBundleWidth:  layout.EntryBundleWidth,
+	Hasher:            tessera.DefaultHasherName,
+	PathLayout:        "nested",
+	BundleCompression: BundleCompressionNone,
+	BundleCodec:       TLogTilesCodec{}.Name(),
+}
+
+// settings returns the settings the storage is configured with, given the name of the hasher in use.
+func (s *Storage) settings(hasherName string) (logSettings, error) {
+	if err := s.cfg.BundleCompression.validate(); err != nil {
+		return logSettings{}, err
+	}
+	return logSettings{
+		Version:           settingsVersion,
+		TileWidth:         layout.TileWidth,
+		EntryBundleWidth:  layout.EntryBundleWidth,
+		Hasher:            hasherName,
+		PathLayout:        s.pathLayoutName(),
+		BundleCompression: s.cfg.BundleCompression,
+		BundleCodec:       s.bundleCodec().Name(),
+	}, nil
+}
+
+// writeSettings creates the settings record for a new log.
+func (s *Storage) writeSettings(ls logSettings) error {
+	data, err := json.Marshal(ls)
+	if err != nil {
+		return fmt.Errorf("error in Marshal: %v", err)
+	}
+	// The record may already exist if a previous attempt to create the log failed part way through, in
+	// which case checkSettings will compare it with the configured settings.
+	if err := s.createExclusive(filepath.Join(s.stateDir(), settingsFile), data); err != nil && !errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("failed to create settings file: %v", err)
+	}
+	return nil
+}
+
+// readSettings returns the settings recorded for the log, or legacySettings if there is no record.
+func (s *Storage) readSettings() (logSettings, error) {
+	data, err := s.readAll(filepath.Join(s.stateDir(), settingsFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return legacySettings, nil
+	} else if err != nil {
+		return logSettings{}, fmt.Errorf("failed to read settings file: %v", err)
+	}
+	var ls logSettings
+	if err := json.Unmarshal(data, &ls); err != nil {
+		return logSettings{}, fmt.Errorf("failed to parse settings: %v", err)
+	}
+	if ls.Version != settingsVersion {
+		return logSettings{}, fmt.Errorf("unsupported settings version %d", ls.Version)
+	}
+	return ls, nil
+}
+
+// checkDefaultHasher will fail with ErrHasherMismatch if the log was created with a hasher other than the
+// default, for operations which can only build its tree with the default hasher.
+func (s *Storage) checkDefaultHasher() error {
+	ls, err := s.readSettings()
+	if err != nil {
+		return err
+	}
+	if ls.Hasher != tessera.DefaultHasherName {
+		return fmt.Errorf("%w: log was created with hasher %q, but only %q is supported", ErrHasherMismatch, ls.Hasher, tessera.DefaultHasherName)
+	}
+	return nil
+}
+
+// checkSettings will fail if the settings recorded for the log are not the expected settings. The error wraps
+// ErrGeometryMismatch or ErrHasherMismatch if the log's geometry or hasher differ.
+func (s *Storage) checkSettings(want logSettings) error {
+	got, err := s.readSettings()
+	if err != nil {
+		return err
 	}
-	if got, want := uint16(parsed), version; got != want {
-		return fmt.Errorf("wanted version %d but found %d", want, got)
+	switch {
+	case got.TileWidth != want.TileWidth || got.EntryBundleWidth != want.EntryBundleWidth:
+		return fmt.Errorf("%w: log was created with tile width %d and entry bundle width %d, but configured with %d and %d", ErrGeometryMismatch, got.TileWidth, got.EntryBundleWidth, want.TileWidth, want.EntryBundleWidth)
+	case got.Hasher != want.Hasher:
+		return fmt.Errorf("%w: log was created with hasher %q but configured with %q", ErrHasherMismatch, got.Hasher, want.Hasher)
+	case got.PathLayout != want.PathLayout:
+		return fmt.Errorf("log was created with the %s path layout, but the %s path layout was requested", got.PathLayout, want.PathLayout)
+	case got.BundleCompression != want.BundleCompression:
+		return fmt.Errorf("log entry bundles are stored with compression %q, but %q was requested", got.BundleCompression, want.BundleCompression)
+	case got.BundleCodec != want.BundleCodec:
+		return fmt.Errorf("log entry bundles are stored with codec %q, but %q was requested", got.BundleCodec, want.BundleCodec)
 	}
 	return nil
 }
 
+// pathLayoutName returns the name of the configured path layout, as recorded in the state directory.
+//
+// The default nested layout is named "nested", and nested layouts with a different number of digits per
+// directory name have that number appended, e.g. "nested-2".
+func (s *Storage) pathLayoutName() string {
+	switch d := s.shardDigits(); {
+	case s.cfg.FlatLayout:
+		return "flat"
+	case d != layout.DefaultShardDigits:
+		return fmt.Sprintf("nested-%d", d)
+	}
+	return "nested"
+}
+
+// shardDigits returns the number of decimal digits in each directory name of the log's paths.
+func (s *Storage) shardDigits() int {
+	if s.cfg.ShardDigits == 0 {
+		return layout.DefaultShardDigits
+	}
+	return s.cfg.ShardDigits
+}
+
+// tilePath returns the path of the tile with the given level, index, and partial width, in the log's layout.
+func (s *Storage) tilePath(level, index uint64, p uint8) string {
+	if s.cfg.FlatLayout {
+		return layout.FlatTilePath(level, index, p)
+	}
+	if d := s.shardDigits(); d != layout.DefaultShardDigits {
+		return layout.ShardedTilePath(d, level, index, p)
+	}
+	return layout.TilePath(level, index, p)
+}
+
+// bundlePath returns the path of the entry bundle with the given index and partial width, in the log's layout.
+func (lrs *logResourceStorage) bundlePath(index uint64, p uint8) string {
+	if lrs.s.cfg.FlatLayout {
+		return layout.FlatEntriesPath(index, p)
+	}
+	if d := lrs.s.shardDigits(); d != layout.DefaultShardDigits {
+		return layout.ShardedEntriesPath(d, index, p)
+	}
+	return lrs.entriesPath(index, p)
+}
+
 // writeTreeState stores the current tree size and root hash on disk.
 func (s *Storage) writeTreeState(ctx context.Context, size uint64, root []byte) error {
-	return otel.TraceErr(ctx, "tessera.storage.posix.writeTreeState", tracer, func(ctx context.Context, span trace.Span) error {
+	return otel.TraceErr(ctx, "tessera.storage.posix.writeTreeState", s.tracer(), func(ctx context.Context, span trace.Span) error {
 		now := time.Now()
 
-		raw, err := json.Marshal(treeState{Size: size, Root: root})
+		raw, err := json.Marshal(treeState{Size: size, Root: root, Integrated: s.clock().Now().UnixNano()})
 		if err != nil {
 			return fmt.Errorf("error in Marshal: %v", err)
 		}
 
-		if err := s.createOverwrite(filepath.Join(stateDir, treeStateFile), raw); err != nil {
+		if s.cfg.RetainPreviousTreeState {
+			prev, err := s.readAll(filepath.Join(s.stateDir(), treeStateFile))
+			if err == nil {
+				if err := s.createOverwrite(filepath.Join(s.stateDir(), prevTreeStateFile), prev); err != nil {
+					return fmt.Errorf("failed to retain previous tree state: %w", err)
+				}
+			} else if !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("failed to read previous tree state: %w", err)
+			}
+		}
+		if err := s.createOverwrite(filepath.Join(s.stateDir(), treeStateFile), raw); err != nil {
 			return fmt.Errorf("failed to create/overwrite private tree state file: %w", err)
 		}
 
@@ -643,37 +2169,84 @@ func (s *Storage) writeTreeState(ctx context.Context, size uint64, root []byte)
 	})
 }
 
+// notifyNewTree calls the configured NewTreeFunc, if any, with the newly integrated tree.
+func (s *Storage) notifyNewTree(ctx context.Context, size uint64, root []byte) {
+	if s.cfg.NewTreeFunc == nil {
+		return
+	}
+	if err := s.cfg.NewTreeFunc(size, root); err != nil {
+		s.logger().WarnContext(ctx, "NewTreeFunc failed", slog.Uint64("size", size), slog.Any("error", err))
+	}
+}
+
 // readTreeState reads and returns the currently stored tree state.
 func (s *Storage) readTreeState(ctx context.Context) (uint64, []byte, error) {
-	return otel.Trace2(ctx, "tessera.storage.posix.readTreeState", tracer, func(ctx context.Context, span trace.Span) (uint64, []byte, error) {
+	ts, err := s.readTreeStateRecord(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+	return ts.Size, ts.Root, nil
+}
+
+// readTreeStateRecord returns the whole of the currently stored tree state.
+func (s *Storage) readTreeStateRecord(ctx context.Context) (*treeState, error) {
+	return otel.Trace(ctx, "tessera.storage.posix.readTreeState", s.tracer(), func(ctx context.Context, span trace.Span) (*treeState, error) {
 		now := time.Now()
 
-		p := filepath.Join(s.cfg.Path, stateDir, treeStateFile)
-		raw, err := os.ReadFile(p)
+		p := filepath.Join(s.cfg.Path, s.stateDir(), treeStateFile)
+		raw, err := s.readFile(p)
 		if err != nil {
-			return 0, nil, fmt.Errorf("error in ReadFile(%q): %w", p, err)
+			return nil, fmt.Errorf("error in ReadFile(%q): %w", p, err)
 		}
 		ts := &treeState{}
 		if err := json.Unmarshal(raw, ts); err != nil {
-			return 0, nil, fmt.Errorf("error in Unmarshal: %v", err)
+			return nil, fmt.Errorf("error in Unmarshal: %v", err)
 		}
 
 		posixOpsHistogram.Record(ctx, time.Since(now).Milliseconds(), metric.WithAttributes(opNameKey.String("readTreeState")))
-		return ts.Size, ts.Root, nil
+		return ts, nil
 	})
 }
 
+// readPublishTime returns the time at which the published checkpoint was published, according to Config.Clock.
+//
+// If no publication time has been recorded, e.g. because the checkpoint was published by an older version, an
+// error wrapping os.ErrNotExist is returned.
+func (s *Storage) readPublishTime() (time.Time, error) {
+	raw, err := s.readAll(filepath.Join(s.stateDir(), publishStateFile))
+	if err != nil {
+		return time.Time{}, err
+	}
+	ps := &publishState{}
+	if err := json.Unmarshal(raw, ps); err != nil {
+		return time.Time{}, fmt.Errorf("error in Unmarshal: %v", err)
+	}
+	return time.Unix(0, ps.Published), nil
+}
+
+// writePublishTime records the time at which the published checkpoint was published.
+func (s *Storage) writePublishTime(t time.Time) error {
+	raw, err := json.Marshal(publishState{Published: t.UnixNano()})
+	if err != nil {
+		return fmt.Errorf("error in Marshal: %v", err)
+	}
+	return s.createOverwrite(filepath.Join(s.stateDir(), publishStateFile), raw)
+}
+
 // publishCheckpoint checks whether the currently published checkpoint (if any) is more than
 // minStaleness old, and, if so, creates and published a fresh checkpoint from the current
 // stored tree state.
+//
+// Since the tree state is only updated once the tiles and entry bundles it implies have been written,
+// the resources committed to by the checkpoint are already present when it's published.
 func (a *appender) publishCheckpoint(ctx context.Context, minStalenessActive, minStalenessRepub time.Duration) (errR error) {
-	return otel.TraceErr(ctx, "tessera.storage.posix.publishCheckpoint", tracer, func(ctx context.Context, span trace.Span) error {
+	return otel.TraceErr(ctx, "tessera.storage.posix.publishCheckpoint", a.s.tracer(), func(ctx context.Context, span trace.Span) error {
 		now := time.Now()
 		defer func() {
 			// Detect any errors and update metrics accordingly.
 			// Non-error cases are explicitly handled in the body of the function below.
 			if errR != nil {
-				publishCount.Add(ctx, 1, metric.WithAttributes(errorTypeKey.String("error")))
+				publishCount.Add(ctx, 1, metric.WithAttributes(outcomeTypeKey.String("error")))
 			}
 		}()
 
@@ -684,59 +2257,87 @@ func (a *appender) publishCheckpoint(ctx context.Context, minStalenessActive, mi
 		}
 		defer func() {
 			if err := unlock(); err != nil {
-				slog.WarnContext(ctx, "unlock", slog.String("publishlock", publishLock), slog.Any("error", err))
+				a.s.logger().WarnContext(ctx, "unlock", slog.String("publishlock", publishLock), slog.Any("error", err))
 			}
 		}()
 
 		var publishedAge time.Duration
 		var publishedSize uint64
 		cpExists := true
-		info, err := a.s.stat(layout.CheckpointPath)
-		if errors.Is(err, os.ErrNotExist) {
-			slog.DebugContext(ctx, "No checkpoint exists, publishing")
+		if _, err := a.s.stat(layout.CheckpointPath); errors.Is(err, os.ErrNotExist) {
+			a.s.logger().DebugContext(ctx, "No checkpoint exists, publishing")
 			cpExists = false
 		} else if err != nil {
 			return fmt.Errorf("stat(%s): %v", layout.CheckpointPath, err)
 		} else {
-			publishedAge = time.Since(info.ModTime())
+			publishedAt, err := a.s.readPublishTime()
+			if errors.Is(err, os.ErrNotExist) {
+				// We don't know when the checkpoint was published, so treat it as stale.
+				publishedAge = time.Duration(math.MaxInt64)
+			} else if err != nil {
+				return fmt.Errorf("readPublishTime: %v", err)
+			} else {
+				publishedAge = a.s.clock().Now().Sub(publishedAt)
+			}
 			if publishedAge < minStalenessActive {
-				slog.DebugContext(ctx, "publishCheckpoint: skipping publish because previous checkpoint too fresh", slog.Duration("age", publishedAge), slog.Duration("minstalenessactive", minStalenessActive))
-				publishCount.Add(ctx, 1, metric.WithAttributes(errorTypeKey.String("skipped")))
+				a.s.logger().DebugContext(ctx, "publishCheckpoint: skipping publish because previous checkpoint too fresh", slog.Duration("age", publishedAge), slog.Duration("minstalenessactive", minStalenessActive))
+				publishCount.Add(ctx, 1, metric.WithAttributes(outcomeTypeKey.String("skipped")))
 				return nil
 			}
 			publishedSize, err = a.publishedSize(ctx)
 			if err != nil {
-				slog.DebugContext(ctx, "publishCheckpoint: skipping publish because unable to determine previously published size", slog.Any("error", err))
+				a.s.logger().DebugContext(ctx, "publishCheckpoint: skipping publish because unable to determine previously published size", slog.Any("error", err))
 				return err
 			}
 		}
 
-		size, root, err := a.s.readTreeState(ctx)
+		ts, err := a.s.readTreeStateRecord(ctx)
 		if err != nil {
-			publishCount.Add(ctx, 1, metric.WithAttributes(errorTypeKey.String("error")))
 			return fmt.Errorf("readTreeState: %v", err)
 		}
+		size, root := ts.Size, ts.Root
 		if cpExists && size == publishedSize {
 			if minStalenessRepub == 0 || publishedAge < minStalenessRepub {
-				slog.DebugContext(ctx, "publishCheckpoint: skipping publish because tree hasn't grown and previous checkpoint is too recent")
-				publishCount.Add(ctx, 1, metric.WithAttributes(errorTypeKey.String("skipped_no_growth")))
+				a.s.logger().DebugContext(ctx, "publishCheckpoint: skipping publish because tree hasn't grown and previous checkpoint is too recent")
+				publishCount.Add(ctx, 1, metric.WithAttributes(outcomeTypeKey.String("skipped_no_growth")))
 				return nil
 			}
 		}
 
 		cpRaw, err := a.newCP(ctx, size, root)
 		if err != nil {
+			a.s.signer.failed(a.s.clock().Now(), err)
 			return fmt.Errorf("newCP: %v", err)
 		}
+		a.s.signer.succeeded()
 
 		if err := a.s.createOverwrite(layout.CheckpointPath, cpRaw); err != nil {
 			return fmt.Errorf("createOverwrite(%s): %v", layout.CheckpointPath, err)
 		}
+		// If this fails, the checkpoint will just be treated as stale next time.
+		if err := a.s.writePublishTime(a.s.clock().Now()); err != nil {
+			a.s.logger().WarnContext(ctx, "Failed to record checkpoint publication time", slog.Uint64("size", size), slog.Any("error", err))
+		}
+		if a.s.cfg.CheckpointHistory > 0 {
+			// The checkpoint is already published, so failing to archive it isn't fatal.
+			if err := a.s.archiveCheckpoint(size, cpRaw); err != nil {
+				a.s.logger().WarnContext(ctx, "Failed to archive checkpoint", slog.Uint64("size", size), slog.Any("error", err))
+			}
+		}
 
-		slog.DebugContext(ctx, "Published latest checkpoint", slog.Uint64("size", size), slog.String("root", fmt.Sprintf("%x", root)))
+		a.s.logger().DebugContext(ctx, "Published latest checkpoint", slog.Uint64("size", size), slog.String("root", fmt.Sprintf("%x", root)))
+		if f := a.s.cfg.CheckpointPublishedFunc; f != nil {
+			if err := f(ctx, cpRaw); err != nil {
+				a.s.logger().WarnContext(ctx, "CheckpointPublishedFunc failed", slog.Uint64("size", size), slog.Any("error", err))
+			}
+		}
+		// Republishing a checkpoint for a tree which hasn't grown doesn't tell us anything about the lag.
+		if (!cpExists || size > publishedSize) && ts.Integrated != 0 {
+			publishLagHistogram.Record(ctx, a.s.clock().Now().Sub(time.Unix(0, ts.Integrated)).Milliseconds())
+		}
 
 		posixOpsHistogram.Record(ctx, time.Since(now).Milliseconds(), metric.WithAttributes(opNameKey.String("publishCheckpoint")))
-		publishCount.Add(ctx, 1)
+		publishCount.Add(ctx, 1, metric.WithAttributes(outcomeTypeKey.String("success")))
 
 		return nil
 	})
@@ -777,8 +2378,11 @@ func (a *appender) garbageCollectorJob(ctx context.Context, i time.Duration) {
 			return
 		case <-t.C:
 		}
+		if !a.s.IsLeader() {
+			continue
+		}
 
-		if err := otel.TraceErr(ctx, "tessera.storage.posix.garbageCollectJob", tracer, func(ctx context.Context, span trace.Span) error {
+		if err := otel.TraceErr(ctx, "tessera.storage.posix.garbageCollectJob", a.s.tracer(), func(ctx context.Context, span trace.Span) error {
 			ctx, cancel := context.WithTimeout(ctx, defaultGCTimeout)
 			defer cancel()
 
@@ -790,9 +2394,9 @@ func (a *appender) garbageCollectorJob(ctx context.Context, i time.Duration) {
 				return err
 			}
 
-			return a.s.garbageCollect(ctx, pubSize, maxBundlesPerRun, a.logStorage.entriesPath)
+			return a.s.garbageCollect(ctx, pubSize, maxBundlesPerRun, a.logStorage.bundlePath)
 		}, trace.WithAttributes(otel.PeriodicKey.Bool(true))); err != nil {
-			slog.WarnContext(ctx, "GarbageCollect failed", slog.Any("error", err))
+			a.s.logger().WarnContext(ctx, "GarbageCollect failed", slog.Any("error", err))
 		}
 	}
 }
@@ -810,7 +2414,7 @@ func (s *Storage) writeGCState(size uint64) error {
 		return fmt.Errorf("error in Marshal: %v", err)
 	}
 
-	if err := s.createOverwrite(filepath.Join(stateDir, gcStateFile), raw); err != nil {
+	if err := s.createOverwrite(filepath.Join(s.stateDir(), gcStateFile), raw); err != nil {
 		return fmt.Errorf("failed to create/overwrite private GC state file: %w", err)
 	}
 	return nil
@@ -821,8 +2425,8 @@ func (s *Storage) writeGCState(size uint64) error {
 // If no GC state is stored, no GC run has completed successfully, so zero is returned to indicate
 // that GC should start from the beginning of the log.
 func (s *Storage) readGCState() (uint64, error) {
-	p := filepath.Join(s.cfg.Path, stateDir, gcStateFile)
-	raw, err := os.ReadFile(p)
+	p := filepath.Join(s.cfg.Path, s.stateDir(), gcStateFile)
+	raw, err := s.readFile(p)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
 			// gcState file doesn't exist yet - we've probably just not completed a GC run before so start from index 0.
@@ -845,7 +2449,7 @@ func (s *Storage) garbageCollect(ctx context.Context, treeSize uint64, maxBundle
 	}
 	defer func() {
 		if err := unlock(); err != nil {
-			slog.WarnContext(ctx, "unlock", slog.String("gcstatelock", gcStateLock), slog.Any("error", err))
+			s.logger().WarnContext(ctx, "unlock", slog.String("gcstatelock", gcStateLock), slog.Any("error", err))
 		}
 	}()
 
@@ -872,7 +2476,7 @@ func (s *Storage) garbageCollect(ctx context.Context, treeSize uint64, maxBundle
 		if err := s.removeDirAll(entriesPath(ri.Index, 0) + ".p/"); err != nil {
 			return err
 		}
-		if err := s.removeDirAll(layout.TilePath(0, ri.Index, 0) + ".p/"); err != nil {
+		if err := s.removeDirAll(s.tilePath(0, ri.Index, 0) + ".p/"); err != nil {
 			return err
 		}
 		fromSize += uint64(ri.N)
@@ -887,7 +2491,7 @@ func (s *Storage) garbageCollect(ctx context.Context, treeSize uint64, maxBundle
 			// Move our coordinates up to the parent
 			pL, pIdx = pL+1, pIdx>>layout.TileHeight
 			// GC any partial versions of the parent tile.
-			if err := s.removeDirAll(layout.TilePath(pL, pIdx, 0) + ".p/"); err != nil {
+			if err := s.removeDirAll(s.tilePath(pL, pIdx, 0) + ".p/"); err != nil {
 				return err
 			}
 
@@ -910,18 +2514,32 @@ func isLastLeafInParent(i uint64) bool {
 // It will error if a file already exists at the specified location, or it's unable to fully write the
 // data & close the file.
 func (s *Storage) createExclusive(p string, d []byte) error {
-	return createEx(filepath.Join(s.cfg.Path, p), d)
+	return s.retry(func() error {
+		return createEx(filepath.Join(s.cfg.Path, p), d, !s.cfg.DisableSyncWrites)
+	})
 }
 
 // createOverwrite atomically creates or overwrites a file at the given path with the provided data.
 func (s *Storage) createOverwrite(p string, d []byte) error {
-	return overwrite(filepath.Join(s.cfg.Path, p), d)
+	return s.retry(func() error {
+		return overwrite(filepath.Join(s.cfg.Path, p), d, !s.cfg.DisableSyncWrites)
+	})
 }
 
+// readAll returns the contents of the file at the given path, relative to the log root.
 func (s *Storage) readAll(p string) ([]byte, error) {
-	p = filepath.Join(s.cfg.Path, p)
-	return os.ReadFile(p)
+	return s.readFile(filepath.Join(s.cfg.Path, p))
+}
 
+// readFile returns the contents of the file at the given absolute path, retrying transient errors.
+func (s *Storage) readFile(p string) ([]byte, error) {
+	var r []byte
+	err := s.retry(func() error {
+		var err error
+		r, err = os.ReadFile(p)
+		return err
+	})
+	return r, err
 }
 
 // stat returns os.Stat info for the speficied file relative to the log root.
@@ -933,9 +2551,9 @@ func (s *Storage) stat(p string) (os.FileInfo, error) {
 // removeDirAll removes the named directory and anything it contains.
 // The provided path is interpreted relative to the log root.
 func (s *Storage) removeDirAll(p string) error {
-	return otel.TraceErr(context.Background(), "tessera.storage.posix.removeDirAll", tracer, func(ctx context.Context, span trace.Span) error {
+	return otel.TraceErr(context.Background(), "tessera.storage.posix.removeDirAll", s.tracer(), func(ctx context.Context, span trace.Span) error {
 		p = filepath.Join(s.cfg.Path, p)
-		slog.DebugContext(context.Background(), "rm", slog.String("p", p))
+		s.logger().DebugContext(context.Background(), "rm", slog.String("p", p))
 		if err := os.RemoveAll(p); err != nil && !errors.Is(err, os.ErrNotExist) {
 			return err
 		}
@@ -945,13 +2563,29 @@ func (s *Storage) removeDirAll(p string) error {
 
 // MigrationWriter creates a new POSIX storage for the MigrationTarget lifecycle mode.
 func (s *Storage) MigrationWriter(ctx context.Context, opts *tessera.MigrationOptions) (migrate.MigrationWriter, tessera.LogReader, error) {
+	// Migrated bundles arrive in the tlog-tiles format, whose entries can't in general be split apart to be
+	// re-encoded.
+	if _, ok := s.bundleCodec().(TLogTilesCodec); !ok {
+		return nil, nil, fmt.Errorf("migration is not supported with bundle codec %q", s.bundleCodec().Name())
+	}
 	r := &MigrationStorage{
 		s: s,
 		logStorage: &logResourceStorage{
 			entriesPath: opts.EntriesPath(),
 			s:           s,
 		},
-		bundleHasher: opts.LeafHasher(),
+		bundleHasher:     opts.LeafHasher(),
+		progress:         opts.ProgressCallback(),
+		expectedRoot:     opts.ExpectedRoot(),
+		verifyResume:     opts.ResumeVerification(),
+		maxBundles:       defaultMigrationMaxBundles,
+		fetchConcurrency: defaultMigrationFetchConcurrency,
+	}
+	if n := opts.MaxBundlesPerPass(); n > 0 {
+		r.maxBundles = n
+	}
+	if n := opts.FetchConcurrency(); n > 0 {
+		r.fetchConcurrency = n
 	}
 	if err := r.initialise(ctx); err != nil {
 		return nil, nil, err
@@ -964,7 +2598,15 @@ type MigrationStorage struct {
 	s            *Storage
 	logStorage   *logResourceStorage
 	bundleHasher func(entryBundle []byte) ([][]byte, error)
+	progress     func(current, target uint64)
+	expectedRoot []byte
+	verifyResume bool
 	curSize      uint64
+
+	// maxBundles is the maximum number of entry bundles to integrate in each pass.
+	maxBundles uint
+	// fetchConcurrency is the maximum number of entry bundles to read concurrently.
+	fetchConcurrency uint
 }
 
 var _ migrate.MigrationWriter = &MigrationStorage{}
@@ -979,58 +2621,103 @@ func (m *MigrationStorage) AwaitIntegration(ctx context.Context, sourceSize uint
 		case <-t.C:
 		}
 		if err := m.buildTree(ctx, sourceSize); err != nil {
-			slog.WarnContext(ctx, "buildTree", slog.Any("error", err))
+			if errors.Is(err, tessera.ErrRootMismatch) {
+				return nil, err
+			}
+			m.s.logger().WarnContext(ctx, "buildTree", slog.Any("error", err))
 		}
 		s, r, err := m.s.readTreeState(ctx)
 		if err != nil {
-			slog.WarnContext(ctx, "readTreeState", slog.Any("error", err))
+			m.s.logger().WarnContext(ctx, "readTreeState", slog.Any("error", err))
+		} else if m.progress != nil {
+			m.progress(s, sourceSize)
 		}
 		if s == sourceSize {
+			// The tree may have reached the source size in an earlier run, without the root being checked.
+			if m.expectedRoot != nil && !bytes.Equal(r, m.expectedRoot) {
+				return nil, fmt.Errorf("root %x at size %d, want %x: %w", r, s, m.expectedRoot, tessera.ErrRootMismatch)
+			}
 			return r, nil
 		}
 	}
 }
 
-func (m *MigrationStorage) initialise(ctx context.Context) error {
+func (m *MigrationStorage) initialise(ctx context.Context) (errR error) {
 	// Idempotent: If folder exists, nothing happens.
-	if err := mkdirAll(filepath.Join(m.s.cfg.Path, stateDir), dirPerm); err != nil {
+	if err := m.s.retry(func() error { return mkdirAll(filepath.Join(m.s.cfg.Path, m.s.stateDir()), dirPerm) }); err != nil {
 		return fmt.Errorf("failed to create log directory: %q", err)
 	}
-	// Double locking:
-	// - The mutex `Lock()` ensures that multiple concurrent calls to this function within a task are serialised.
-	// - The POSIX `lockFile()` ensures that distinct tasks are serialised.
-	m.s.mu.Lock()
-	unlock, err := m.s.lockFile(ctx, treeStateLock)
+	unlock, err := m.s.lockTreeState(ctx)
 	if err != nil {
-		panic(err)
+		return err
 	}
 	defer func() {
-		if err := unlock(); err != nil {
-			panic(err)
+		if err := unlock(); err != nil && errR == nil {
+			errR = err
 		}
-		m.s.mu.Unlock()
 	}()
 
-	if err := m.s.ensureVersion(compatibilityVersion); err != nil {
+	// Migration always builds the tree with the default hasher, so refuses logs created with another.
+	if err := m.s.ensureVersion(compatibilityVersion, tessera.DefaultHasherName); err != nil {
 		return err
 	}
-	curSize, _, err := m.s.readTreeState(ctx)
+	curSize, curRoot, err := m.s.readTreeState(ctx)
 	if err != nil {
 		if !errors.Is(err, os.ErrNotExist) {
 			return fmt.Errorf("failed to load checkpoint for log: %v", err)
 		}
 		// Create the directory structure and write out an empty checkpoint
-		slog.InfoContext(ctx, "Initializing directory for POSIX log (this should only happen ONCE per log!)", slog.String("path", m.s.cfg.Path))
+		m.s.logger().InfoContext(ctx, "Initializing directory for POSIX log (this should only happen ONCE per log!)", slog.String("path", m.s.cfg.Path))
 		if err := m.s.writeTreeState(ctx, 0, rfc6962.DefaultHasher.EmptyRoot()); err != nil {
 			return fmt.Errorf("failed to write tree-state checkpoint: %v", err)
 		}
 		return nil
 	}
+	if m.verifyResume && curSize > 0 {
+		if err := m.verifyTree(ctx, curSize, curRoot); err != nil {
+			return err
+		}
+	}
 	m.curSize = curSize
 
 	return nil
 }
 
+// verifyTree checks that the tree state of the given size and root, as persisted by an earlier run of the
+// migration, is consistent with the locally stored tiles and entry bundles.
+//
+// Rather than rehashing every entry bundle, the root is recalculated from the tiles holding the compact range
+// for the tree, and the level 0 tile at the end of the tree is checked against the leaf hashes of the entry
+// bundle which was last integrated, since that's the one most likely to be inconsistent after an unclean
+// shutdown. The tree is checked against the source log's root once it reaches the source size.
+func (m *MigrationStorage) verifyTree(ctx context.Context, size uint64, root []byte) error {
+	m.s.logger().InfoContext(ctx, "Verifying existing tree before resuming migration", slog.Uint64("size", size))
+	// Integrating no leaves recalculates the root of the tree from the stored tiles.
+	_, r, err := doIntegrate(ctx, rfc6962.DefaultHasher, size, nil, m.logStorage)
+	if err != nil {
+		return fmt.Errorf("failed to recalculate root from tiles: %v", err)
+	}
+	if !bytes.Equal(r, root) {
+		return fmt.Errorf("tiles have root %x at size %d, but tree state has %x: %w", r, size, root, tessera.ErrRootMismatch)
+	}
+
+	// Entry bundles cover the same entries as level 0 tiles.
+	i := (size - 1) / layout.EntryBundleWidth
+	from := i * layout.EntryBundleWidth
+	lh, err := m.fetchLeafHashes(ctx, from, size, size)
+	if err != nil {
+		return fmt.Errorf("fetchLeafHashes(%d, %d): %w", from, size, err)
+	}
+	tiles, err := m.logStorage.readTiles(ctx, []TileID{{Level: 0, Index: i}}, size)
+	if err != nil {
+		return fmt.Errorf("failed to read level 0 tile %d: %v", i, err)
+	}
+	if tiles[0] == nil || !slices.EqualFunc(tiles[0].Nodes, lh, bytes.Equal) {
+		return fmt.Errorf("entry bundle %d doesn't match level 0 tile at size %d: %w", i, size, tessera.ErrRootMismatch)
+	}
+	return nil
+}
+
 func (m *MigrationStorage) SetEntryBundle(ctx context.Context, index uint64, partial uint8, bundle []byte) error {
 	return m.logStorage.writeBundle(ctx, index, partial, bundle)
 }
@@ -1040,20 +2727,15 @@ func (m *MigrationStorage) IntegratedSize(ctx context.Context) (uint64, error) {
 	return sz, err
 }
 
-func (m *MigrationStorage) buildTree(ctx context.Context, targetSize uint64) error {
-	// Double locking:
-	// - The mutex `Lock()` ensures that multiple concurrent calls to this function within a task are serialised.
-	// - The POSIX `lockFile()` ensures that distinct tasks are serialised.
-	m.s.mu.Lock()
-	unlock, err := m.s.lockFile(ctx, treeStateLock)
+func (m *MigrationStorage) buildTree(ctx context.Context, targetSize uint64) (errR error) {
+	unlock, err := m.s.lockTreeState(ctx)
 	if err != nil {
-		panic(err)
+		return err
 	}
 	defer func() {
-		if err := unlock(); err != nil {
-			panic(err)
+		if err := unlock(); err != nil && errR == nil {
+			errR = err
 		}
-		m.s.mu.Unlock()
 	}()
 
 	size, _, err := m.s.readTreeState(ctx)
@@ -1064,50 +2746,119 @@ func (m *MigrationStorage) buildTree(ctx context.Context, targetSize uint64) err
 		size = 0
 	}
 	m.curSize = size
-	slog.DebugContext(ctx, "Building", slog.Uint64("from", m.curSize))
+	m.s.logger().DebugContext(ctx, "Building", slog.Uint64("from", m.curSize))
 
 	lh, err := m.fetchLeafHashes(ctx, size, targetSize, targetSize)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
 			// We just don't have the bundle yet.
 			// Bail quietly and the caller can retry.
-			slog.DebugContext(ctx, "fetchLeafHashes", slog.Uint64("size", size), slog.Uint64("targetsize", targetSize), slog.Any("error", err))
+			m.s.logger().DebugContext(ctx, "fetchLeafHashes", slog.Uint64("size", size), slog.Uint64("targetsize", targetSize), slog.Any("error", err))
 			return nil
 		}
-		return fmt.Errorf("fetchLeafHashes(%d, %d): %v", size, targetSize, err)
+		return fmt.Errorf("fetchLeafHashes(%d, %d): %w", size, targetSize, err)
+	}
+
+	return m.integrate(ctx, size, lh, targetSize)
+}
+
+// SetLeafHashes integrates the provided leaf hashes, of the entries starting at index from, into the tree
+// without reading their entry bundles. This is intended for migrating from sources which publish their
+// leaf hashes, e.g. in level 0 tiles, so that they needn't be recalculated from the entry bundles.
+//
+// The entry bundles must still be copied separately via SetEntryBundle.
+//
+// Hashes for entries which are already integrated are ignored, but from must not be beyond the end of
+// the current tree.
+func (m *MigrationStorage) SetLeafHashes(ctx context.Context, from uint64, hashes [][]byte) (errR error) {
+	unlock, err := m.s.lockTreeState(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := unlock(); err != nil && errR == nil {
+			errR = err
+		}
+	}()
+
+	size, _, err := m.s.readTreeState(ctx)
+	if err != nil {
+		return err
+	}
+	m.curSize = size
+	if from > size {
+		return fmt.Errorf("leaf hashes start at %d, beyond the end of the tree at %d", from, size)
+	}
+	if end := from + uint64(len(hashes)); end <= size {
+		return nil
 	}
+	// The expected root can only be checked once the migration is complete, which is left to AwaitIntegration.
+	return m.integrate(ctx, size, hashes[size-from:], 0)
+}
 
-	newSize, newRoot, err := doIntegrate(ctx, size, lh, m.logStorage)
+// integrate adds the provided leaf hashes to the tree of the given size, and stores the new tree state.
+// If the resulting tree has the target size and an expected root was configured, it must match.
+//
+// The caller must hold the tree state lock.
+func (m *MigrationStorage) integrate(ctx context.Context, size uint64, lh [][]byte, targetSize uint64) error {
+	newSize, newRoot, err := doIntegrate(ctx, rfc6962.DefaultHasher, size, lh, m.logStorage)
 	if err != nil {
 		return fmt.Errorf("doIntegrate(%d, ...): %v", size, err)
 	}
+	if newSize == targetSize && m.expectedRoot != nil && !bytes.Equal(newRoot, m.expectedRoot) {
+		return fmt.Errorf("root %x at size %d, want %x: %w", newRoot, newSize, m.expectedRoot, tessera.ErrRootMismatch)
+	}
 	if err := m.s.writeTreeState(ctx, newSize, newRoot); err != nil {
 		return fmt.Errorf("failed to write new tree state: %v", err)
 	}
+	m.s.notifyNewTree(ctx, newSize, newRoot)
 
 	return nil
 }
 
 func (m *MigrationStorage) fetchLeafHashes(ctx context.Context, from, to, sourceSize uint64) ([][]byte, error) {
-	const maxBundles = 300
-
-	lh := make([][]byte, 0, maxBundles)
-	n := 0
+	ranges := make([]layout.RangeInfo, 0, m.maxBundles)
 	for ri := range layout.Range(from, to, sourceSize) {
-		b, err := m.logStorage.ReadEntryBundle(ctx, ri.Index, ri.Partial)
-		if err != nil {
-			return nil, fmt.Errorf("ReadEntryBundle(%d.%d): %w", ri.Index, ri.Partial, err)
-		}
-
-		bh, err := m.bundleHasher(b)
-		if err != nil {
-			return nil, fmt.Errorf("bundleHasherFunc for bundle index %d: %v", ri.Index, err)
-		}
-		lh = append(lh, bh[ri.First:ri.First+ri.N]...)
-		n++
-		if n >= maxBundles {
+		ranges = append(ranges, ri)
+		if uint(len(ranges)) >= m.maxBundles {
 			break
 		}
 	}
+
+	// Bundles may be fetched in any order, so collect the leaf hashes from each bundle separately
+	// and assemble them in range order once they've all been fetched.
+	bundleHashes := make([][][]byte, len(ranges))
+	// Once one fetch fails, the context passed to the others is cancelled and no more are started.
+	eg, ctx := errgroup.WithContext(ctx)
+	eg.SetLimit(int(m.fetchConcurrency))
+	for i, ri := range ranges {
+		eg.Go(func() error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			b, err := m.logStorage.ReadEntryBundle(ctx, ri.Index, ri.Partial)
+			if err != nil {
+				return fmt.Errorf("ReadEntryBundle(%d.%d): %w", ri.Index, ri.Partial, err)
+			}
+
+			bh, err := m.bundleHasher(b)
+			if err != nil {
+				return fmt.Errorf("bundleHasherFunc for bundle index %d: %w", ri.Index, err)
+			}
+			if want := int(ri.First + ri.N); len(bh) < want {
+				return fmt.Errorf("bundle index %d has %d entries, want at least %d: %w", ri.Index, len(bh), want, api.ErrBundleTruncated)
+			}
+			bundleHashes[i] = bh[ri.First : ri.First+ri.N]
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+
+	lh := make([][]byte, 0, len(ranges)*layout.EntryBundleWidth)
+	for _, bh := range bundleHashes {
+		lh = append(lh, bh...)
+	}
 	return lh, nil
 }
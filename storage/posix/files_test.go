@@ -17,20 +17,30 @@ package posix
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
+	"math/rand/v2"
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/transparency-dev/formats/log"
+	"github.com/transparency-dev/merkle/compact"
 	"github.com/transparency-dev/merkle/rfc6962"
 	"github.com/transparency-dev/tessera"
 	"github.com/transparency-dev/tessera/api"
 	"github.com/transparency-dev/tessera/api/layout"
 	"github.com/transparency-dev/tessera/fsck"
+	"github.com/transparency-dev/tessera/internal/parse"
 	"golang.org/x/mod/sumdb/note"
 )
 
@@ -311,10 +321,14 @@ func TestPublishTree(t *testing.T) {
 			ctx, cancel := context.WithCancel(t.Context())
 			defer cancel()
 
+			clk := newFakeClock()
+			// Start the clock well away from the wall clock, so that publication can't depend on file times.
+			clk.Advance(-24 * time.Hour)
 			s := &Storage{
 				cfg: Config{
 					HTTPClient: http.DefaultClient,
 					Path:       t.TempDir(),
+					Clock:      clk,
 				},
 			}
 			sk, _ := mustGenerateKeys(t)
@@ -332,9 +346,11 @@ func TestPublishTree(t *testing.T) {
 				t.Fatalf("Appender: %v", err)
 			}
 
-			// Add time as an extension line on the checkpoint so we can easily tell when it's been updated.
+			// Add a counter as an extension line on the checkpoint so we can easily tell when it's been updated.
+			var n int
 			appender.newCP = func(_ context.Context, size uint64, hash []byte) ([]byte, error) {
-				return fmt.Appendf(nil, "origin\n%d\n%x\n%d\n,", size, hash, time.Now().Unix()), nil
+				n++
+				return fmt.Appendf(nil, "origin\n%d\n%x\n%d\n,", size, hash, n), nil
 			}
 
 			if err := appender.publishCheckpoint(ctx, test.publishInterval, test.republishInterval); err != nil {
@@ -356,7 +372,7 @@ func TestPublishTree(t *testing.T) {
 			}
 
 			for _, d := range test.attempts {
-				time.Sleep(d)
+				clk.Advance(d)
 				if err := appender.publishCheckpoint(ctx, test.publishInterval, test.republishInterval); err != nil {
 					t.Fatalf("publishTree: %v", err)
 				}
@@ -376,6 +392,128 @@ func TestPublishTree(t *testing.T) {
 	}
 }
 
+func TestSignerOutage(t *testing.T) {
+	for _, test := range []struct {
+		name            string
+		maxSignerOutage time.Duration
+		wantAddErr      bool
+	}{
+		{
+			name:       "keep integrating",
+			wantAddErr: false,
+		}, {
+			name:            "stop integrating",
+			maxSignerOutage: time.Hour,
+			wantAddErr:      true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := t.Context()
+			clk := newFakeClock()
+			s := &Storage{
+				cfg: Config{
+					HTTPClient:      http.DefaultClient,
+					Path:            t.TempDir(),
+					MaxSignerOutage: test.maxSignerOutage,
+					Clock:           clk,
+				},
+			}
+			sk, _ := mustGenerateKeys(t)
+			opts := tessera.NewAppendOptions().
+				WithCheckpointInterval(10*time.Minute).
+				WithBatching(1, minCheckpointInterval).
+				WithCheckpointSigner(sk)
+			logStorage := &logResourceStorage{
+				s:           s,
+				entriesPath: opts.EntriesPath(),
+			}
+			appender, _, err := s.newAppender(ctx, logStorage, opts)
+			if err != nil {
+				t.Fatalf("Appender: %v", err)
+			}
+			if st := s.Stats(); !st.SignerHealthy {
+				t.Fatalf("Stats() reports unhealthy signer before any failure: %+v", st)
+			}
+			// Grow the tree so that there's something new to publish below.
+			if _, err := appender.Add(ctx, tessera.NewEntry([]byte("first")))(); err != nil {
+				t.Fatalf("Add: %v", err)
+			}
+
+			// Ensure the fake clock is later than the initial checkpoint's modification time.
+			clk.Advance(time.Minute)
+			signerErr := errors.New("signer unavailable")
+			appender.newCP = func(context.Context, uint64, []byte) ([]byte, error) {
+				return nil, signerErr
+			}
+			if err := appender.publishCheckpoint(ctx, 0, 0); err == nil {
+				t.Fatal("publishCheckpoint succeeded with broken signer")
+			}
+			st := s.Stats()
+			if st.SignerHealthy || st.SignerFailingSince.IsZero() || !errors.Is(st.SignerLastError, signerErr) {
+				t.Fatalf("Stats() doesn't report signer failure: %+v", st)
+			}
+			if !st.SignerFailingSince.Equal(clk.Now()) {
+				t.Fatalf("Stats() reports signer failing since %v, want %v", st.SignerFailingSince, clk.Now())
+			}
+
+			// The outage is measured using the storage's clock, rather than the system clock.
+			if _, err := appender.Add(ctx, tessera.NewEntry([]byte("within outage")))(); err != nil {
+				t.Fatalf("Add before MaxSignerOutage has elapsed: %v", err)
+			}
+			clk.Advance(2 * time.Hour)
+			_, err = appender.Add(ctx, tessera.NewEntry([]byte("hello")))()
+			if gotErr := err != nil; gotErr != test.wantAddErr {
+				t.Fatalf("Add: got err %v, want err %t", err, test.wantAddErr)
+			}
+			if test.wantAddErr && !errors.Is(err, tessera.ErrPushback) {
+				t.Fatalf("Add: got err %v, want %v", err, tessera.ErrPushback)
+			}
+		})
+	}
+}
+
+func TestSequenceBatchLockFailure(t *testing.T) {
+	ctx := t.Context()
+	s := &Storage{
+		cfg: Config{
+			HTTPClient: http.DefaultClient,
+			Path:       t.TempDir(),
+		},
+	}
+	sk, _ := mustGenerateKeys(t)
+	opts := tessera.NewAppendOptions().
+		WithCheckpointInterval(10*time.Minute).
+		WithBatching(1, minCheckpointInterval).
+		WithCheckpointSigner(sk)
+	logStorage := &logResourceStorage{
+		s:           s,
+		entriesPath: opts.EntriesPath(),
+	}
+	appender, _, err := s.newAppender(ctx, logStorage, opts)
+	if err != nil {
+		t.Fatalf("Appender: %v", err)
+	}
+
+	// Removing the state directory means the lock file can no longer be created.
+	if err := os.RemoveAll(filepath.Join(s.cfg.Path, defaultStateDir)); err != nil {
+		t.Fatalf("RemoveAll: %v", err)
+	}
+	if _, err := appender.Add(ctx, tessera.NewEntry([]byte("hello")))(); err == nil {
+		t.Fatal("Add succeeded, want error")
+	}
+
+	// The storage should still be usable once the problem is resolved.
+	if err := mkdirAll(filepath.Join(s.cfg.Path, defaultStateDir), dirPerm); err != nil {
+		t.Fatalf("mkdirAll: %v", err)
+	}
+	if err := s.writeTreeState(ctx, 0, rfc6962.DefaultHasher.EmptyRoot()); err != nil {
+		t.Fatalf("writeTreeState: %v", err)
+	}
+	if _, err := appender.Add(ctx, tessera.NewEntry([]byte("hello")))(); err != nil {
+		t.Fatalf("Add after recovery: %v", err)
+	}
+}
+
 func findAllPartialDirs(t *testing.T, root string) (map[string]struct{}, error) {
 	t.Helper()
 	if !strings.HasSuffix(root, "/") {
@@ -439,3 +577,2011 @@ func defaultMerkleLeafHasher(bundle []byte) ([][]byte, error) {
 	}
 	return r, nil
 }
+
+func TestFlush(t *testing.T) {
+	ctx := t.Context()
+	s := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: t.TempDir()}}
+	if err := s.Flush(ctx); err == nil {
+		t.Fatal("Flush succeeded without an appender")
+	}
+
+	sk, _ := mustGenerateKeys(t)
+	// Use batching parameters which won't trigger a flush of their own during the test.
+	opts := tessera.NewAppendOptions().
+		WithCheckpointInterval(10*time.Minute).
+		WithBatching(1000, time.Hour).
+		WithCheckpointSigner(sk)
+	logStorage := &logResourceStorage{
+		s:           s,
+		entriesPath: opts.EntriesPath(),
+	}
+	appender, _, err := s.newAppender(ctx, logStorage, opts)
+	if err != nil {
+		t.Fatalf("Appender: %v", err)
+	}
+	const n = 10
+	for i := range n {
+		_ = appender.Add(ctx, tessera.NewEntry(fmt.Appendf(nil, "entry %d", i)))
+	}
+	if err := s.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	size, err := logStorage.IntegratedSize(ctx)
+	if err != nil {
+		t.Fatalf("IntegratedSize: %v", err)
+	}
+	if size != n {
+		t.Fatalf("IntegratedSize() = %d, want %d", size, n)
+	}
+}
+
+func TestAddBatch(t *testing.T) {
+	ctx := t.Context()
+	s := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: t.TempDir(), DisableAutoPublish: true}}
+	if _, err := s.AddBatch(ctx, []*tessera.Entry{tessera.NewEntry([]byte("early"))})[0](); err == nil {
+		t.Fatal("AddBatch succeeded without an appender")
+	}
+
+	sk, _ := mustGenerateKeys(t)
+	// Use a batch size smaller than the number of entries so that they're sequenced across several batches.
+	opts := tessera.NewAppendOptions().
+		WithCheckpointInterval(10*time.Minute).
+		WithBatching(7, time.Hour).
+		WithCheckpointSigner(sk)
+	logStorage := &logResourceStorage{
+		s:           s,
+		entriesPath: opts.EntriesPath(),
+	}
+	if _, _, err := s.newAppender(ctx, logStorage, opts); err != nil {
+		t.Fatalf("Appender: %v", err)
+	}
+	const n = 20
+	entries := make([]*tessera.Entry, 0, n)
+	for i := range n {
+		entries = append(entries, tessera.NewEntry(fmt.Appendf(nil, "entry %d", i)))
+	}
+	futures := s.AddBatch(ctx, entries)
+	if len(futures) != n {
+		t.Fatalf("AddBatch returned %d futures, want %d", len(futures), n)
+	}
+	if err := s.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	for i, f := range futures {
+		idx, err := f()
+		if err != nil {
+			t.Fatalf("Future %d: %v", i, err)
+		}
+		if idx.Index != uint64(i) {
+			t.Errorf("Entry %d was assigned index %d", i, idx.Index)
+		}
+		e, err := s.ReadEntry(ctx, idx.Index)
+		if err != nil {
+			t.Fatalf("ReadEntry(%d): %v", idx.Index, err)
+		}
+		if want := fmt.Sprintf("entry %d", i); string(e) != want {
+			t.Errorf("ReadEntry(%d) = %q, want %q", idx.Index, e, want)
+		}
+	}
+}
+
+func TestAddBatchDecorated(t *testing.T) {
+	ctx := t.Context()
+	s := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: t.TempDir(), DisableAutoPublish: true}}
+	sk, _ := mustGenerateKeys(t)
+	opts := tessera.NewAppendOptions().
+		WithCheckpointInterval(10*time.Minute).
+		WithBatching(100, time.Hour).
+		WithCheckpointSigner(sk).
+		WithEntryValidator(func(e *tessera.Entry) error {
+			if bytes.Equal(e.Data(), []byte("bad")) {
+				return errors.New("bad entry")
+			}
+			return nil
+		})
+	if _, _, _, err := tessera.NewAppender(ctx, s, opts); err != nil {
+		t.Fatalf("NewAppender: %v", err)
+	}
+
+	futures := s.AddBatch(ctx, []*tessera.Entry{tessera.NewEntry([]byte("good")), tessera.NewEntry([]byte("bad")), tessera.NewEntry([]byte("also good"))})
+	if err := s.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	for i, want := range []error{nil, tessera.ErrInvalidEntry, nil} {
+		if _, err := futures[i](); !errors.Is(err, want) {
+			t.Errorf("Future %d: got %v, want %v", i, err, want)
+		}
+	}
+	if size, _, err := s.TreeState(ctx); err != nil || size != 2 {
+		t.Errorf("TreeState = %d, %v, want size 2", size, err)
+	}
+}
+
+func TestAddSync(t *testing.T) {
+	ctx := t.Context()
+	s := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: t.TempDir(), DisableAutoPublish: true}}
+	if _, err := s.AddSync(ctx, tessera.NewEntry([]byte("early"))); err == nil {
+		t.Fatal("AddSync succeeded without an appender")
+	}
+
+	sk, _ := mustGenerateKeys(t)
+	// Use a short max age so that each entry is sequenced promptly.
+	opts := tessera.NewAppendOptions().
+		WithCheckpointInterval(10*time.Minute).
+		WithBatching(100, time.Millisecond).
+		WithCheckpointSigner(sk)
+	if _, _, err := s.newAppender(ctx, &logResourceStorage{s: s, entriesPath: opts.EntriesPath()}, opts); err != nil {
+		t.Fatalf("Appender: %v", err)
+	}
+	for i := range uint64(3) {
+		idx, err := s.AddSync(ctx, tessera.NewEntry(fmt.Appendf(nil, "entry %d", i)))
+		if err != nil {
+			t.Fatalf("AddSync: %v", err)
+		}
+		if idx != i {
+			t.Errorf("AddSync = %d, want %d", idx, i)
+		}
+	}
+}
+
+func TestAddSyncDecorated(t *testing.T) {
+	ctx := t.Context()
+	s := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: t.TempDir(), DisableAutoPublish: true}}
+	sk, _ := mustGenerateKeys(t)
+	opts := tessera.NewAppendOptions().
+		WithCheckpointInterval(10*time.Minute).
+		WithBatching(100, time.Millisecond).
+		WithCheckpointSigner(sk).
+		WithEntryValidator(func(e *tessera.Entry) error {
+			if bytes.Equal(e.Data(), []byte("bad")) {
+				return errors.New("bad entry")
+			}
+			return nil
+		})
+	if _, _, _, err := tessera.NewAppender(ctx, s, opts); err != nil {
+		t.Fatalf("NewAppender: %v", err)
+	}
+
+	if _, err := s.AddSync(ctx, tessera.NewEntry([]byte("bad"))); !errors.Is(err, tessera.ErrInvalidEntry) {
+		t.Errorf("AddSync(bad): got %v, want %v", err, tessera.ErrInvalidEntry)
+	}
+	if idx, err := s.AddSync(ctx, tessera.NewEntry([]byte("good"))); err != nil || idx != 0 {
+		t.Errorf("AddSync(good) = %d, %v, want 0, nil", idx, err)
+	}
+}
+
+// syncBuffer is a bytes.Buffer which is safe for concurrent use.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestLogger(t *testing.T) {
+	ctx := t.Context()
+	logs := &syncBuffer{}
+	logger := slog.New(slog.NewJSONHandler(logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	s := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: t.TempDir(), DisableAutoPublish: true, Logger: logger}}
+
+	sk, _ := mustGenerateKeys(t)
+	opts := tessera.NewAppendOptions().
+		WithCheckpointInterval(10*time.Minute).
+		WithBatching(100, time.Millisecond).
+		WithCheckpointSigner(sk)
+	if _, _, err := s.newAppender(ctx, &logResourceStorage{s: s, entriesPath: opts.EntriesPath()}, opts); err != nil {
+		t.Fatalf("Appender: %v", err)
+	}
+	if _, err := s.AddSync(ctx, tessera.NewEntry([]byte("entry"))); err != nil {
+		t.Fatalf("AddSync: %v", err)
+	}
+	// Fill the first tile, so that the partial tile written above is relinked to the full one.
+	entries := make([]*tessera.Entry, layout.TileWidth-1)
+	for i := range entries {
+		entries[i] = tessera.NewEntry(fmt.Appendf(nil, "entry %d", i))
+	}
+	s.AddBatch(ctx, entries)
+	if err := s.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	for _, want := range []string{`"msg":"Sequencing","fromSeq":0,"batch":1`, `"msg":"New tree","size":1`, `"msg":"relink partial"`} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("Logs do not contain %q:\n%s", want, logs)
+		}
+	}
+}
+
+func TestPublishedCheckpointResourcesExist(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	// exists returns whether the resource at p, or the full resource it's a partial version of, exists.
+	var s *Storage
+	exists := func(p string) bool {
+		if _, err := s.stat(p); err == nil {
+			return true
+		}
+		full, _, _ := strings.Cut(p, ".p/")
+		_, err := s.stat(full)
+		return err == nil
+	}
+	var mu sync.Mutex
+	var published int
+	var missing []string
+	s = &Storage{cfg: Config{
+		HTTPClient: http.DefaultClient,
+		Path:       t.TempDir(),
+		CheckpointPublishedFunc: func(_ context.Context, cpRaw []byte) error {
+			cp := &log.Checkpoint{}
+			if _, err := cp.Unmarshal(cpRaw); err != nil {
+				return err
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			published++
+			for _, p := range append(layout.TilePathsForSize(cp.Size), layout.EntryBundlePathsForSize(cp.Size)...) {
+				if !exists(p) {
+					missing = append(missing, fmt.Sprintf("%s (checkpoint size %d)", p, cp.Size))
+				}
+			}
+			return nil
+		},
+	}}
+
+	sk, _ := mustGenerateKeys(t)
+	opts := tessera.NewAppendOptions().
+		WithCheckpointInterval(minCheckpointInterval).
+		WithBatching(100, time.Millisecond).
+		WithCheckpointSigner(sk)
+	appender, _, err := s.newAppender(ctx, &logResourceStorage{s: s, entriesPath: opts.EntriesPath()}, opts)
+	if err != nil {
+		t.Fatalf("Appender: %v", err)
+	}
+
+	// Integrate batches of varying sizes while checkpoints are being published, so that they commit to many
+	// different partial tiles and bundles.
+	const size = 3*layout.TileWidth + 7
+	for i := uint64(0); i < size; {
+		n := min(i%41+1, size-i)
+		for j := range n {
+			appender.Add(ctx, tessera.NewEntry(fmt.Appendf(nil, "entry %d", i+j)))
+		}
+		if err := s.Flush(ctx); err != nil {
+			t.Fatalf("Flush: %v", err)
+		}
+		i += n
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if published < 2 {
+		t.Errorf("Only %d checkpoints were published", published)
+	}
+	for _, m := range missing {
+		t.Errorf("Published checkpoint commits to missing resource %s", m)
+	}
+}
+
+func TestAppendFramedBundle(t *testing.T) {
+	ctx := t.Context()
+	s := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: t.TempDir()}}
+	sk, _ := mustGenerateKeys(t)
+	opts := tessera.NewAppendOptions().
+		WithCheckpointInterval(10*time.Minute).
+		WithBatching(1, minCheckpointInterval).
+		WithCheckpointSigner(sk)
+	logStorage := &logResourceStorage{
+		s:           s,
+		entriesPath: opts.EntriesPath(),
+	}
+	appender, _, err := s.newAppender(ctx, logStorage, opts)
+	if err != nil {
+		t.Fatalf("Appender: %v", err)
+	}
+	first := tessera.NewEntry([]byte("first"))
+	if _, err := appender.Add(ctx, first)(); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	wantBundle := first.MarshalBundleData(0)
+	framed := []byte{}
+	leafHashes := [][]byte{}
+	for i := range 3 {
+		e := tessera.NewEntry(fmt.Appendf(nil, "framed %d", i))
+		d := e.MarshalBundleData(uint64(i + 1))
+		framed = append(framed, d...)
+		leafHashes = append(leafHashes, e.LeafHash())
+	}
+	wantBundle = append(wantBundle, framed...)
+
+	for _, test := range []struct {
+		name       string
+		framed     []byte
+		leafHashes [][]byte
+	}{
+		{
+			name:       "too few leaf hashes",
+			framed:     framed,
+			leafHashes: leafHashes[:2],
+		}, {
+			name:       "truncated entry",
+			framed:     framed[:len(framed)-1],
+			leafHashes: leafHashes,
+		}, {
+			name:       "truncated length prefix",
+			framed:     append(framed, 0),
+			leafHashes: leafHashes,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := s.AppendFramedBundle(ctx, test.framed, test.leafHashes); err == nil {
+				t.Fatal("AppendFramedBundle succeeded, want error")
+			}
+		})
+	}
+
+	idx, err := s.AppendFramedBundle(ctx, framed, leafHashes)
+	if err != nil {
+		t.Fatalf("AppendFramedBundle: %v", err)
+	}
+	if idx != 1 {
+		t.Fatalf("AppendFramedBundle returned index %d, want 1", idx)
+	}
+	size, err := logStorage.IntegratedSize(ctx)
+	if err != nil {
+		t.Fatalf("IntegratedSize: %v", err)
+	}
+	if size != 4 {
+		t.Fatalf("IntegratedSize() = %d, want 4", size)
+	}
+	gotBundle, err := logStorage.ReadEntryBundle(ctx, 0, 4)
+	if err != nil {
+		t.Fatalf("ReadEntryBundle: %v", err)
+	}
+	if !bytes.Equal(gotBundle, wantBundle) {
+		t.Fatalf("ReadEntryBundle() = %x, want %x", gotBundle, wantBundle)
+	}
+}
+
+func TestGeometryMismatch(t *testing.T) {
+	ctx := t.Context()
+	s := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: t.TempDir()}}
+	sk, _ := mustGenerateKeys(t)
+	opts := tessera.NewAppendOptions().
+		WithCheckpointInterval(10 * time.Minute).
+		WithCheckpointSigner(sk)
+	logStorage := &logResourceStorage{
+		s:           s,
+		entriesPath: opts.EntriesPath(),
+	}
+	if _, _, err := s.newAppender(ctx, logStorage, opts); err != nil {
+		t.Fatalf("Appender: %v", err)
+	}
+	// Reinitialising with the same geometry should be fine.
+	if _, _, err := s.newAppender(ctx, logStorage, opts); err != nil {
+		t.Fatalf("Appender with unchanged geometry: %v", err)
+	}
+
+	// Simulate the log having been created with a different geometry.
+	ls, err := s.readSettings()
+	if err != nil {
+		t.Fatalf("readSettings: %v", err)
+	}
+	ls.TileWidth, ls.EntryBundleWidth = 512, 512
+	data, err := json.Marshal(ls)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if err := s.createOverwrite(filepath.Join(defaultStateDir, settingsFile), data); err != nil {
+		t.Fatalf("createOverwrite: %v", err)
+	}
+	if _, _, err := s.newAppender(ctx, logStorage, opts); !errors.Is(err, ErrGeometryMismatch) {
+		t.Fatalf("Appender with changed geometry: got %v, want %v", err, ErrGeometryMismatch)
+	}
+}
+
+func TestOpenLegacyLogDoesNotModifyState(t *testing.T) {
+	ctx := t.Context()
+	dir := t.TempDir()
+	s := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: dir, DisableAutoPublish: true}}
+	// Simulate a log which was created before its settings were recorded.
+	if err := os.MkdirAll(filepath.Join(dir, defaultStateDir), dirPerm); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, defaultStateDir, "version"), fmt.Appendf(nil, "%d", compatibilityVersion), filePerm); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := s.writeTreeState(ctx, 0, rfc6962.DefaultHasher.EmptyRoot()); err != nil {
+		t.Fatalf("writeTreeState: %v", err)
+	}
+	stateFiles := func() []string {
+		t.Helper()
+		es, err := os.ReadDir(filepath.Join(dir, defaultStateDir))
+		if err != nil {
+			t.Fatalf("ReadDir: %v", err)
+		}
+		var r []string
+		for _, e := range es {
+			if !strings.HasSuffix(e.Name(), ".lock") {
+				r = append(r, e.Name())
+			}
+		}
+		return r
+	}
+	want := stateFiles()
+
+	sk, _ := mustGenerateKeys(t)
+	opts := tessera.NewAppendOptions().
+		WithCheckpointInterval(10 * time.Minute).
+		WithCheckpointSigner(sk)
+	if _, _, err := s.newAppender(ctx, &logResourceStorage{s: s, entriesPath: opts.EntriesPath()}, opts); err != nil {
+		t.Fatalf("Appender: %v", err)
+	}
+	if d := cmp.Diff(want, stateFiles()); d != "" {
+		t.Errorf("State files changed by opening log (-want +got):\n%s", d)
+	}
+}
+
+func TestMigrationProgress(t *testing.T) {
+	ctx := t.Context()
+	s := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: t.TempDir()}}
+
+	type progress struct{ current, target uint64 }
+	var got []progress
+	opts := tessera.NewMigrationOptions().WithProgressCallback(func(current, target uint64) {
+		got = append(got, progress{current, target})
+	})
+	m, _, err := s.MigrationWriter(ctx, opts)
+	if err != nil {
+		t.Fatalf("MigrationWriter: %v", err)
+	}
+
+	const size = 3
+	bundle := []byte{}
+	for i := range size {
+		bundle = append(bundle, tessera.NewEntry(fmt.Appendf(nil, "entry %d", i)).MarshalBundleData(uint64(i))...)
+	}
+	if err := m.SetEntryBundle(ctx, 0, size, bundle); err != nil {
+		t.Fatalf("SetEntryBundle: %v", err)
+	}
+	if _, err := m.AwaitIntegration(ctx, size); err != nil {
+		t.Fatalf("AwaitIntegration: %v", err)
+	}
+	if len(got) == 0 {
+		t.Fatal("Progress callback was not called")
+	}
+	if last, want := got[len(got)-1], (progress{size, size}); last != want {
+		t.Fatalf("Final progress %+v, want %+v", last, want)
+	}
+}
+
+func TestMigrationExpectedRoot(t *testing.T) {
+	const size = 3
+	bundle := []byte{}
+	rf := compact.RangeFactory{Hash: rfc6962.DefaultHasher.HashChildren}
+	cr := rf.NewEmptyRange(0)
+	for i := range size {
+		e := tessera.NewEntry(fmt.Appendf(nil, "entry %d", i))
+		bundle = append(bundle, e.MarshalBundleData(uint64(i))...)
+		if err := cr.Append(e.LeafHash(), nil); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+	root, err := cr.GetRootHash(nil)
+	if err != nil {
+		t.Fatalf("GetRootHash: %v", err)
+	}
+
+	for _, test := range []struct {
+		name         string
+		expectedRoot []byte
+		wantErr      bool
+	}{
+		{
+			name:         "matching root",
+			expectedRoot: root,
+		}, {
+			name:         "mismatched root",
+			expectedRoot: []byte("not the root"),
+			wantErr:      true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := t.Context()
+			s := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: t.TempDir()}}
+			m, _, err := s.MigrationWriter(ctx, tessera.NewMigrationOptions().WithExpectedRoot(test.expectedRoot))
+			if err != nil {
+				t.Fatalf("MigrationWriter: %v", err)
+			}
+			if err := m.SetEntryBundle(ctx, 0, size, bundle); err != nil {
+				t.Fatalf("SetEntryBundle: %v", err)
+			}
+			gotRoot, err := m.AwaitIntegration(ctx, size)
+			if gotErr := errors.Is(err, tessera.ErrRootMismatch); gotErr != test.wantErr {
+				t.Fatalf("AwaitIntegration: got err %v, want ErrRootMismatch %t", err, test.wantErr)
+			}
+			if test.wantErr {
+				// The mismatched tree must not have been committed.
+				if gotSize, err := m.IntegratedSize(ctx); err != nil || gotSize != 0 {
+					t.Fatalf("IntegratedSize: got (%d, %v), want (0, nil)", gotSize, err)
+				}
+				return
+			}
+			if !bytes.Equal(gotRoot, root) {
+				t.Fatalf("AwaitIntegration got root %x, want %x", gotRoot, root)
+			}
+		})
+	}
+}
+
+func TestMigrationSetLeafHashes(t *testing.T) {
+	ctx := t.Context()
+	const size = layout.EntryBundleWidth + 10
+	bundles := make([][]byte, 2)
+	leafHashes := make([][]byte, 0, size)
+	rf := compact.RangeFactory{Hash: rfc6962.DefaultHasher.HashChildren}
+	cr := rf.NewEmptyRange(0)
+	for i := range size {
+		e := tessera.NewEntry(fmt.Appendf(nil, "entry %d", i))
+		bundles[i/layout.EntryBundleWidth] = append(bundles[i/layout.EntryBundleWidth], e.MarshalBundleData(uint64(i))...)
+		leafHashes = append(leafHashes, e.LeafHash())
+		if err := cr.Append(e.LeafHash(), nil); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+	root, err := cr.GetRootHash(nil)
+	if err != nil {
+		t.Fatalf("GetRootHash: %v", err)
+	}
+
+	s := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: t.TempDir()}}
+	mw, _, err := s.MigrationWriter(ctx, tessera.NewMigrationOptions().WithExpectedRoot(root))
+	if err != nil {
+		t.Fatalf("MigrationWriter: %v", err)
+	}
+	m := mw.(*MigrationStorage)
+	// The entry bundles must not be needed to build the tree.
+	m.bundleHasher = func([]byte) ([][]byte, error) {
+		return nil, errors.New("bundle hasher called")
+	}
+
+	if err := m.SetLeafHashes(ctx, 20, leafHashes[20:]); err == nil {
+		t.Fatal("SetLeafHashes beyond the end of the tree succeeded")
+	}
+	// Overlapping ranges of hashes are fine.
+	if err := m.SetLeafHashes(ctx, 0, leafHashes[:100]); err != nil {
+		t.Fatalf("SetLeafHashes: %v", err)
+	}
+	if err := m.SetLeafHashes(ctx, 50, leafHashes[50:]); err != nil {
+		t.Fatalf("SetLeafHashes: %v", err)
+	}
+	if err := m.SetLeafHashes(ctx, 0, leafHashes[:10]); err != nil {
+		t.Fatalf("SetLeafHashes of integrated entries: %v", err)
+	}
+	if err := m.SetEntryBundle(ctx, 0, 0, bundles[0]); err != nil {
+		t.Fatalf("SetEntryBundle: %v", err)
+	}
+	if err := m.SetEntryBundle(ctx, 1, 10, bundles[1]); err != nil {
+		t.Fatalf("SetEntryBundle: %v", err)
+	}
+
+	gotRoot, err := m.AwaitIntegration(ctx, size)
+	if err != nil {
+		t.Fatalf("AwaitIntegration: %v", err)
+	}
+	if !bytes.Equal(gotRoot, root) {
+		t.Errorf("AwaitIntegration got root %x, want %x", gotRoot, root)
+	}
+}
+
+func TestMigrationResumeVerification(t *testing.T) {
+	ctx := t.Context()
+	const size = 2*layout.EntryBundleWidth + 5
+	bundles := map[uint64][]byte{}
+	for i := range uint64(size) {
+		bundles[i/layout.EntryBundleWidth] = append(bundles[i/layout.EntryBundleWidth], tessera.NewEntry(fmt.Appendf(nil, "entry %d", i)).MarshalBundleData(i)...)
+	}
+
+	s := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: t.TempDir()}}
+	m, _, err := s.MigrationWriter(ctx, tessera.NewMigrationOptions())
+	if err != nil {
+		t.Fatalf("MigrationWriter: %v", err)
+	}
+	for ri := range layout.Range(0, size, size) {
+		if err := m.SetEntryBundle(ctx, ri.Index, ri.Partial, bundles[ri.Index]); err != nil {
+			t.Fatalf("SetEntryBundle: %v", err)
+		}
+	}
+	if _, err := m.AwaitIntegration(ctx, size); err != nil {
+		t.Fatalf("AwaitIntegration: %v", err)
+	}
+
+	// Resuming with verification should succeed.
+	if _, _, err := s.MigrationWriter(ctx, tessera.NewMigrationOptions().WithResumeVerification(true)); err != nil {
+		t.Fatalf("MigrationWriter with verification: %v", err)
+	}
+
+	// The most recently integrated bundle is checked against the tiles.
+	if err := m.SetEntryBundle(ctx, 2, 5, bundles[1][:len(bundles[2])]); err != nil {
+		t.Fatalf("SetEntryBundle: %v", err)
+	}
+	if _, _, err := s.MigrationWriter(ctx, tessera.NewMigrationOptions().WithResumeVerification(true)); !errors.Is(err, tessera.ErrRootMismatch) {
+		t.Fatalf("MigrationWriter with verification of corrupt bundle: %v, want %v", err, tessera.ErrRootMismatch)
+	}
+	if _, _, err := s.MigrationWriter(ctx, tessera.NewMigrationOptions()); err != nil {
+		t.Fatalf("MigrationWriter without verification: %v", err)
+	}
+	if err := m.SetEntryBundle(ctx, 2, 5, bundles[2]); err != nil {
+		t.Fatalf("SetEntryBundle: %v", err)
+	}
+
+	// The tiles are checked against the tree state.
+	lrs := &logResourceStorage{s: s, entriesPath: layout.EntriesPath}
+	tile, err := lrs.ReadTile(ctx, 1, 0, 2)
+	if err != nil {
+		t.Fatalf("ReadTile: %v", err)
+	}
+	tile[0] ^= 1
+	if err := lrs.writeTile(ctx, 1, 0, 2, tile); err != nil {
+		t.Fatalf("writeTile: %v", err)
+	}
+	if _, _, err := s.MigrationWriter(ctx, tessera.NewMigrationOptions().WithResumeVerification(true)); !errors.Is(err, tessera.ErrRootMismatch) {
+		t.Fatalf("MigrationWriter with verification of corrupt tile: %v, want %v", err, tessera.ErrRootMismatch)
+	}
+}
+
+func TestMigrationResumeExpectedRoot(t *testing.T) {
+	ctx := t.Context()
+	const size = layout.EntryBundleWidth + 5
+	bundles := map[uint64][]byte{}
+	for i := range uint64(size) {
+		bundles[i/layout.EntryBundleWidth] = append(bundles[i/layout.EntryBundleWidth], tessera.NewEntry(fmt.Appendf(nil, "entry %d", i)).MarshalBundleData(i)...)
+	}
+
+	s := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: t.TempDir()}}
+	m, _, err := s.MigrationWriter(ctx, tessera.NewMigrationOptions())
+	if err != nil {
+		t.Fatalf("MigrationWriter: %v", err)
+	}
+	for ri := range layout.Range(0, size, size) {
+		if err := m.SetEntryBundle(ctx, ri.Index, ri.Partial, bundles[ri.Index]); err != nil {
+			t.Fatalf("SetEntryBundle: %v", err)
+		}
+	}
+	root, err := m.AwaitIntegration(ctx, size)
+	if err != nil {
+		t.Fatalf("AwaitIntegration: %v", err)
+	}
+
+	// A resumed migration whose tree already has the source size is checked against the source root.
+	m, _, err = s.MigrationWriter(ctx, tessera.NewMigrationOptions().WithExpectedRoot(root))
+	if err != nil {
+		t.Fatalf("MigrationWriter: %v", err)
+	}
+	if _, err := m.AwaitIntegration(ctx, size); err != nil {
+		t.Fatalf("AwaitIntegration with expected root: %v", err)
+	}
+	m, _, err = s.MigrationWriter(ctx, tessera.NewMigrationOptions().WithExpectedRoot([]byte("wrong")))
+	if err != nil {
+		t.Fatalf("MigrationWriter: %v", err)
+	}
+	if _, err := m.AwaitIntegration(ctx, size); !errors.Is(err, tessera.ErrRootMismatch) {
+		t.Fatalf("AwaitIntegration with wrong expected root: %v, want %v", err, tessera.ErrRootMismatch)
+	}
+}
+
+func TestMigrationFetchLeafHashes(t *testing.T) {
+	ctx := t.Context()
+	s := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: t.TempDir()}}
+	opts := tessera.NewMigrationOptions().WithBundleFetching(2, 4)
+	mw, _, err := s.MigrationWriter(ctx, opts)
+	if err != nil {
+		t.Fatalf("MigrationWriter: %v", err)
+	}
+	m := mw.(*MigrationStorage)
+
+	const size = 3*layout.EntryBundleWidth + 10
+	leafHashes := make([][]byte, 0, size)
+	bundle := []byte{}
+	for i := range uint64(size) {
+		e := tessera.NewEntry(fmt.Appendf(nil, "entry %d", i))
+		leafHashes = append(leafHashes, e.LeafHash())
+		bundle = append(bundle, e.MarshalBundleData(i)...)
+		if n := (i + 1) % layout.EntryBundleWidth; n == 0 || i == size-1 {
+			if err := m.SetEntryBundle(ctx, i/layout.EntryBundleWidth, uint8(n), bundle); err != nil {
+				t.Fatalf("SetEntryBundle: %v", err)
+			}
+			bundle = []byte{}
+		}
+	}
+
+	for _, test := range []struct {
+		name string
+		from uint64
+		want [][]byte
+	}{
+		{
+			name: "from start",
+			from: 0,
+			want: leafHashes[:2*layout.EntryBundleWidth],
+		}, {
+			name: "from middle of bundle",
+			from: 10,
+			want: leafHashes[10 : 2*layout.EntryBundleWidth],
+		}, {
+			name: "to end",
+			from: 2*layout.EntryBundleWidth + 5,
+			want: leafHashes[2*layout.EntryBundleWidth+5:],
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := m.fetchLeafHashes(ctx, test.from, size, size)
+			if err != nil {
+				t.Fatalf("fetchLeafHashes: %v", err)
+			}
+			if d := cmp.Diff(test.want, got); d != "" {
+				t.Fatalf("fetchLeafHashes diff (-want +got):\n%s", d)
+			}
+		})
+	}
+}
+
+func TestMigrationFetchLeafHashesBadBundle(t *testing.T) {
+	ctx := t.Context()
+	s := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: t.TempDir()}}
+	mw, _, err := s.MigrationWriter(ctx, tessera.NewMigrationOptions())
+	if err != nil {
+		t.Fatalf("MigrationWriter: %v", err)
+	}
+	m := mw.(*MigrationStorage)
+
+	bundle := []byte{}
+	for i := range uint64(10) {
+		bundle = append(bundle, tessera.NewEntry(fmt.Appendf(nil, "entry %d", i)).MarshalBundleData(i)...)
+	}
+	for _, test := range []struct {
+		name   string
+		bundle []byte
+	}{
+		{name: "truncated entry", bundle: bundle[:len(bundle)-1]},
+		{name: "too few entries", bundle: bundle[:len(bundle)/2]},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if err := m.SetEntryBundle(ctx, 3, 10, test.bundle); err != nil {
+				t.Fatalf("SetEntryBundle: %v", err)
+			}
+			_, err := m.fetchLeafHashes(ctx, 3*layout.EntryBundleWidth, 3*layout.EntryBundleWidth+10, 3*layout.EntryBundleWidth+10)
+			if !errors.Is(err, api.ErrBundleTruncated) {
+				t.Errorf("fetchLeafHashes = %v, want error wrapping %v", err, api.ErrBundleTruncated)
+			}
+			if err == nil || !strings.Contains(err.Error(), "bundle index 3") {
+				t.Errorf("fetchLeafHashes = %v, want error naming bundle index 3", err)
+			}
+		})
+	}
+}
+
+func TestMigrationFetchLeafHashesParallel(t *testing.T) {
+	ctx := t.Context()
+	s := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: t.TempDir()}}
+	mw, _, err := s.MigrationWriter(ctx, tessera.NewMigrationOptions())
+	if err != nil {
+		t.Fatalf("MigrationWriter: %v", err)
+	}
+	m := mw.(*MigrationStorage)
+
+	const size = 10*layout.EntryBundleWidth + 17
+	bundle := []byte{}
+	for i := range uint64(size) {
+		bundle = append(bundle, tessera.NewEntry(fmt.Appendf(nil, "entry %d", i)).MarshalBundleData(i)...)
+		if n := (i + 1) % layout.EntryBundleWidth; n == 0 || i == size-1 {
+			if err := m.SetEntryBundle(ctx, i/layout.EntryBundleWidth, uint8(n), bundle); err != nil {
+				t.Fatalf("SetEntryBundle: %v", err)
+			}
+			bundle = []byte{}
+		}
+	}
+
+	fetch := func(from, to uint64, concurrency uint) [][]byte {
+		t.Helper()
+		m.fetchConcurrency = concurrency
+		lh, err := m.fetchLeafHashes(ctx, from, to, size)
+		if err != nil {
+			t.Fatalf("fetchLeafHashes(%d, %d) with concurrency %d: %v", from, to, concurrency, err)
+		}
+		return lh
+	}
+
+	for range 50 {
+		from := rand.Uint64N(size)
+		to := from + 1 + rand.Uint64N(size-from)
+		serial := fetch(from, to, 1)
+		if len(serial) == 0 {
+			t.Fatalf("fetchLeafHashes(%d, %d) returned no hashes", from, to)
+		}
+		if d := cmp.Diff(serial, fetch(from, to, 8)); d != "" {
+			t.Fatalf("fetchLeafHashes(%d, %d) serial vs parallel diff (-serial +parallel):\n%s", from, to, d)
+		}
+	}
+}
+
+func TestMigrationFetchLeafHashesStopsOnError(t *testing.T) {
+	ctx := t.Context()
+	s := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: t.TempDir()}}
+	mw, _, err := s.MigrationWriter(ctx, tessera.NewMigrationOptions().WithBundleFetching(10, 1))
+	if err != nil {
+		t.Fatalf("MigrationWriter: %v", err)
+	}
+	m := mw.(*MigrationStorage)
+
+	const size = 10 * layout.EntryBundleWidth
+	for i := range uint64(size / layout.EntryBundleWidth) {
+		if err := m.SetEntryBundle(ctx, i, 0, []byte("bundle")); err != nil {
+			t.Fatalf("SetEntryBundle: %v", err)
+		}
+	}
+	calls := 0
+	m.bundleHasher = func([]byte) ([][]byte, error) {
+		calls++
+		return nil, errors.New("bad bundle")
+	}
+	if _, err := m.fetchLeafHashes(ctx, 0, size, size); err == nil {
+		t.Fatal("fetchLeafHashes succeeded, want error")
+	}
+	if calls != 1 {
+		t.Errorf("Bundle hasher called %d times after the first failure, want 0", calls-1)
+	}
+}
+
+func TestIntegrateRetryAfterCrash(t *testing.T) {
+	ctx := t.Context()
+	s := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: t.TempDir()}}
+	sk, _ := mustGenerateKeys(t)
+	opts := tessera.NewAppendOptions().
+		WithCheckpointInterval(10*time.Minute).
+		WithBatching(1000, time.Hour).
+		WithCheckpointSigner(sk)
+	logStorage := &logResourceStorage{
+		s:           s,
+		entriesPath: opts.EntriesPath(),
+	}
+	if _, _, err := s.newAppender(ctx, logStorage, opts); err != nil {
+		t.Fatalf("Appender: %v", err)
+	}
+
+	leafHashes := func(prefix string, n int) [][]byte {
+		r := make([][]byte, 0, n)
+		for i := range n {
+			r = append(r, tessera.NewEntry(fmt.Appendf(nil, "%s %d", prefix, i)).LeafHash())
+		}
+		return r
+	}
+	rootOf := func(lh ...[][]byte) []byte {
+		t.Helper()
+		rf := compact.RangeFactory{Hash: rfc6962.DefaultHasher.HashChildren}
+		cr := rf.NewEmptyRange(0)
+		for _, hs := range lh {
+			for _, h := range hs {
+				if err := cr.Append(h, nil); err != nil {
+					t.Fatalf("Append: %v", err)
+				}
+			}
+		}
+		r, err := cr.GetRootHash(nil)
+		if err != nil {
+			t.Fatalf("GetRootHash: %v", err)
+		}
+		return r
+	}
+
+	// Start with a committed tree which has a partial tile on the right-hand edge.
+	committed := leafHashes("committed", layout.TileWidth+10)
+	size, root, err := doIntegrate(ctx, rfc6962.DefaultHasher, 0, committed, logStorage)
+	if err != nil {
+		t.Fatalf("doIntegrate: %v", err)
+	}
+	if err := s.writeTreeState(ctx, size, root); err != nil {
+		t.Fatalf("writeTreeState: %v", err)
+	}
+
+	// Simulate a crash after integration has written tiles, but before the tree state is updated.
+	crashed := leafHashes("crashed", layout.TileWidth)
+	if _, _, err := doIntegrate(ctx, rfc6962.DefaultHasher, size, crashed, logStorage); err != nil {
+		t.Fatalf("doIntegrate: %v", err)
+	}
+	tPath := filepath.Join(s.cfg.Path, layout.TilePath(0, 1, 0))
+	before, err := os.Stat(tPath)
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+
+	// Retrying with the same leaves should converge on the same tree, and leave identical tiles untouched.
+	gotSize, gotRoot, err := doIntegrate(ctx, rfc6962.DefaultHasher, size, crashed, logStorage)
+	if err != nil {
+		t.Fatalf("doIntegrate: %v", err)
+	}
+	if wantSize, wantRoot := size+uint64(len(crashed)), rootOf(committed, crashed); gotSize != wantSize || !bytes.Equal(gotRoot, wantRoot) {
+		t.Fatalf("doIntegrate got (%d, %x), want (%d, %x)", gotSize, gotRoot, wantSize, wantRoot)
+	}
+	after, err := os.Stat(tPath)
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if !os.SameFile(before, after) {
+		t.Errorf("Tile %q with identical content was rewritten", tPath)
+	}
+
+	// The crashed batch was never committed, so a different batch may be integrated in its place.
+	retry := leafHashes("retry", layout.TileWidth+3)
+	gotSize, gotRoot, err = doIntegrate(ctx, rfc6962.DefaultHasher, size, retry, logStorage)
+	if err != nil {
+		t.Fatalf("doIntegrate: %v", err)
+	}
+	if wantSize, wantRoot := size+uint64(len(retry)), rootOf(committed, retry); gotSize != wantSize || !bytes.Equal(gotRoot, wantRoot) {
+		t.Fatalf("doIntegrate got (%d, %x), want (%d, %x)", gotSize, gotRoot, wantSize, wantRoot)
+	}
+	if err := s.writeTreeState(ctx, gotSize, gotRoot); err != nil {
+		t.Fatalf("writeTreeState: %v", err)
+	}
+
+	// Integration should continue correctly from the recovered tree.
+	more := leafHashes("more", 7)
+	gotSize, gotRoot, err = doIntegrate(ctx, rfc6962.DefaultHasher, gotSize, more, logStorage)
+	if err != nil {
+		t.Fatalf("doIntegrate: %v", err)
+	}
+	if wantSize, wantRoot := size+uint64(len(retry)+len(more)), rootOf(committed, retry, more); gotSize != wantSize || !bytes.Equal(gotRoot, wantRoot) {
+		t.Fatalf("doIntegrate got (%d, %x), want (%d, %x)", gotSize, gotRoot, wantSize, wantRoot)
+	}
+}
+
+func TestStreamEntries(t *testing.T) {
+	ctx := t.Context()
+	s := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: t.TempDir()}}
+	sk, _ := mustGenerateKeys(t)
+	opts := tessera.NewAppendOptions().
+		WithCheckpointInterval(10*time.Minute).
+		WithBatching(100, time.Hour).
+		WithCheckpointSigner(sk)
+	logStorage := &logResourceStorage{
+		s:           s,
+		entriesPath: opts.EntriesPath(),
+	}
+	appender, _, err := s.newAppender(ctx, logStorage, opts)
+	if err != nil {
+		t.Fatalf("Appender: %v", err)
+	}
+
+	// Add entries in several batches so that the log contains a mix of full and partial bundles.
+	const size = 2*layout.EntryBundleWidth + 10
+	want := make([][]byte, 0, size)
+	for i := range size {
+		want = append(want, fmt.Appendf(nil, "entry %d", i))
+		appender.Add(ctx, tessera.NewEntry(want[i]))
+	}
+	if err := s.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	for _, test := range []struct {
+		name       string
+		start, end uint64
+		wantErr    bool
+	}{
+		{name: "all", start: 0, end: size},
+		{name: "empty", start: 10, end: 10},
+		{name: "within bundle", start: 3, end: 20},
+		{name: "across bundles", start: layout.EntryBundleWidth - 5, end: 2*layout.EntryBundleWidth + 3},
+		{name: "partial bundle", start: 2*layout.EntryBundleWidth + 1, end: size},
+		{name: "beyond tree", start: 0, end: size + 1, wantErr: true},
+		{name: "inverted", start: 10, end: 5, wantErr: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var got [][]byte
+			for e, err := range s.StreamEntries(ctx, test.start, test.end) {
+				if err != nil {
+					if !test.wantErr {
+						t.Fatalf("StreamEntries: %v", err)
+					}
+					return
+				}
+				got = append(got, e)
+			}
+			if test.wantErr {
+				t.Fatal("StreamEntries succeeded, want error")
+			}
+			if d := cmp.Diff(want[test.start:test.end], got, cmpopts.EquateEmpty()); d != "" {
+				t.Fatalf("StreamEntries diff (-want +got):\n%s", d)
+			}
+		})
+	}
+
+	t.Run("cancelled", func(t *testing.T) {
+		cctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		n := 0
+		var gotErr error
+		for _, err := range s.StreamEntries(cctx, 0, size) {
+			if err != nil {
+				gotErr = err
+				break
+			}
+			if n++; n == 1 {
+				cancel()
+			}
+		}
+		if !errors.Is(gotErr, context.Canceled) {
+			t.Fatalf("StreamEntries got err %v, want %v", gotErr, context.Canceled)
+		}
+		if n != layout.EntryBundleWidth {
+			t.Fatalf("StreamEntries yielded %d entries before cancellation, want %d", n, layout.EntryBundleWidth)
+		}
+	})
+}
+
+func TestReadEntries(t *testing.T) {
+	ctx := t.Context()
+	s := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: t.TempDir(), DisableAutoPublish: true}}
+	sk, _ := mustGenerateKeys(t)
+	opts := tessera.NewAppendOptions().
+		WithCheckpointInterval(10*time.Minute).
+		WithBatching(100, time.Hour).
+		WithCheckpointSigner(sk)
+	appender, _, err := s.newAppender(ctx, &logResourceStorage{s: s, entriesPath: opts.EntriesPath()}, opts)
+	if err != nil {
+		t.Fatalf("Appender: %v", err)
+	}
+
+	const size = 2*layout.EntryBundleWidth + 10
+	want := make([][]byte, 0, size)
+	for i := range size {
+		want = append(want, fmt.Appendf(nil, "entry %d", i))
+		appender.Add(ctx, tessera.NewEntry(want[i]))
+	}
+	if err := s.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	for _, test := range []struct {
+		name         string
+		start, count uint64
+		wantErr      bool
+	}{
+		{name: "all", start: 0, count: size},
+		{name: "none", start: 10, count: 0},
+		{name: "leading and trailing partial bundles", start: layout.EntryBundleWidth - 5, count: layout.EntryBundleWidth + 8},
+		{name: "partial bundle", start: 2*layout.EntryBundleWidth + 1, count: 9},
+		{name: "beyond tree", start: size - 1, count: 2, wantErr: true},
+		{name: "overflow", start: 1, count: ^uint64(0), wantErr: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := s.ReadEntries(ctx, test.start, test.count)
+			if gotErr := err != nil; gotErr != test.wantErr {
+				t.Fatalf("ReadEntries: %v, wantErr %t", err, test.wantErr)
+			}
+			if test.wantErr {
+				return
+			}
+			if d := cmp.Diff(want[test.start:test.start+test.count], got, cmpopts.EquateEmpty()); d != "" {
+				t.Fatalf("ReadEntries diff (-want +got):\n%s", d)
+			}
+		})
+	}
+}
+
+func TestReadEntry(t *testing.T) {
+	ctx := t.Context()
+	s := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: t.TempDir()}}
+	sk, _ := mustGenerateKeys(t)
+	opts := tessera.NewAppendOptions().
+		WithCheckpointInterval(10*time.Minute).
+		WithBatching(100, time.Hour).
+		WithCheckpointSigner(sk)
+	logStorage := &logResourceStorage{
+		s:           s,
+		entriesPath: opts.EntriesPath(),
+	}
+	appender, _, err := s.newAppender(ctx, logStorage, opts)
+	if err != nil {
+		t.Fatalf("Appender: %v", err)
+	}
+
+	const size = layout.EntryBundleWidth + 10
+	for i := range size {
+		appender.Add(ctx, tessera.NewEntry(fmt.Appendf(nil, "entry %d", i)))
+	}
+	if err := s.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	for _, i := range []uint64{0, 17, layout.EntryBundleWidth - 1, layout.EntryBundleWidth, size - 1} {
+		got, err := s.ReadEntry(ctx, i)
+		if err != nil {
+			t.Fatalf("ReadEntry(%d): %v", i, err)
+		}
+		if want := fmt.Appendf(nil, "entry %d", i); !bytes.Equal(got, want) {
+			t.Errorf("ReadEntry(%d) = %q, want %q", i, got, want)
+		}
+	}
+	if _, err := s.ReadEntry(ctx, size); err == nil {
+		t.Errorf("ReadEntry(%d) succeeded, want error", size)
+	}
+}
+
+func TestReadTiles(t *testing.T) {
+	ctx := t.Context()
+	s := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: t.TempDir()}}
+	sk, _ := mustGenerateKeys(t)
+	opts := tessera.NewAppendOptions().
+		WithCheckpointInterval(10*time.Minute).
+		WithBatching(1000, time.Hour).
+		WithCheckpointSigner(sk)
+	logStorage := &logResourceStorage{
+		s:           s,
+		entriesPath: opts.EntriesPath(),
+	}
+	appender, _, err := s.newAppender(ctx, logStorage, opts)
+	if err != nil {
+		t.Fatalf("Appender: %v", err)
+	}
+	const size = 3*layout.TileWidth + 5
+	for i := range size {
+		appender.Add(ctx, tessera.NewEntry(fmt.Appendf(nil, "entry %d", i)))
+	}
+	if err := s.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	ids := []TileID{
+		{Level: 0, Index: 3},
+		{Level: 1, Index: 0},
+		{Level: 0, Index: 0},
+		{Level: 0, Index: 10},
+		{Level: 0, Index: 2},
+	}
+	got, err := s.ReadTiles(ctx, ids, size)
+	if err != nil {
+		t.Fatalf("ReadTiles: %v", err)
+	}
+	if len(got) != len(ids) {
+		t.Fatalf("ReadTiles returned %d tiles, want %d", len(got), len(ids))
+	}
+	for i, id := range ids {
+		want, err := logStorage.readTile(ctx, id.Level, id.Index, layout.PartialTileSize(id.Level, id.Index, size))
+		if err != nil {
+			t.Fatalf("readTile(%v): %v", id, err)
+		}
+		if d := cmp.Diff(want, got[i]); d != "" {
+			t.Errorf("ReadTiles[%d] (%v) diff (-want +got):\n%s", i, id, d)
+		}
+		byID, err := s.ReadTileByID(ctx, id, size)
+		if err != nil {
+			t.Fatalf("ReadTileByID(%v): %v", id, err)
+		}
+		if d := cmp.Diff(want, byID); d != "" {
+			t.Errorf("ReadTileByID(%v) diff (-want +got):\n%s", id, d)
+		}
+	}
+	if got[3] != nil {
+		t.Errorf("ReadTiles returned %v for missing tile, want nil", got[3])
+	}
+	if got[0] == nil || len(got[0].Nodes) != 5 {
+		t.Errorf("ReadTiles returned %v for partial tile, want 5 nodes", got[0])
+	}
+}
+
+func TestIterateTiles(t *testing.T) {
+	ctx := t.Context()
+	s := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: t.TempDir(), DisableAutoPublish: true}}
+	sk, _ := mustGenerateKeys(t)
+	opts := tessera.NewAppendOptions().
+		WithCheckpointInterval(10*time.Minute).
+		WithBatching(1000, time.Hour).
+		WithCheckpointSigner(sk)
+	logStorage := &logResourceStorage{s: s, entriesPath: opts.EntriesPath()}
+	appender, _, err := s.newAppender(ctx, logStorage, opts)
+	if err != nil {
+		t.Fatalf("Appender: %v", err)
+	}
+	const size = 3*layout.TileWidth + 5
+	for i := range size {
+		appender.Add(ctx, tessera.NewEntry(fmt.Appendf(nil, "entry %d", i)))
+	}
+	if err := s.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	for _, test := range []struct {
+		level     uint64
+		wantTiles uint64
+	}{
+		{level: 0, wantTiles: 4},
+		{level: 1, wantTiles: 1},
+		{level: 2, wantTiles: 0},
+	} {
+		i := uint64(0)
+		for tile, err := range s.IterateTiles(ctx, test.level) {
+			if err != nil {
+				t.Fatalf("Level %d: IterateTiles: %v", test.level, err)
+			}
+			id := tile.ID
+			if want := (TileID{Level: test.level, Index: i}); id != want {
+				t.Errorf("Level %d: got tile %v, want %v", test.level, id, want)
+			}
+			want, err := logStorage.readTile(ctx, id.Level, id.Index, layout.PartialTileSize(id.Level, id.Index, size))
+			if err != nil {
+				t.Fatalf("readTile(%v): %v", id, err)
+			}
+			if d := cmp.Diff(want, tile.Tile); d != "" {
+				t.Errorf("Tile %v diff (-want +got):\n%s", id, d)
+			}
+			i++
+		}
+		if i != test.wantTiles {
+			t.Errorf("Level %d: got %d tiles, want %d", test.level, i, test.wantTiles)
+		}
+	}
+
+	// Iteration stops when the consumer does, or once ctx is done.
+	n := 0
+	for range s.IterateTiles(ctx, 0) {
+		n++
+		break
+	}
+	if n != 1 {
+		t.Errorf("Got %d tiles after break, want 1", n)
+	}
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	n = 0
+	for tile, err := range s.IterateTiles(cctx, 0) {
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Got tile %v, %v after ctx was cancelled, want %v", tile.ID, err, context.Canceled)
+		}
+		n++
+	}
+	if n != 1 {
+		t.Errorf("Got %d results after ctx was cancelled, want 1", n)
+	}
+
+	// A tile which can't be read is yielded as an error, rather than silently ending the iteration.
+	if err := os.Remove(filepath.Join(s.cfg.Path, layout.TilePath(0, 1, 0))); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	var ids []TileID
+	var gotErr error
+	for tile, err := range s.IterateTiles(ctx, 0) {
+		if err != nil {
+			gotErr = err
+			continue
+		}
+		ids = append(ids, tile.ID)
+	}
+	if want := []TileID{{Level: 0, Index: 0}}; !errors.Is(gotErr, os.ErrNotExist) || !slices.Equal(ids, want) {
+		t.Errorf("IterateTiles with missing tile: got %v, %v, want %v, %v", ids, gotErr, want, os.ErrNotExist)
+	}
+}
+
+func TestTreeState(t *testing.T) {
+	ctx := t.Context()
+	s := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: t.TempDir()}}
+	sk, _ := mustGenerateKeys(t)
+	opts := tessera.NewAppendOptions().
+		WithCheckpointInterval(10*time.Minute).
+		WithBatching(100, time.Hour).
+		WithCheckpointSigner(sk)
+	logStorage := &logResourceStorage{
+		s:           s,
+		entriesPath: opts.EntriesPath(),
+	}
+	appender, _, err := s.newAppender(ctx, logStorage, opts)
+	if err != nil {
+		t.Fatalf("Appender: %v", err)
+	}
+
+	e := tessera.NewEntry([]byte("one"))
+	appender.Add(ctx, e)
+	if err := s.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	size, root, err := s.TreeState(ctx)
+	if err != nil {
+		t.Fatalf("TreeState: %v", err)
+	}
+	if size != 1 || !bytes.Equal(root, e.LeafHash()) {
+		t.Fatalf("TreeState = (%d, %x), want (1, %x)", size, root, e.LeafHash())
+	}
+}
+
+func TestNewTreeFunc(t *testing.T) {
+	ctx := t.Context()
+	var mu sync.Mutex
+	var sizes []uint64
+	var lastRoot []byte
+	s := &Storage{cfg: Config{
+		HTTPClient: http.DefaultClient,
+		Path:       t.TempDir(),
+		NewTreeFunc: func(size uint64, root []byte) error {
+			mu.Lock()
+			defer mu.Unlock()
+			sizes = append(sizes, size)
+			lastRoot = root
+			// Errors must not prevent integration.
+			return errors.New("boom")
+		},
+	}}
+	sk, _ := mustGenerateKeys(t)
+	opts := tessera.NewAppendOptions().
+		WithCheckpointInterval(10*time.Minute).
+		WithBatching(100, time.Hour).
+		WithCheckpointSigner(sk)
+	appender, _, err := s.newAppender(ctx, &logResourceStorage{s: s, entriesPath: opts.EntriesPath()}, opts)
+	if err != nil {
+		t.Fatalf("Appender: %v", err)
+	}
+
+	for i := range 3 {
+		for j := range 5 {
+			appender.Add(ctx, tessera.NewEntry(fmt.Appendf(nil, "entry %d-%d", i, j)))
+		}
+		if err := s.Flush(ctx); err != nil {
+			t.Fatalf("Flush: %v", err)
+		}
+	}
+
+	size, root, err := s.TreeState(ctx)
+	if err != nil {
+		t.Fatalf("TreeState: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if diff := cmp.Diff([]uint64{5, 10, 15}, sizes); diff != "" {
+		t.Errorf("NewTreeFunc sizes diff (-want +got):\n%s", diff)
+	}
+	if size != 15 || !bytes.Equal(root, lastRoot) {
+		t.Errorf("TreeState = (%d, %x), want (15, %x)", size, root, lastRoot)
+	}
+}
+
+func TestCheckpointPublishedFunc(t *testing.T) {
+	ctx := t.Context()
+	var mu sync.Mutex
+	var published [][]byte
+	s := &Storage{cfg: Config{
+		HTTPClient: http.DefaultClient,
+		Path:       t.TempDir(),
+		CheckpointPublishedFunc: func(_ context.Context, cpRaw []byte) error {
+			mu.Lock()
+			defer mu.Unlock()
+			published = append(published, cpRaw)
+			// Errors must not unpublish the checkpoint.
+			return errors.New("boom")
+		},
+	}}
+	sk, _ := mustGenerateKeys(t)
+	opts := tessera.NewAppendOptions().
+		WithCheckpointInterval(10*time.Minute).
+		WithBatching(100, time.Hour).
+		WithCheckpointSigner(sk)
+	appender, lr, err := s.newAppender(ctx, &logResourceStorage{s: s, entriesPath: opts.EntriesPath()}, opts)
+	if err != nil {
+		t.Fatalf("Appender: %v", err)
+	}
+
+	appender.Add(ctx, tessera.NewEntry([]byte("entry")))
+	if err := s.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if err := appender.publishCheckpoint(ctx, 0, 0); err != nil {
+		t.Fatalf("publishCheckpoint: %v", err)
+	}
+
+	cpRaw, err := lr.ReadCheckpoint(ctx)
+	if err != nil {
+		t.Fatalf("ReadCheckpoint: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(published) == 0 {
+		t.Fatal("CheckpointPublishedFunc was not called")
+	}
+	if got := published[len(published)-1]; !bytes.Equal(got, cpRaw) {
+		t.Errorf("CheckpointPublishedFunc got checkpoint %q, want %q", got, cpRaw)
+	}
+}
+
+func TestReadersNotFound(t *testing.T) {
+	ctx := t.Context()
+	s := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: t.TempDir()}}
+	lr := s.logReader()
+
+	for _, test := range []struct {
+		name string
+		read func() ([]byte, error)
+	}{
+		{name: "checkpoint", read: func() ([]byte, error) { return lr.ReadCheckpoint(ctx) }},
+		{name: "tile", read: func() ([]byte, error) { return lr.ReadTile(ctx, 0, 0, 0) }},
+		{name: "partial tile", read: func() ([]byte, error) { return lr.ReadTile(ctx, 0, 0, 12) }},
+		{name: "entry bundle", read: func() ([]byte, error) { return lr.ReadEntryBundle(ctx, 0, 0) }},
+		{name: "partial entry bundle", read: func() ([]byte, error) { return lr.ReadEntryBundle(ctx, 0, 12) }},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := test.read()
+			if !errors.Is(err, tessera.ErrNotFound) {
+				t.Errorf("got error %v, want %v", err, tessera.ErrNotFound)
+			}
+			if !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("got error %v, want %v", err, os.ErrNotExist)
+			}
+		})
+	}
+}
+
+func TestCheckpointAheadOfTreeState(t *testing.T) {
+	ctx := t.Context()
+	dir := t.TempDir()
+	sk, _ := mustGenerateKeys(t)
+	opts := tessera.NewAppendOptions().
+		WithCheckpointInterval(10*time.Minute).
+		WithBatching(100, time.Hour).
+		WithCheckpointSigner(sk)
+	newAppender := func(republish bool) (*appender, error) {
+		s := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: dir, RepublishCheckpointOnMismatch: republish}}
+		a, _, err := s.newAppender(ctx, &logResourceStorage{s: s, entriesPath: opts.EntriesPath()}, opts)
+		return a, err
+	}
+	a, err := newAppender(false)
+	if err != nil {
+		t.Fatalf("Appender: %v", err)
+	}
+
+	// Publish a checkpoint for a larger tree than has been integrated.
+	cpRaw, err := a.newCP(ctx, 5, rfc6962.DefaultHasher.EmptyRoot())
+	if err != nil {
+		t.Fatalf("newCP: %v", err)
+	}
+	if err := a.s.createOverwrite(layout.CheckpointPath, cpRaw); err != nil {
+		t.Fatalf("createOverwrite: %v", err)
+	}
+
+	_, err = newAppender(false)
+	if !errors.Is(err, ErrCheckpointAhead) {
+		t.Fatalf("Appender with checkpoint ahead = %v, want %v", err, ErrCheckpointAhead)
+	}
+	if want := "checkpoint has size 5, but tree state has size 0"; !strings.Contains(err.Error(), want) {
+		t.Errorf("Appender error %q does not contain %q", err, want)
+	}
+
+	a, err = newAppender(true)
+	if err != nil {
+		t.Fatalf("Appender with RepublishCheckpointOnMismatch: %v", err)
+	}
+	if size, err := a.publishedSize(ctx); err != nil || size != 0 {
+		t.Errorf("publishedSize after republish = %d, %v, want 0", size, err)
+	}
+}
+
+func TestStateDir(t *testing.T) {
+	ctx := t.Context()
+	if _, err := New(ctx, Config{Path: t.TempDir(), StateDir: "../state"}); err == nil {
+		t.Error("New succeeded with StateDir outside of the log directory")
+	}
+
+	dir := t.TempDir()
+	// Don't publish checkpoints in the background, as they may race with removal of the temporary directory.
+	d, err := New(ctx, Config{Path: dir, StateDir: "state", DisableAutoPublish: true})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	s := d.(*Storage)
+	sk, _ := mustGenerateKeys(t)
+	opts := tessera.NewAppendOptions().
+		WithCheckpointInterval(10*time.Minute).
+		WithBatching(100, time.Hour).
+		WithCheckpointSigner(sk)
+	appender, _, err := s.newAppender(ctx, &logResourceStorage{s: s, entriesPath: opts.EntriesPath()}, opts)
+	if err != nil {
+		t.Fatalf("Appender: %v", err)
+	}
+	appender.Add(ctx, tessera.NewEntry([]byte("entry")))
+	if err := s.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	for _, f := range []string{treeStateFile, treeStateLock, "version"} {
+		if _, err := os.Stat(filepath.Join(dir, "state", f)); err != nil {
+			t.Errorf("Stat(%s): %v", f, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, defaultStateDir)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Stat(%s) = %v, want %v", defaultStateDir, err, os.ErrNotExist)
+	}
+}
+
+func TestDisableAutoPublish(t *testing.T) {
+	ctx := t.Context()
+	dir := t.TempDir()
+	s := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: dir, DisableAutoPublish: true}}
+	sk, _ := mustGenerateKeys(t)
+	opts := tessera.NewAppendOptions().
+		WithCheckpointInterval(time.Second).
+		WithBatching(100, time.Hour).
+		WithCheckpointSigner(sk)
+	appender, _, err := s.newAppender(ctx, &logResourceStorage{s: s, entriesPath: opts.EntriesPath()}, opts)
+	if err != nil {
+		t.Fatalf("Appender: %v", err)
+	}
+	appender.Add(ctx, tessera.NewEntry([]byte("entry")))
+	if err := s.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	size, _, err := s.TreeState(ctx)
+	if err != nil {
+		t.Fatalf("TreeState: %v", err)
+	}
+	if size != 1 {
+		t.Errorf("TreeState size = %d, want 1", size)
+	}
+	if _, err := os.Stat(filepath.Join(dir, layout.CheckpointPath)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Stat(checkpoint) = %v, want %v", err, os.ErrNotExist)
+	}
+}
+
+func TestMaxIntegrateLeaves(t *testing.T) {
+	ctx := t.Context()
+	var sizes []uint64
+	s := &Storage{cfg: Config{
+		HTTPClient:         http.DefaultClient,
+		Path:               t.TempDir(),
+		DisableAutoPublish: true,
+		MaxIntegrateLeaves: 10,
+		NewTreeFunc: func(size uint64, _ []byte) error {
+			sizes = append(sizes, size)
+			return nil
+		},
+	}}
+	sk, _ := mustGenerateKeys(t)
+	opts := tessera.NewAppendOptions().
+		WithCheckpointInterval(10*time.Minute).
+		WithBatching(100, time.Hour).
+		WithCheckpointSigner(sk)
+	if _, _, err := s.newAppender(ctx, &logResourceStorage{s: s, entriesPath: opts.EntriesPath()}, opts); err != nil {
+		t.Fatalf("Appender: %v", err)
+	}
+	entries := make([]*tessera.Entry, 0, 25)
+	for i := range 25 {
+		entries = append(entries, tessera.NewEntry(fmt.Appendf(nil, "entry %d", i)))
+	}
+	futures := s.AddBatch(ctx, entries)
+	if err := s.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	for i, f := range futures {
+		if idx, err := f(); err != nil || idx.Index != uint64(i) {
+			t.Errorf("Future %d = %d, %v", i, idx.Index, err)
+		}
+	}
+
+	// The batch should have been integrated in chunks.
+	if d := cmp.Diff([]uint64{10, 20, 25}, sizes); d != "" {
+		t.Errorf("Integrated tree sizes diff (-want +got):\n%s", d)
+	}
+	// The final tree must be the same as if the entries had been integrated at once.
+	wantRoot := entriesRoot(t, entries)
+	if _, root, err := s.TreeState(ctx); err != nil || !bytes.Equal(root, wantRoot) {
+		t.Errorf("TreeState root = %x, %v, want %x", root, err, wantRoot)
+	}
+}
+
+// entriesRoot returns the root hash of the tree containing the provided entries.
+func entriesRoot(t *testing.T, entries []*tessera.Entry) []byte {
+	t.Helper()
+	rf := compact.RangeFactory{Hash: rfc6962.DefaultHasher.HashChildren}
+	cr := rf.NewEmptyRange(0)
+	for _, e := range entries {
+		if err := cr.Append(e.LeafHash(), nil); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+	root, err := cr.GetRootHash(nil)
+	if err != nil {
+		t.Fatalf("GetRootHash: %v", err)
+	}
+	return root
+}
+
+func TestInitializeLog(t *testing.T) {
+	ctx := t.Context()
+	dir := t.TempDir()
+	sk, _ := mustGenerateKeys(t)
+	opts := tessera.NewAppendOptions().
+		WithCheckpointInterval(10*time.Minute).
+		WithBatching(1, time.Millisecond).
+		WithCheckpointSigner(sk)
+	if err := InitializeLog(ctx, Config{Path: dir}, opts); err != nil {
+		t.Fatalf("InitializeLog: %v", err)
+	}
+
+	cp, err := os.ReadFile(filepath.Join(dir, layout.CheckpointPath))
+	if err != nil {
+		t.Fatalf("ReadFile(checkpoint): %v", err)
+	}
+	if !bytes.Contains(cp, []byte("\n0\n")) {
+		t.Errorf("Initial checkpoint is not for an empty tree:\n%s", cp)
+	}
+	if _, err := os.Stat(filepath.Join(dir, defaultStateDir, "version")); err != nil {
+		t.Errorf("Stat(version): %v", err)
+	}
+
+	if err := InitializeLog(ctx, Config{Path: dir}, opts); !errors.Is(err, ErrLogExists) {
+		t.Errorf("InitializeLog on existing log = %v, want %v", err, ErrLogExists)
+	}
+
+	// The log can be opened and appended to as usual.
+	s := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: dir, DisableAutoPublish: true}}
+	if _, _, err := s.newAppender(ctx, &logResourceStorage{s: s, entriesPath: opts.EntriesPath()}, opts); err != nil {
+		t.Fatalf("Appender: %v", err)
+	}
+	if idx, err := s.AddSync(ctx, tessera.NewEntry([]byte("entry"))); err != nil || idx != 0 {
+		t.Errorf("AddSync = %d, %v, want 0", idx, err)
+	}
+}
+
+func TestExists(t *testing.T) {
+	ctx := t.Context()
+	dir := t.TempDir()
+	cfg := Config{Path: dir}
+	if ok, err := Exists(ctx, cfg); err != nil || ok {
+		t.Fatalf("Exists on empty directory = %t, %v, want false, nil", ok, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, defaultStateDir)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Exists created the state directory: %v", err)
+	}
+
+	sk, _ := mustGenerateKeys(t)
+	if err := InitializeLog(ctx, cfg, tessera.NewAppendOptions().WithCheckpointSigner(sk)); err != nil {
+		t.Fatalf("InitializeLog: %v", err)
+	}
+	if ok, err := Exists(ctx, cfg); err != nil || !ok {
+		t.Fatalf("Exists on initialized log = %t, %v, want true, nil", ok, err)
+	}
+
+	versionFile := filepath.Join(dir, defaultStateDir, "version")
+	if err := os.WriteFile(versionFile, []byte("0"), filePerm); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if ok, err := Exists(ctx, cfg); err == nil || ok {
+		t.Errorf("Exists with incompatible version = %t, %v, want false, error", ok, err)
+	}
+
+	if err := os.Remove(filepath.Join(dir, defaultStateDir, treeStateFile)); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if _, err := Exists(ctx, cfg); !errors.Is(err, ErrPartiallyInitialized) {
+		t.Errorf("Exists without tree state = %v, want %v", err, ErrPartiallyInitialized)
+	}
+}
+
+func TestIntegrateLeafHashes(t *testing.T) {
+	ctx := t.Context()
+	s := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: t.TempDir()}}
+
+	entries := make([]*tessera.Entry, 0, 300)
+	lh := make([][]byte, 0, 300)
+	for i := range 300 {
+		e := tessera.NewEntry(fmt.Appendf(nil, "entry %d", i))
+		entries = append(entries, e)
+		lh = append(lh, e.LeafHash())
+	}
+	if size, _, err := s.IntegrateLeafHashes(ctx, 0, lh[:100]); err != nil || size != 100 {
+		t.Fatalf("IntegrateLeafHashes(0) = %d, %v, want 100", size, err)
+	}
+	size, root, err := s.IntegrateLeafHashes(ctx, 100, lh[100:])
+	if err != nil {
+		t.Fatalf("IntegrateLeafHashes(100): %v", err)
+	}
+	if want := entriesRoot(t, entries); size != 300 || !bytes.Equal(root, want) {
+		t.Errorf("IntegrateLeafHashes = %d, %x, want 300, %x", size, root, want)
+	}
+
+	tiles, err := s.ReadTiles(ctx, []TileID{{Level: 0, Index: 0}, {Level: 0, Index: 1}}, size)
+	if err != nil {
+		t.Fatalf("ReadTiles: %v", err)
+	}
+	if got := len(tiles[0].Nodes) + len(tiles[1].Nodes); got != 300 {
+		t.Errorf("Tiles hold %d leaf hashes, want 300", got)
+	}
+	// The tree state is left to the caller.
+	if _, _, err := s.TreeState(ctx); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("TreeState = %v, want %v", err, os.ErrNotExist)
+	}
+}
+
+func BenchmarkIntegrateLeafHashes(b *testing.B) {
+	ctx := b.Context()
+	s := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: b.TempDir()}}
+
+	chunkSize := 200
+	seq := uint64(0)
+	for chunk := 0; b.Loop(); chunk++ {
+		lh := make([][]byte, chunkSize)
+		for i := range lh {
+			lh[i] = tessera.NewEntry(fmt.Appendf(nil, "entry %d", seq+uint64(i))).LeafHash()
+		}
+		size, _, err := s.IntegrateLeafHashes(ctx, seq, lh)
+		if err != nil {
+			b.Fatalf("[%d] IntegrateLeafHashes: %v", chunk, err)
+		}
+		seq = size
+	}
+}
+
+func TestFlatLayout(t *testing.T) {
+	ctx := t.Context()
+	dir := t.TempDir()
+	sk, _ := mustGenerateKeys(t)
+	opts := tessera.NewAppendOptions().
+		WithCheckpointInterval(10*time.Minute).
+		WithBatching(1, time.Millisecond).
+		WithCheckpointSigner(sk)
+	s := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: dir, DisableAutoPublish: true, FlatLayout: true}}
+	a, lr, err := s.newAppender(ctx, &logResourceStorage{s: s, entriesPath: opts.EntriesPath()}, opts)
+	if err != nil {
+		t.Fatalf("Appender: %v", err)
+	}
+	if _, err := a.Add(ctx, tessera.NewEntry([]byte("entry")))(); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if _, err := lr.ReadEntryBundle(ctx, 0, 1); err != nil {
+		t.Errorf("ReadEntryBundle: %v", err)
+	}
+	if _, err := lr.ReadTile(ctx, 0, 0, 1); err != nil {
+		t.Errorf("ReadTile: %v", err)
+	}
+
+	// Resources far enough into the log to need more than one digit group are stored without nesting.
+	lrs := a.logStorage
+	if err := lrs.writeTile(ctx, 0, 1234567, 0, make([]byte, 32)); err != nil {
+		t.Fatalf("writeTile: %v", err)
+	}
+	if err := lrs.writeBundle(ctx, 1234567, 0, []byte("bundle")); err != nil {
+		t.Fatalf("writeBundle: %v", err)
+	}
+	for _, p := range []string{"tile/0/x001-x234-567", "tile/entries/x001-x234-567"} {
+		if _, err := os.Stat(filepath.Join(dir, p)); err != nil {
+			t.Errorf("Stat(%s): %v", p, err)
+		}
+	}
+	if got, err := lr.ReadEntryBundle(ctx, 1234567, 0); err != nil || string(got) != "bundle" {
+		t.Errorf("ReadEntryBundle = %q, %v, want %q", got, err, "bundle")
+	}
+
+	// The layout is recorded, and can't be changed once the log has been created.
+	s = &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: dir, DisableAutoPublish: true}}
+	if _, _, err := s.newAppender(ctx, &logResourceStorage{s: s, entriesPath: opts.EntriesPath()}, opts); err == nil {
+		t.Error("Appender with nested layout succeeded for log created with flat layout")
+	}
+}
+
+func TestShardDigits(t *testing.T) {
+	ctx := t.Context()
+	dir := t.TempDir()
+	sk, _ := mustGenerateKeys(t)
+	opts := tessera.NewAppendOptions().
+		WithCheckpointInterval(10*time.Minute).
+		WithBatching(1, time.Millisecond).
+		WithCheckpointSigner(sk)
+	s := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: dir, DisableAutoPublish: true, ShardDigits: 2}}
+	a, lr, err := s.newAppender(ctx, &logResourceStorage{s: s, entriesPath: opts.EntriesPath()}, opts)
+	if err != nil {
+		t.Fatalf("Appender: %v", err)
+	}
+	if _, err := a.Add(ctx, tessera.NewEntry([]byte("entry")))(); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if _, err := lr.ReadEntryBundle(ctx, 0, 1); err != nil {
+		t.Errorf("ReadEntryBundle: %v", err)
+	}
+	if _, err := lr.ReadTile(ctx, 0, 0, 1); err != nil {
+		t.Errorf("ReadTile: %v", err)
+	}
+
+	// Resources are stored with the configured number of digits per directory.
+	lrs := a.logStorage
+	if err := lrs.writeTile(ctx, 0, 1234567, 0, make([]byte, 32)); err != nil {
+		t.Fatalf("writeTile: %v", err)
+	}
+	if err := lrs.writeBundle(ctx, 1234567, 0, []byte("bundle")); err != nil {
+		t.Fatalf("writeBundle: %v", err)
+	}
+	for _, p := range []string{"tile/0/x01/x23/x45/67", "tile/entries/x01/x23/x45/67", "tile/entries/00.p/1"} {
+		if _, err := os.Stat(filepath.Join(dir, p)); err != nil {
+			t.Errorf("Stat(%s): %v", p, err)
+		}
+	}
+	if got, err := lr.ReadEntryBundle(ctx, 1234567, 0); err != nil || string(got) != "bundle" {
+		t.Errorf("ReadEntryBundle = %q, %v, want %q", got, err, "bundle")
+	}
+	if got, err := s.ListEntryBundles(ctx); err != nil || !slices.Equal(got, []uint64{0, 1234567}) {
+		t.Errorf("ListEntryBundles = %v, %v, want [0 1234567]", got, err)
+	}
+
+	// The layout is recorded, and can't be changed once the log has been created.
+	for _, d := range []int{0, 3, 4} {
+		s = &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: dir, DisableAutoPublish: true, ShardDigits: d}}
+		if _, _, err := s.newAppender(ctx, &logResourceStorage{s: s, entriesPath: opts.EntriesPath()}, opts); err == nil {
+			t.Errorf("Appender with ShardDigits %d succeeded for log created with 2", d)
+		}
+	}
+
+	for _, cfg := range []Config{{Path: dir, ShardDigits: 1}, {Path: dir, ShardDigits: 5}, {Path: dir, ShardDigits: 2, FlatLayout: true}} {
+		if _, err := New(ctx, cfg); err == nil {
+			t.Errorf("New(ShardDigits: %d, FlatLayout: %t) succeeded", cfg.ShardDigits, cfg.FlatLayout)
+		}
+	}
+}
+
+func TestSyncPerBatch(t *testing.T) {
+	ctx := t.Context()
+	sk, _ := mustGenerateKeys(t)
+	opts := tessera.NewAppendOptions().
+		WithCheckpointInterval(10*time.Minute).
+		WithBatching(1000, time.Hour).
+		WithCheckpointSigner(sk)
+	s := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: t.TempDir(), DisableAutoPublish: true, SyncMode: SyncPerBatch}}
+	a, lr, err := s.newAppender(ctx, &logResourceStorage{s: s, entriesPath: opts.EntriesPath()}, opts)
+	if err != nil {
+		t.Fatalf("Appender: %v", err)
+	}
+	// A single batch which spans several entry bundles.
+	const size = 2*layout.EntryBundleWidth + 7
+	for i := range size {
+		a.Add(ctx, tessera.NewEntry(fmt.Appendf(nil, "entry %d", i)))
+	}
+	if err := s.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if got, err := lr.IntegratedSize(ctx); err != nil || got != size {
+		t.Fatalf("IntegratedSize: got (%d, %v), want %d", got, err, size)
+	}
+	for i := range uint64(3) {
+		p := layout.PartialTileSize(0, i, size)
+		raw, err := lr.ReadEntryBundle(ctx, i, p)
+		if err != nil {
+			t.Fatalf("ReadEntryBundle(%d): %v", i, err)
+		}
+		want := int(p)
+		if p == 0 {
+			want = layout.EntryBundleWidth
+		}
+		if got, err := api.ParseEntryBundle(raw); err != nil || len(got) != want {
+			t.Errorf("Entry bundle %d: got %d entries (err: %v), want %d", i, len(got), err, want)
+		}
+	}
+
+	if _, err := New(ctx, Config{Path: t.TempDir(), SyncMode: SyncPerBatch + 1}); err == nil {
+		t.Error("New with unknown SyncMode succeeded")
+	}
+}
+
+func TestClose(t *testing.T) {
+	ctx := t.Context()
+	sk, _ := mustGenerateKeys(t)
+	opts := tessera.NewAppendOptions().
+		WithCheckpointInterval(10*time.Minute).
+		WithBatching(1000, time.Hour).
+		WithCheckpointSigner(sk)
+	s := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: t.TempDir()}}
+	if err := s.Close(ctx); err != nil {
+		t.Fatalf("Close with no appender: %v", err)
+	}
+	a, lr, err := s.newAppender(ctx, &logResourceStorage{s: s, entriesPath: opts.EntriesPath()}, opts)
+	if err != nil {
+		t.Fatalf("Appender: %v", err)
+	}
+	const size = 10
+	futures := make([]tessera.IndexFuture, 0, size)
+	for i := range size {
+		futures = append(futures, a.Add(ctx, tessera.NewEntry(fmt.Appendf(nil, "entry %d", i))))
+	}
+
+	if err := s.Close(ctx); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	for _, f := range futures {
+		if _, err := f(); err != nil {
+			t.Errorf("Add: %v", err)
+		}
+	}
+	cp, err := lr.ReadCheckpoint(ctx)
+	if err != nil {
+		t.Fatalf("ReadCheckpoint: %v", err)
+	}
+	if _, cpSize, _, err := parse.CheckpointUnsafe(cp); err != nil || cpSize != size {
+		t.Errorf("Final checkpoint: got size %d (err: %v), want %d", cpSize, err, size)
+	}
+	if a.publishHeartbeat.Load() != 0 {
+		t.Error("Checkpoint publication job still running after Close")
+	}
+	if _, err := a.Add(ctx, tessera.NewEntry([]byte("too late")))(); !errors.Is(err, ErrClosed) {
+		t.Errorf("Add after Close: got %v, want %v", err, ErrClosed)
+	}
+	if err := s.Close(ctx); err != nil {
+		t.Errorf("Second Close: %v", err)
+	}
+}
+
+func TestHealthCheck(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	clk := newFakeClock()
+	s := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: t.TempDir(), Clock: clk}}
+	sk, _ := mustGenerateKeys(t)
+	opts := tessera.NewAppendOptions().
+		WithCheckpointInterval(10 * time.Minute).
+		WithCheckpointSigner(sk)
+	if _, _, err := s.newAppender(ctx, &logResourceStorage{s: s, entriesPath: opts.EntriesPath()}, opts); err != nil {
+		t.Fatalf("Appender: %v", err)
+	}
+	// eventually waits for the health of the log to settle, since the publication job runs asynchronously.
+	eventually := func(healthy bool) {
+		t.Helper()
+		for {
+			err := s.HealthCheck(t.Context())
+			if (err == nil) == healthy {
+				return
+			}
+			select {
+			case <-t.Context().Done():
+				t.Fatalf("HealthCheck: got %v, want healthy=%t", err, healthy)
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}
+
+	if err := s.HealthCheck(t.Context()); err != nil {
+		t.Fatalf("HealthCheck: %v", err)
+	}
+
+	// The publication job hasn't run for too long.
+	clk.Advance(30 * time.Minute)
+	if err := s.HealthCheck(t.Context()); err == nil {
+		t.Error("HealthCheck with stale publication job: got nil error")
+	}
+	clk.Tick(t.Context())
+	eventually(true)
+
+	// The tree state is corrupt.
+	p := filepath.Join(s.cfg.Path, s.stateDir(), treeStateFile)
+	raw, err := os.ReadFile(p)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if err := os.WriteFile(p, []byte("garbage"), filePerm); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := s.HealthCheck(t.Context()); err == nil {
+		t.Error("HealthCheck with corrupt tree state: got nil error")
+	}
+	if err := os.WriteFile(p, raw, filePerm); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	// The publication job has stopped.
+	cancel()
+	eventually(false)
+}
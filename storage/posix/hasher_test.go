@@ -0,0 +1,124 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package posix
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/transparency-dev/merkle/compact"
+	"github.com/transparency-dev/tessera"
+)
+
+// sha512_256Hasher is an RFC6962-style hasher which uses SHA-512/256.
+type sha512_256Hasher struct{}
+
+func (sha512_256Hasher) EmptyRoot() []byte {
+	h := sha512.Sum512_256(nil)
+	return h[:]
+}
+
+func (sha512_256Hasher) HashLeaf(leaf []byte) []byte {
+	h := sha512.Sum512_256(append([]byte{0}, leaf...))
+	return h[:]
+}
+
+func (sha512_256Hasher) HashChildren(l, r []byte) []byte {
+	h := sha512.Sum512_256(append(append([]byte{1}, l...), r...))
+	return h[:]
+}
+
+func (sha512_256Hasher) Size() int {
+	return sha512.Size256
+}
+
+func TestCustomHasher(t *testing.T) {
+	ctx := t.Context()
+	dir := t.TempDir()
+	h := sha512_256Hasher{}
+	sk, _ := mustGenerateKeys(t)
+	opts := tessera.NewAppendOptions().
+		WithCheckpointInterval(10*time.Minute).
+		WithBatching(100, time.Hour).
+		WithCheckpointSigner(sk).
+		WithHasher("sha512_256", h)
+
+	s := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: dir}}
+	a, _, _, err := tessera.NewAppender(ctx, s, opts)
+	if err != nil {
+		t.Fatalf("NewAppender: %v", err)
+	}
+	if _, root, err := s.TreeState(ctx); err != nil || !bytes.Equal(root, h.EmptyRoot()) {
+		t.Fatalf("TreeState = %x, %v, want empty root %x", root, err, h.EmptyRoot())
+	}
+
+	cr := (&compact.RangeFactory{Hash: h.HashChildren}).NewEmptyRange(0)
+	for i := range 300 {
+		data := fmt.Appendf(nil, "entry %d", i)
+		a.Add(ctx, tessera.NewEntry(data))
+		if err := cr.Append(h.HashLeaf(data), nil); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+		if i%100 == 0 {
+			if err := s.Flush(ctx); err != nil {
+				t.Fatalf("Flush: %v", err)
+			}
+		}
+	}
+	if err := s.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	wantRoot, err := cr.GetRootHash(nil)
+	if err != nil {
+		t.Fatalf("GetRootHash: %v", err)
+	}
+	if size, root, err := s.TreeState(ctx); err != nil || size != 300 || !bytes.Equal(root, wantRoot) {
+		t.Errorf("TreeState = (%d, %x), %v, want (300, %x)", size, root, err, wantRoot)
+	}
+
+	// Reopening the log with the default hasher must fail.
+	s2 := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: dir}}
+	defaultOpts := tessera.NewAppendOptions().
+		WithCheckpointInterval(10 * time.Minute).
+		WithCheckpointSigner(sk)
+	if _, _, err := s2.newAppender(ctx, &logResourceStorage{s: s2, entriesPath: defaultOpts.EntriesPath()}, defaultOpts); !errors.Is(err, ErrHasherMismatch) {
+		t.Errorf("newAppender with default hasher = %v, want ErrHasherMismatch", err)
+	}
+
+	// Operations which only support the default hasher must refuse to run.
+	if _, _, err := s2.MigrationWriter(ctx, tessera.NewMigrationOptions()); !errors.Is(err, ErrHasherMismatch) {
+		t.Errorf("MigrationWriter = %v, want ErrHasherMismatch", err)
+	}
+	if err := s2.Verify(ctx); !errors.Is(err, ErrHasherMismatch) {
+		t.Errorf("Verify = %v, want ErrHasherMismatch", err)
+	}
+	if _, _, err := s2.IntegrateLeafHashes(ctx, 300, [][]byte{h.HashLeaf([]byte("more"))}); !errors.Is(err, ErrHasherMismatch) {
+		t.Errorf("IntegrateLeafHashes = %v, want ErrHasherMismatch", err)
+	}
+	if _, err := s2.ConsistencyProof(ctx, 10, 300); !errors.Is(err, ErrHasherMismatch) {
+		t.Errorf("ConsistencyProof = %v, want ErrHasherMismatch", err)
+	}
+	if _, err := s2.InclusionProof(ctx, 10, 300); !errors.Is(err, ErrHasherMismatch) {
+		t.Errorf("InclusionProof = %v, want ErrHasherMismatch", err)
+	}
+	if _, err := s2.ReadTileAtSize(ctx, 0, 0, 300); !errors.Is(err, ErrHasherMismatch) {
+		t.Errorf("ReadTileAtSize = %v, want ErrHasherMismatch", err)
+	}
+}
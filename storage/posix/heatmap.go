@@ -0,0 +1,73 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package posix
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// tileHeatmap keeps a sampled count of reads for each tile.
+type tileHeatmap struct {
+	// rate is the sampling rate; one in every rate reads is recorded.
+	rate uint64
+	// n is the total number of reads seen, used to decide which reads to sample.
+	n atomic.Uint64
+
+	mu     sync.Mutex
+	counts map[TileID]uint64
+}
+
+func newTileHeatmap(rate uint) *tileHeatmap {
+	return &tileHeatmap{
+		rate:   uint64(rate),
+		counts: make(map[TileID]uint64),
+	}
+}
+
+// record notes a read of the tile at the given coordinates, if this read is selected by sampling.
+//
+// Sampled reads are weighted by the sampling rate so that the recorded counts approximate the
+// true number of reads.
+func (h *tileHeatmap) record(level, index uint64) {
+	if h.n.Add(1)%h.rate != 0 {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.counts[TileID{Level: level, Index: index}] += h.rate
+}
+
+// snapshot returns the current counts, and starts counting afresh so that the number of tiles
+// tracked is bounded by the number read between snapshots.
+func (h *tileHeatmap) snapshot() map[TileID]uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	r := h.counts
+	h.counts = make(map[TileID]uint64)
+	return r
+}
+
+// TileHeatmap returns the approximate number of reads seen for each tile since the previous call to
+// TileHeatmap, or since the storage was created if there was none.
+//
+// Tile read tracking is disabled by default, in which case nil is returned. It can be enabled via
+// the TileReadSampleRate field in Config.
+func (s *Storage) TileHeatmap() map[TileID]uint64 {
+	if s.heatmap == nil {
+		return nil
+	}
+	return s.heatmap.snapshot()
+}
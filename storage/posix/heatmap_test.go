@@ -0,0 +1,69 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package posix
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/transparency-dev/tessera/api/layout"
+)
+
+func TestTileHeatmap(t *testing.T) {
+	ctx := t.Context()
+
+	for _, test := range []struct {
+		name       string
+		sampleRate uint
+		reads      []TileID
+		want       map[TileID]uint64
+	}{
+		{
+			name:  "disabled",
+			reads: []TileID{{Level: 0, Index: 0}, {Level: 1, Index: 0}},
+			want:  nil,
+		}, {
+			name:       "every read",
+			sampleRate: 1,
+			reads:      []TileID{{Level: 0, Index: 0}, {Level: 0, Index: 1}, {Level: 1, Index: 0}, {Level: 0, Index: 1}},
+			want:       map[TileID]uint64{{Level: 0, Index: 0}: 1, {Level: 0, Index: 1}: 2, {Level: 1, Index: 0}: 1},
+		}, {
+			name:       "sampled",
+			sampleRate: 2,
+			reads:      []TileID{{Level: 0, Index: 0}, {Level: 0, Index: 1}, {Level: 0, Index: 0}, {Level: 0, Index: 1}},
+			want:       map[TileID]uint64{{Level: 0, Index: 1}: 4},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			d, err := New(ctx, Config{Path: t.TempDir(), TileReadSampleRate: test.sampleRate})
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			s := d.(*Storage)
+			lrs := &logResourceStorage{s: s, entriesPath: layout.EntriesPath}
+			for _, r := range test.reads {
+				// We don't care whether the tile exists, only that the read was attempted.
+				_, _ = lrs.ReadTile(ctx, r.Level, r.Index, 0)
+			}
+			if d := cmp.Diff(test.want, s.TileHeatmap()); d != "" {
+				t.Fatalf("TileHeatmap() diff (-want +got):\n%s", d)
+			}
+			// Counts are reset by each call.
+			if got := s.TileHeatmap(); len(got) != 0 {
+				t.Errorf("Second TileHeatmap() = %v, want empty", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package posix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+
+	"github.com/transparency-dev/tessera/internal/otel"
+	storage "github.com/transparency-dev/tessera/storage/internal"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// checkpointHistoryDir is the directory, relative to the log root, in which historical checkpoints are stored.
+const checkpointHistoryDir = "checkpoints"
+
+// checkpointHistoryPath returns the path, relative to the log root, of the historical checkpoint for the given size.
+func checkpointHistoryPath(size uint64) string {
+	return filepath.Join(checkpointHistoryDir, strconv.FormatUint(size, 10))
+}
+
+// ReadCheckpointAtSize returns the most recently published checkpoint for a tree of the given size.
+//
+// Checkpoints are only retained if Config.CheckpointHistory is non-zero, and only for the most recent
+// sizes; the error returned for other sizes wraps tessera.ErrNotFound.
+func (s *Storage) ReadCheckpointAtSize(ctx context.Context, size uint64) ([]byte, error) {
+	return otel.Trace(ctx, "tessera.storage.posix.ReadCheckpointAtSize", s.tracer(), func(ctx context.Context, span trace.Span) ([]byte, error) {
+		r, err := s.readAll(checkpointHistoryPath(size))
+		return r, storage.WrapNotFound(err)
+	})
+}
+
+// archiveCheckpoint stores a copy of the checkpoint for a tree of the given size in the checkpoint
+// history, and prunes the history down to the configured number of checkpoints.
+//
+// The caller must hold the publish lock.
+func (s *Storage) archiveCheckpoint(size uint64, cpRaw []byte) error {
+	if err := s.createOverwrite(checkpointHistoryPath(size), cpRaw); err != nil {
+		return fmt.Errorf("failed to store checkpoint: %v", err)
+	}
+	entries, err := os.ReadDir(filepath.Join(s.cfg.Path, checkpointHistoryDir))
+	if err != nil {
+		return fmt.Errorf("failed to list checkpoint history: %v", err)
+	}
+	sizes := make([]uint64, 0, len(entries))
+	for _, e := range entries {
+		// Ignore anything which isn't a historical checkpoint, e.g. temporary files.
+		if n, err := strconv.ParseUint(e.Name(), 10, 64); err == nil && e.Type().IsRegular() {
+			sizes = append(sizes, n)
+		}
+	}
+	if len(sizes) <= int(s.cfg.CheckpointHistory) {
+		return nil
+	}
+	slices.Sort(sizes)
+	for _, n := range sizes[:len(sizes)-int(s.cfg.CheckpointHistory)] {
+		if err := os.Remove(filepath.Join(s.cfg.Path, checkpointHistoryPath(n))); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to prune checkpoint for size %d: %v", n, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package posix
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/transparency-dev/formats/log"
+	"github.com/transparency-dev/tessera"
+)
+
+func TestCheckpointHistory(t *testing.T) {
+	ctx := t.Context()
+	s := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: t.TempDir(), CheckpointHistory: 2}}
+	sk, vk := mustGenerateKeys(t)
+	opts := tessera.NewAppendOptions().
+		WithCheckpointInterval(10*time.Minute).
+		WithBatching(100, time.Hour).
+		WithCheckpointSigner(sk)
+	appender, lr, err := s.newAppender(ctx, &logResourceStorage{s: s, entriesPath: opts.EntriesPath()}, opts)
+	if err != nil {
+		t.Fatalf("Appender: %v", err)
+	}
+
+	for i := range 3 {
+		appender.Add(ctx, tessera.NewEntry(fmt.Appendf(nil, "entry %d", i)))
+		if err := s.Flush(ctx); err != nil {
+			t.Fatalf("Flush: %v", err)
+		}
+		if err := appender.publishCheckpoint(ctx, 0, 0); err != nil {
+			t.Fatalf("publishCheckpoint: %v", err)
+		}
+	}
+
+	latest, err := lr.ReadCheckpoint(ctx)
+	if err != nil {
+		t.Fatalf("ReadCheckpoint: %v", err)
+	}
+	if got, err := s.ReadCheckpointAtSize(ctx, 3); err != nil || !bytes.Equal(got, latest) {
+		t.Errorf("ReadCheckpointAtSize(3) = %q, %v, want %q", got, err, latest)
+	}
+	cpRaw, err := s.ReadCheckpointAtSize(ctx, 2)
+	if err != nil {
+		t.Fatalf("ReadCheckpointAtSize(2): %v", err)
+	}
+	if cp, _, _, err := log.ParseCheckpoint(cpRaw, vk.Name(), vk); err != nil || cp.Size != 2 {
+		t.Errorf("ReadCheckpointAtSize(2) returned checkpoint %+v, %v, want size 2", cp, err)
+	}
+	for _, size := range []uint64{0, 1, 4} {
+		if _, err := s.ReadCheckpointAtSize(ctx, size); !errors.Is(err, tessera.ErrNotFound) {
+			t.Errorf("ReadCheckpointAtSize(%d) = %v, want ErrNotFound", size, err)
+		}
+	}
+}
@@ -0,0 +1,394 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package posix
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/transparency-dev/tessera"
+	"github.com/transparency-dev/tessera/internal/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// sequencedStateFile is the name of the file in the state directory which records the entries which have
+	// been sequenced, but not yet integrated, when Config.AsyncIntegration is set.
+	//
+	// It holds a header of two big-endian uint64s, the index of the first entry whose leaf hash is recorded
+	// and the number of leaf hashes recorded, followed by those leaf hashes. Newly sequenced leaf hashes are
+	// appended, and then included by updating the header, so the cost of recording a batch doesn't depend on
+	// how many entries are awaiting integration.
+	sequencedStateFile = "sequencedState"
+	// sequenceLock must be held when sequencing entries or writing to the sequencedState file.
+	sequenceLock = sequencedStateFile + ".lock"
+	// sequencedStateHeaderSize is the size of the header of the sequencedState file.
+	sequencedStateHeaderSize = 16
+
+	// integrationPollInterval is how often the integrator, and callers waiting for entries to be integrated,
+	// check for work done by other processes.
+	integrationPollInterval = time.Second
+)
+
+// lockSequence takes the locks which must be held when sequencing entries or writing to the sequencedState
+// file. As with lockTreeState, a mutex serialises calls within a task, and a lock file distinct tasks.
+//
+// If both are needed, this lock must be taken before the tree state lock.
+//
+// The returned function must be called to release both locks.
+func (s *Storage) lockSequence(ctx context.Context) (func() error, error) {
+	s.seqMu.Lock()
+	unlock, err := s.lockFile(ctx, sequenceLock)
+	if err != nil {
+		s.seqMu.Unlock()
+		return nil, fmt.Errorf("lockFile(%s): %w", sequenceLock, err)
+	}
+	return func() error {
+		defer s.seqMu.Unlock()
+		if err := unlock(); err != nil {
+			return fmt.Errorf("unlock(%s): %w", sequenceLock, err)
+		}
+		return nil
+	}, nil
+}
+
+// lockForSequencing takes the locks which must be held when sequencing entries.
+//
+// When integration is asynchronous this is just the sequence lock, so that sequencing can proceed while
+// the integrator holds the tree state lock. Otherwise, newly sequenced entries are integrated immediately,
+// so the tree state lock is taken too.
+func (a *appender) lockForSequencing(ctx context.Context) (func() error, error) {
+	unlockSeq, err := a.s.lockSequence(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if a.s.cfg.AsyncIntegration {
+		return unlockSeq, nil
+	}
+	unlockTree, err := a.s.lockTreeState(ctx)
+	if err != nil {
+		return nil, errors.Join(err, unlockSeq())
+	}
+	return func() error {
+		return errors.Join(unlockTree(), unlockSeq())
+	}, nil
+}
+
+// readSequencedState returns the number of entries which have been sequenced in a log whose tree has the
+// given size, along with the leaf hashes of those which have not yet been integrated, each of which is
+// hashSize bytes long.
+//
+// If hashSize is zero, no leaf hashes are returned.
+func (s *Storage) readSequencedState(ctx context.Context, treeSize uint64, hashSize int) (uint64, [][]byte, error) {
+	raw, err := s.readAll(filepath.Join(s.stateDir(), sequencedStateFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return treeSize, nil, nil
+		}
+		return 0, nil, fmt.Errorf("failed to read sequenced state: %v", err)
+	}
+	if len(raw) < sequencedStateHeaderSize {
+		return 0, nil, fmt.Errorf("sequenced state is truncated at %d bytes", len(raw))
+	}
+	from, n := binary.BigEndian.Uint64(raw), binary.BigEndian.Uint64(raw[8:])
+	size := from + n
+	if size < treeSize {
+		// The tree has since been grown by an appender which integrates synchronously.
+		return treeSize, nil, nil
+	}
+	if from > treeSize {
+		return 0, nil, fmt.Errorf("sequenced state is missing leaf hashes for entries [%d, %d)", treeSize, from)
+	}
+	if hashSize == 0 {
+		return size, nil, nil
+	}
+	hashes := raw[sequencedStateHeaderSize:]
+	if uint64(len(hashes)) < n*uint64(hashSize) {
+		return 0, nil, fmt.Errorf("sequenced state holds %d bytes of leaf hashes, want %d", len(hashes), n*uint64(hashSize))
+	}
+	// Drop the leaf hashes of any entries which were integrated before the state was last updated.
+	pending := make([][]byte, 0, size-treeSize)
+	for i := treeSize - from; i < n; i++ {
+		pending = append(pending, hashes[i*uint64(hashSize):(i+1)*uint64(hashSize)])
+	}
+	return size, pending, nil
+}
+
+// writeSequencedState replaces the sequenced state with one recording that the entries starting at from
+// have the given leaf hashes, and may not yet be integrated.
+//
+// The caller must hold the sequence lock.
+func (s *Storage) writeSequencedState(from uint64, leafHashes [][]byte) error {
+	raw := binary.BigEndian.AppendUint64(nil, from)
+	raw = binary.BigEndian.AppendUint64(raw, uint64(len(leafHashes)))
+	for _, h := range leafHashes {
+		raw = append(raw, h...)
+	}
+	if err := s.createOverwrite(filepath.Join(s.stateDir(), sequencedStateFile), raw); err != nil {
+		return fmt.Errorf("failed to write sequenced state: %w", err)
+	}
+	return nil
+}
+
+// appendSequencedState records that the entries sequenced at fromSize have the given leaf hashes, and may
+// not yet be integrated. They're appended to the existing sequenced state, if it ends at fromSize, and
+// otherwise it's replaced.
+//
+// The caller must hold the sequence lock.
+func (s *Storage) appendSequencedState(fromSize uint64, leafHashes [][]byte) error {
+	if len(leafHashes) == 0 {
+		return nil
+	}
+	p := filepath.Join(s.cfg.Path, s.stateDir(), sequencedStateFile)
+	hdr := make([]byte, sequencedStateHeaderSize)
+	f, err := os.OpenFile(p, os.O_RDWR, 0)
+	if err == nil {
+		if _, err = f.ReadAt(hdr, 0); err != nil {
+			_ = f.Close()
+		}
+	}
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) || errors.Is(err, io.EOF) {
+			return s.writeSequencedState(fromSize, leafHashes)
+		}
+		return fmt.Errorf("failed to read sequenced state: %v", err)
+	}
+	from, n := binary.BigEndian.Uint64(hdr), binary.BigEndian.Uint64(hdr[8:])
+	if n == 0 || from+n != fromSize {
+		// There's nothing awaiting integration which is worth keeping.
+		return errors.Join(f.Close(), s.writeSequencedState(fromSize, leafHashes))
+	}
+	var buf []byte
+	for _, h := range leafHashes {
+		buf = append(buf, h...)
+	}
+	hashSize := len(leafHashes[0])
+	// The leaf hashes are durable before the header includes them, so a failed write leaves the state as it
+	// was, and is overwritten when retried.
+	err = s.retry(func() error {
+		if _, err := f.WriteAt(buf, sequencedStateHeaderSize+int64(n)*int64(hashSize)); err != nil {
+			return err
+		}
+		if !s.cfg.DisableSyncWrites {
+			if err := f.Sync(); err != nil {
+				return err
+			}
+		}
+		if _, err := f.WriteAt(binary.BigEndian.AppendUint64(nil, n+uint64(len(leafHashes))), 8); err != nil {
+			return err
+		}
+		if !s.cfg.DisableSyncWrites {
+			return f.Sync()
+		}
+		return nil
+	})
+	if err := errors.Join(err, f.Close()); err != nil {
+		return fmt.Errorf("failed to append to sequenced state: %v", err)
+	}
+	return nil
+}
+
+// nextSequence returns the index at which the next entry should be sequenced in a log whose tree has the
+// given size.
+//
+// The caller must hold the sequence lock.
+func (a *appender) nextSequence(ctx context.Context, treeSize uint64) (uint64, error) {
+	if !a.s.cfg.AsyncIntegration {
+		return treeSize, nil
+	}
+	size, _, err := a.s.readSequencedState(ctx, treeSize, 0)
+	return size, err
+}
+
+// recordSequenced records that the entries with the given leaf hashes have been sequenced at fromSize,
+// and prompts the integrator to integrate them.
+//
+// The caller must hold the sequence lock.
+func (a *appender) recordSequenced(ctx context.Context, fromSize uint64, leafHashes [][]byte) error {
+	treeSize, _, err := a.s.readTreeState(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read tree state: %v", err)
+	}
+	size, _, err := a.s.readSequencedState(ctx, treeSize, 0)
+	if err != nil {
+		return err
+	}
+	if size != fromSize {
+		return fmt.Errorf("entries were sequenced at %d, but %d entries have been sequenced", fromSize, size)
+	}
+	for i, h := range leafHashes {
+		if len(h) != a.hasher.Size() {
+			return fmt.Errorf("leaf hash %d has length %d, want %d", i, len(h), a.hasher.Size())
+		}
+	}
+	if err := a.s.appendSequencedState(fromSize, leafHashes); err != nil {
+		return err
+	}
+	// Prompt the integrator, but don't block if there's already an outstanding prompt.
+	select {
+	case a.integrate <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// trimSequencedState drops the leaf hashes of entries which have been integrated from the sequenced state.
+func (a *appender) trimSequencedState(ctx context.Context) (errR error) {
+	unlock, err := a.s.lockSequence(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := unlock(); err != nil && errR == nil {
+			errR = err
+		}
+	}()
+	treeSize, _, err := a.s.readTreeState(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read tree state: %v", err)
+	}
+	size, pending, err := a.s.readSequencedState(ctx, treeSize, a.hasher.Size())
+	if err != nil {
+		return err
+	}
+	return a.s.writeSequencedState(size-uint64(len(pending)), pending)
+}
+
+// integrationJob integrates sequenced entries into the tree whenever prompted to, and periodically in case
+// entries have been sequenced by another process.
+func (a *appender) integrationJob(ctx context.Context) {
+	t := time.NewTicker(integrationPollInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-a.integrate:
+		case <-t.C:
+		}
+		if !a.s.IsLeader() {
+			continue
+		}
+		if err := a.integratePending(ctx); err != nil {
+			a.s.logger().WarnContext(ctx, "Failed to integrate sequenced entries", slog.Any("error", err))
+		}
+	}
+}
+
+// integratePending integrates any sequenced entries which are not yet in the tree.
+//
+// Only the tree state lock is held while integrating, so entries can continue to be sequenced meanwhile.
+func (a *appender) integratePending(ctx context.Context) error {
+	return otel.TraceErr(ctx, "tessera.storage.posix.integratePending", a.s.tracer(), func(ctx context.Context, span trace.Span) error {
+		ctx, cancel := context.WithTimeout(ctx, defaultIntegrationTimeout)
+		defer cancel()
+
+		integrated, err := func() (_ bool, errR error) {
+			unlock, err := a.s.lockTreeState(ctx)
+			if err != nil {
+				return false, err
+			}
+			defer func() {
+				if err := unlock(); err != nil && errR == nil {
+					errR = err
+				}
+			}()
+
+			treeSize, _, err := a.s.readTreeState(ctx)
+			if err != nil {
+				return false, fmt.Errorf("failed to read tree state: %v", err)
+			}
+			_, pending, err := a.s.readSequencedState(ctx, treeSize, a.hasher.Size())
+			if err != nil {
+				return false, err
+			}
+			if len(pending) == 0 {
+				return false, nil
+			}
+			span.SetAttributes(fromSizeKey.Int64(otel.Clamp64(treeSize)), numEntriesKey.Int(len(pending)))
+			return true, a.integrateLeaves(ctx, treeSize, pending)
+		}()
+		if err != nil || !integrated {
+			return err
+		}
+		// The entries are now in the tree, so there's no need to keep their leaf hashes around. This is done
+		// after releasing the tree state lock, since the sequence lock must be taken first.
+		if err := a.trimSequencedState(ctx); err != nil {
+			a.s.logger().WarnContext(ctx, "Failed to trim sequenced state", slog.Any("error", err))
+		}
+		return nil
+	})
+}
+
+// treeGrown returns a channel which is closed the next time this appender grows the tree.
+func (a *appender) treeGrown() <-chan struct{} {
+	a.grownMu.Lock()
+	defer a.grownMu.Unlock()
+	return a.grown
+}
+
+// notifyTreeGrown wakes any callers waiting for the tree to grow.
+func (a *appender) notifyTreeGrown() {
+	a.grownMu.Lock()
+	defer a.grownMu.Unlock()
+	close(a.grown)
+	a.grown = make(chan struct{})
+}
+
+// awaitIntegration blocks until the tree contains at least size entries, or ctx becomes done.
+func (a *appender) awaitIntegration(ctx context.Context, size uint64) error {
+	for {
+		// Take the channel before checking the tree, so that growth in between isn't missed.
+		grown := a.treeGrown()
+		treeSize, _, err := a.s.readTreeState(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to read tree state: %v", err)
+		}
+		if treeSize >= size {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-grown:
+		case <-time.After(integrationPollInterval):
+		}
+	}
+}
+
+// awaitIntegrationDecorator wraps the future for an entry so that, when Config.AsyncIntegration is set, it
+// only resolves once the entry has been integrated into the tree.
+func (a *appender) awaitIntegrationDecorator(ctx context.Context, f tessera.IndexFuture) tessera.IndexFuture {
+	if !a.s.cfg.AsyncIntegration {
+		return f
+	}
+	return func() (tessera.Index, error) {
+		idx, err := f()
+		if err != nil {
+			return idx, err
+		}
+		if err := a.awaitIntegration(ctx, idx.Index+1); err != nil {
+			return tessera.Index{}, fmt.Errorf("entry %d was sequenced, but not integrated: %w", idx.Index, err)
+		}
+		return idx, nil
+	}
+}
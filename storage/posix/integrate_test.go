@@ -0,0 +1,166 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package posix
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/transparency-dev/tessera"
+)
+
+func TestAsyncIntegration(t *testing.T) {
+	ctx := t.Context()
+	s := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: t.TempDir(), DisableAutoPublish: true, AsyncIntegration: true}}
+	sk, _ := mustGenerateKeys(t)
+	opts := tessera.NewAppendOptions().
+		WithCheckpointInterval(10*time.Minute).
+		WithBatching(7, time.Millisecond).
+		WithCheckpointSigner(sk)
+	a, lr, err := s.newAppender(ctx, &logResourceStorage{s: s, entriesPath: opts.EntriesPath()}, opts)
+	if err != nil {
+		t.Fatalf("Appender: %v", err)
+	}
+
+	entries := make([]*tessera.Entry, 0, 50)
+	futures := make([]tessera.IndexFuture, 0, 50)
+	for i := range 50 {
+		entries = append(entries, tessera.NewEntry(fmt.Appendf(nil, "entry %d", i)))
+		futures = append(futures, a.Add(ctx, entries[i]))
+	}
+	for i, f := range futures {
+		idx, err := f()
+		if err != nil || idx.Index != uint64(i) {
+			t.Fatalf("Future %d = %d, %v", i, idx.Index, err)
+		}
+		// Futures only resolve once their entries are in the tree.
+		if size, err := lr.IntegratedSize(ctx); err != nil || size <= idx.Index {
+			t.Fatalf("IntegratedSize = %d, %v after future for entry %d resolved", size, err, idx.Index)
+		}
+	}
+
+	if err := s.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if next, err := lr.NextIndex(ctx); err != nil || next != 50 {
+		t.Errorf("NextIndex = %d, %v, want 50", next, err)
+	}
+	wantRoot := entriesRoot(t, entries)
+	if size, root, err := s.TreeState(ctx); err != nil || size != 50 || !bytes.Equal(root, wantRoot) {
+		t.Errorf("TreeState = %d, %x, %v, want 50, %x", size, root, err, wantRoot)
+	}
+}
+
+func TestAsyncIntegrationRecovery(t *testing.T) {
+	ctx := t.Context()
+	dir := t.TempDir()
+	sk, _ := mustGenerateKeys(t)
+	opts := tessera.NewAppendOptions().
+		WithCheckpointInterval(10*time.Minute).
+		WithBatching(100, time.Millisecond).
+		WithCheckpointSigner(sk)
+
+	// Sequence some entries without the integrator running, as if the process had stopped before they
+	// could be integrated.
+	s := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: dir, DisableAutoPublish: true, AsyncIntegration: true}}
+	actx, cancel := context.WithCancel(ctx)
+	a, lr, err := s.newAppender(actx, &logResourceStorage{s: s, entriesPath: opts.EntriesPath()}, opts)
+	if err != nil {
+		t.Fatalf("Appender: %v", err)
+	}
+	cancel()
+	entries := make([]*tessera.Entry, 0, 20)
+	for i := range 20 {
+		entries = append(entries, tessera.NewEntry(fmt.Appendf(nil, "entry %d", i)))
+	}
+	if err := a.sequenceBatch(ctx, entries); err != nil {
+		t.Fatalf("sequenceBatch: %v", err)
+	}
+	if size, err := lr.IntegratedSize(ctx); err != nil || size != 0 {
+		t.Fatalf("IntegratedSize = %d, %v, want 0", size, err)
+	}
+	if next, err := lr.NextIndex(ctx); err != nil || next != 20 {
+		t.Fatalf("NextIndex = %d, %v, want 20", next, err)
+	}
+
+	// A new appender, even one which integrates synchronously, integrates the pending entries.
+	s = &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: dir, DisableAutoPublish: true}}
+	if _, _, err := s.newAppender(ctx, &logResourceStorage{s: s, entriesPath: opts.EntriesPath()}, opts); err != nil {
+		t.Fatalf("Appender: %v", err)
+	}
+	wantRoot := entriesRoot(t, entries)
+	if size, root, err := s.TreeState(ctx); err != nil || size != 20 || !bytes.Equal(root, wantRoot) {
+		t.Errorf("TreeState = %d, %x, %v, want 20, %x", size, root, err, wantRoot)
+	}
+	if idx, err := s.AddSync(ctx, tessera.NewEntry([]byte("after recovery"))); err != nil || idx != 20 {
+		t.Errorf("AddSync = %d, %v, want 20", idx, err)
+	}
+}
+
+func TestAsyncIntegrationSequencesDuringIntegration(t *testing.T) {
+	ctx := t.Context()
+	s := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: t.TempDir(), DisableAutoPublish: true, AsyncIntegration: true}}
+	sk, _ := mustGenerateKeys(t)
+	opts := tessera.NewAppendOptions().
+		WithCheckpointInterval(10*time.Minute).
+		WithBatching(100, time.Millisecond).
+		WithCheckpointSigner(sk)
+	a, lr, err := s.newAppender(ctx, &logResourceStorage{s: s, entriesPath: opts.EntriesPath()}, opts)
+	if err != nil {
+		t.Fatalf("Appender: %v", err)
+	}
+
+	// Hold the tree state lock, as the integrator does while integrating, and check that sequencing isn't
+	// held up by it.
+	unlock, err := s.lockTreeState(ctx)
+	if err != nil {
+		t.Fatalf("lockTreeState: %v", err)
+	}
+	entries := make([]*tessera.Entry, 0, 30)
+	for b := range 3 {
+		batch := make([]*tessera.Entry, 0, 10)
+		for i := range 10 {
+			batch = append(batch, tessera.NewEntry(fmt.Appendf(nil, "entry %d", 10*b+i)))
+		}
+		entries = append(entries, batch...)
+		sctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		err := a.sequenceBatch(sctx, batch)
+		cancel()
+		if err != nil {
+			t.Fatalf("sequenceBatch %d: %v", b, err)
+		}
+	}
+	if next, err := lr.NextIndex(ctx); err != nil || next != 30 {
+		t.Errorf("NextIndex = %d, %v, want 30", next, err)
+	}
+	if _, pending, err := s.readSequencedState(ctx, 0, a.hasher.Size()); err != nil || len(pending) != 30 {
+		t.Errorf("readSequencedState = %d pending, %v, want 30", len(pending), err)
+	}
+	if err := unlock(); err != nil {
+		t.Fatalf("unlock: %v", err)
+	}
+
+	if err := s.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	wantRoot := entriesRoot(t, entries)
+	if size, root, err := s.TreeState(ctx); err != nil || size != 30 || !bytes.Equal(root, wantRoot) {
+		t.Errorf("TreeState = %d, %x, %v, want 30, %x", size, root, err, wantRoot)
+	}
+}
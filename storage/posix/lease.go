@@ -0,0 +1,128 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package posix
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// ErrNotLeader is returned when entries are added via a storage instance which is configured with a
+// leader lease, but which does not currently hold it.
+var ErrNotLeader = errors.New("not the leader")
+
+const (
+	// leaderLeaseFile records the current holder of the leader lease, and when it expires.
+	leaderLeaseFile = "leaderLease"
+	// leaderLeaseLock must be held when reading or updating the leaderLease file.
+	leaderLeaseLock = leaderLeaseFile + ".lock"
+)
+
+// leaderLease is the serialised form of the leaderLease file.
+type leaderLease struct {
+	Owner string `json:"owner"`
+	// Expiry is the time, in nanoseconds since the Unix epoch, after which the lease is considered abandoned.
+	Expiry int64 `json:"expiry"`
+}
+
+// IsLeader returns true if this storage instance may currently write to the log.
+//
+// If Config.LeaderLease is not set, leader election is disabled and this always returns true.
+func (s *Storage) IsLeader() bool {
+	if s.cfg.LeaderLease <= 0 {
+		return true
+	}
+	return s.clock().Now().UnixNano() < s.leaseExpiry.Load()
+}
+
+// renewLease attempts to take, or extend, the leader lease for this storage instance.
+//
+// The lease is taken if it is not currently held, has expired, or is already held by this instance.
+// Returns true if this instance holds the lease on return.
+func (s *Storage) renewLease(ctx context.Context) (bool, error) {
+	unlock, err := s.lockFile(ctx, leaderLeaseLock)
+	if err != nil {
+		return false, fmt.Errorf("lockFile(%s): %v", leaderLeaseLock, err)
+	}
+	defer func() {
+		if err := unlock(); err != nil {
+			s.logger().WarnContext(ctx, "unlock", slog.String("lock", leaderLeaseLock), slog.Any("error", err))
+		}
+	}()
+
+	// Note the time before doing any I/O so that our view of the expiry is never later than the one we record.
+	now := s.clock().Now()
+	leasePath := filepath.Join(s.stateDir(), leaderLeaseFile)
+	cur := leaderLease{}
+	raw, err := s.readAll(leasePath)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return false, fmt.Errorf("failed to read leader lease: %v", err)
+	default:
+		if err := json.Unmarshal(raw, &cur); err != nil {
+			return false, fmt.Errorf("failed to parse leader lease: %v", err)
+		}
+	}
+
+	if cur.Owner != s.leaseID && cur.Expiry > now.UnixNano() {
+		s.leaseExpiry.Store(0)
+		return false, nil
+	}
+
+	expiry := now.Add(s.cfg.LeaderLease).UnixNano()
+	raw, err = json.Marshal(leaderLease{Owner: s.leaseID, Expiry: expiry})
+	if err != nil {
+		return false, fmt.Errorf("error in Marshal: %v", err)
+	}
+	if err := s.createOverwrite(leasePath, raw); err != nil {
+		s.leaseExpiry.Store(0)
+		return false, fmt.Errorf("failed to write leader lease: %v", err)
+	}
+	if cur.Owner != s.leaseID {
+		s.logger().InfoContext(ctx, "Acquired leader lease", slog.String("owner", s.leaseID))
+	}
+	s.leaseExpiry.Store(expiry)
+	return true, nil
+}
+
+// leaseJob periodically renews the leader lease until ctx is done.
+//
+// Renewal happens several times per lease period so that a leader does not lose the lease because of
+// a single slow or failed renewal.
+func (s *Storage) leaseJob(ctx context.Context) {
+	t := s.clock().NewTicker(s.cfg.LeaderLease / 3)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C():
+		}
+		wasLeader := s.IsLeader()
+		isLeader, err := s.renewLease(ctx)
+		if err != nil {
+			s.logger().WarnContext(ctx, "Failed to renew leader lease", slog.Any("error", err))
+		}
+		if wasLeader && !isLeader && !s.IsLeader() {
+			s.logger().WarnContext(ctx, "Lost leader lease", slog.String("owner", s.leaseID))
+		}
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package posix
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/transparency-dev/tessera"
+)
+
+func TestLeaderLease(t *testing.T) {
+	ctx := t.Context()
+	dir := t.TempDir()
+	if err := mkdirAll(filepath.Join(dir, defaultStateDir), dirPerm); err != nil {
+		t.Fatalf("mkdirAll: %v", err)
+	}
+	const lease = time.Minute
+	clk := newFakeClock()
+	s1 := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: dir, LeaderLease: lease, Clock: clk}, leaseID: "one"}
+	s2 := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: dir, LeaderLease: lease, Clock: clk}, leaseID: "two"}
+
+	if ok, err := s1.renewLease(ctx); err != nil || !ok {
+		t.Fatalf("s1.renewLease: got (%t, %v), want (true, nil)", ok, err)
+	}
+	if ok, err := s2.renewLease(ctx); err != nil || ok {
+		t.Fatalf("s2.renewLease while s1 holds lease: got (%t, %v), want (false, nil)", ok, err)
+	}
+	if !s1.IsLeader() || s2.IsLeader() {
+		t.Fatalf("IsLeader: got (%t, %t), want (true, false)", s1.IsLeader(), s2.IsLeader())
+	}
+	// The holder can renew its own lease.
+	if ok, err := s1.renewLease(ctx); err != nil || !ok {
+		t.Fatalf("s1.renewLease again: got (%t, %v), want (true, nil)", ok, err)
+	}
+
+	// Once s1 stops renewing, its lease expires and s2 can take over.
+	clk.Advance(lease)
+	if s1.IsLeader() {
+		t.Fatal("s1.IsLeader after lease expiry = true, want false")
+	}
+	if ok, err := s2.renewLease(ctx); err != nil || !ok {
+		t.Fatalf("s2.renewLease after expiry: got (%t, %v), want (true, nil)", ok, err)
+	}
+	if ok, err := s1.renewLease(ctx); err != nil || ok {
+		t.Fatalf("s1.renewLease while s2 holds lease: got (%t, %v), want (false, nil)", ok, err)
+	}
+}
+
+func TestAddNotLeader(t *testing.T) {
+	ctx := t.Context()
+	dir := t.TempDir()
+	sk, _ := mustGenerateKeys(t)
+	opts := tessera.NewAppendOptions().
+		WithCheckpointInterval(10*time.Minute).
+		WithBatching(1, minCheckpointInterval).
+		WithCheckpointSigner(sk)
+
+	newAppender := func(id string) *appender {
+		t.Helper()
+		s := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: dir, LeaderLease: time.Hour}, leaseID: id}
+		a, _, err := s.newAppender(ctx, &logResourceStorage{s: s, entriesPath: opts.EntriesPath()}, opts)
+		if err != nil {
+			t.Fatalf("newAppender: %v", err)
+		}
+		return a
+	}
+	leader := newAppender("leader")
+	follower := newAppender("follower")
+
+	if _, err := leader.Add(ctx, tessera.NewEntry([]byte("one")))(); err != nil {
+		t.Fatalf("Add via leader: %v", err)
+	}
+	if _, err := follower.Add(ctx, tessera.NewEntry([]byte("two")))(); !errors.Is(err, ErrNotLeader) {
+		t.Fatalf("Add via follower: got %v, want %v", err, ErrNotLeader)
+	}
+	if _, err := follower.s.AppendFramedBundle(ctx, nil, nil); !errors.Is(err, ErrNotLeader) {
+		t.Fatalf("AppendFramedBundle via follower: got %v, want %v", err, ErrNotLeader)
+	}
+	if _, err := os.Stat(filepath.Join(dir, defaultStateDir, leaderLeaseFile)); err != nil {
+		t.Fatalf("Stat(leader lease): %v", err)
+	}
+}
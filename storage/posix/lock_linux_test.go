@@ -0,0 +1,71 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package posix
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestLockFileCancellation(t *testing.T) {
+	ctx := t.Context()
+	s := &Storage{
+		cfg: Config{
+			HTTPClient: http.DefaultClient,
+			Path:       t.TempDir(),
+		},
+	}
+	if err := mkdirAll(filepath.Join(s.cfg.Path, defaultStateDir), dirPerm); err != nil {
+		t.Fatalf("mkdirAll: %v", err)
+	}
+
+	// Traditional POSIX record locks are per-process, so we can't simulate contention from another
+	// process by simply calling lockFile twice. Open File Description locks, however, do conflict
+	// with record locks held by the same process, so we'll use one of those to hold the lock instead.
+	f, err := os.OpenFile(filepath.Join(s.cfg.Path, defaultStateDir, treeStateLock), os.O_CREATE|os.O_RDWR, filePerm)
+	if err != nil {
+		t.Fatalf("OpenFile: %v", err)
+	}
+	ofd := unix.Flock_t{Type: unix.F_WRLCK, Whence: io.SeekStart}
+	if err := unix.FcntlFlock(f.Fd(), unix.F_OFD_SETLK, &ofd); err != nil {
+		t.Fatalf("F_OFD_SETLK: %v", err)
+	}
+
+	cctx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer cancel()
+	if _, err := s.lockFile(cctx, treeStateLock); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("lockFile with contended lock: got err %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// Releasing the other lock should allow lockFile to succeed.
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	unlock, err := s.lockFile(ctx, treeStateLock)
+	if err != nil {
+		t.Fatalf("lockFile with uncontended lock: %v", err)
+	}
+	if err := unlock(); err != nil {
+		t.Fatalf("unlock: %v", err)
+	}
+}
@@ -0,0 +1,268 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package posix
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/transparency-dev/tessera/api/layout"
+	"github.com/transparency-dev/tessera/internal/otel"
+	"github.com/transparency-dev/tessera/internal/parse"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// defaultTempFileMaxAge is the default value of Config.TempFileMaxAge.
+	defaultTempFileMaxAge = time.Hour
+	// minTempFileAge is the age below which temporary files for targets which don't yet exist are never removed.
+	minTempFileAge = time.Minute
+)
+
+// Compact relinks any partial tiles which are still stored as regular files, despite the corresponding
+// full tile existing, to that full tile.
+//
+// This can happen, for example, for logs written by older versions of this storage, or if the process
+// was interrupted while writing a full tile. Since partial tiles are replaced using an atomic rename,
+// it is safe to call this while the log is being read.
+func (s *Storage) Compact(ctx context.Context) (errR error) {
+	return otel.TraceErr(ctx, "tessera.storage.posix.Compact", s.tracer(), func(ctx context.Context, span trace.Span) error {
+		unlock, err := s.lockTreeState(ctx)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := unlock(); err != nil && errR == nil {
+				errR = err
+			}
+		}()
+
+		n := 0
+		err = filepath.WalkDir(filepath.Join(s.cfg.Path, "tile"), func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() || !strings.HasSuffix(p, ".p") {
+				return nil
+			}
+			full := strings.TrimSuffix(p, ".p")
+			if fi, err := os.Lstat(full); err != nil || !fi.Mode().IsRegular() {
+				// There's no full tile yet, so these partials are still current.
+				return fs.SkipDir
+			}
+			partials, err := os.ReadDir(p)
+			if err != nil {
+				return err
+			}
+			for _, e := range partials {
+				if !e.Type().IsRegular() {
+					continue
+				}
+				if err := s.relinkPartialTile(ctx, filepath.Join(p, e.Name()), full); err != nil {
+					return err
+				}
+				n++
+			}
+			return fs.SkipDir
+		})
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to compact tiles: %v", err)
+		}
+		s.logger().InfoContext(ctx, "Compacted partial tiles", slog.Int("relinked", n))
+		return nil
+	})
+}
+
+// CleanupTempFiles removes temporary files and links, left behind by writes which were interrupted by the
+// process exiting, which are older than Config.TempFileMaxAge.
+//
+// As a safety measure, temporary files which were modified within the last minute are never removed if the
+// file they were intended to become does not exist, regardless of Config.TempFileMaxAge.
+func (s *Storage) CleanupTempFiles(ctx context.Context) (errR error) {
+	return otel.TraceErr(ctx, "tessera.storage.posix.CleanupTempFiles", s.tracer(), func(ctx context.Context, span trace.Span) error {
+		unlock, err := s.lockTreeState(ctx)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := unlock(); err != nil && errR == nil {
+				errR = err
+			}
+		}()
+
+		maxAge := s.cfg.TempFileMaxAge
+		if maxAge <= 0 {
+			maxAge = defaultTempFileMaxAge
+		}
+		now := time.Now()
+		n := 0
+		err = filepath.WalkDir(s.cfg.Path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			var target string
+			switch {
+			case d.IsDir():
+				return nil
+			case strings.HasSuffix(p, tempSuffix):
+				// Temporary files are named <target>.<random number>.temp
+				target = strings.TrimSuffix(p, tempSuffix)
+				if i := strings.LastIndex(target, "."); i >= 0 {
+					target = target[:i]
+				}
+			case strings.HasSuffix(p, linkSuffix):
+				target = strings.TrimSuffix(p, linkSuffix)
+			default:
+				return nil
+			}
+			fi, err := d.Info()
+			if err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+			age := now.Sub(fi.ModTime())
+			if age < maxAge {
+				return nil
+			}
+			if _, err := os.Lstat(target); errors.Is(err, os.ErrNotExist) && age < minTempFileAge {
+				return nil
+			}
+			s.logger().DebugContext(ctx, "Removing abandoned temporary file", slog.String("path", p), slog.Duration("age", age))
+			if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+			n++
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to clean up temporary files: %v", err)
+		}
+		s.logger().InfoContext(ctx, "Removed abandoned temporary files", slog.Int("removed", n))
+		return nil
+	})
+}
+
+// ListEntryBundles returns the sorted indices of all entry bundles, full or partial, which are present on disk
+// under tile/entries, regardless of the size of the tree.
+//
+// This is intended as a diagnostic, e.g. to find gaps in incomplete copies of a log. Files which are not entry
+// bundles, such as temporary files left behind by interrupted writes, are ignored.
+func (s *Storage) ListEntryBundles(ctx context.Context) ([]uint64, error) {
+	return otel.Trace(ctx, "tessera.storage.posix.ListEntryBundles", s.tracer(), func(ctx context.Context, span trace.Span) ([]uint64, error) {
+		parse := layout.ParseEntriesPath
+		if s.cfg.FlatLayout {
+			parse = layout.ParseFlatEntriesPath
+		} else if d := s.shardDigits(); d != layout.DefaultShardDigits {
+			parse = func(p string) (uint64, uint8, error) { return layout.ParseShardedEntriesPath(d, p) }
+		}
+		seen := make(map[uint64]bool)
+		err := filepath.WalkDir(filepath.Join(s.cfg.Path, "tile", "entries"), func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			rel, err := filepath.Rel(s.cfg.Path, p)
+			if err != nil {
+				return err
+			}
+			idx, _, err := parse(filepath.ToSlash(rel))
+			if err != nil {
+				s.logger().DebugContext(ctx, "ListEntryBundles: ignoring unrecognised file", slog.String("path", p))
+				return nil
+			}
+			seen[idx] = true
+			return nil
+		})
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to list entry bundles: %v", err)
+		}
+		r := slices.Sorted(maps.Keys(seen))
+		return r, nil
+	})
+}
+
+// RollbackTreeState restores the tree state which was replaced by the most recent integration, as retained
+// when Config.RetainPreviousTreeState is set. This is a break-glass tool for recovering from an integration
+// which stored a wrong root, and should only be used while no appender is running against the log.
+//
+// To avoid the log equivocating, this fails if the published checkpoint commits to a tree larger than, or
+// different from, the one being restored. It also fails if there's no retained tree state, and the retained
+// state is discarded once it's been restored, so only a single integration can be rolled back.
+func (s *Storage) RollbackTreeState(ctx context.Context) error {
+	return otel.TraceErr(ctx, "tessera.storage.posix.RollbackTreeState", s.tracer(), func(ctx context.Context, span trace.Span) (errR error) {
+		unlock, err := s.lockTreeState(ctx)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := unlock(); err != nil && errR == nil {
+				errR = err
+			}
+		}()
+
+		prevPath := filepath.Join(s.stateDir(), prevTreeStateFile)
+		raw, err := s.readAll(prevPath)
+		if err != nil {
+			return fmt.Errorf("failed to read previous tree state: %w", err)
+		}
+		prev := &treeState{}
+		if err := json.Unmarshal(raw, prev); err != nil {
+			return fmt.Errorf("failed to parse previous tree state: %v", err)
+		}
+
+		cp, err := s.readAll(layout.CheckpointPath)
+		if err == nil {
+			_, cpSize, cpRoot, err := parse.CheckpointUnsafe(cp)
+			if err != nil {
+				return fmt.Errorf("failed to parse published checkpoint: %v", err)
+			}
+			if cpSize > prev.Size || (cpSize == prev.Size && !bytes.Equal(cpRoot, prev.Root)) {
+				return fmt.Errorf("published checkpoint at size %d commits to a newer tree than the previous tree state at size %d", cpSize, prev.Size)
+			}
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to read published checkpoint: %v", err)
+		}
+
+		if err := s.createOverwrite(filepath.Join(s.stateDir(), treeStateFile), raw); err != nil {
+			return fmt.Errorf("failed to restore tree state: %w", err)
+		}
+		if err := os.Remove(filepath.Join(s.cfg.Path, prevPath)); err != nil {
+			return fmt.Errorf("failed to remove previous tree state: %v", err)
+		}
+		s.logger().WarnContext(ctx, "Rolled back tree state", slog.Uint64("size", prev.Size), slog.String("root", fmt.Sprintf("%x", prev.Root)))
+		return nil
+	})
+}
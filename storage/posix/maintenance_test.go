@@ -0,0 +1,248 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package posix
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/transparency-dev/tessera/api/layout"
+)
+
+func TestCompact(t *testing.T) {
+	ctx := t.Context()
+	// Integrating the log in several batches leaves partial tiles behind, which should be relinked as soon
+	// as the full tile is written.
+	s, _, _ := newProofTestLog(ctx, t, layout.TileWidth+10)
+	partial := filepath.Join(s.cfg.Path, layout.TilePath(0, 0, 78))
+	full := filepath.Join(s.cfg.Path, layout.TilePath(0, 0, 0))
+	isLink := func() bool {
+		t.Helper()
+		fi, err := os.Lstat(partial)
+		if err != nil {
+			t.Fatalf("Lstat: %v", err)
+		}
+		return fi.Mode()&os.ModeSymlink != 0
+	}
+	if !isLink() {
+		t.Fatalf("Partial tile %q was not relinked when the full tile was written", partial)
+	}
+
+	// Simulate a stale partial tile, e.g. left behind by an older version of the storage.
+	raw, err := os.ReadFile(partial)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if err := os.Remove(partial); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if err := os.WriteFile(partial, raw, filePerm); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	// Partial tiles whose full tile doesn't yet exist must be left alone.
+	current := filepath.Join(s.cfg.Path, layout.TilePath(0, 1, 10))
+
+	if err := s.Compact(ctx); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+	if !isLink() {
+		t.Errorf("Partial tile %q was not relinked by Compact", partial)
+	}
+	got, err := os.ReadFile(partial)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	want, err := os.ReadFile(full)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("Relinked partial tile does not contain the full tile")
+	}
+	if fi, err := os.Lstat(current); err != nil || !fi.Mode().IsRegular() {
+		t.Errorf("Current partial tile %q: got (%v, %v), want regular file", current, fi, err)
+	}
+}
+
+func TestCleanupTempFiles(t *testing.T) {
+	ctx := t.Context()
+	s, _, _ := newProofTestLog(ctx, t, 10)
+	s.cfg.TempFileMaxAge = 10 * time.Minute
+
+	old := time.Now().Add(-time.Hour)
+	for _, test := range []struct {
+		name        string
+		path        string
+		modTime     time.Time
+		wantRemoved bool
+	}{
+		{
+			name:        "old temp file",
+			path:        layout.TilePath(0, 0, 10) + ".123" + tempSuffix,
+			modTime:     old,
+			wantRemoved: true,
+		}, {
+			name:        "old temp file without target",
+			path:        layout.TilePath(0, 1, 0) + ".456" + tempSuffix,
+			modTime:     old,
+			wantRemoved: true,
+		}, {
+			name:        "old link",
+			path:        layout.TilePath(0, 0, 1) + linkSuffix,
+			modTime:     old,
+			wantRemoved: true,
+		}, {
+			name:    "recent temp file",
+			path:    layout.TilePath(0, 0, 10) + ".789" + tempSuffix,
+			modTime: time.Now().Add(-time.Minute),
+		}, {
+			name:    "old non-temp file",
+			path:    layout.TilePath(0, 0, 10),
+			modTime: old,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			p := filepath.Join(s.cfg.Path, test.path)
+			if _, err := os.Stat(p); errors.Is(err, os.ErrNotExist) {
+				if err := os.MkdirAll(filepath.Dir(p), dirPerm); err != nil {
+					t.Fatalf("MkdirAll: %v", err)
+				}
+				if err := os.WriteFile(p, []byte("temp"), filePerm); err != nil {
+					t.Fatalf("WriteFile: %v", err)
+				}
+			}
+			if err := os.Chtimes(p, test.modTime, test.modTime); err != nil {
+				t.Fatalf("Chtimes: %v", err)
+			}
+
+			if err := s.CleanupTempFiles(ctx); err != nil {
+				t.Fatalf("CleanupTempFiles: %v", err)
+			}
+			_, err := os.Stat(p)
+			if gotRemoved := errors.Is(err, os.ErrNotExist); gotRemoved != test.wantRemoved {
+				t.Errorf("Removed %q: %t, want %t", test.path, gotRemoved, test.wantRemoved)
+			}
+		})
+	}
+}
+
+func TestCleanupTempFilesSafety(t *testing.T) {
+	ctx := t.Context()
+	s, _, _ := newProofTestLog(ctx, t, 10)
+	// Even with a tiny maximum age, recent temporary files for targets which don't exist must be kept.
+	s.cfg.TempFileMaxAge = time.Nanosecond
+
+	p := filepath.Join(s.cfg.Path, layout.TilePath(0, 1, 0)+".123"+tempSuffix)
+	if err := os.MkdirAll(filepath.Dir(p), dirPerm); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	if err := os.WriteFile(p, []byte("temp"), filePerm); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := s.CleanupTempFiles(ctx); err != nil {
+		t.Fatalf("CleanupTempFiles: %v", err)
+	}
+	if _, err := os.Stat(p); err != nil {
+		t.Errorf("Recent temp file was removed: %v", err)
+	}
+}
+
+func TestListEntryBundles(t *testing.T) {
+	ctx := t.Context()
+	s, _, _ := newProofTestLog(ctx, t, layout.TileWidth+10)
+
+	// Simulate a bundle copied beyond the end of the tree, and a temporary file left behind by a write.
+	for _, p := range []string{layout.EntriesPath(3, 0), layout.EntriesPath(2, 5) + ".12345.temp"} {
+		p = filepath.Join(s.cfg.Path, p)
+		if err := os.MkdirAll(filepath.Dir(p), dirPerm); err != nil {
+			t.Fatalf("MkdirAll: %v", err)
+		}
+		if err := os.WriteFile(p, nil, filePerm); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+
+	got, err := s.ListEntryBundles(ctx)
+	if err != nil {
+		t.Fatalf("ListEntryBundles: %v", err)
+	}
+	if want := []uint64{0, 1, 3}; !slices.Equal(got, want) {
+		t.Errorf("ListEntryBundles: got %v, want %v", got, want)
+	}
+
+	empty := &Storage{cfg: Config{Path: t.TempDir()}}
+	if got, err := empty.ListEntryBundles(ctx); err != nil || len(got) != 0 {
+		t.Errorf("ListEntryBundles on empty log: got (%v, %v), want no bundles", got, err)
+	}
+}
+
+func TestRollbackTreeState(t *testing.T) {
+	ctx := t.Context()
+	s := &Storage{cfg: Config{Path: t.TempDir(), RetainPreviousTreeState: true}}
+	if err := os.MkdirAll(filepath.Join(s.cfg.Path, s.stateDir()), dirPerm); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	publish := func(size uint64, root []byte) {
+		t.Helper()
+		cp := fmt.Sprintf("example.com/log\n%d\n%s\n", size, base64.StdEncoding.EncodeToString(root))
+		if err := s.createOverwrite(layout.CheckpointPath, []byte(cp)); err != nil {
+			t.Fatalf("createOverwrite: %v", err)
+		}
+	}
+	r1, r2 := []byte("root one"), []byte("root two")
+
+	if err := s.RollbackTreeState(ctx); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("RollbackTreeState with no previous state: got %v, want %v", err, os.ErrNotExist)
+	}
+	for i, r := range [][]byte{r1, r2} {
+		if err := s.writeTreeState(ctx, uint64(i+1), r); err != nil {
+			t.Fatalf("writeTreeState: %v", err)
+		}
+	}
+
+	// A published checkpoint committing to the newer tree must prevent the rollback.
+	publish(2, r2)
+	if err := s.RollbackTreeState(ctx); err == nil {
+		t.Error("RollbackTreeState with newer checkpoint published: got nil error")
+	}
+	// As must one committing to a different tree of the same size.
+	publish(1, r2)
+	if err := s.RollbackTreeState(ctx); err == nil {
+		t.Error("RollbackTreeState with conflicting checkpoint published: got nil error")
+	}
+
+	publish(1, r1)
+	if err := s.RollbackTreeState(ctx); err != nil {
+		t.Fatalf("RollbackTreeState: %v", err)
+	}
+	size, root, err := s.readTreeState(ctx)
+	if err != nil {
+		t.Fatalf("readTreeState: %v", err)
+	}
+	if size != 1 || !bytes.Equal(root, r1) {
+		t.Errorf("readTreeState after rollback: got (%d, %q), want (1, %q)", size, root, r1)
+	}
+	// Only a single integration can be rolled back.
+	if err := s.RollbackTreeState(ctx); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Second RollbackTreeState: got %v, want %v", err, os.ErrNotExist)
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package posix
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/transparency-dev/tessera/internal/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// metadataFile is the name of the file in the state directory which holds the log's descriptive metadata.
+const metadataFile = "metadata.json"
+
+// Metadata returns the descriptive metadata which was configured via Config.Metadata when the log was
+// created. An empty map is returned if the log has no metadata.
+func (s *Storage) Metadata(ctx context.Context) (map[string]string, error) {
+	return otel.Trace(ctx, "tessera.storage.posix.Metadata", s.tracer(), func(ctx context.Context, span trace.Span) (map[string]string, error) {
+		raw, err := s.readAll(filepath.Join(s.stateDir(), metadataFile))
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return map[string]string{}, nil
+			}
+			return nil, fmt.Errorf("failed to read metadata: %v", err)
+		}
+		r := map[string]string{}
+		if err := json.Unmarshal(raw, &r); err != nil {
+			return nil, fmt.Errorf("failed to parse metadata: %v", err)
+		}
+		return r, nil
+	})
+}
+
+// writeMetadata stores the configured metadata in the state directory, if there is any.
+//
+// This is only called when the log is created; the metadata plays no part in the operation of the log.
+func (s *Storage) writeMetadata() error {
+	if len(s.cfg.Metadata) == 0 {
+		return nil
+	}
+	raw, err := json.Marshal(s.cfg.Metadata)
+	if err != nil {
+		return fmt.Errorf("failed to marshal metadata: %v", err)
+	}
+	// Overwrite rather than failing if the file exists, in case a previous attempt to create the log was interrupted.
+	if err := s.createOverwrite(filepath.Join(s.stateDir(), metadataFile), raw); err != nil {
+		return fmt.Errorf("failed to write metadata file: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package posix
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/transparency-dev/tessera"
+)
+
+func TestMetadata(t *testing.T) {
+	ctx := t.Context()
+	dir := t.TempDir()
+	sk, _ := mustGenerateKeys(t)
+	opts := tessera.NewAppendOptions().
+		WithCheckpointInterval(10 * time.Minute).
+		WithCheckpointSigner(sk)
+
+	newStorage := func(md map[string]string) *Storage {
+		t.Helper()
+		s := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: dir, Metadata: md}}
+		if _, _, err := s.newAppender(ctx, &logResourceStorage{s: s, entriesPath: opts.EntriesPath()}, opts); err != nil {
+			t.Fatalf("newAppender: %v", err)
+		}
+		return s
+	}
+
+	want := map[string]string{"name": "Test Log", "environment": "test"}
+	s := newStorage(want)
+	got, err := s.Metadata(ctx)
+	if err != nil {
+		t.Fatalf("Metadata: %v", err)
+	}
+	if d := cmp.Diff(want, got); d != "" {
+		t.Errorf("Metadata diff (-want +got):\n%s", d)
+	}
+
+	// Metadata configured when opening an existing log is ignored.
+	s = newStorage(map[string]string{"name": "Other"})
+	got, err = s.Metadata(ctx)
+	if err != nil {
+		t.Fatalf("Metadata after reopening: %v", err)
+	}
+	if d := cmp.Diff(want, got); d != "" {
+		t.Errorf("Metadata after reopening diff (-want +got):\n%s", d)
+	}
+
+	// A log created without metadata has none.
+	s = &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: t.TempDir()}}
+	if _, _, err := s.newAppender(ctx, &logResourceStorage{s: s, entriesPath: opts.EntriesPath()}, opts); err != nil {
+		t.Fatalf("newAppender: %v", err)
+	}
+	got, err = s.Metadata(ctx)
+	if err != nil {
+		t.Fatalf("Metadata without metadata: %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("Metadata without metadata = %v, want empty", got)
+	}
+}
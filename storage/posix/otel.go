@@ -30,15 +30,24 @@ var (
 	meter  = otel.Meter(name)
 	tracer = otel.Tracer(name)
 
-	errorTypeKey  = attribute.Key("error.type")
-	filenameKey   = attribute.Key("file.name")
-	numEntriesKey = attribute.Key("tessera.numEntries")
-	opNameKey     = attribute.Key("op_name")
+	cacheHitKey    = attribute.Key("tessera.cacheHit")
+	filenameKey    = attribute.Key("file.name")
+	fromSizeKey    = attribute.Key("tessera.fromSize")
+	indexKey       = attribute.Key("tessera.index")
+	numEntriesKey  = attribute.Key("tessera.numEntries")
+	numTilesKey    = attribute.Key("tessera.numTiles")
+	opNameKey      = attribute.Key("op_name")
+	outcomeTypeKey = attribute.Key("tessera.publishCheckpoint.outcome")
+	partialKey     = attribute.Key("tessera.partial")
+	treeSizeKey    = attribute.Key("tessera.treeSize")
 )
 
 var (
-	publishCount      metric.Int64Counter
-	posixOpsHistogram metric.Int64Histogram
+	publishCount        metric.Int64Counter
+	publishLagHistogram metric.Int64Histogram
+	posixOpsHistogram   metric.Int64Histogram
+	bundleWriteCount    metric.Int64Counter
+	batchSizeHistogram  metric.Int64Histogram
 
 	// Custom histogram buckets as we're interested in low-millis upto low-seconds.
 	histogramBuckets = []float64{0, 1, 2, 5, 10, 20, 50, 100, 200, 300, 400, 500, 600, 700, 800, 900, 1000, 1200, 1400, 1600, 1800, 2000, 2500, 3000, 4000, 5000, 6000, 8000, 10000}
@@ -59,10 +68,38 @@ func init() {
 
 	publishCount, err = meter.Int64Counter(
 		"tessera.appender.checkpoint.publication.counter",
-		metric.WithDescription("Number of checkpoint publication attempts by result"),
+		metric.WithDescription("Number of checkpoint publication attempts by outcome (success, skipped, skipped_no_growth, or error)"),
 		metric.WithUnit("{call}"))
 	if err != nil {
 		slog.ErrorContext(context.Background(), "Failed to create checkpoint publication counter metric", slog.Any("error", err))
 		os.Exit(1)
 	}
+
+	publishLagHistogram, err = meter.Int64Histogram(
+		"tessera.appender.checkpoint.publication.lag",
+		metric.WithDescription("Time between the tree state being updated and a checkpoint committing to it being published"),
+		metric.WithUnit("ms"),
+		metric.WithExplicitBucketBoundaries(histogramBuckets...))
+	if err != nil {
+		slog.ErrorContext(context.Background(), "Failed to create publishLagHistogram metric", slog.Any("error", err))
+		os.Exit(1)
+	}
+
+	bundleWriteCount, err = meter.Int64Counter(
+		"tessera.appender.entry_bundle.writes",
+		metric.WithDescription("Number of entry bundles written, including partial bundles"),
+		metric.WithUnit("{bundle}"))
+	if err != nil {
+		slog.ErrorContext(context.Background(), "Failed to create bundleWriteCount metric", slog.Any("error", err))
+		os.Exit(1)
+	}
+
+	batchSizeHistogram, err = meter.Int64Histogram(
+		"tessera.appender.sequence.batch_size",
+		metric.WithDescription("Number of entries in each batch sequenced by the appender"),
+		metric.WithUnit("{entry}"))
+	if err != nil {
+		slog.ErrorContext(context.Background(), "Failed to create batchSizeHistogram metric", slog.Any("error", err))
+		os.Exit(1)
+	}
 }
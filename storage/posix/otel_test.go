@@ -0,0 +1,177 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package posix
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/transparency-dev/tessera"
+	"go.opentelemetry.io/otel"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestAppenderMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+
+	ctx := t.Context()
+	s := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: t.TempDir()}}
+	sk, _ := mustGenerateKeys(t)
+	opts := tessera.NewAppendOptions().
+		WithCheckpointInterval(10*time.Minute).
+		WithBatching(100, time.Hour).
+		WithCheckpointSigner(sk)
+	logStorage := &logResourceStorage{
+		s:           s,
+		entriesPath: opts.EntriesPath(),
+	}
+	appender, _, err := s.newAppender(ctx, logStorage, opts)
+	if err != nil {
+		t.Fatalf("Appender: %v", err)
+	}
+	for i := range 10 {
+		appender.Add(ctx, tessera.NewEntry(fmt.Appendf(nil, "entry %d", i)))
+	}
+	if err := s.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if err := appender.publishCheckpoint(ctx, 0, 0); err != nil {
+		t.Fatalf("publishCheckpoint: %v", err)
+	}
+	// The checkpoint we just published is fresh, so this should be skipped.
+	if err := appender.publishCheckpoint(ctx, time.Hour, 0); err != nil {
+		t.Fatalf("publishCheckpoint: %v", err)
+	}
+	// The tree hasn't grown, so this republishes the checkpoint without recording the publication lag.
+	if err := appender.publishCheckpoint(ctx, 0, time.Nanosecond); err != nil {
+		t.Fatalf("publishCheckpoint: %v", err)
+	}
+
+	rm := metricdata.ResourceMetrics{}
+	if err := reader.Collect(ctx, &rm); err != nil {
+		t.Fatalf("Collect: %v", err)
+	}
+	got := make(map[string]bool)
+	publishOutcomes := make(map[string]int64)
+	var publishLags uint64
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			got[m.Name] = true
+			switch m.Name {
+			case "tessera.appender.checkpoint.publication.counter":
+				for _, dp := range m.Data.(metricdata.Sum[int64]).DataPoints {
+					o, _ := dp.Attributes.Value(outcomeTypeKey)
+					publishOutcomes[o.AsString()] += dp.Value
+				}
+			case "tessera.appender.checkpoint.publication.lag":
+				for _, dp := range m.Data.(metricdata.Histogram[int64]).DataPoints {
+					publishLags += dp.Count
+				}
+			}
+		}
+	}
+	for _, want := range []string{
+		"tessera.appender.sequence.batch_size",
+		"tessera.appender.entry_bundle.writes",
+		"tessera.appender.checkpoint.publication.lag",
+		"tessera.storage.tree_builder.integrate.duration",
+	} {
+		if !got[want] {
+			t.Errorf("Metric %q was not recorded", want)
+		}
+	}
+	for _, want := range []string{"success", "skipped"} {
+		if publishOutcomes[want] == 0 {
+			t.Errorf("Publish outcome %q was not recorded, got %v", want, publishOutcomes)
+		}
+	}
+	// Every publication except the republication of an unchanged tree should record the lag.
+	if got, want := publishLags, uint64(publishOutcomes["success"]-1); got != want {
+		t.Errorf("Got %d publication lags for %d publications, want %d", got, publishOutcomes["success"], want)
+	}
+}
+
+func TestAppenderTracing(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+
+	ctx := t.Context()
+	s := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: t.TempDir(), TracerProvider: tp}}
+	sk, _ := mustGenerateKeys(t)
+	opts := tessera.NewAppendOptions().
+		WithCheckpointInterval(10*time.Minute).
+		WithBatching(100, time.Hour).
+		WithCheckpointSigner(sk)
+	logStorage := &logResourceStorage{
+		s:           s,
+		entriesPath: opts.EntriesPath(),
+	}
+	appender, _, err := s.newAppender(ctx, logStorage, opts)
+	if err != nil {
+		t.Fatalf("Appender: %v", err)
+	}
+	const n = 3
+	for i := range n {
+		appender.Add(ctx, tessera.NewEntry(fmt.Appendf(nil, "entry %d", i)))
+	}
+	if err := s.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	spans := make(map[string][]sdktrace.ReadOnlySpan)
+	for _, s := range sr.Ended() {
+		spans[s.Name()] = append(spans[s.Name()], s)
+	}
+	if got := len(spans["tessera.storage.posix.Add"]); got != n {
+		t.Fatalf("Got %d Add spans, want %d", got, n)
+	}
+	addSpans := make(map[string]bool)
+	for _, s := range spans["tessera.storage.posix.Add"] {
+		addSpans[s.SpanContext().SpanID().String()] = true
+	}
+	flushes := spans["tessera.storage.queue.doFlush"]
+	if len(flushes) != 1 {
+		t.Fatalf("Got %d doFlush spans, want 1", len(flushes))
+	}
+	if got := len(flushes[0].Links()); got != n {
+		t.Errorf("doFlush span has %d links, want %d", got, n)
+	}
+	for _, l := range flushes[0].Links() {
+		if !addSpans[l.SpanContext.SpanID().String()] {
+			t.Errorf("doFlush span has link to unexpected span %v", l.SpanContext.SpanID())
+		}
+	}
+
+	integrates := spans["tessera.storage.posix.integrate"]
+	if len(integrates) != 1 {
+		t.Fatalf("Got %d integrate spans, want 1", len(integrates))
+	}
+	attrs := make(map[string]int64)
+	for _, kv := range integrates[0].Attributes() {
+		attrs[string(kv.Key)] = kv.Value.AsInt64()
+	}
+	if got, want := attrs[string(fromSizeKey)], int64(0); got != want {
+		t.Errorf("integrate span %s = %d, want %d", fromSizeKey, got, want)
+	}
+	if got, want := attrs[string(treeSizeKey)], int64(n); got != want {
+		t.Errorf("integrate span %s = %d, want %d", treeSizeKey, got, want)
+	}
+}
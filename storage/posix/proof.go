@@ -0,0 +1,169 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package posix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/transparency-dev/tessera/api"
+	"github.com/transparency-dev/tessera/api/layout"
+	"github.com/transparency-dev/tessera/client"
+	"github.com/transparency-dev/tessera/internal/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrTreeSizeTooLarge is returned when a proof is requested for a tree size which has not yet been integrated.
+var ErrTreeSizeTooLarge = errors.New("tree size is larger than the integrated tree")
+
+// ConsistencyProof returns an RFC 6962 consistency proof between the trees of size first and second, built
+// from the stored tiles.
+//
+// Proofs can be built for any pair of sizes up to the integrated size of the tree, not only those of
+// published checkpoints.
+//
+// Returns an error wrapping ErrTreeSizeTooLarge if second is larger than the integrated tree, or one
+// wrapping ErrHasherMismatch if the log uses a hasher other than the default.
+func (s *Storage) ConsistencyProof(ctx context.Context, first, second uint64) ([][]byte, error) {
+	return otel.Trace(ctx, "tessera.storage.posix.ConsistencyProof", s.tracer(), func(ctx context.Context, span trace.Span) ([][]byte, error) {
+		span.SetAttributes(fromSizeKey.Int64(otel.Clamp64(first)), treeSizeKey.Int64(otel.Clamp64(second)))
+
+		if first > second {
+			return nil, fmt.Errorf("first tree size %d is larger than second tree size %d", first, second)
+		}
+		pb, err := s.proofBuilder(ctx, second)
+		if err != nil {
+			return nil, err
+		}
+		return pb.ConsistencyProof(ctx, first, second)
+	})
+}
+
+// InclusionProof returns an RFC 6962 inclusion proof for the leaf at index in the tree of the given size,
+// built from the stored tiles.
+//
+// Returns an error wrapping ErrTreeSizeTooLarge if treeSize is larger than the integrated tree, or one
+// wrapping ErrHasherMismatch if the log uses a hasher other than the default.
+func (s *Storage) InclusionProof(ctx context.Context, index, treeSize uint64) ([][]byte, error) {
+	return otel.Trace(ctx, "tessera.storage.posix.InclusionProof", s.tracer(), func(ctx context.Context, span trace.Span) ([][]byte, error) {
+		span.SetAttributes(indexKey.Int64(otel.Clamp64(index)), treeSizeKey.Int64(otel.Clamp64(treeSize)))
+
+		if index >= treeSize {
+			return nil, fmt.Errorf("index %d is outside of tree of size %d", index, treeSize)
+		}
+		pb, err := s.proofBuilder(ctx, treeSize)
+		if err != nil {
+			return nil, err
+		}
+		return pb.InclusionProof(ctx, index)
+	})
+}
+
+// ReadTileAtSize returns the tile at the given level and index as it was in the tree of size atSize.
+//
+// The returned tile holds exactly the nodes which were present at that size, and is read from whichever
+// stored tile covers them: the partial tile written at that size if there is one, or otherwise a larger
+// partial tile or the full tile, whose prefix it is.
+//
+// Returns an error wrapping ErrTreeSizeTooLarge if atSize is larger than the integrated tree, or one
+// wrapping ErrHasherMismatch if the log uses a hasher other than the default.
+func (s *Storage) ReadTileAtSize(ctx context.Context, level, index, atSize uint64) (*api.HashTile, error) {
+	return otel.Trace(ctx, "tessera.storage.posix.ReadTileAtSize", s.tracer(), func(ctx context.Context, span trace.Span) (*api.HashTile, error) {
+		span.SetAttributes(treeSizeKey.Int64(otel.Clamp64(atSize)))
+
+		if err := s.checkDefaultHasher(); err != nil {
+			return nil, err
+		}
+
+		size, _, err := s.readTreeState(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tree state: %v", err)
+		}
+		if atSize > size {
+			return nil, fmt.Errorf("tree size %d, integrated size %d: %w", atSize, size, ErrTreeSizeTooLarge)
+		}
+		if index*layout.TileWidth >= atSize>>(level*layout.TileHeight) {
+			return nil, fmt.Errorf("tile at level %d index %d is not present in tree of size %d", level, index, atSize)
+		}
+
+		p := layout.PartialTileSize(level, index, atSize)
+		raw, err := s.logReader().readProofTile(ctx, level, index, p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tile at level %d index %d: %w", level, index, err)
+		}
+		t := &api.HashTile{}
+		if err := t.UnmarshalText(raw); err != nil {
+			return nil, fmt.Errorf("failed to parse tile at level %d index %d: %v", level, index, err)
+		}
+		want := int(p)
+		if p == 0 {
+			want = layout.TileWidth
+		}
+		if len(t.Nodes) < want {
+			return nil, fmt.Errorf("tile at level %d index %d has %d nodes, want at least %d", level, index, len(t.Nodes), want)
+		}
+		t.Nodes = t.Nodes[:want]
+		return t, nil
+	})
+}
+
+// proofBuilder returns a ProofBuilder for the tree of the given size, which must not be larger than the
+// integrated tree.
+//
+// ProofBuilder only supports the default hasher, so this fails if the log uses another one.
+func (s *Storage) proofBuilder(ctx context.Context, treeSize uint64) (*client.ProofBuilder, error) {
+	if err := s.checkDefaultHasher(); err != nil {
+		return nil, err
+	}
+	size, _, err := s.readTreeState(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tree state: %v", err)
+	}
+	if treeSize > size {
+		return nil, fmt.Errorf("tree size %d, integrated size %d: %w", treeSize, size, ErrTreeSizeTooLarge)
+	}
+	return client.NewProofBuilder(ctx, treeSize, s.logReader().readProofTile)
+}
+
+// readProofTile returns the tile at the given location with at least p nodes.
+//
+// Partial tiles are only written for the tree sizes at which integration happened to stop, so there may be no
+// partial tile of exactly the requested size. Since the nodes in a tile never change once written, a larger
+// partial tile can be used in its place, in the same way that a full tile can.
+func (lrs *logResourceStorage) readProofTile(ctx context.Context, level, index uint64, p uint8) ([]byte, error) {
+	t, err := lrs.ReadTile(ctx, level, index, p)
+	if !errors.Is(err, os.ErrNotExist) || p == 0 {
+		return t, err
+	}
+	partials, rErr := os.ReadDir(filepath.Join(lrs.s.cfg.Path, lrs.s.tilePath(level, index, 0)+".p"))
+	if rErr != nil {
+		return nil, err
+	}
+	for _, e := range partials {
+		// Directory entries are sorted by name rather than numerically, so consider them all.
+		larger, pErr := strconv.ParseUint(e.Name(), 10, 8)
+		if pErr != nil || uint8(larger) <= p {
+			continue
+		}
+		if t, err = lrs.ReadTile(ctx, level, index, uint8(larger)); err == nil {
+			return t, nil
+		}
+	}
+	return nil, err
+}
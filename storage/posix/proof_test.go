@@ -0,0 +1,196 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package posix
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/transparency-dev/merkle/compact"
+	"github.com/transparency-dev/merkle/proof"
+	"github.com/transparency-dev/merkle/rfc6962"
+	"github.com/transparency-dev/tessera"
+	"github.com/transparency-dev/tessera/api/layout"
+)
+
+// newProofTestLog creates a log containing size entries, and returns it along with the leaf hashes of those
+// entries and a function which returns the root hash of the tree at a given size.
+func newProofTestLog(ctx context.Context, t *testing.T, size uint64) (*Storage, [][]byte, func(uint64) []byte) {
+	t.Helper()
+	s := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: t.TempDir()}}
+	sk, _ := mustGenerateKeys(t)
+	opts := tessera.NewAppendOptions().
+		WithCheckpointInterval(10*time.Minute).
+		WithBatching(100, time.Hour).
+		WithCheckpointSigner(sk)
+	appender, _, err := s.newAppender(ctx, &logResourceStorage{s: s, entriesPath: opts.EntriesPath()}, opts)
+	if err != nil {
+		t.Fatalf("Appender: %v", err)
+	}
+
+	lh := make([][]byte, 0, size)
+	for i := range size {
+		e := tessera.NewEntry(fmt.Appendf(nil, "entry %d", i))
+		lh = append(lh, e.LeafHash())
+		appender.Add(ctx, e)
+		// Flush at irregular intervals so that the tree is built from many partial tiles.
+		if i%77 == 0 {
+			if err := s.Flush(ctx); err != nil {
+				t.Fatalf("Flush: %v", err)
+			}
+		}
+	}
+	if err := s.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	rootAt := func(size uint64) []byte {
+		t.Helper()
+		rf := compact.RangeFactory{Hash: rfc6962.DefaultHasher.HashChildren}
+		cr := rf.NewEmptyRange(0)
+		for _, h := range lh[:size] {
+			if err := cr.Append(h, nil); err != nil {
+				t.Fatalf("Append: %v", err)
+			}
+		}
+		r, err := cr.GetRootHash(nil)
+		if err != nil {
+			t.Fatalf("GetRootHash: %v", err)
+		}
+		return r
+	}
+	return s, lh, rootAt
+}
+
+func TestConsistencyProof(t *testing.T) {
+	ctx := t.Context()
+	const size = layout.TileWidth*2 + 13
+	s, _, rootAt := newProofTestLog(ctx, t, size)
+
+	for _, test := range []struct {
+		first, second uint64
+	}{
+		{first: 1, second: 2},
+		{first: 10, second: layout.TileWidth},
+		{first: 100, second: layout.TileWidth + 1},
+		{first: layout.TileWidth, second: size},
+		{first: layout.TileWidth + 7, second: size - 1},
+		{first: size, second: size},
+	} {
+		t.Run(fmt.Sprintf("%d-%d", test.first, test.second), func(t *testing.T) {
+			p, err := s.ConsistencyProof(ctx, test.first, test.second)
+			if err != nil {
+				t.Fatalf("ConsistencyProof: %v", err)
+			}
+			if err := proof.VerifyConsistency(rfc6962.DefaultHasher, test.first, test.second, p, rootAt(test.first), rootAt(test.second)); err != nil {
+				t.Fatalf("VerifyConsistency: %v", err)
+			}
+		})
+	}
+
+	if _, err := s.ConsistencyProof(ctx, 1, size+1); !errors.Is(err, ErrTreeSizeTooLarge) {
+		t.Errorf("ConsistencyProof beyond tree size: %v, want %v", err, ErrTreeSizeTooLarge)
+	}
+	if _, err := s.ConsistencyProof(ctx, 10, 5); err == nil {
+		t.Error("ConsistencyProof with first > second: got nil error, want error")
+	}
+}
+
+func TestInclusionProof(t *testing.T) {
+	ctx := t.Context()
+	const size = layout.TileWidth*2 + 13
+	s, lh, rootAt := newProofTestLog(ctx, t, size)
+
+	for _, test := range []struct {
+		index, treeSize uint64
+	}{
+		{index: 0, treeSize: 1},
+		{index: 0, treeSize: size},
+		{index: 5, treeSize: 100},
+		{index: layout.TileWidth - 1, treeSize: layout.TileWidth},
+		{index: layout.TileWidth, treeSize: layout.TileWidth + 3},
+		{index: 17, treeSize: size - 1},
+		{index: size - 1, treeSize: size},
+	} {
+		t.Run(fmt.Sprintf("%d-%d", test.index, test.treeSize), func(t *testing.T) {
+			p, err := s.InclusionProof(ctx, test.index, test.treeSize)
+			if err != nil {
+				t.Fatalf("InclusionProof: %v", err)
+			}
+			if err := proof.VerifyInclusion(rfc6962.DefaultHasher, test.index, test.treeSize, lh[test.index], p, rootAt(test.treeSize)); err != nil {
+				t.Fatalf("VerifyInclusion: %v", err)
+			}
+		})
+	}
+
+	if _, err := s.InclusionProof(ctx, 1, size+1); !errors.Is(err, ErrTreeSizeTooLarge) {
+		t.Errorf("InclusionProof beyond tree size: %v, want %v", err, ErrTreeSizeTooLarge)
+	}
+	if _, err := s.InclusionProof(ctx, 10, 10); err == nil {
+		t.Error("InclusionProof with index >= treeSize: got nil error, want error")
+	}
+}
+
+func TestReadTileAtSize(t *testing.T) {
+	ctx := t.Context()
+	const size = layout.TileWidth*2 + 13
+	s, lh, _ := newProofTestLog(ctx, t, size)
+
+	for _, test := range []struct {
+		index, atSize uint64
+		want          [][]byte
+	}{
+		// Partial tiles written at exactly this size.
+		{index: 0, atSize: 78, want: lh[:78]},
+		{index: 2, atSize: size, want: lh[2*layout.TileWidth:]},
+		// Sizes at which no partial tile was written, which must be read from a larger partial or full tile.
+		{index: 0, atSize: 100, want: lh[:100]},
+		{index: 1, atSize: layout.TileWidth + 1, want: lh[layout.TileWidth : layout.TileWidth+1]},
+		{index: 2, atSize: size - 1, want: lh[2*layout.TileWidth : size-1]},
+		// Full tiles.
+		{index: 0, atSize: size, want: lh[:layout.TileWidth]},
+		{index: 1, atSize: 2 * layout.TileWidth, want: lh[layout.TileWidth : 2*layout.TileWidth]},
+	} {
+		t.Run(fmt.Sprintf("%d-%d", test.index, test.atSize), func(t *testing.T) {
+			tile, err := s.ReadTileAtSize(ctx, 0, test.index, test.atSize)
+			if err != nil {
+				t.Fatalf("ReadTileAtSize: %v", err)
+			}
+			if len(tile.Nodes) != len(test.want) {
+				t.Fatalf("Got %d nodes, want %d", len(tile.Nodes), len(test.want))
+			}
+			for i := range test.want {
+				if !bytes.Equal(tile.Nodes[i], test.want[i]) {
+					t.Fatalf("Node %d = %x, want %x", i, tile.Nodes[i], test.want[i])
+				}
+			}
+		})
+	}
+
+	if tile, err := s.ReadTileAtSize(ctx, 1, 0, size); err != nil || len(tile.Nodes) != 2 {
+		t.Errorf("ReadTileAtSize(1, 0, %d) = %v, %v, want tile with 2 nodes", size, tile, err)
+	}
+	if _, err := s.ReadTileAtSize(ctx, 0, 0, size+1); !errors.Is(err, ErrTreeSizeTooLarge) {
+		t.Errorf("ReadTileAtSize beyond tree size: %v, want %v", err, ErrTreeSizeTooLarge)
+	}
+	if _, err := s.ReadTileAtSize(ctx, 0, 1, layout.TileWidth); err == nil {
+		t.Error("ReadTileAtSize for tile not in tree: got nil error, want error")
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package posix
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"syscall"
+	"time"
+)
+
+// defaultRetryBaseDelay is the default value of Config.RetryBaseDelay.
+const defaultRetryBaseDelay = 10 * time.Millisecond
+
+// isRetriable returns true if err is a transient filesystem error which may succeed if the operation is
+// retried, as seen from network filesystems such as NFS.
+func isRetriable(err error) bool {
+	return errors.Is(err, syscall.EAGAIN) || errors.Is(err, syscall.ESTALE) || errors.Is(err, syscall.EINTR)
+}
+
+// tileReadMaxAttempts is the number of times that readTiles attempts to read each tile whose read fails with a
+// transient error, so that a single flaky read doesn't fail an entire integration. This is in addition to any
+// retries of the underlying file reads configured by Config.RetryMaxAttempts.
+const tileReadMaxAttempts = 3
+
+// retry calls op until it succeeds, returns an error which is not retriable, or has been attempted
+// Config.RetryMaxAttempts times, doubling the delay between attempts each time.
+//
+// Operations passed to this func must be safe to repeat.
+func (s *Storage) retry(op func() error) error {
+	return s.retryContext(context.Background(), s.cfg.RetryMaxAttempts, op)
+}
+
+// retryContext is like retry, but op is attempted at most maxAttempts times, and no further attempts are
+// made once ctx is done.
+func (s *Storage) retryContext(ctx context.Context, maxAttempts uint, op func() error) error {
+	delay := s.cfg.RetryBaseDelay
+	if delay <= 0 {
+		delay = defaultRetryBaseDelay
+	}
+	for attempt := uint(1); ; attempt++ {
+		err := op()
+		if err == nil || !isRetriable(err) || attempt >= maxAttempts {
+			return err
+		}
+		s.logger().DebugContext(ctx, "Retrying filesystem operation", slog.Uint64("attempt", uint64(attempt)), slog.Any("error", err))
+		select {
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
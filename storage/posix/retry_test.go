@@ -0,0 +1,110 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package posix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRetry(t *testing.T) {
+	for _, test := range []struct {
+		name         string
+		maxAttempts  uint
+		errs         []error
+		wantAttempts int
+		wantErr      error
+	}{
+		{
+			name:         "success",
+			maxAttempts:  3,
+			errs:         []error{nil},
+			wantAttempts: 1,
+		}, {
+			name:         "transient then success",
+			maxAttempts:  3,
+			errs:         []error{&os.PathError{Op: "open", Path: "x", Err: syscall.ESTALE}, fmt.Errorf("wrapped: %w", syscall.EAGAIN), nil},
+			wantAttempts: 3,
+		}, {
+			name:         "transient exhausts attempts",
+			maxAttempts:  2,
+			errs:         []error{syscall.EAGAIN, syscall.EAGAIN, nil},
+			wantAttempts: 2,
+			wantErr:      syscall.EAGAIN,
+		}, {
+			name:         "retries disabled",
+			maxAttempts:  0,
+			errs:         []error{syscall.EAGAIN, nil},
+			wantAttempts: 1,
+			wantErr:      syscall.EAGAIN,
+		}, {
+			name:         "permanent error",
+			maxAttempts:  3,
+			errs:         []error{&os.PathError{Op: "open", Path: "x", Err: syscall.ENOENT}, nil},
+			wantAttempts: 1,
+			wantErr:      os.ErrNotExist,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			s := &Storage{cfg: Config{RetryMaxAttempts: test.maxAttempts, RetryBaseDelay: time.Millisecond}}
+			attempts := 0
+			err := s.retry(func() error {
+				err := test.errs[attempts]
+				attempts++
+				return err
+			})
+			if attempts != test.wantAttempts {
+				t.Errorf("got %d attempts, want %d", attempts, test.wantAttempts)
+			}
+			if test.wantErr == nil && err != nil || !errors.Is(err, test.wantErr) {
+				t.Errorf("got err %v, want %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestRetryContext(t *testing.T) {
+	s := &Storage{cfg: Config{RetryBaseDelay: time.Millisecond}}
+
+	// The attempts given are used in place of Config.RetryMaxAttempts.
+	attempts := 0
+	err := s.retryContext(t.Context(), tileReadMaxAttempts, func() error {
+		attempts++
+		if attempts < tileReadMaxAttempts {
+			return syscall.EAGAIN
+		}
+		return nil
+	})
+	if err != nil || attempts != tileReadMaxAttempts {
+		t.Errorf("got (%d attempts, %v), want (%d attempts, nil)", attempts, err, tileReadMaxAttempts)
+	}
+
+	// No further attempts are made once the context is done.
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	attempts = 0
+	err = s.retryContext(ctx, 10, func() error {
+		attempts++
+		return syscall.ESTALE
+	})
+	if attempts != 1 || !errors.Is(err, syscall.ESTALE) || !errors.Is(err, context.Canceled) {
+		t.Errorf("got (%d attempts, %v), want (1 attempt, ESTALE and context.Canceled)", attempts, err)
+	}
+}
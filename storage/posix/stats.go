@@ -0,0 +1,128 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package posix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/transparency-dev/tessera/api/layout"
+	"github.com/transparency-dev/tessera/internal/otel"
+	"github.com/transparency-dev/tessera/internal/parse"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Stats describes the current operational state of the storage.
+type Stats struct {
+	// SignerHealthy is false if the most recent attempt to create a checkpoint failed.
+	SignerHealthy bool
+	// SignerFailingSince is the time of the first of the current run of failed attempts to create a
+	// checkpoint, or the zero time if SignerHealthy is true.
+	SignerFailingSince time.Time
+	// SignerLastError is the error returned by the most recent failed attempt to create a checkpoint,
+	// or nil if SignerHealthy is true.
+	SignerLastError error
+}
+
+// Stats returns a snapshot of the current operational state of the storage.
+func (s *Storage) Stats() Stats {
+	since, err := s.signer.failure()
+	return Stats{
+		SignerHealthy:      since.IsZero(),
+		SignerFailingSince: since,
+		SignerLastError:    err,
+	}
+}
+
+// signerHealth tracks whether checkpoints are being successfully created.
+type signerHealth struct {
+	mu           sync.Mutex
+	failingSince time.Time
+	lastErr      error
+}
+
+// succeeded records a successful attempt to create a checkpoint.
+func (h *signerHealth) succeeded() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.failingSince, h.lastErr = time.Time{}, nil
+}
+
+// failed records a failed attempt, made at the given time, to create a checkpoint.
+func (h *signerHealth) failed(now time.Time, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.failingSince.IsZero() {
+		h.failingSince = now
+	}
+	h.lastErr = err
+}
+
+// failure returns the time since which checkpoint creation has been failing, along with the
+// most recent error, or the zero time and nil if the most recent attempt succeeded.
+func (h *signerHealth) failure() (time.Time, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.failingSince, h.lastErr
+}
+
+// HealthCheck returns an error describing the problem if the log doesn't appear to be functioning, and nil
+// otherwise. It's cheap enough to be called frequently, e.g. by a readiness probe.
+//
+// The log is considered healthy if the state directory is readable, the tree state can be parsed, the published
+// checkpoint can be read, and, if an appender with automatic checkpoint publication has been created, the
+// publication job has run within twice the checkpoint interval, plus the time allowed for a publication.
+func (s *Storage) HealthCheck(ctx context.Context) error {
+	return otel.TraceErr(ctx, "tessera.storage.posix.HealthCheck", s.tracer(), func(ctx context.Context, span trace.Span) error {
+		dir := filepath.Join(s.cfg.Path, s.stateDir())
+		f, err := os.Open(dir)
+		if err != nil {
+			return fmt.Errorf("state directory is not readable: %v", err)
+		}
+		_, err = f.Readdirnames(1)
+		_ = f.Close()
+		if err != nil {
+			return fmt.Errorf("state directory %q is not readable: %v", dir, err)
+		}
+
+		if _, _, err := s.readTreeState(ctx); err != nil {
+			return fmt.Errorf("failed to read tree state: %v", err)
+		}
+
+		if a := s.appender.Load(); a != nil && a.publishInterval > 0 {
+			hb := a.publishHeartbeat.Load()
+			if hb == 0 {
+				return errors.New("checkpoint publication job is not running")
+			}
+			if age, limit := s.clock().Now().Sub(time.Unix(0, hb)), 2*a.publishInterval+defaultPublicationTimeout; age > limit {
+				return fmt.Errorf("checkpoint publication job last ran %v ago, more than %v", age, limit)
+			}
+		}
+
+		cp, err := s.readAll(layout.CheckpointPath)
+		if err != nil {
+			return fmt.Errorf("failed to read checkpoint: %v", err)
+		}
+		if _, _, _, err := parse.CheckpointUnsafe(cp); err != nil {
+			return fmt.Errorf("failed to parse checkpoint: %v", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,112 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package posix
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/transparency-dev/tessera/api/layout"
+)
+
+// tileCacheKey identifies a cached tile.
+//
+// The partial tile size is part of the key, so a partial tile is never served in place of a fuller one.
+// A stored tile can be overwritten, e.g. when an integration attempt which was never committed to the
+// tree state is retried, so writers must evict the tiles they write from the cache.
+type tileCacheKey struct {
+	level, index uint64
+	p            uint8
+}
+
+type tileCacheEntry struct {
+	key  tileCacheKey
+	data []byte
+}
+
+// tileCache is a least-recently-used cache of raw tiles, bounded by the total size of the tiles it holds.
+type tileCache struct {
+	maxBytes int
+
+	mu    sync.Mutex
+	bytes int
+	// lru holds *tileCacheEntry values, with the most recently used at the front.
+	lru   *list.List
+	items map[tileCacheKey]*list.Element
+}
+
+func newTileCache(maxBytes int) *tileCache {
+	return &tileCache{
+		maxBytes: maxBytes,
+		lru:      list.New(),
+		items:    make(map[tileCacheKey]*list.Element),
+	}
+}
+
+// get returns the cached tile for the given key, if present.
+func (c *tileCache) get(k tileCacheKey) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.items[k]
+	if !ok {
+		return nil, false
+	}
+	c.lru.MoveToFront(e)
+	return e.Value.(*tileCacheEntry).data, true
+}
+
+// add stores the tile for the given key, evicting the least recently used tiles as necessary to stay
+// within the size bound. Tiles larger than the bound are not cached.
+func (c *tileCache) add(k tileCacheKey, data []byte) {
+	if len(data) > c.maxBytes {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.items[k]; ok {
+		c.lru.MoveToFront(e)
+		return
+	}
+	c.items[k] = c.lru.PushFront(&tileCacheEntry{key: k, data: data})
+	c.bytes += len(data)
+	for c.bytes > c.maxBytes {
+		c.removeLocked(c.lru.Back().Value.(*tileCacheEntry).key)
+	}
+}
+
+// evict removes the tile at the given level, index, and partial size from the cache. Since reads of
+// partial tiles may be served from the full tile, evicting a full tile (p == 0) also evicts all of the
+// partial tiles at that level and index.
+func (c *tileCache) evict(level, index uint64, p uint8) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if p != 0 {
+		c.removeLocked(tileCacheKey{level: level, index: index, p: p})
+		return
+	}
+	for p := range layout.TileWidth {
+		c.removeLocked(tileCacheKey{level: level, index: index, p: uint8(p)})
+	}
+}
+
+func (c *tileCache) removeLocked(k tileCacheKey) {
+	e, ok := c.items[k]
+	if !ok {
+		return
+	}
+	c.lru.Remove(e)
+	delete(c.items, k)
+	c.bytes -= len(e.Value.(*tileCacheEntry).data)
+}
@@ -0,0 +1,137 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package posix
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/transparency-dev/tessera"
+	"github.com/transparency-dev/tessera/api/layout"
+)
+
+func TestTileCache(t *testing.T) {
+	c := newTileCache(10)
+	k := func(i uint64) tileCacheKey { return tileCacheKey{level: 0, index: i} }
+
+	c.add(k(0), []byte("0000"))
+	c.add(k(1), []byte("1111"))
+	// Use tile 0 so that tile 1 becomes the least recently used.
+	if _, ok := c.get(k(0)); !ok {
+		t.Fatal("tile 0 not cached")
+	}
+	c.add(k(2), []byte("2222"))
+	if _, ok := c.get(k(1)); ok {
+		t.Error("tile 1 was not evicted")
+	}
+	for _, i := range []uint64{0, 2} {
+		if got, ok := c.get(k(i)); !ok || !bytes.Equal(got, fmt.Appendf(nil, "%d%d%d%d", i, i, i, i)) {
+			t.Errorf("get(%d) = %q, %t", i, got, ok)
+		}
+	}
+
+	// Partial tiles are cached separately from fuller versions of the same tile.
+	c.add(tileCacheKey{level: 0, index: 3, p: 1}, []byte("3"))
+	if _, ok := c.get(tileCacheKey{level: 0, index: 3, p: 2}); ok {
+		t.Error("partial tile served for a different partial size")
+	}
+
+	c.add(k(4), []byte("too large to cache"))
+	if _, ok := c.get(k(4)); ok {
+		t.Error("tile larger than cache was cached")
+	}
+}
+
+func TestTileCacheEvict(t *testing.T) {
+	c := newTileCache(100)
+	c.add(tileCacheKey{level: 0, index: 1, p: 1}, []byte("1"))
+	c.add(tileCacheKey{level: 0, index: 1, p: 2}, []byte("12"))
+	c.add(tileCacheKey{level: 0, index: 2, p: 1}, []byte("1"))
+
+	c.evict(0, 1, 1)
+	if _, ok := c.get(tileCacheKey{level: 0, index: 1, p: 1}); ok {
+		t.Error("evicted partial tile still cached")
+	}
+	if _, ok := c.get(tileCacheKey{level: 0, index: 1, p: 2}); !ok {
+		t.Error("different partial tile was evicted")
+	}
+	// Evicting the full tile evicts all partial tiles at the same location, but not elsewhere.
+	c.evict(0, 1, 0)
+	if _, ok := c.get(tileCacheKey{level: 0, index: 1, p: 2}); ok {
+		t.Error("partial tile still cached after eviction of full tile")
+	}
+	if _, ok := c.get(tileCacheKey{level: 0, index: 2, p: 1}); !ok {
+		t.Error("partial tile at different index was evicted")
+	}
+	if c.bytes != 1 {
+		t.Errorf("cache holds %d bytes, want 1", c.bytes)
+	}
+}
+
+func TestReadTileCached(t *testing.T) {
+	ctx := t.Context()
+	dir := t.TempDir()
+	s := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: dir, DisableAutoPublish: true}, tileCache: newTileCache(1 << 20)}
+	sk, _ := mustGenerateKeys(t)
+	opts := tessera.NewAppendOptions().
+		WithCheckpointInterval(10*time.Minute).
+		WithBatching(100, time.Hour).
+		WithCheckpointSigner(sk)
+	appender, lr, err := s.newAppender(ctx, &logResourceStorage{s: s, entriesPath: opts.EntriesPath()}, opts)
+	if err != nil {
+		t.Fatalf("Appender: %v", err)
+	}
+	for i := range 3 {
+		appender.Add(ctx, tessera.NewEntry(fmt.Appendf(nil, "entry %d", i)))
+	}
+	if err := s.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	want, err := lr.ReadTile(ctx, 0, 0, 3)
+	if err != nil {
+		t.Fatalf("ReadTile: %v", err)
+	}
+	// Once cached, the tile is served without reading the disk.
+	if err := os.Remove(filepath.Join(dir, layout.TilePath(0, 0, 3))); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	got, err := lr.ReadTile(ctx, 0, 0, 3)
+	if err != nil {
+		t.Fatalf("ReadTile after removal: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("ReadTile after removal = %x, want %x", got, want)
+	}
+
+	// Overwriting a tile must not leave the old version in the cache.
+	want = bytes.Clone(want)
+	want[0] ^= 1
+	if err := s.logReader().writeTile(ctx, 0, 0, 3, want); err != nil {
+		t.Fatalf("writeTile: %v", err)
+	}
+	got, err = lr.ReadTile(ctx, 0, 0, 3)
+	if err != nil {
+		t.Fatalf("ReadTile after overwrite: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("ReadTile after overwrite = %x, want %x", got, want)
+	}
+}
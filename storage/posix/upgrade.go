@@ -0,0 +1,107 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package posix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/transparency-dev/tessera/internal/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrUnsupportedUpgrade is returned when asked to upgrade the state directory of a log between versions for
+// which no migration is known.
+var ErrUnsupportedUpgrade = errors.New("unsupported version upgrade")
+
+// upgrades holds the migrations between consecutive versions of the log state directory, keyed by the version
+// they upgrade from. Each is run with the tree state lock held, and the stored version is bumped once it has
+// succeeded.
+//
+// Migrations must be safe to re-run, since the process may stop after a migration has been applied but before
+// the version has been bumped.
+var upgrades = map[uint16]func(ctx context.Context, s *Storage) error{}
+
+// Upgrade migrates the state directory of the log from version from to version to, running the registered
+// migration for each version in between. It fails if the log's stored version is not from.
+//
+// Returns an error wrapping ErrUnsupportedUpgrade, without changing anything, if there's no known migration
+// path between the versions.
+func (s *Storage) Upgrade(ctx context.Context, from, to uint16) error {
+	return otel.TraceErr(ctx, "tessera.storage.posix.Upgrade", s.tracer(), func(ctx context.Context, span trace.Span) (errR error) {
+		unlock, err := s.lockTreeState(ctx)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := unlock(); err != nil && errR == nil {
+				errR = err
+			}
+		}()
+
+		return s.upgrade(ctx, from, to)
+	})
+}
+
+// autoUpgrade upgrades the state directory of an existing log to the current compatibility version, if it was
+// created with an older one.
+//
+// The caller must hold the tree state lock.
+func (s *Storage) autoUpgrade(ctx context.Context) error {
+	v, err := s.readVersion()
+	if errors.Is(err, os.ErrNotExist) {
+		// Either this is a new log, or one which predates the version file; ensureVersion handles both.
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if v >= compatibilityVersion {
+		return nil
+	}
+	return s.upgrade(ctx, v, compatibilityVersion)
+}
+
+// upgrade implements Upgrade.
+//
+// The caller must hold the tree state lock.
+func (s *Storage) upgrade(ctx context.Context, from, to uint16) error {
+	if from >= to || to > compatibilityVersion {
+		return fmt.Errorf("%w: from version %d to %d", ErrUnsupportedUpgrade, from, to)
+	}
+	// Check the whole path up front, so that the log isn't left part way between versions.
+	for v := from; v < to; v++ {
+		if _, ok := upgrades[v]; !ok {
+			return fmt.Errorf("%w: no migration from version %d to %d", ErrUnsupportedUpgrade, v, v+1)
+		}
+	}
+	if err := s.checkVersion(from); err != nil {
+		return err
+	}
+
+	for v := from; v < to; v++ {
+		s.logger().InfoContext(ctx, "Upgrading log state", slog.String("path", s.cfg.Path), slog.Uint64("from", uint64(v)), slog.Uint64("to", uint64(v+1)))
+		if err := upgrades[v](ctx, s); err != nil {
+			return fmt.Errorf("failed to upgrade from version %d to %d: %v", v, v+1, err)
+		}
+		if err := s.createOverwrite(filepath.Join(s.stateDir(), "version"), fmt.Appendf(nil, "%d", v+1)); err != nil {
+			return fmt.Errorf("failed to write version %d: %v", v+1, err)
+		}
+	}
+	return nil
+}
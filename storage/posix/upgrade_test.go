@@ -0,0 +1,112 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package posix
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/transparency-dev/tessera"
+)
+
+// withOldVersion creates a log whose state directory claims to have been created with version 0, and registers
+// a migration from that version which counts the number of times it's run.
+func withOldVersion(t *testing.T) (string, *int) {
+	t.Helper()
+	dir := t.TempDir()
+	sk, _ := mustGenerateKeys(t)
+	if err := InitializeLog(t.Context(), Config{Path: dir}, tessera.NewAppendOptions().WithCheckpointSigner(sk)); err != nil {
+		t.Fatalf("InitializeLog: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, defaultStateDir, "version"), []byte("0"), filePerm); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	runs := 0
+	upgrades[0] = func(context.Context, *Storage) error {
+		runs++
+		return nil
+	}
+	t.Cleanup(func() { delete(upgrades, 0) })
+	return dir, &runs
+}
+
+func TestUpgrade(t *testing.T) {
+	ctx := t.Context()
+	dir, runs := withOldVersion(t)
+	s := &Storage{cfg: Config{Path: dir}}
+
+	for _, test := range []struct {
+		name     string
+		from, to uint16
+	}{
+		{name: "downgrade", from: 1, to: 0},
+		{name: "beyond current version", from: 0, to: compatibilityVersion + 1},
+		{name: "no migration", from: compatibilityVersion, to: compatibilityVersion + 1},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if err := s.Upgrade(ctx, test.from, test.to); !errors.Is(err, ErrUnsupportedUpgrade) {
+				t.Errorf("Upgrade(%d, %d) = %v, want %v", test.from, test.to, err, ErrUnsupportedUpgrade)
+			}
+		})
+	}
+	if *runs != 0 {
+		t.Fatalf("Migration run %d times for unsupported upgrades", *runs)
+	}
+
+	if err := s.Upgrade(ctx, 0, 1); err != nil {
+		t.Fatalf("Upgrade: %v", err)
+	}
+	if *runs != 1 {
+		t.Errorf("Migration run %d times, want 1", *runs)
+	}
+	if err := s.checkVersion(1); err != nil {
+		t.Errorf("checkVersion after Upgrade: %v", err)
+	}
+	// The log is no longer at the version being upgraded from.
+	if err := s.Upgrade(ctx, 0, 1); err == nil {
+		t.Error("Upgrade of already upgraded log succeeded")
+	}
+}
+
+func TestAutoUpgrade(t *testing.T) {
+	ctx := t.Context()
+	dir, runs := withOldVersion(t)
+	sk, _ := mustGenerateKeys(t)
+	opts := tessera.NewAppendOptions().
+		WithCheckpointInterval(10 * time.Minute).
+		WithCheckpointSigner(sk)
+
+	s := &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: dir, DisableAutoPublish: true}}
+	if _, _, err := s.newAppender(ctx, &logResourceStorage{s: s, entriesPath: opts.EntriesPath()}, opts); err == nil {
+		t.Fatal("Appender succeeded for log with old version without AutoUpgrade")
+	}
+
+	s = &Storage{cfg: Config{HTTPClient: http.DefaultClient, Path: dir, DisableAutoPublish: true, AutoUpgrade: true}}
+	if _, _, err := s.newAppender(ctx, &logResourceStorage{s: s, entriesPath: opts.EntriesPath()}, opts); err != nil {
+		t.Fatalf("Appender with AutoUpgrade: %v", err)
+	}
+	if *runs != 1 {
+		t.Errorf("Migration run %d times, want 1", *runs)
+	}
+	if err := s.checkVersion(compatibilityVersion); err != nil {
+		t.Errorf("checkVersion after auto upgrade: %v", err)
+	}
+}
@@ -0,0 +1,185 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package posix
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/transparency-dev/formats/log"
+	"github.com/transparency-dev/merkle/compact"
+	"github.com/transparency-dev/merkle/rfc6962"
+	"github.com/transparency-dev/tessera/api/layout"
+	"github.com/transparency-dev/tessera/internal/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrTreeInconsistent is returned by Verify when the stored tiles, tree state, and checkpoint do not agree.
+var ErrTreeInconsistent = errors.New("stored tree is inconsistent")
+
+// Verify checks that the stored tiles, tree state, and published checkpoint are mutually consistent.
+//
+// Every tile for the integrated tree is read, and checked against the tile above it in the tree. The root
+// hash of the tree is then recomputed from the level 0 tiles and compared with the stored tree state, and
+// with the published checkpoint if one exists and can be parsed.
+//
+// Any inconsistency is reported with an error wrapping ErrTreeInconsistent, which identifies the first
+// divergent tile where possible. This reads the entire tree, so it may take some time for large logs.
+// Logs created with a hasher other than the default can't be verified, and an error wrapping ErrHasherMismatch
+// is returned.
+func (s *Storage) Verify(ctx context.Context) error {
+	return otel.TraceErr(ctx, "tessera.storage.posix.Verify", s.tracer(), func(ctx context.Context, span trace.Span) error {
+		if err := s.checkDefaultHasher(); err != nil {
+			return err
+		}
+		size, root, err := s.readTreeState(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to read tree state: %v", err)
+		}
+		span.SetAttributes(treeSizeKey.Int64(otel.Clamp64(size)))
+
+		cp := s.readCheckpointForVerify(ctx)
+		if cp != nil && cp.Size > size {
+			return fmt.Errorf("%w: checkpoint has size %d, but tree state has size %d", ErrTreeInconsistent, cp.Size, size)
+		}
+
+		v := &treeVerifier{
+			lrs:     s.logReader(),
+			size:    size,
+			rf:      compact.RangeFactory{Hash: rfc6962.DefaultHasher.HashChildren},
+			parents: make(map[uint64]tileNodes),
+		}
+		cr := v.rf.NewEmptyRange(0)
+		var cpRoot []byte
+		if cp != nil && cp.Size == 0 {
+			cpRoot = rfc6962.DefaultHasher.EmptyRoot()
+		}
+		for index := uint64(0); index*layout.TileWidth < size; index++ {
+			nodes, err := v.readTile(ctx, 0, index)
+			if err != nil {
+				return err
+			}
+			for _, h := range nodes {
+				if err := cr.Append(h, nil); err != nil {
+					return fmt.Errorf("failed to append leaf hash: %v", err)
+				}
+				if cp != nil && cr.End() == cp.Size {
+					if cpRoot, err = cr.GetRootHash(nil); err != nil {
+						return fmt.Errorf("failed to calculate root hash: %v", err)
+					}
+				}
+			}
+		}
+
+		got := rfc6962.DefaultHasher.EmptyRoot()
+		if size > 0 {
+			if got, err = cr.GetRootHash(nil); err != nil {
+				return fmt.Errorf("failed to calculate root hash: %v", err)
+			}
+		}
+		if !bytes.Equal(got, root) {
+			return fmt.Errorf("%w: tiles have root %x at size %d, but tree state has %x", ErrTreeInconsistent, got, size, root)
+		}
+		if cp != nil && !bytes.Equal(cpRoot, cp.Hash) {
+			return fmt.Errorf("%w: tiles have root %x at size %d, but checkpoint has %x", ErrTreeInconsistent, cpRoot, cp.Size, cp.Hash)
+		}
+		s.logger().InfoContext(ctx, "Verified tree", slog.Uint64("size", size), slog.String("root", fmt.Sprintf("%x", root)))
+		return nil
+	})
+}
+
+// readCheckpointForVerify returns the published checkpoint, or nil if there is none or it can't be parsed.
+func (s *Storage) readCheckpointForVerify(ctx context.Context) *log.Checkpoint {
+	cpRaw, err := s.logReader().ReadCheckpoint(ctx)
+	if err != nil {
+		s.logger().InfoContext(ctx, "Not verifying checkpoint as it could not be read", slog.Any("error", err))
+		return nil
+	}
+	cp := &log.Checkpoint{}
+	if _, err := cp.Unmarshal(cpRaw); err != nil {
+		s.logger().InfoContext(ctx, "Not verifying checkpoint as it could not be parsed", slog.Any("error", err))
+		return nil
+	}
+	return cp
+}
+
+// tileNodes holds the bottom row of hashes of a tile.
+type tileNodes struct {
+	index uint64
+	nodes [][]byte
+}
+
+// treeVerifier reads the tiles of a tree, checking each full tile against the corresponding node in its
+// parent tile as it goes.
+type treeVerifier struct {
+	lrs  *logResourceStorage
+	size uint64
+	rf   compact.RangeFactory
+	// parents holds the most recently read tile at each level above 0, so that tiles are read only once
+	// when they're visited in order.
+	parents map[uint64]tileNodes
+}
+
+// readTile reads the bottom row of hashes of the given tile in the tree, and checks that it is consistent
+// with the tiles above it.
+func (v *treeVerifier) readTile(ctx context.Context, level, index uint64) ([][]byte, error) {
+	p := layout.PartialTileSize(level, index, v.size)
+	t, err := v.lrs.readTile(ctx, level, index, p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", v.lrs.s.tilePath(level, index, p), err)
+	}
+	width := int(p)
+	if p == 0 {
+		width = layout.TileWidth
+	}
+	if t == nil || len(t.Nodes) < width {
+		return nil, fmt.Errorf("%w: %s is missing or has too few hashes", ErrTreeInconsistent, v.lrs.s.tilePath(level, index, p))
+	}
+	// A full tile may have been read in place of a partial one.
+	nodes := t.Nodes[:width]
+	if p > 0 {
+		// Partial tiles have no corresponding node in their parent tile.
+		return nodes, nil
+	}
+
+	cr := v.rf.NewEmptyRange(0)
+	for _, h := range nodes {
+		if err := cr.Append(h, nil); err != nil {
+			return nil, fmt.Errorf("failed to append hash: %v", err)
+		}
+	}
+	r, err := cr.GetRootHash(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate root hash: %v", err)
+	}
+	parentIndex := index / layout.TileWidth
+	parent, ok := v.parents[level+1]
+	if !ok || parent.index != parentIndex {
+		pNodes, err := v.readTile(ctx, level+1, parentIndex)
+		if err != nil {
+			return nil, err
+		}
+		parent = tileNodes{index: parentIndex, nodes: pNodes}
+		v.parents[level+1] = parent
+	}
+	if want := parent.nodes[index%layout.TileWidth]; !bytes.Equal(r, want) {
+		return nil, fmt.Errorf("%w: %s has root %x, but node %d of its parent %s is %x", ErrTreeInconsistent,
+			v.lrs.s.tilePath(level, index, 0), r, index%layout.TileWidth, v.lrs.s.tilePath(level+1, parentIndex, layout.PartialTileSize(level+1, parentIndex, v.size)), want)
+	}
+	return nodes, nil
+}
@@ -0,0 +1,107 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package posix
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/transparency-dev/tessera/api"
+	"github.com/transparency-dev/tessera/api/layout"
+)
+
+func TestVerify(t *testing.T) {
+	const size = 3*layout.TileWidth + 50
+
+	for _, test := range []struct {
+		name    string
+		corrupt func(t *testing.T, s *Storage)
+		wantErr string
+	}{
+		{
+			name: "consistent",
+		}, {
+			name: "corrupt tile",
+			corrupt: func(t *testing.T, s *Storage) {
+				p := layout.TilePath(0, 1, 0)
+				raw, err := s.readAll(p)
+				if err != nil {
+					t.Fatalf("readAll: %v", err)
+				}
+				tile := api.HashTile{}
+				if err := tile.UnmarshalText(raw); err != nil {
+					t.Fatalf("UnmarshalText: %v", err)
+				}
+				tile.Nodes[3][0] ^= 1
+				raw, err = tile.MarshalText()
+				if err != nil {
+					t.Fatalf("MarshalText: %v", err)
+				}
+				if err := s.createOverwrite(p, raw); err != nil {
+					t.Fatalf("createOverwrite: %v", err)
+				}
+			},
+			wantErr: "tile/0/001 has root",
+		}, {
+			name: "missing tile",
+			corrupt: func(t *testing.T, s *Storage) {
+				if err := s.removeDirAll(layout.TilePath(0, 3, 0) + ".p"); err != nil {
+					t.Fatalf("removeDirAll: %v", err)
+				}
+			},
+			wantErr: "tile/0/003.p/50",
+		}, {
+			name: "checkpoint mismatch",
+			corrupt: func(t *testing.T, s *Storage) {
+				cp := fmt.Sprintf("example.com/log\n%d\n%s\n", size-1, base64.StdEncoding.EncodeToString(make([]byte, 32)))
+				if err := s.createOverwrite(layout.CheckpointPath, []byte(cp)); err != nil {
+					t.Fatalf("createOverwrite: %v", err)
+				}
+			},
+			wantErr: "but checkpoint has",
+		}, {
+			name: "checkpoint too large",
+			corrupt: func(t *testing.T, s *Storage) {
+				cp := fmt.Sprintf("example.com/log\n%d\n%s\n", size+1, base64.StdEncoding.EncodeToString(make([]byte, 32)))
+				if err := s.createOverwrite(layout.CheckpointPath, []byte(cp)); err != nil {
+					t.Fatalf("createOverwrite: %v", err)
+				}
+			},
+			wantErr: "but tree state has size",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			// Use the subtest's context, so that the log's background jobs stop before its directory is removed.
+			ctx := t.Context()
+			s, _, _ := newProofTestLog(ctx, t, size)
+			if test.corrupt != nil {
+				test.corrupt(t, s)
+			}
+			err := s.Verify(ctx)
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Verify: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrTreeInconsistent) || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("Verify = %v, want ErrTreeInconsistent containing %q", err, test.wantErr)
+			}
+		})
+	}
+}
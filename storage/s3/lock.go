@@ -0,0 +1,194 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const (
+	// lockIDAttr is the name of the partition key attribute of the lock table.
+	lockIDAttr = "LockID"
+	// lockOwnerAttr holds a random token identifying the current holder of a lock.
+	lockOwnerAttr = "Owner"
+	// lockExpiryAttr holds the time, in milliseconds since the Unix epoch, after which a lock is considered abandoned.
+	lockExpiryAttr = "Expiry"
+
+	// minLockRetryInterval and maxLockRetryInterval bound the backoff used between attempts to take a lock
+	// which is currently held by another process.
+	minLockRetryInterval = 5 * time.Millisecond
+	maxLockRetryInterval = 250 * time.Millisecond
+)
+
+// locker describes a type which can provide mutual exclusion across processes.
+type locker interface {
+	// lock blocks until the named lock is held, or ctx is done.
+	// Once locked, the caller should perform whatever operations are necessary before calling the
+	// returned function to unlock it.
+	lock(ctx context.Context, name string) (func(context.Context) error, error)
+}
+
+// dynamoDBAPI is the subset of the DynamoDB client used by dynamoLocker.
+type dynamoDBAPI interface {
+	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+	DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
+}
+
+// dynamoLocker implements locks using conditional writes to a DynamoDB table.
+//
+// This is the analogue of the lock files used by the POSIX storage implementation. The table must have a
+// string partition key called "LockID".
+//
+// Locks are leased: a lock which has not been released within the lease duration is considered to have been
+// abandoned (e.g. because the process holding it crashed), and may be taken by another process. Leases are
+// renewed periodically while the lock is held, but the lease duration must still be comfortably longer than
+// any stall which the holder might experience, and writes made under the lock should be fenced.
+type dynamoLocker struct {
+	client dynamoDBAPI
+	table  string
+	lease  time.Duration
+}
+
+// lock takes the named lock, retrying with backoff until it succeeds or ctx is done.
+func (l *dynamoLocker) lock(ctx context.Context, name string) (func(context.Context) error, error) {
+	ownerRaw := make([]byte, 16)
+	if _, err := rand.Read(ownerRaw); err != nil {
+		return nil, fmt.Errorf("failed to generate lock owner token: %v", err)
+	}
+	owner := hex.EncodeToString(ownerRaw)
+
+	wait := minLockRetryInterval
+	for {
+		now := time.Now()
+		_, err := l.client.PutItem(ctx, &dynamodb.PutItemInput{
+			TableName: aws.String(l.table),
+			Item: map[string]types.AttributeValue{
+				lockIDAttr:     &types.AttributeValueMemberS{Value: name},
+				lockOwnerAttr:  &types.AttributeValueMemberS{Value: owner},
+				lockExpiryAttr: &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Add(l.lease).UnixMilli(), 10)},
+			},
+			ConditionExpression: aws.String("attribute_not_exists(#id) OR #expiry < :now"),
+			ExpressionAttributeNames: map[string]string{
+				"#id":     lockIDAttr,
+				"#expiry": lockExpiryAttr,
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":now": &types.AttributeValueMemberN{Value: strconv.FormatInt(now.UnixMilli(), 10)},
+			},
+		})
+		if err == nil {
+			break
+		}
+		var ccfe *types.ConditionalCheckFailedException
+		if !errors.As(err, &ccfe) {
+			return nil, fmt.Errorf("failed to take lock %q: %v", name, err)
+		}
+		// Someone else holds the lock, back off and try again.
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+		wait = min(2*wait, maxLockRetryInterval)
+	}
+
+	renewCtx, stopRenew := context.WithCancel(context.WithoutCancel(ctx))
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		l.renew(renewCtx, name, owner)
+	}()
+
+	return func(ctx context.Context) error {
+		stopRenew()
+		wg.Wait()
+		_, err := l.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+			TableName: aws.String(l.table),
+			Key: map[string]types.AttributeValue{
+				lockIDAttr: &types.AttributeValueMemberS{Value: name},
+			},
+			ConditionExpression: aws.String("#owner = :owner"),
+			ExpressionAttributeNames: map[string]string{
+				"#owner": lockOwnerAttr,
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":owner": &types.AttributeValueMemberS{Value: owner},
+			},
+		})
+		if err != nil {
+			var ccfe *types.ConditionalCheckFailedException
+			if errors.As(err, &ccfe) {
+				return fmt.Errorf("lock %q was lost before being released", name)
+			}
+			return fmt.Errorf("failed to release lock %q: %v", name, err)
+		}
+		return nil
+	}, nil
+}
+
+// renew extends the lease on the named lock every third of the lease duration until ctx is done, or until
+// the lock is found to be held by someone other than owner.
+func (l *dynamoLocker) renew(ctx context.Context, name, owner string) {
+	t := time.NewTicker(l.lease / 3)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		_, err := l.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+			TableName: aws.String(l.table),
+			Key: map[string]types.AttributeValue{
+				lockIDAttr: &types.AttributeValueMemberS{Value: name},
+			},
+			UpdateExpression:    aws.String("SET #expiry = :expiry"),
+			ConditionExpression: aws.String("#owner = :owner"),
+			ExpressionAttributeNames: map[string]string{
+				"#owner":  lockOwnerAttr,
+				"#expiry": lockExpiryAttr,
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":owner":  &types.AttributeValueMemberS{Value: owner},
+				":expiry": &types.AttributeValueMemberN{Value: strconv.FormatInt(time.Now().Add(l.lease).UnixMilli(), 10)},
+			},
+		})
+		if err != nil {
+			var ccfe *types.ConditionalCheckFailedException
+			if errors.As(err, &ccfe) {
+				slog.WarnContext(ctx, "Lost lock lease", slog.String("lock", name))
+				return
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			slog.WarnContext(ctx, "Failed to renew lock lease", slog.String("lock", name), slog.Any("error", err))
+		}
+	}
+}
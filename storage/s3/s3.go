@@ -0,0 +1,692 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package s3 contains an S3-compatible object storage implementation of Tessera.
+//
+// Log resources are stored using the same layout as the POSIX implementation, and, like that
+// implementation, new entries are integrated into the tree in-line as they're sequenced.
+// Coordination between multiple writers is provided by a lock held in a DynamoDB table.
+package s3
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"time"
+
+	"log/slog"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/transparency-dev/merkle/rfc6962"
+	"github.com/transparency-dev/tessera"
+	"github.com/transparency-dev/tessera/api"
+	"github.com/transparency-dev/tessera/api/layout"
+	"github.com/transparency-dev/tessera/internal/fetcher"
+	"github.com/transparency-dev/tessera/internal/otel"
+	"github.com/transparency-dev/tessera/internal/parse"
+	storage "github.com/transparency-dev/tessera/storage/internal"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	logContType      = "application/octet-stream"
+	ckptContType     = "text/plain; charset=utf-8"
+	logCacheControl  = "max-age=604800,immutable"
+	ckptCacheControl = "no-cache"
+
+	// stateDir holds any private (but not secret) internal state needed to maintain/operate the log.
+	stateDir = ".state"
+	// treeStateObj contains the integrated (but not necessarily published) state of the tree.
+	treeStateObj = stateDir + "/treeState"
+	// treeStateLock must be held when integrating entries into the tree or writing to the treeState object.
+	treeStateLock = "treeState"
+	// publishLock must be held when checking/updating the published checkpoint.
+	publishLock = "publish"
+
+	minCheckpointInterval = time.Second
+
+	// DefaultLockLease is the default duration after which an unreleased lock is considered abandoned.
+	DefaultLockLease = 30 * time.Second
+
+	// defaultIntegrationTimeout is the default context timeout applied when undertaking an integration task.
+	defaultIntegrationTimeout = 10 * time.Second
+	// defaultPublicationTimeout is the default context timeout applied when undertaking a checkpoint publication task.
+	defaultPublicationTimeout = 5 * time.Second
+)
+
+// Storage is an S3-compatible object storage implementation for Tessera.
+type Storage struct {
+	cfg      Config
+	objStore objStore
+	locker   locker
+}
+
+// objStore describes a type which can store and retrieve objects.
+type objStore interface {
+	getObject(ctx context.Context, obj string) ([]byte, error)
+	// getObjectETag is like getObject, but also returns the ETag of the returned data.
+	getObjectETag(ctx context.Context, obj string) ([]byte, string, error)
+	setObject(ctx context.Context, obj string, data []byte, contType string, cacheControl string) error
+	// setObjectIfMatch is like setObject, but only stores the data if the object's current ETag is etag, or, if etag
+	// is empty, if the object doesn't exist. Otherwise, an error wrapping errPreconditionFailed is returned.
+	setObjectIfMatch(ctx context.Context, obj string, data []byte, contType string, cacheControl string, etag string) error
+	lastModified(ctx context.Context, obj string) (time.Time, error)
+}
+
+// errPreconditionFailed is returned when a conditional write fails because the object was changed by someone else.
+var errPreconditionFailed = errors.New("precondition failed")
+
+// Config holds S3 and DynamoDB configuration for a storage instance.
+type Config struct {
+	// SDKConfig is an optional AWS config to use when configuring service clients, e.g. to
+	// use non-AWS S3 or DynamoDB services.
+	//
+	// If nil, the value from config.LoadDefaultConfig() will be used.
+	SDKConfig *aws.Config
+	// S3Options is an optional function which can be used to configure the S3 library.
+	// This is primarily useful when configuring the use of non-AWS S3 services.
+	S3Options func(*s3.Options)
+	// DynamoDBOptions is an optional function which can be used to configure the DynamoDB library.
+	DynamoDBOptions func(*dynamodb.Options)
+	// Bucket is the name of the S3 bucket to use for storing log state.
+	Bucket string
+	// BucketPrefix is an optional prefix to prepend to all log resource paths.
+	// This can be used e.g. to store multiple logs in the same bucket.
+	BucketPrefix string
+	// LockTable is the name of the DynamoDB table used to coordinate writes to the log.
+	// The table must have a string partition key named "LockID".
+	LockTable string
+	// LockLease is the duration after which a lock which has not been released is considered abandoned.
+	// Leases are renewed while locks are held, but must be longer than the time allowed for integrating a
+	// batch of entries, which is 10 seconds. If unset, DefaultLockLease is used.
+	LockLease time.Duration
+
+	// HTTPClient will be used for other HTTP requests. If unset, Tessera will use the net/http DefaultClient.
+	HTTPClient *http.Client
+}
+
+// New creates a new instance of the S3 based Storage.
+func New(ctx context.Context, cfg Config) (tessera.Driver, error) {
+	if cfg.Bucket == "" {
+		return nil, errors.New("bucket must be set")
+	}
+	if cfg.LockTable == "" {
+		return nil, errors.New("lock table must be set")
+	}
+	if cfg.LockLease == 0 {
+		cfg.LockLease = DefaultLockLease
+	}
+	if cfg.LockLease <= defaultIntegrationTimeout {
+		return nil, fmt.Errorf("lock lease %v must be longer than the integration timeout %v", cfg.LockLease, defaultIntegrationTimeout)
+	}
+	if cfg.SDKConfig == nil {
+		sdkConfig, err := config.LoadDefaultConfig(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load default AWS configuration: %v", err)
+		}
+		cfg.SDKConfig = &sdkConfig
+	}
+	// We need non-nil options funcs to pass in to the NewFromConfig calls below or they'll panic.
+	if cfg.S3Options == nil {
+		cfg.S3Options = func(_ *s3.Options) {}
+	}
+	if cfg.DynamoDBOptions == nil {
+		cfg.DynamoDBOptions = func(_ *dynamodb.Options) {}
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = http.DefaultClient
+	}
+
+	return &Storage{
+		cfg: cfg,
+		objStore: &s3Storage{
+			s3Client:     s3.NewFromConfig(*cfg.SDKConfig, cfg.S3Options),
+			bucket:       cfg.Bucket,
+			bucketPrefix: cfg.BucketPrefix,
+		},
+		locker: &dynamoLocker{
+			client: dynamodb.NewFromConfig(*cfg.SDKConfig, cfg.DynamoDBOptions),
+			table:  cfg.LockTable,
+			lease:  cfg.LockLease,
+		},
+	}, nil
+}
+
+// appender implements the Tessera append lifecycle.
+type appender struct {
+	s          *Storage
+	logStorage *logResourceStorage
+	queue      *storage.Queue
+
+	cpUpdated chan struct{}
+	newCP     func(context.Context, uint64, []byte) ([]byte, error)
+}
+
+func (s *Storage) Appender(ctx context.Context, opts *tessera.AppendOptions) (*tessera.Appender, tessera.LogReader, error) {
+	if opts.CheckpointInterval() < minCheckpointInterval {
+		return nil, nil, fmt.Errorf("requested CheckpointInterval (%v) is less than minimum permitted %v", opts.CheckpointInterval(), minCheckpointInterval)
+	}
+	a, err := s.newAppender(ctx, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &tessera.Appender{
+		Add: a.Add,
+	}, a.logStorage, nil
+}
+
+func (s *Storage) newAppender(ctx context.Context, opts *tessera.AppendOptions) (*appender, error) {
+	logStorage := &logResourceStorage{
+		s:           s,
+		entriesPath: opts.EntriesPath(),
+	}
+	a := &appender{
+		s:          s,
+		logStorage: logStorage,
+		cpUpdated:  make(chan struct{}),
+		newCP:      opts.CheckpointPublisher(logStorage, s.cfg.HTTPClient),
+	}
+	if err := a.initialise(ctx); err != nil {
+		return nil, err
+	}
+	a.queue = storage.NewQueue(ctx, opts.BatchMaxAge(), opts.BatchMaxSize(), nil, func(ctx context.Context, entries []*tessera.Entry) error {
+		ctx, cancel := context.WithTimeout(ctx, defaultIntegrationTimeout)
+		defer cancel()
+		return a.sequenceBatch(ctx, entries)
+	})
+	a.queue.SetMaxPending(opts.MaxPendingEntries())
+
+	go a.publishCheckpointJob(ctx, opts.CheckpointInterval(), opts.CheckpointRepublishInterval())
+
+	return a, nil
+}
+
+// Add takes an entry and queues it for inclusion in the log.
+// Upon placing the entry in an in-memory queue to be sequenced, it returns a future that will evaluate to either the sequence
+// number assigned to this entry, or an error.
+func (a *appender) Add(ctx context.Context, e *tessera.Entry) tessera.IndexFuture {
+	return a.queue.Add(ctx, e)
+}
+
+func (a *appender) publishCheckpointJob(ctx context.Context, pubInterval, republishInterval time.Duration) {
+	t := time.NewTicker(pubInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-a.cpUpdated:
+		case <-t.C:
+		}
+		if err := otel.TraceErr(ctx, "tessera.storage.s3.publishCheckpointJob", tracer, func(ctx context.Context, span trace.Span) error {
+			ctx, cancel := context.WithTimeout(ctx, defaultPublicationTimeout)
+			defer cancel()
+			return a.publishCheckpoint(ctx, pubInterval, republishInterval)
+		}, trace.WithAttributes(otel.PeriodicKey.Bool(true))); err != nil {
+			slog.WarnContext(ctx, "publishCheckpoint failed", slog.Any("error", err))
+		}
+	}
+}
+
+// withLock takes the named lock, calls f, and then releases the lock.
+func (s *Storage) withLock(ctx context.Context, name string, f func() error) (errR error) {
+	unlock, err := s.locker.lock(ctx, name)
+	if err != nil {
+		return fmt.Errorf("lock(%s): %w", name, err)
+	}
+	defer func() {
+		// Release the lock even if ctx is done, rather than leaving it to expire.
+		if err := unlock(context.WithoutCancel(ctx)); err != nil && errR == nil {
+			errR = fmt.Errorf("unlock(%s): %w", name, err)
+		}
+	}()
+	return f()
+}
+
+// initialise ensures that the tree state exists, creating an empty tree and publishing its
+// checkpoint if this is a new log.
+func (a *appender) initialise(ctx context.Context) error {
+	return a.s.withLock(ctx, treeStateLock, func() error {
+		if _, _, err := a.s.readTreeState(ctx); err == nil {
+			return nil
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to load tree state for log: %v", err)
+		}
+		slog.InfoContext(ctx, "Initializing S3 log (this should only happen ONCE per log!)", slog.String("bucket", a.s.cfg.Bucket), slog.String("prefix", a.s.cfg.BucketPrefix))
+		if err := a.s.writeTreeState(ctx, 0, rfc6962.DefaultHasher.EmptyRoot(), ""); err != nil {
+			return fmt.Errorf("failed to write tree state: %v", err)
+		}
+		if err := a.publishCheckpoint(ctx, 0, 0); err != nil {
+			return fmt.Errorf("failed to publish checkpoint: %v", err)
+		}
+		return nil
+	})
+}
+
+// sequenceBatch writes the entries from the provided batch into the entry bundle files of the log,
+// and integrates them into the tree.
+//
+// This func starts filling entries bundles at the next available slot in the log, ensuring that the
+// sequenced entries are contiguous from the zeroth entry (i.e left-hand dense).
+func (a *appender) sequenceBatch(ctx context.Context, entries []*tessera.Entry) error {
+	return otel.TraceErr(ctx, "tessera.storage.s3.sequenceBatch", tracer, func(ctx context.Context, span trace.Span) error {
+		span.SetAttributes(numEntriesKey.Int(len(entries)))
+
+		return a.s.withLock(ctx, treeStateLock, func() error {
+			seq, _, etag, err := a.s.readTreeStateETag(ctx)
+			if err != nil {
+				return fmt.Errorf("readTreeState: %v", err)
+			}
+			slog.DebugContext(ctx, "Sequencing", slog.Uint64("from", seq))
+			if len(entries) == 0 {
+				return nil
+			}
+
+			currBundle := &bytes.Buffer{}
+			bundleIndex, entriesInBundle := seq/layout.EntryBundleWidth, seq%layout.EntryBundleWidth
+			if entriesInBundle > 0 {
+				// If the latest bundle is partial, we need to read the data it contains in for our newer, larger, bundle.
+				part, err := a.logStorage.ReadEntryBundle(ctx, bundleIndex, uint8(entriesInBundle))
+				if err != nil {
+					return err
+				}
+				currBundle.Write(part)
+			}
+
+			leafHashes := make([][]byte, 0, len(entries))
+			for i, e := range entries {
+				currBundle.Write(e.MarshalBundleData(seq + uint64(i)))
+				leafHashes = append(leafHashes, e.LeafHash())
+
+				entriesInBundle++
+				if entriesInBundle == layout.EntryBundleWidth {
+					if err := a.logStorage.setEntryBundle(ctx, bundleIndex, 0, currBundle.Bytes()); err != nil {
+						return err
+					}
+					bundleIndex++
+					entriesInBundle = 0
+					currBundle = &bytes.Buffer{}
+				}
+			}
+			if entriesInBundle > 0 {
+				if err := a.logStorage.setEntryBundle(ctx, bundleIndex, uint8(entriesInBundle), currBundle.Bytes()); err != nil {
+					return err
+				}
+			}
+
+			newSize, newRoot, err := integrate(ctx, seq, leafHashes, a.logStorage)
+			if err != nil {
+				return err
+			}
+			// The tree state is only written if it hasn't changed since it was read, so that a writer which has
+			// lost its lock, e.g. because it stalled for longer than the lease, can't fork the tree.
+			if err := a.s.writeTreeState(ctx, newSize, newRoot, etag); err != nil {
+				return fmt.Errorf("failed to write new tree state: %v", err)
+			}
+			// Notify that we know for sure there's a new checkpoint, but don't block if there's already
+			// an outstanding notification in the channel.
+			select {
+			case a.cpUpdated <- struct{}{}:
+			default:
+			}
+			return nil
+		})
+	}, trace.WithAttributes(otel.PeriodicKey.Bool(true)))
+}
+
+// integrate adds the provided leaf hashes to the merkle tree, starting at the provided location.
+func integrate(ctx context.Context, fromSeq uint64, lh [][]byte, lrs *logResourceStorage) (uint64, []byte, error) {
+	newSize, newRoot, tiles, err := storage.Integrate(ctx, lrs.getTiles, fromSeq, lh)
+	if err != nil {
+		return 0, nil, fmt.Errorf("storage.Integrate: %v", err)
+	}
+	errG := errgroup.Group{}
+	for k, v := range tiles {
+		errG.Go(func() error {
+			return lrs.setTile(ctx, uint64(k.Level), k.Index, newSize, v)
+		})
+	}
+	if err := errG.Wait(); err != nil {
+		return 0, nil, err
+	}
+	slog.DebugContext(ctx, "New tree", slog.Uint64("size", newSize), slog.String("root", fmt.Sprintf("%x", newRoot)))
+	return newSize, newRoot, nil
+}
+
+type treeState struct {
+	Size uint64 `json:"size"`
+	Root []byte `json:"root"`
+}
+
+// writeTreeState stores the current tree size and root hash, provided that the stored tree state still has
+// the given ETag, as returned by readTreeStateETag, or that there is no stored tree state if etag is empty.
+func (s *Storage) writeTreeState(ctx context.Context, size uint64, root []byte, etag string) error {
+	raw, err := json.Marshal(treeState{Size: size, Root: root})
+	if err != nil {
+		return fmt.Errorf("error in Marshal: %v", err)
+	}
+	return s.objStore.setObjectIfMatch(ctx, treeStateObj, raw, logContType, ckptCacheControl, etag)
+}
+
+// readTreeState reads and returns the currently stored tree state.
+//
+// Returns a wrapped os.ErrNotExist if no tree state has been stored.
+func (s *Storage) readTreeState(ctx context.Context) (uint64, []byte, error) {
+	size, root, _, err := s.readTreeStateETag(ctx)
+	return size, root, err
+}
+
+// readTreeStateETag is like readTreeState, but also returns the ETag of the stored tree state.
+func (s *Storage) readTreeStateETag(ctx context.Context) (uint64, []byte, string, error) {
+	raw, etag, err := s.objStore.getObjectETag(ctx, treeStateObj)
+	if err != nil {
+		return 0, nil, "", err
+	}
+	ts := &treeState{}
+	if err := json.Unmarshal(raw, ts); err != nil {
+		return 0, nil, "", fmt.Errorf("error in Unmarshal: %v", err)
+	}
+	return ts.Size, ts.Root, etag, nil
+}
+
+// publishCheckpoint checks whether the currently published checkpoint (if any) is more than
+// minStaleness old, and, if so, creates and published a fresh checkpoint from the current
+// stored tree state.
+func (a *appender) publishCheckpoint(ctx context.Context, minStalenessActive, minStalenessRepub time.Duration) error {
+	return otel.TraceErr(ctx, "tessera.storage.s3.publishCheckpoint", tracer, func(ctx context.Context, span trace.Span) (errR error) {
+		now := time.Now()
+		defer func() {
+			// Detect any errors and update metrics accordingly.
+			// Non-error cases are explicitly handled in the body of the function below.
+			if errR != nil {
+				publishCount.Add(ctx, 1, metric.WithAttributes(outcomeTypeKey.String("error")))
+			}
+		}()
+
+		return a.s.withLock(ctx, publishLock, func() error {
+			var publishedAge time.Duration
+			var publishedSize uint64
+			cpExists := true
+			mod, err := a.s.objStore.lastModified(ctx, layout.CheckpointPath)
+			if errors.Is(err, os.ErrNotExist) {
+				slog.DebugContext(ctx, "No checkpoint exists, publishing")
+				cpExists = false
+			} else if err != nil {
+				return fmt.Errorf("lastModified(%s): %v", layout.CheckpointPath, err)
+			} else {
+				publishedAge = time.Since(mod)
+				if publishedAge < minStalenessActive {
+					slog.DebugContext(ctx, "publishCheckpoint: skipping publish because previous checkpoint too fresh", slog.Duration("age", publishedAge), slog.Duration("minstalenessactive", minStalenessActive))
+					publishCount.Add(ctx, 1, metric.WithAttributes(outcomeTypeKey.String("skipped")))
+					return nil
+				}
+				cp, err := a.logStorage.ReadCheckpoint(ctx)
+				if err != nil {
+					return fmt.Errorf("ReadCheckpoint: %v", err)
+				}
+				if _, publishedSize, _, err = parse.CheckpointUnsafe(cp); err != nil {
+					return fmt.Errorf("failed to parse published checkpoint: %v", err)
+				}
+			}
+
+			size, root, err := a.s.readTreeState(ctx)
+			if err != nil {
+				return fmt.Errorf("readTreeState: %v", err)
+			}
+			if cpExists && size == publishedSize {
+				if minStalenessRepub == 0 || publishedAge < minStalenessRepub {
+					slog.DebugContext(ctx, "publishCheckpoint: skipping publish because tree hasn't grown and previous checkpoint is too recent")
+					publishCount.Add(ctx, 1, metric.WithAttributes(outcomeTypeKey.String("skipped_no_growth")))
+					return nil
+				}
+			}
+
+			cpRaw, err := a.newCP(ctx, size, root)
+			if err != nil {
+				return fmt.Errorf("newCP: %v", err)
+			}
+			if err := a.s.objStore.setObject(ctx, layout.CheckpointPath, cpRaw, ckptContType, ckptCacheControl); err != nil {
+				return fmt.Errorf("setObject(%s): %v", layout.CheckpointPath, err)
+			}
+			slog.DebugContext(ctx, "Published latest checkpoint", slog.Uint64("size", size), slog.String("root", fmt.Sprintf("%x", root)))
+
+			opsHistogram.Record(ctx, time.Since(now).Milliseconds(), metric.WithAttributes(opNameKey.String("publishCheckpoint")))
+			publishCount.Add(ctx, 1, metric.WithAttributes(outcomeTypeKey.String("success")))
+			return nil
+		})
+	})
+}
+
+// logResourceStorage implements the tessera.LogReader interface, along with methods for writing
+// log resources.
+type logResourceStorage struct {
+	s           *Storage
+	entriesPath func(uint64, uint8) string
+}
+
+func (lrs *logResourceStorage) ReadCheckpoint(ctx context.Context) ([]byte, error) {
+	r, err := lrs.s.objStore.getObject(ctx, layout.CheckpointPath)
+	return r, storage.WrapNotFound(err)
+}
+
+func (lrs *logResourceStorage) ReadTile(ctx context.Context, l, i uint64, p uint8) ([]byte, error) {
+	r, err := fetcher.PartialOrFullResource(ctx, p, func(ctx context.Context, p uint8) ([]byte, error) {
+		return lrs.s.objStore.getObject(ctx, layout.TilePath(l, i, p))
+	})
+	return r, storage.WrapNotFound(err)
+}
+
+func (lrs *logResourceStorage) ReadEntryBundle(ctx context.Context, i uint64, p uint8) ([]byte, error) {
+	r, err := fetcher.PartialOrFullResource(ctx, p, func(ctx context.Context, p uint8) ([]byte, error) {
+		return lrs.s.objStore.getObject(ctx, lrs.entriesPath(i, p))
+	})
+	return r, storage.WrapNotFound(err)
+}
+
+func (lrs *logResourceStorage) IntegratedSize(ctx context.Context) (uint64, error) {
+	size, _, err := lrs.s.readTreeState(ctx)
+	return size, err
+}
+
+// NextIndex returns the next available index in the log.
+//
+// Since entries are integrated as they're sequenced, this is the same as the integrated size.
+func (lrs *logResourceStorage) NextIndex(ctx context.Context) (uint64, error) {
+	return lrs.IntegratedSize(ctx)
+}
+
+// setTile stores the provided tile at the location implied by the given level, index, and treeSize.
+func (lrs *logResourceStorage) setTile(ctx context.Context, level, index, logSize uint64, tile *api.HashTile) error {
+	start := time.Now()
+	data, err := tile.MarshalText()
+	if err != nil {
+		return err
+	}
+	tPath := layout.TilePath(level, index, layout.PartialTileSize(level, index, logSize))
+	slog.DebugContext(ctx, "StoreTile", slog.String("tpath", tPath), slog.Int("count", len(tile.Nodes)))
+
+	err = lrs.s.objStore.setObject(ctx, tPath, data, logContType, logCacheControl)
+	opsHistogram.Record(ctx, time.Since(start).Milliseconds(), metric.WithAttributes(opNameKey.String("writeTile")))
+	return err
+}
+
+// getTiles returns the tiles with the given tile-coords for the specified log size.
+//
+// Tiles are returned in the same order as they're requested, nils represent tiles which were not found.
+func (lrs *logResourceStorage) getTiles(ctx context.Context, tileIDs []storage.TileID, logSize uint64) ([]*api.HashTile, error) {
+	r := make([]*api.HashTile, len(tileIDs))
+	errG := errgroup.Group{}
+	for i, id := range tileIDs {
+		errG.Go(func() error {
+			objName := layout.TilePath(id.Level, id.Index, layout.PartialTileSize(id.Level, id.Index, logSize))
+			data, err := lrs.s.objStore.getObject(ctx, objName)
+			if err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					// Depending on context, this may be ok.
+					// We'll signal to higher levels that it wasn't found by retuning a nil for this tile.
+					return nil
+				}
+				return err
+			}
+			t := &api.HashTile{}
+			if err := t.UnmarshalText(data); err != nil {
+				return fmt.Errorf("unmarshal(%q): %v", objName, err)
+			}
+			r[i] = t
+			return nil
+		})
+	}
+	if err := errG.Wait(); err != nil {
+		return nil, fmt.Errorf("getTiles: %w", err)
+	}
+	return r, nil
+}
+
+// setEntryBundle stores the serialised entry bundle at the location implied by the bundleIndex and treeSize.
+func (lrs *logResourceStorage) setEntryBundle(ctx context.Context, bundleIndex uint64, p uint8, bundleRaw []byte) error {
+	objName := lrs.entriesPath(bundleIndex, p)
+	if err := lrs.s.objStore.setObject(ctx, objName, bundleRaw, logContType, logCacheControl); err != nil {
+		return fmt.Errorf("setObject(%q): %v", objName, err)
+	}
+	return nil
+}
+
+// s3Storage knows how to store and retrieve objects from S3.
+type s3Storage struct {
+	bucket       string
+	bucketPrefix string
+	s3Client     *s3.Client
+}
+
+// getObject returns the data of the specified object, or an error.
+//
+// Returns a wrapped os.ErrNotExist if the object does not exist.
+func (s *s3Storage) getObject(ctx context.Context, obj string) ([]byte, error) {
+	d, _, err := s.getObjectETag(ctx, obj)
+	return d, err
+}
+
+// getObjectETag returns the data of the specified object along with its ETag, or an error.
+//
+// Returns a wrapped os.ErrNotExist if the object does not exist.
+func (s *s3Storage) getObjectETag(ctx context.Context, obj string) ([]byte, string, error) {
+	if s.bucketPrefix != "" {
+		obj = path.Join(s.bucketPrefix, obj)
+	}
+
+	r, err := s.s3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(obj),
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("getObject: failed to create reader for object %q in bucket %q: %w", obj, s.bucket, notExist(err))
+	}
+
+	d, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("getObject: failed to read %q: %v", obj, err)
+	}
+	return d, aws.ToString(r.ETag), r.Body.Close()
+}
+
+// setObject stores the provided data in the specified object.
+func (s *s3Storage) setObject(ctx context.Context, obj string, data []byte, contType string, cacheControl string) error {
+	if s.bucketPrefix != "" {
+		obj = path.Join(s.bucketPrefix, obj)
+	}
+
+	put := &s3.PutObjectInput{
+		Bucket:       aws.String(s.bucket),
+		Key:          aws.String(obj),
+		Body:         bytes.NewReader(data),
+		ContentType:  aws.String(contType),
+		CacheControl: aws.String(cacheControl),
+	}
+	if _, err := s.s3Client.PutObject(ctx, put); err != nil {
+		return fmt.Errorf("failed to write object %q to bucket %q: %w", obj, s.bucket, err)
+	}
+	return nil
+}
+
+// setObjectIfMatch stores the provided data in the specified object, provided that the object's current ETag
+// is etag, or that the object doesn't exist if etag is empty.
+func (s *s3Storage) setObjectIfMatch(ctx context.Context, obj string, data []byte, contType string, cacheControl string, etag string) error {
+	if s.bucketPrefix != "" {
+		obj = path.Join(s.bucketPrefix, obj)
+	}
+
+	put := &s3.PutObjectInput{
+		Bucket:       aws.String(s.bucket),
+		Key:          aws.String(obj),
+		Body:         bytes.NewReader(data),
+		ContentType:  aws.String(contType),
+		CacheControl: aws.String(cacheControl),
+	}
+	if etag == "" {
+		put.IfNoneMatch = aws.String("*")
+	} else {
+		put.IfMatch = aws.String(etag)
+	}
+	if _, err := s.s3Client.PutObject(ctx, put); err != nil {
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) && (apiErr.ErrorCode() == "PreconditionFailed" || apiErr.ErrorCode() == "ConditionalRequestConflict") {
+			err = fmt.Errorf("%v: %w", err, errPreconditionFailed)
+		}
+		return fmt.Errorf("failed to write object %q to bucket %q: %w", obj, s.bucket, err)
+	}
+	return nil
+}
+
+// lastModified returns the time at which the specified object was last written.
+//
+// Returns a wrapped os.ErrNotExist if the object does not exist.
+func (s *s3Storage) lastModified(ctx context.Context, obj string) (time.Time, error) {
+	if s.bucketPrefix != "" {
+		obj = path.Join(s.bucketPrefix, obj)
+	}
+
+	r, err := s.s3Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(obj),
+	})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to stat object %q in bucket %q: %w", obj, s.bucket, notExist(err))
+	}
+	return aws.ToTime(r.LastModified), nil
+}
+
+// notExist maps S3 not-found errors to os.ErrNotExist so that higher levels can differentiate between
+// this and other errors in the same way as they do for the POSIX implementation.
+func notExist(err error) error {
+	// Do not use errors.Is. Keep errors.As to compare by type and not by value.
+	var nske *types.NoSuchKey
+	var nf *types.NotFound
+	if errors.As(err, &nske) || errors.As(err, &nf) {
+		return fmt.Errorf("%v: %w", err, os.ErrNotExist)
+	}
+	return err
+}
@@ -0,0 +1,431 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/transparency-dev/merkle/compact"
+	"github.com/transparency-dev/merkle/rfc6962"
+	"github.com/transparency-dev/tessera"
+	"github.com/transparency-dev/tessera/api/layout"
+	storage "github.com/transparency-dev/tessera/storage/internal"
+	"golang.org/x/mod/sumdb/note"
+)
+
+func newTestStorage() *Storage {
+	return &Storage{
+		cfg:      Config{HTTPClient: http.DefaultClient},
+		objStore: newMemObjStore(),
+		locker:   newMemLocker(),
+	}
+}
+
+func TestAppend(t *testing.T) {
+	ctx := t.Context()
+	s := newTestStorage()
+	sk, _ := mustGenerateKeys(t)
+	opts := tessera.NewAppendOptions().
+		WithCheckpointSigner(sk).
+		WithBatching(100, 10*time.Millisecond)
+	a, err := s.newAppender(ctx, opts)
+	if err != nil {
+		t.Fatalf("newAppender: %v", err)
+	}
+	if _, err := a.logStorage.ReadCheckpoint(ctx); err != nil {
+		t.Fatalf("ReadCheckpoint after initialisation: %v", err)
+	}
+
+	// Add enough entries to span more than one entry bundle.
+	const n = layout.EntryBundleWidth + 10
+	rf := compact.RangeFactory{Hash: rfc6962.DefaultHasher.HashChildren}
+	cr := rf.NewEmptyRange(0)
+	futures := make([]tessera.IndexFuture, 0, n)
+	for i := range n {
+		e := tessera.NewEntry(fmt.Appendf(nil, "entry %d", i))
+		if err := cr.Append(e.LeafHash(), nil); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+		futures = append(futures, a.Add(ctx, e))
+	}
+	for i, f := range futures {
+		idx, err := f()
+		if err != nil {
+			t.Fatalf("Add(%d): %v", i, err)
+		}
+		if idx.Index >= n {
+			t.Fatalf("Add(%d) got index %d, want < %d", i, idx.Index, n)
+		}
+	}
+
+	size, root, err := s.readTreeState(ctx)
+	if err != nil {
+		t.Fatalf("readTreeState: %v", err)
+	}
+	wantRoot, err := cr.GetRootHash(nil)
+	if err != nil {
+		t.Fatalf("GetRootHash: %v", err)
+	}
+	if size != n || !bytes.Equal(root, wantRoot) {
+		t.Fatalf("Got tree (%d, %x), want (%d, %x)", size, root, n, wantRoot)
+	}
+	if _, err := a.logStorage.ReadEntryBundle(ctx, 0, 0); err != nil {
+		t.Errorf("ReadEntryBundle(full): %v", err)
+	}
+	if _, err := a.logStorage.ReadEntryBundle(ctx, 1, 10); err != nil {
+		t.Errorf("ReadEntryBundle(partial): %v", err)
+	}
+	if _, err := a.logStorage.ReadTile(ctx, 0, 0, 0); err != nil {
+		t.Errorf("ReadTile: %v", err)
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	ctx := t.Context()
+	lrs := &logResourceStorage{s: newTestStorage(), entriesPath: layout.EntriesPath}
+
+	if _, err := lrs.ReadCheckpoint(ctx); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("ReadCheckpoint: got %v, want os.ErrNotExist", err)
+	}
+	if _, err := lrs.ReadTile(ctx, 0, 0, 0); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("ReadTile: got %v, want os.ErrNotExist", err)
+	}
+	if _, err := lrs.ReadEntryBundle(ctx, 0, 0); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("ReadEntryBundle: got %v, want os.ErrNotExist", err)
+	}
+	tiles, err := lrs.getTiles(ctx, []storage.TileID{{Level: 0, Index: 0}}, 1)
+	if err != nil {
+		t.Fatalf("getTiles: %v", err)
+	}
+	if tiles[0] != nil {
+		t.Errorf("getTiles returned %v for missing tile, want nil", tiles[0])
+	}
+}
+
+func TestDynamoLocker(t *testing.T) {
+	ctx := t.Context()
+	db := newMemDynamoDB()
+	l1 := &dynamoLocker{client: db, table: "locks", lease: time.Hour}
+	l2 := &dynamoLocker{client: db, table: "locks", lease: time.Hour}
+
+	unlock, err := l1.lock(ctx, "treeState")
+	if err != nil {
+		t.Fatalf("lock: %v", err)
+	}
+	// A different lock name should be independent.
+	unlockOther, err := l2.lock(ctx, "publish")
+	if err != nil {
+		t.Fatalf("lock(other): %v", err)
+	}
+	if err := unlockOther(ctx); err != nil {
+		t.Fatalf("unlock(other): %v", err)
+	}
+
+	// The held lock cannot be taken by another process.
+	cctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := l2.lock(cctx, "treeState"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("lock while held: got %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	if err := unlock(ctx); err != nil {
+		t.Fatalf("unlock: %v", err)
+	}
+	unlock, err = l2.lock(ctx, "treeState")
+	if err != nil {
+		t.Fatalf("lock after release: %v", err)
+	}
+	if err := unlock(ctx); err != nil {
+		t.Fatalf("unlock: %v", err)
+	}
+}
+
+func TestDynamoLockerRenewal(t *testing.T) {
+	ctx := t.Context()
+	db := newMemDynamoDB()
+	l1 := &dynamoLocker{client: db, table: "locks", lease: 30 * time.Millisecond}
+	l2 := &dynamoLocker{client: db, table: "locks", lease: time.Hour}
+
+	unlock, err := l1.lock(ctx, "treeState")
+	if err != nil {
+		t.Fatalf("lock: %v", err)
+	}
+	// The lock is held for several lease durations, but is renewed so cannot be taken by someone else.
+	cctx, cancel := context.WithTimeout(ctx, 150*time.Millisecond)
+	defer cancel()
+	if _, err := l2.lock(cctx, "treeState"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("lock while held: got %v, want %v", err, context.DeadlineExceeded)
+	}
+	if err := unlock(ctx); err != nil {
+		t.Fatalf("unlock: %v", err)
+	}
+}
+
+func TestDynamoLockerExpiry(t *testing.T) {
+	ctx := t.Context()
+	db := newMemDynamoDB()
+	// Simulate the holder stalling, so that its lease is not renewed.
+	db.failUpdates = true
+	l1 := &dynamoLocker{client: db, table: "locks", lease: time.Millisecond}
+	l2 := &dynamoLocker{client: db, table: "locks", lease: time.Hour}
+
+	unlock1, err := l1.lock(ctx, "treeState")
+	if err != nil {
+		t.Fatalf("lock: %v", err)
+	}
+	// Once the lease has expired, the lock may be taken by someone else...
+	time.Sleep(5 * time.Millisecond)
+	unlock2, err := l2.lock(ctx, "treeState")
+	if err != nil {
+		t.Fatalf("lock after expiry: %v", err)
+	}
+	// ... and the original holder must find out that it lost the lock, without releasing it.
+	if err := unlock1(ctx); err == nil {
+		t.Fatal("unlock of expired lock succeeded, want error")
+	}
+	if err := unlock2(ctx); err != nil {
+		t.Fatalf("unlock: %v", err)
+	}
+}
+
+func TestTreeStateFencing(t *testing.T) {
+	ctx := t.Context()
+	s := &Storage{objStore: newMemObjStore()}
+
+	if err := s.writeTreeState(ctx, 0, []byte("root0"), ""); err != nil {
+		t.Fatalf("writeTreeState: %v", err)
+	}
+	// The tree state can only be created once.
+	if err := s.writeTreeState(ctx, 0, []byte("root0"), ""); !errors.Is(err, errPreconditionFailed) {
+		t.Fatalf("second create: got %v, want %v", err, errPreconditionFailed)
+	}
+	_, _, etag, err := s.readTreeStateETag(ctx)
+	if err != nil {
+		t.Fatalf("readTreeStateETag: %v", err)
+	}
+	if err := s.writeTreeState(ctx, 1, []byte("root1"), etag); err != nil {
+		t.Fatalf("writeTreeState: %v", err)
+	}
+	// A writer which read the tree state before it was updated must not be able to overwrite it.
+	if err := s.writeTreeState(ctx, 2, []byte("root2"), etag); !errors.Is(err, errPreconditionFailed) {
+		t.Fatalf("stale write: got %v, want %v", err, errPreconditionFailed)
+	}
+	if size, _, err := s.readTreeState(ctx); err != nil || size != 1 {
+		t.Fatalf("readTreeState: got size %d, err %v, want size 1", size, err)
+	}
+}
+
+func TestNewRejectsShortLease(t *testing.T) {
+	_, err := New(t.Context(), Config{Bucket: "bucket", LockTable: "locks", LockLease: defaultIntegrationTimeout})
+	if err == nil {
+		t.Fatal("New with lease no longer than the integration timeout succeeded, want error")
+	}
+}
+
+type memObjStore struct {
+	sync.RWMutex
+	mem  map[string][]byte
+	mod  map[string]time.Time
+	etag map[string]string
+	gen  int
+}
+
+func newMemObjStore() *memObjStore {
+	return &memObjStore{
+		mem:  make(map[string][]byte),
+		mod:  make(map[string]time.Time),
+		etag: make(map[string]string),
+	}
+}
+
+func (m *memObjStore) getObject(ctx context.Context, obj string) ([]byte, error) {
+	d, _, err := m.getObjectETag(ctx, obj)
+	return d, err
+}
+
+func (m *memObjStore) getObjectETag(_ context.Context, obj string) ([]byte, string, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	d, ok := m.mem[obj]
+	if !ok {
+		return nil, "", fmt.Errorf("obj %q not found: %w", obj, notExist(&types.NoSuchKey{}))
+	}
+	return d, m.etag[obj], nil
+}
+
+func (m *memObjStore) setObject(_ context.Context, obj string, data []byte, _, _ string) error {
+	m.Lock()
+	defer m.Unlock()
+	m.store(obj, data)
+	return nil
+}
+
+func (m *memObjStore) setObjectIfMatch(_ context.Context, obj string, data []byte, _, _ string, etag string) error {
+	m.Lock()
+	defer m.Unlock()
+	if m.etag[obj] != etag {
+		return fmt.Errorf("obj %q has etag %q, want %q: %w", obj, m.etag[obj], etag, errPreconditionFailed)
+	}
+	m.store(obj, data)
+	return nil
+}
+
+func (m *memObjStore) store(obj string, data []byte) {
+	m.gen++
+	m.mem[obj] = data
+	m.mod[obj] = time.Now()
+	m.etag[obj] = strconv.Itoa(m.gen)
+}
+
+func (m *memObjStore) lastModified(_ context.Context, obj string) (time.Time, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	t, ok := m.mod[obj]
+	if !ok {
+		return time.Time{}, fmt.Errorf("obj %q not found: %w", obj, notExist(&types.NotFound{}))
+	}
+	return t, nil
+}
+
+// memLocker is an in-process locker.
+type memLocker struct {
+	mu    sync.Mutex
+	locks map[string]chan struct{}
+}
+
+func newMemLocker() *memLocker {
+	return &memLocker{locks: make(map[string]chan struct{})}
+}
+
+func (m *memLocker) lock(ctx context.Context, name string) (func(context.Context) error, error) {
+	m.mu.Lock()
+	l, ok := m.locks[name]
+	if !ok {
+		l = make(chan struct{}, 1)
+		m.locks[name] = l
+	}
+	m.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case l <- struct{}{}:
+	}
+	return func(context.Context) error {
+		<-l
+		return nil
+	}, nil
+}
+
+// memDynamoDB implements just enough of the DynamoDB API to exercise dynamoLocker.
+type memDynamoDB struct {
+	mu    sync.Mutex
+	items map[string]map[string]dtypes.AttributeValue
+	// failUpdates causes all calls to UpdateItem to fail.
+	failUpdates bool
+}
+
+func newMemDynamoDB() *memDynamoDB {
+	return &memDynamoDB{items: make(map[string]map[string]dtypes.AttributeValue)}
+}
+
+func (m *memDynamoDB) PutItem(_ context.Context, in *dynamodb.PutItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id := in.Item[lockIDAttr].(*dtypes.AttributeValueMemberS).Value
+	if cur, ok := m.items[id]; ok {
+		// Evaluate "attribute_not_exists(#id) OR #expiry < :now".
+		expiry := mustParseN(cur[lockExpiryAttr])
+		now := mustParseN(in.ExpressionAttributeValues[":now"])
+		if expiry >= now {
+			return nil, &dtypes.ConditionalCheckFailedException{}
+		}
+	}
+	m.items[id] = in.Item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (m *memDynamoDB) UpdateItem(_ context.Context, in *dynamodb.UpdateItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.failUpdates {
+		return nil, errors.New("update failed")
+	}
+	id := in.Key[lockIDAttr].(*dtypes.AttributeValueMemberS).Value
+	// Evaluate "#owner = :owner" and apply "SET #expiry = :expiry".
+	cur, ok := m.items[id]
+	if !ok || cur[lockOwnerAttr].(*dtypes.AttributeValueMemberS).Value != in.ExpressionAttributeValues[":owner"].(*dtypes.AttributeValueMemberS).Value {
+		return nil, &dtypes.ConditionalCheckFailedException{}
+	}
+	upd := maps.Clone(cur)
+	upd[lockExpiryAttr] = in.ExpressionAttributeValues[":expiry"]
+	m.items[id] = upd
+	return &dynamodb.UpdateItemOutput{}, nil
+}
+
+func (m *memDynamoDB) DeleteItem(_ context.Context, in *dynamodb.DeleteItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id := in.Key[lockIDAttr].(*dtypes.AttributeValueMemberS).Value
+	// Evaluate "#owner = :owner".
+	cur, ok := m.items[id]
+	if !ok || cur[lockOwnerAttr].(*dtypes.AttributeValueMemberS).Value != in.ExpressionAttributeValues[":owner"].(*dtypes.AttributeValueMemberS).Value {
+		return nil, &dtypes.ConditionalCheckFailedException{}
+	}
+	delete(m.items, id)
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+func mustParseN(v dtypes.AttributeValue) int64 {
+	n, err := strconv.ParseInt(v.(*dtypes.AttributeValueMemberN).Value, 10, 64)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+func mustGenerateKeys(t *testing.T) (note.Signer, note.Verifier) {
+	sk, vk, err := note.GenerateKey(nil, "testlog")
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	s, err := note.NewSigner(sk)
+	if err != nil {
+		t.Fatalf("NewSigner: %v", err)
+	}
+	v, err := note.NewVerifier(vk)
+	if err != nil {
+		t.Fatalf("NewVerifier: %v", err)
+	}
+	return s, v
+}
@@ -0,0 +1,68 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package verify provides helpers for consumers of Tessera logs to verify the checkpoints they publish.
+package verify
+
+import (
+	"fmt"
+
+	"github.com/transparency-dev/formats/log"
+	"golang.org/x/mod/sumdb/note"
+)
+
+// Verifier verifies checkpoints published by a particular log, along with any witness cosignatures
+// which are required on them.
+type Verifier struct {
+	origin      string
+	logKey      note.Verifier
+	witnessKeys []note.Verifier
+}
+
+// CheckpointVerifier returns a Verifier for checkpoints from the log with the given origin, which must be
+// signed by logKey and cosigned by every one of witnessKeys.
+func CheckpointVerifier(origin string, logKey note.Verifier, witnessKeys ...note.Verifier) *Verifier {
+	return &Verifier{
+		origin:      origin,
+		logKey:      logKey,
+		witnessKeys: witnessKeys,
+	}
+}
+
+// VerifyCheckpoint parses the provided note-formatted checkpoint, checks that it has the expected origin and
+// carries all of the required signatures, and returns the tree size and root hash it commits to.
+//
+// Signatures from keys unknown to the Verifier are ignored.
+func (v *Verifier) VerifyCheckpoint(cpRaw []byte) (uint64, []byte, error) {
+	cp, _, n, err := log.ParseCheckpoint(cpRaw, v.origin, v.logKey, v.witnessKeys...)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to parse checkpoint: %v", err)
+	}
+	for _, w := range v.witnessKeys {
+		if !signedBy(n, w) {
+			return 0, nil, fmt.Errorf("checkpoint is not cosigned by witness %q", w.Name())
+		}
+	}
+	return cp.Size, cp.Hash, nil
+}
+
+// signedBy returns true if n carries a verified signature from v.
+func signedBy(n *note.Note, v note.Verifier) bool {
+	for _, s := range n.Sigs {
+		if s.Name == v.Name() && s.Hash == v.KeyHash() {
+			return true
+		}
+	}
+	return false
+}
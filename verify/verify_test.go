@@ -0,0 +1,114 @@
+// Copyright 2025 The Tessera authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/transparency-dev/formats/log"
+	"github.com/transparency-dev/tessera/verify"
+	"golang.org/x/mod/sumdb/note"
+)
+
+func mustGenerateKeys(t *testing.T, name string) (note.Signer, note.Verifier) {
+	t.Helper()
+	skey, vkey, err := note.GenerateKey(nil, name)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	s, err := note.NewSigner(skey)
+	if err != nil {
+		t.Fatalf("NewSigner: %v", err)
+	}
+	v, err := note.NewVerifier(vkey)
+	if err != nil {
+		t.Fatalf("NewVerifier: %v", err)
+	}
+	return s, v
+}
+
+func TestVerifyCheckpoint(t *testing.T) {
+	const origin = "example.com/log"
+	logS, logV := mustGenerateKeys(t, "log")
+	w1S, w1V := mustGenerateKeys(t, "witness1")
+	w2S, w2V := mustGenerateKeys(t, "witness2")
+	otherS, _ := mustGenerateKeys(t, "other")
+
+	root := bytes.Repeat([]byte{0x42}, 32)
+	checkpoint := func(origin string, signers ...note.Signer) []byte {
+		t.Helper()
+		cp := log.Checkpoint{Origin: origin, Size: 123, Hash: root}
+		n, err := note.Sign(&note.Note{Text: string(cp.Marshal())}, signers...)
+		if err != nil {
+			t.Fatalf("Sign: %v", err)
+		}
+		return n
+	}
+
+	for _, test := range []struct {
+		name     string
+		verifier *verify.Verifier
+		cp       []byte
+		wantErr  bool
+	}{
+		{
+			name:     "log only",
+			verifier: verify.CheckpointVerifier(origin, logV),
+			cp:       checkpoint(origin, logS),
+		}, {
+			name:     "with witnesses",
+			verifier: verify.CheckpointVerifier(origin, logV, w1V, w2V),
+			cp:       checkpoint(origin, logS, w1S, w2S),
+		}, {
+			name:     "unknown signatures ignored",
+			verifier: verify.CheckpointVerifier(origin, logV, w1V),
+			cp:       checkpoint(origin, logS, w1S, otherS),
+		}, {
+			name:     "missing witness",
+			verifier: verify.CheckpointVerifier(origin, logV, w1V, w2V),
+			cp:       checkpoint(origin, logS, w1S),
+			wantErr:  true,
+		}, {
+			name:     "missing log signature",
+			verifier: verify.CheckpointVerifier(origin, logV, w1V),
+			cp:       checkpoint(origin, w1S),
+			wantErr:  true,
+		}, {
+			name:     "wrong origin",
+			verifier: verify.CheckpointVerifier(origin, logV),
+			cp:       checkpoint("example.com/other", logS),
+			wantErr:  true,
+		}, {
+			name:     "garbage",
+			verifier: verify.CheckpointVerifier(origin, logV),
+			cp:       []byte("not a checkpoint"),
+			wantErr:  true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			size, gotRoot, err := test.verifier.VerifyCheckpoint(test.cp)
+			if gotErr := err != nil; gotErr != test.wantErr {
+				t.Fatalf("VerifyCheckpoint: %v, wantErr %t", err, test.wantErr)
+			}
+			if test.wantErr {
+				return
+			}
+			if size != 123 || !bytes.Equal(gotRoot, root) {
+				t.Errorf("VerifyCheckpoint = %d, %x, want 123, %x", size, gotRoot, root)
+			}
+		})
+	}
+}